@@ -19,12 +19,21 @@ limitations under the License.
 package main
 
 import (
+	"crypto/x509"
+	"encoding/json"
 	"flag"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"net"
 	"strconv"
+	"time"
 
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/admission"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/audit"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/cloudprovider"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/ipam"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/master"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 )
@@ -32,23 +41,74 @@ import (
 var (
 	port                        = flag.Uint("port", 8080, "The port to listen on.  Default 8080.")
 	address                     = flag.String("address", "127.0.0.1", "The address on the local server to listen to. Default 127.0.0.1")
-	apiPrefix                   = flag.String("api_prefix", "/api/v1beta1", "The prefix for API requests on the server. Default '/api/v1beta1'")
+	apiPrefix                   = flag.String("api_prefix", "/api", "The prefix for API requests on the server. Each version of the API is served under it, e.g. /api/v1beta1, so it must not end in a version. Default '/api'")
+	kubeletPort                 = flag.Uint("kubelet_port", 10250, "The port the kubelets of minions serve on. It must match their -port.")
 	cloudProvider               = flag.String("cloud_provider", "", "The provider for cloud services.  Empty string for no provider.")
+	libvirtBalancerHost         = flag.String("libvirt_balancer_host", "", "The host that runs load balancers for the libvirt cloud provider. Defaults to the first libvirt host.")
+	podNetwork                  = flag.String("pod_network", "", "If non empty, a CIDR (e.g. 10.244.0.0/16) to assign each minion a pod subnet from.")
+	podSubnetPrefix             = flag.Int("pod_subnet_prefix", 24, "The prefix length of the pod subnets assigned to minions from -pod_network.")
+	basicAuthFile               = flag.String("basic_auth_file", "", "If set, a file of 'password,user' lines that authenticates API requests by basic auth.")
+	tokenAuthFile               = flag.String("token_auth_file", "", "If set, a file of 'token,user' lines that authenticates API requests by bearer token.")
+	clientCAFile                = flag.String("client_ca_file", "", "If set, API requests with a client certificate signed by one of the CAs in this file are authenticated as its common name. Requires -tls_cert_file.")
+	policyFile                  = flag.String("authorization_policy_file", "", "If set, a file of JSON policies, one per line, that API requests must match. It is reloaded when it changes.")
+	admissionConfigFile         = flag.String("admission_control_config", "", "If set, a JSON file with the configuration of the -admission_control plugins, by name, e.g. {\"MinHostPort\": {\"min\": 1024}}.")
+	auditLogFile                = flag.String("audit_log_file", "", "If set, the file that requests that change objects are recorded in, as one JSON object per line.")
+	auditLogMaxSize             = flag.Int64("audit_log_max_size", 100, "The size in megabytes the -audit_log_file is rotated at. 0 never rotates it.")
+	auditLogMaxBackups          = flag.Int("audit_log_max_backups", 5, "The number of rotated -audit_log_file files that are kept.")
+	tlsCertFile                 = flag.String("tls_cert_file", "", "If set, the file of the certificate the master serves HTTPS with.")
+	tlsPrivateKeyFile           = flag.String("tls_private_key_file", "", "The file of the private key of -tls_cert_file.")
 	etcdServerList, machineList util.StringList
+	libvirtHostList             util.StringList
+	admissionControlList        util.StringList
 )
 
 func init() {
 	flag.Var(&etcdServerList, "etcd_servers", "Servers for the etcd (http://ip:port), comma separated")
 	flag.Var(&machineList, "machines", "List of machines to schedule onto, comma separated.")
+	flag.Var(&libvirtHostList, "libvirt_hosts", "List of hypervisor hosts for the libvirt cloud provider, comma separated. Defaults to -machines.")
+	flag.Var(&admissionControlList, "admission_control", "Admission plugins that objects must pass before they are created or updated, in order, comma separated: DefaultResources, MinHostPort, ImageRegistries.")
+}
+
+// makeAuthenticator returns the authenticators picked by the auth flags, or nil if there are none.
+func makeAuthenticator() (auth.Authenticator, error) {
+	var union auth.Union
+	if len(*basicAuthFile) > 0 {
+		passwords, err := auth.LoadPasswordFile(*basicAuthFile)
+		if err != nil {
+			return nil, err
+		}
+		union = append(union, auth.BasicAuth{Password: passwords})
+	}
+	if len(*tokenAuthFile) > 0 {
+		tokens, err := auth.LoadTokenFile(*tokenAuthFile)
+		if err != nil {
+			return nil, err
+		}
+		union = append(union, auth.BearerToken{Token: tokens})
+	}
+	if len(*clientCAFile) > 0 {
+		if len(*tlsCertFile) == 0 {
+			return nil, fmt.Errorf("-client_ca_file requires -tls_cert_file")
+		}
+		data, err := ioutil.ReadFile(*clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates in %s", *clientCAFile)
+		}
+		union = append(union, auth.ClientCert{Roots: roots})
+	}
+	if len(union) == 0 {
+		return nil, nil
+	}
+	return union, nil
 }
 
 func main() {
 	flag.Parse()
 
-	if len(machineList) == 0 {
-		log.Fatal("No machines specified!")
-	}
-
 	var cloud cloudprovider.Interface
 	switch *cloudProvider {
 	case "gce":
@@ -57,6 +117,16 @@ func main() {
 		if err != nil {
 			log.Fatal("Couldn't connect to GCE cloud: %#v", err)
 		}
+	case "libvirt":
+		hosts := libvirtHostList
+		if len(hosts) == 0 {
+			hosts = machineList
+		}
+		var err error
+		cloud, err = cloudprovider.NewLibvirtCloud(hosts, *libvirtBalancerHost)
+		if err != nil {
+			log.Fatalf("Couldn't create libvirt cloud: %#v", err)
+		}
 	default:
 		if len(*cloudProvider) > 0 {
 			log.Printf("Unknown cloud provider: %s", *cloudProvider)
@@ -65,12 +135,73 @@ func main() {
 		}
 	}
 
+	if len(machineList) == 0 {
+		log.Fatal("No machines specified!")
+	}
+
+	var podSubnets *ipam.SubnetAllocator
+	if len(*podNetwork) > 0 {
+		_, network, err := net.ParseCIDR(*podNetwork)
+		if err != nil {
+			log.Fatalf("Invalid pod network: %#v", err)
+		}
+		podSubnets, err = ipam.MakeSubnetAllocator(network, *podSubnetPrefix)
+		if err != nil {
+			log.Fatalf("Invalid pod network: %#v", err)
+		}
+	}
+
 	var m *master.Master
 	if len(etcdServerList) > 0 {
-		m = master.New(etcdServerList, machineList, cloud)
+		m = master.New(etcdServerList, machineList, cloud, podSubnets, *kubeletPort)
 	} else {
-		m = master.NewMemoryServer(machineList, cloud)
+		m = master.NewMemoryServer(machineList, cloud, podSubnets, *kubeletPort)
+	}
+
+	authenticator, err := makeAuthenticator()
+	if err != nil {
+		log.Fatalf("Invalid authentication flags: %v", err)
+	}
+	if authenticator == nil {
+		log.Print("No authentication configured, anyone who can reach the master can use the API.")
+	}
+
+	var authorizer auth.Authorizer
+	if len(*policyFile) > 0 {
+		policies, err := auth.LoadPolicyFile(*policyFile)
+		if err != nil {
+			log.Fatalf("Invalid authorization policy file: %v", err)
+		}
+		go util.Forever(func() {
+			if err := policies.Reload(); err != nil {
+				log.Printf("Failed to reload authorization policies: %v", err)
+			}
+		}, 10*time.Second)
+		authorizer = policies
+	}
+
+	var admit admission.Interface
+	if len(admissionControlList) > 0 {
+		var config map[string]json.RawMessage
+		if len(*admissionConfigFile) > 0 {
+			config, err = admission.LoadConfigFile(*admissionConfigFile)
+			if err != nil {
+				log.Fatalf("Invalid admission control config: %v", err)
+			}
+		}
+		admit, err = admission.MakeChain(admissionControlList, config)
+		if err != nil {
+			log.Fatalf("Invalid admission control: %v", err)
+		}
+	}
+
+	var auditSink audit.Sink
+	if len(*auditLogFile) > 0 {
+		auditSink, err = audit.OpenFileSink(*auditLogFile, *auditLogMaxSize*1024*1024, *auditLogMaxBackups)
+		if err != nil {
+			log.Fatalf("Couldn't open audit log: %v", err)
+		}
 	}
 
-	log.Fatal(m.Run(net.JoinHostPort(*address, strconv.Itoa(int(*port))), *apiPrefix))
+	log.Fatal(m.Run(net.JoinHostPort(*address, strconv.Itoa(int(*port))), *apiPrefix, authenticator, authorizer, admit, auditSink, *tlsCertFile, *tlsPrivateKeyFile))
 }
@@ -18,6 +18,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -47,6 +48,9 @@ var (
 	json         = flag.Bool("json", false, "If true, print raw JSON for responses")
 	yaml         = flag.Bool("yaml", false, "If true, print raw YAML for responses")
 	verbose      = flag.Bool("verbose", false, "If true, print extra information")
+	follow       = flag.Bool("f", false, "If true, keep printing new console output, only used with 'console'")
+	container    = flag.String("container", "", "The container to read the console of, only used with 'console'. Defaults to the pod's first container")
+	namespace    = flag.String("namespace", "", "The namespace to work in. If empty, lists span all namespaces and other requests use the default namespace")
 )
 
 func usage() {
@@ -60,6 +64,12 @@ func usage() {
   cloudcfg [OPTIONS] run <image> <replicas> <controller>
   cloudcfg [OPTIONS] resize <controller> <replicas>
 
+  Debug pods:
+  cloudcfg [OPTIONS] [-f] [-container <name>] console <pod>
+
+  Manage pods:
+  cloudcfg [OPTIONS] migrate <pod> <minion>
+
   Options:
 `)
 	flag.PrintDefaults()
@@ -119,7 +129,7 @@ func main() {
 		}
 	}
 
-	matchFound := executeAPIRequest(method, auth) || executeControllerRequest(method, auth)
+	matchFound := executeAPIRequest(method, auth) || executeControllerRequest(method, auth) || executePodRequest(method, auth)
 	if matchFound == false {
 		log.Fatalf("Unknown command %s", method)
 	}
@@ -135,6 +145,9 @@ func executeAPIRequest(method string, auth *kube_client.AuthInfo) bool {
 	}
 
 	readUrl := func(storage string) string {
+		if len(*namespace) > 0 && !strings.HasPrefix(storage, "minions") {
+			storage = path.Join("namespaces", *namespace, storage)
+		}
 		return *httpServer + path.Join("/api/v1beta1", storage)
 	}
 
@@ -193,13 +206,14 @@ func executeControllerRequest(method string, auth *kube_client.AuthInfo) bool {
 	var err error
 	switch method {
 	case "stop":
-		err = cloudcfg.StopController(parseController(), kube_client.Client{Host: *httpServer, Auth: auth})
+		err = cloudcfg.StopController(parseController(), kube_client.Client{Host: *httpServer, Auth: auth, Namespace: *namespace})
 	case "rm":
-		err = cloudcfg.DeleteController(parseController(), kube_client.Client{Host: *httpServer, Auth: auth})
+		err = cloudcfg.DeleteController(parseController(), kube_client.Client{Host: *httpServer, Auth: auth, Namespace: *namespace})
 	case "rollingupdate":
 		client := &kube_client.Client{
-			Host: *httpServer,
-			Auth: auth,
+			Host:      *httpServer,
+			Auth:      auth,
+			Namespace: *namespace,
 		}
 		err = cloudcfg.Update(parseController(), client, *updatePeriod)
 	case "run":
@@ -212,7 +226,7 @@ func executeControllerRequest(method string, auth *kube_client.AuthInfo) bool {
 		if err != nil {
 			log.Fatalf("Error parsing replicas: %#v", err)
 		}
-		err = cloudcfg.RunController(image, name, replicas, kube_client.Client{Host: *httpServer, Auth: auth}, *portSpec, *servicePort)
+		err = cloudcfg.RunController(image, name, replicas, kube_client.Client{Host: *httpServer, Auth: auth, Namespace: *namespace}, *portSpec, *servicePort)
 	case "resize":
 		args := flag.Args()
 		if len(args) < 3 {
@@ -223,7 +237,7 @@ func executeControllerRequest(method string, auth *kube_client.AuthInfo) bool {
 		if err != nil {
 			log.Fatalf("Error parsing replicas: %#v", err)
 		}
-		err = cloudcfg.ResizeController(name, replicas, kube_client.Client{Host: *httpServer, Auth: auth})
+		err = cloudcfg.ResizeController(name, replicas, kube_client.Client{Host: *httpServer, Auth: auth, Namespace: *namespace})
 	default:
 		return false
 	}
@@ -232,3 +246,35 @@ func executeControllerRequest(method string, auth *kube_client.AuthInfo) bool {
 	}
 	return true
 }
+
+// Attempts to execute a pod request
+func executePodRequest(method string, auth *kube_client.AuthInfo) bool {
+	switch method {
+	case "console":
+		if len(flag.Args()) != 2 {
+			log.Fatal("usage: cloudcfg [OPTIONS] [-f] [-container <name>] console <pod>")
+		}
+		client := kube_client.Client{Host: *httpServer, Auth: auth, Namespace: *namespace}
+		console, err := client.GetPodConsole(flag.Arg(1), *container, *follow)
+		if err != nil {
+			log.Fatalf("Error: %#v", err)
+		}
+		defer console.Close()
+		if _, err := io.Copy(os.Stdout, console); err != nil {
+			log.Fatalf("Error reading console: %#v", err)
+		}
+	case "migrate":
+		if len(flag.Args()) != 3 {
+			log.Fatal("usage: cloudcfg [OPTIONS] migrate <pod> <minion>")
+		}
+		client := kube_client.Client{Host: *httpServer, Auth: auth, Namespace: *namespace}
+		pod, err := client.MigratePod(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			log.Fatalf("Error: %#v", err)
+		}
+		fmt.Printf("Migrated %s to %s\n", pod.ID, pod.CurrentState.Host)
+	default:
+		return false
+	}
+	return true
+}
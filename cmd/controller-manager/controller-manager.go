@@ -36,6 +36,9 @@ import (
 var (
 	etcd_servers = flag.String("etcd_servers", "", "Servers for the etcd (http://ip:port).")
 	master       = flag.String("master", "", "The address of the Kubernetes API server")
+	apiToken     = flag.String("api_token", "", "If non-empty, the bearer token the controller manager authenticates to -master with")
+	apiUser      = flag.String("api_user", "", "If non-empty, the user the controller manager authenticates to -master as with basic auth, using -api_password")
+	apiPassword  = flag.String("api_password", "", "The basic auth password of -api_user")
 )
 
 func main() {
@@ -48,10 +51,13 @@ func main() {
 	// Set up logger for etcd client
 	etcd.SetLogger(log.New(os.Stderr, "etcd ", log.LstdFlags))
 
-	controllerManager := registry.MakeReplicationManager(etcd.NewClient([]string{*etcd_servers}),
-		client.Client{
-			Host: "http://" + *master,
-		})
+	kubeClient := client.Client{
+		Host: "http://" + *master,
+	}
+	if *apiToken != "" || *apiUser != "" {
+		kubeClient.Auth = &client.AuthInfo{User: *apiUser, Password: *apiPassword, BearerToken: *apiToken}
+	}
+	controllerManager := registry.MakeReplicationManager(etcd.NewClient([]string{*etcd_servers}), kubeClient)
 
 	go util.Forever(func() { controllerManager.Synchronize() }, 20*time.Second)
 	go util.Forever(func() { controllerManager.WatchControllers() }, 20*time.Second)
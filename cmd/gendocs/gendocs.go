@@ -0,0 +1,53 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// gendocs writes the JSON Schemas and the RAML documentation of the API, generated from the
+// types of the API version they document, into the api directory.
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/schema"
+)
+
+var (
+	out     = flag.String("out", "api", "The api directory to write the documentation into.")
+	version = flag.String("version", api.StorageVersion, "The version of the API to document.")
+)
+
+func main() {
+	flag.Parse()
+
+	files, err := schema.Docs(*version)
+	if err != nil {
+		log.Fatalf("Couldn't generate documentation: %v", err)
+	}
+	for name, data := range files {
+		file := path.Join(*out, name)
+		if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+			log.Fatalf("Couldn't create %s: %v", path.Dir(file), err)
+		}
+		if err := ioutil.WriteFile(file, data, 0644); err != nil {
+			log.Fatalf("Couldn't write %s: %v", file, err)
+		}
+	}
+}
@@ -40,10 +40,11 @@ func main() {
 	etcdClient := etcd.NewClient(servers)
 	machineList := []string{"machine"}
 
-	reg := registry.MakeEtcdRegistry(etcdClient, machineList)
+	minions := registry.MakeMemoryMinionRegistry(machineList)
+	reg := registry.MakeEtcdRegistry(etcdClient, minions)
 
 	apiserver := apiserver.New(map[string]apiserver.RESTStorage{
-		"pods": registry.MakePodRegistryStorage(reg, &client.FakeContainerInfo{}, registry.MakeRoundRobinScheduler(machineList)),
+		"pods": registry.MakePodRegistryStorage(reg, &client.FakeContainerInfo{}, &client.FakePodConsole{}, nil, registry.MakeRoundRobinScheduler(minions)),
 		"replicationControllers": registry.MakeControllerRegistryStorage(reg),
 	}, "/api/v1beta1")
 	server := httptest.NewServer(apiserver)
@@ -15,37 +15,76 @@ limitations under the License.
 */
 // The kubelet binary is responsible for maintaining a set of containers on a particular host VM.
 // It sync's data from both configuration file as well as from a quorum of etcd servers.
-// It then queries Docker to see what is currently running.  It synchronizes the configuration data,
-// with the running set of containers by starting or stopping Docker containers.
+// It then queries its runtime (Docker, or libvirt) to see what is currently running.  It synchronizes the
+// configuration data, with the running set of containers by starting or stopping Docker containers or VMs.
 package main
 
 import (
 	"flag"
 	"log"
 	"math/rand"
+	"net"
+	"net/http"
 	"os"
 	"os/exec"
+	"strings"
 	"time"
 
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/libvirt"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/coreos/go-etcd/etcd"
 	"github.com/fsouza/go-dockerclient"
 )
 
 var (
-	file               = flag.String("config", "", "Path to the config file")
-	etcdServers        = flag.String("etcd_servers", "", "Url of etcd servers in the cluster")
-	syncFrequency      = flag.Duration("sync_frequency", 10*time.Second, "Max period between synchronizing running containers and config")
-	fileCheckFrequency = flag.Duration("file_check_frequency", 20*time.Second, "Duration between checking file for new data")
-	httpCheckFrequency = flag.Duration("http_check_frequency", 20*time.Second, "Duration between checking http for new data")
-	manifestUrl        = flag.String("manifest_url", "", "URL for accessing the container manifest")
-	address            = flag.String("address", "127.0.0.1", "The address for the info server to serve on")
-	port               = flag.Uint("port", 10250, "The port for the info server to serve on")
-	hostnameOverride   = flag.String("hostname_override", "", "If non-empty, will use this string as identification instead of the actual hostname.")
+	file                = flag.String("config", "", "Path to the config file")
+	etcdServers         = flag.String("etcd_servers", "", "Url of etcd servers in the cluster")
+	syncFrequency       = flag.Duration("sync_frequency", 10*time.Second, "Max period between synchronizing running containers and config")
+	fileCheckFrequency  = flag.Duration("file_check_frequency", 20*time.Second, "Duration between checking file for new data")
+	httpCheckFrequency  = flag.Duration("http_check_frequency", 20*time.Second, "Duration between checking http for new data")
+	manifestUrl         = flag.String("manifest_url", "", "URL for accessing the container manifest")
+	address             = flag.String("address", "127.0.0.1", "The address for the info server to serve on")
+	port                = flag.Uint("port", 10250, "The port for the info server to serve on")
+	hostnameOverride    = flag.String("hostname_override", "", "If non-empty, will use this string as identification instead of the actual hostname.")
+	runtime             = flag.String("runtime", "docker", "The runtime to run pods with, 'docker' or 'libvirt'")
+	libvirtURI          = flag.String("libvirt_uri", "qemu:///system", "The libvirt connection URI, only used with -runtime=libvirt")
+	libvirtImageDir     = flag.String("libvirt_image_dir", "/var/lib/kubelet/images", "Directory that caches VM base images, only used with -runtime=libvirt")
+	libvirtImageURL     = flag.String("libvirt_image_url", "", "Base URL that VM images which aren't URLs themselves are fetched from, only used with -runtime=libvirt")
+	imageGCFrequency    = flag.Duration("image_gc_frequency", time.Hour, "Duration between removing cached VM images that no pod uses, only used with -runtime=libvirt")
+	libvirtDiskDir      = flag.String("libvirt_disk_dir", "/var/lib/kubelet/disks", "Directory for the root disks of running VMs, only used with -runtime=libvirt")
+	libvirtMigrationURI = flag.String("libvirt_migration_uri", "qemu+ssh://%s/system", "The libvirt URI of the host that VMs are migrated to, with %s in place of its name, only used with -runtime=libvirt")
+	podNetworkName      = flag.String("pod_network", "", "If non-empty, the libvirt network that gives each VM its own IP, only used with -runtime=libvirt")
+	podBridge           = flag.String("pod_bridge", "virbr-pods", "The bridge device of -pod_network, if the kubelet defines it")
+	podCIDR             = flag.String("pod_cidr", "", "The pod subnet of -pod_network. If empty, the subnet the master assigns to this host is used")
+	podLeaseFile        = flag.String("pod_lease_file", "/var/lib/kubelet/pod_leases.json", "File that keeps the pod IP leases of -pod_network")
+	apiServer           = flag.String("api_server", "", "If non-empty, the http://host:port of the master to register with and send heartbeats to")
+	heartbeatFrequency  = flag.Duration("heartbeat_frequency", 10*time.Second, "Duration between heartbeats to the master")
+	apiToken            = flag.String("api_token", "", "If non-empty, the bearer token the kubelet authenticates to -api_server with")
 )
 
 const dockerBinary = "/usr/bin/docker"
 
+// hostIP returns the address the master should use to reach this host: the serving address if it
+// is a specific one, otherwise an address of 'hostname'.
+func hostIP(hostname string) string {
+	if ip := net.ParseIP(*address); ip != nil && !ip.IsUnspecified() && !ip.IsLoopback() {
+		return ip.String()
+	}
+	ips, err := net.LookupIP(hostname)
+	if err != nil {
+		log.Printf("Couldn't look up an address for %s: %v", hostname, err)
+		return ""
+	}
+	for _, ip := range ips {
+		if ip.To4() != nil && !ip.IsLoopback() {
+			return ip.String()
+		}
+	}
+	return ""
+}
+
 func main() {
 	flag.Parse()
 	rand.Seed(time.Now().UTC().UnixNano())
@@ -53,14 +92,42 @@ func main() {
 	// Set up logger for etcd client
 	etcd.SetLogger(log.New(os.Stderr, "etcd ", log.LstdFlags))
 
-	endpoint := "unix:///var/run/docker.sock"
-	dockerClient, err := docker.NewClient(endpoint)
-	if err != nil {
-		log.Fatal("Couldn't connnect to docker.")
+	var podRuntime kubelet.Runtime
+	var podNetwork *kubelet.PodNetwork
+	switch *runtime {
+	case "docker":
+		endpoint := "unix:///var/run/docker.sock"
+		dockerClient, err := docker.NewClient(endpoint)
+		if err != nil {
+			log.Fatal("Couldn't connnect to docker.")
+		}
+		podRuntime = kubelet.MakeDockerRuntime(dockerClient)
+	case "libvirt":
+		fetcher := &kubelet.HTTPImageFetcher{Client: http.DefaultClient, BaseURL: *libvirtImageURL}
+		images := kubelet.MakeImageStore(*libvirtImageDir, fetcher, kubelet.QEMUImgOverlayCreator{})
+		go util.Forever(func() {
+			time.Sleep(*imageGCFrequency)
+			if _, err := images.GarbageCollect(); err != nil {
+				log.Printf("Error collecting images: %v", err)
+			}
+		}, 0)
+		conn := libvirt.MakeVirshConnection(*libvirtURI)
+		if len(*podNetworkName) > 0 {
+			podNetwork = kubelet.MakePodNetwork(conn, *podNetworkName, *podBridge, *podLeaseFile)
+			if len(*podCIDR) > 0 {
+				if err := podNetwork.Configure(*podCIDR); err != nil {
+					log.Fatalf("Couldn't configure the pod network: %v", err)
+				}
+			}
+		}
+		podRuntime = kubelet.MakeLibvirtRuntime(conn, images, kubelet.GenisoimageConfigDriveWriter{}, podNetwork, *libvirtDiskDir, *libvirtMigrationURI)
+	default:
+		log.Fatalf("Unknown runtime: %s", *runtime)
 	}
 
 	hostname := []byte(*hostnameOverride)
 	if string(hostname) == "" {
+		var err error
 		hostname, err = exec.Command("hostname", "-f").Output()
 		if err != nil {
 			log.Fatalf("Couldn't determine hostname: %v", err)
@@ -69,10 +136,25 @@ func main() {
 
 	my_kubelet := kubelet.Kubelet{
 		Hostname:           string(hostname),
-		DockerClient:       dockerClient,
+		Runtime:            podRuntime,
+		PodNetwork:         podNetwork,
 		FileCheckFrequency: *fileCheckFrequency,
 		SyncFrequency:      *syncFrequency,
 		HTTPCheckFrequency: *httpCheckFrequency,
+		HeartbeatFrequency: *heartbeatFrequency,
+	}
+	if *apiServer != "" {
+		capacity, err := kubelet.MachineCapacity()
+		if err != nil {
+			log.Printf("Couldn't determine machine capacity: %v", err)
+		}
+		master := client.Client{Host: *apiServer}
+		if *apiToken != "" {
+			master.Auth = &client.AuthInfo{BearerToken: *apiToken}
+		}
+		my_kubelet.Master = master
+		my_kubelet.HostIP = hostIP(strings.TrimSpace(string(hostname)))
+		my_kubelet.Capacity = capacity
 	}
 	my_kubelet.RunKubelet(*file, *manifestUrl, *etcdServers, *address, *port)
 }
@@ -52,7 +52,7 @@ var (
 var (
 	master_port    = flag.Uint("master_port", 8080, "The port for the master to listen on.  Default 8080.")
 	master_address = flag.String("master_address", "127.0.0.1", "The address for the master to listen to. Default 127.0.0.1")
-	apiPrefix      = flag.String("api_prefix", "/api/v1beta1", "The prefix for API requests on the server. Default '/api/v1beta1'")
+	apiPrefix      = flag.String("api_prefix", "/api", "The prefix for API requests on the server. Each version of the API is served under it, e.g. /api/v1beta1, so it must not end in a version. Default '/api'")
 )
 
 // flags that affect both
@@ -70,18 +70,21 @@ func fake_kubelet() {
 
 	my_kubelet := kubelet.Kubelet{
 		Hostname:           *kubelet_address,
-		DockerClient:       dockerClient,
+		HostIP:             *kubelet_address,
+		Master:             client.Client{Host: fmt.Sprintf("http://%s:%d", *master_address, *master_port)},
+		Runtime:            kubelet.MakeDockerRuntime(dockerClient),
 		FileCheckFrequency: *fileCheckFrequency,
 		SyncFrequency:      *syncFrequency,
 		HTTPCheckFrequency: *httpCheckFrequency,
+		HeartbeatFrequency: 10 * time.Second,
 	}
 	my_kubelet.RunKubelet(*file, *manifest_url, *etcd_server, *kubelet_address, *kubelet_port)
 }
 
 // Starts api services (the master). Never returns.
 func api_server() {
-	m := master.New([]string{*etcd_server}, []string{*kubelet_address}, nil)
-	log.Fatal(m.Run(net.JoinHostPort(*master_address, strconv.Itoa(int(*master_port))), *apiPrefix))
+	m := master.New([]string{*etcd_server}, []string{*kubelet_address}, nil, nil, *kubelet_port)
+	log.Fatal(m.Run(net.JoinHostPort(*master_address, strconv.Itoa(int(*master_port))), *apiPrefix, nil, nil, nil, nil, "", ""))
 }
 
 // Starts up a controller manager. Never returns.
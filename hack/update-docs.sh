@@ -0,0 +1,25 @@
+#!/bin/bash
+
+# Copyright 2014 Google Inc. All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# This script regenerates the JSON Schemas and RAML documentation in api/ from the API types.
+
+set -e
+
+source $(dirname $0)/config-go.sh
+
+cd "${KUBE_TARGET}"
+
+go run "${KUBE_GO_PACKAGE_DIR}"/cmd/gendocs/gendocs.go -out "${KUBE_REPO_ROOT}/api"
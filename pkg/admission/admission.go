@@ -0,0 +1,130 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth"
+)
+
+// Attributes describe an object the apiserver is about to store.
+type Attributes struct {
+	// User made the request. It is nil if the request was not authenticated.
+	User *auth.User
+	// Verb is how the object is stored: "create", "update" or "patch".
+	Verb string
+	// Namespace is the namespace of the request, as it is authorized.
+	Namespace string
+	// Resource is the kind of object, such as "pods".
+	Resource string
+	// Object is the internal api object that is stored. Plugins that change it replace it
+	// with a changed copy.
+	Object interface{}
+}
+
+// Interface is an admission plugin. Admit returns an error that says why if the object in 'a'
+// may not be stored. It may replace a.Object with a changed copy to store instead.
+type Interface interface {
+	Admit(a *Attributes) error
+}
+
+// Chain admits the objects that all of its plugins admit. The plugins are run in order, and
+// see the changes of the ones before them.
+type Chain []Interface
+
+func (chain Chain) Admit(a *Attributes) error {
+	for _, plugin := range chain {
+		if err := plugin.Admit(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Factory makes a plugin from its configuration, which is nil if there is none.
+type Factory func(config json.RawMessage) (Interface, error)
+
+// plugins are the plugins that can be named in a chain.
+var plugins = map[string]Factory{
+	"DefaultResources": MakeDefaultResources,
+	"MinHostPort":      MakeMinHostPort,
+	"ImageRegistries":  MakeImageRegistries,
+}
+
+// MakeChain makes the chain of the plugins 'names', in order. 'config' holds the configuration
+// of each plugin, by name.
+func MakeChain(names []string, config map[string]json.RawMessage) (Chain, error) {
+	chain := Chain{}
+	for _, name := range names {
+		factory, ok := plugins[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown admission plugin: %q", name)
+		}
+		plugin, err := factory(config[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		chain = append(chain, plugin)
+	}
+	return chain, nil
+}
+
+// LoadConfigFile reads the configuration of plugins from the file 'path': a JSON object with a
+// member for each plugin that is configured.
+func LoadConfigFile(path string) (map[string]json.RawMessage, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return config, nil
+}
+
+// updateContainers calls 'update' with a copy of the containers of the pod or pod template in
+// 'a', and replaces a.Object with a copy that has the containers 'update' left. Objects that
+// have no containers are left alone.
+func updateContainers(a *Attributes, update func(containers []api.Container) error) error {
+	switch obj := a.Object.(type) {
+	case api.Pod:
+		if err := updateManifest(&obj.DesiredState.Manifest, update); err != nil {
+			return err
+		}
+		a.Object = obj
+	case api.ReplicationController:
+		if err := updateManifest(&obj.DesiredState.PodTemplate.DesiredState.Manifest, update); err != nil {
+			return err
+		}
+		a.Object = obj
+	}
+	return nil
+}
+
+func updateManifest(manifest *api.ContainerManifest, update func(containers []api.Container) error) error {
+	containers := append([]api.Container{}, manifest.Containers...)
+	if err := update(containers); err != nil {
+		return err
+	}
+	manifest.Containers = containers
+	return nil
+}
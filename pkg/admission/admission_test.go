@@ -0,0 +1,144 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// recordingPlugin records the objects it sees, and renames pods to its name.
+type recordingPlugin struct {
+	name string
+	seen []interface{}
+	err  error
+}
+
+func (r *recordingPlugin) Admit(a *Attributes) error {
+	r.seen = append(r.seen, a.Object)
+	if pod, ok := a.Object.(api.Pod); ok {
+		pod.ID = r.name
+		a.Object = pod
+	}
+	return r.err
+}
+
+func TestChain(t *testing.T) {
+	first := &recordingPlugin{name: "first"}
+	second := &recordingPlugin{name: "second"}
+	a := &Attributes{Verb: "create", Resource: "pods", Object: api.Pod{JSONBase: api.JSONBase{ID: "foo"}}}
+	if err := (Chain{first, second}).Admit(a); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if a.Object.(api.Pod).ID != "second" || second.seen[0].(api.Pod).ID != "first" {
+		t.Errorf("Unexpected admission: %#v %#v", a.Object, second.seen)
+	}
+}
+
+func TestChainRejects(t *testing.T) {
+	first := &recordingPlugin{name: "first", err: fmt.Errorf("no")}
+	second := &recordingPlugin{name: "second"}
+	a := &Attributes{Verb: "create", Resource: "pods", Object: api.Pod{}}
+	if err := (Chain{first, second}).Admit(a); err == nil {
+		t.Errorf("Expected an error")
+	}
+	if len(second.seen) != 0 {
+		t.Errorf("Unexpected admission after a rejection: %#v", second.seen)
+	}
+}
+
+func TestMakeChain(t *testing.T) {
+	config := map[string]json.RawMessage{
+		"DefaultResources": json.RawMessage(`{"memory": 1024}`),
+		"MinHostPort":      json.RawMessage(`{"min": 8000}`),
+	}
+	chain, err := MakeChain([]string{"MinHostPort", "DefaultResources"}, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := Chain{&MinHostPort{Min: 8000}, &DefaultResources{Memory: 1024}}
+	if !reflect.DeepEqual(chain, expected) {
+		t.Errorf("Unexpected chain: %#v", chain)
+	}
+
+	if _, err := MakeChain([]string{"Unknown"}, nil); err == nil {
+		t.Errorf("Expected an error for an unknown plugin")
+	}
+	if _, err := MakeChain([]string{"ImageRegistries"}, nil); err == nil {
+		t.Errorf("Expected an error for a plugin without its configuration")
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	file, err := ioutil.TempFile("", "admission")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.Remove(file.Name())
+	fmt.Fprint(file, `{"ImageRegistries": {"registries": ["registry.example.com"]}}`)
+	file.Close()
+
+	config, err := LoadConfigFile(file.Name())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	chain, err := MakeChain([]string{"ImageRegistries"}, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := Chain{&ImageRegistries{Registries: []string{"registry.example.com"}}}
+	if !reflect.DeepEqual(chain, expected) {
+		t.Errorf("Unexpected chain: %#v", chain)
+	}
+}
+
+func TestUpdateContainers(t *testing.T) {
+	controller := api.ReplicationController{}
+	controller.DesiredState.PodTemplate.DesiredState.Manifest.Containers = []api.Container{{Name: "foo"}}
+	a := &Attributes{Object: controller}
+	err := updateContainers(a, func(containers []api.Container) error {
+		containers[0].Image = "bar"
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	containers := a.Object.(api.ReplicationController).DesiredState.PodTemplate.DesiredState.Manifest.Containers
+	if len(containers) != 1 || containers[0].Image != "bar" {
+		t.Errorf("Unexpected containers: %#v", containers)
+	}
+	if controller.DesiredState.PodTemplate.DesiredState.Manifest.Containers[0].Image != "" {
+		t.Errorf("Unexpected change of the original: %#v", controller)
+	}
+
+	minion := api.Minion{JSONBase: api.JSONBase{ID: "foo"}}
+	a = &Attributes{Object: minion}
+	called := false
+	updateContainers(a, func(containers []api.Container) error {
+		called = true
+		return nil
+	})
+	if called || !reflect.DeepEqual(a.Object, minion) {
+		t.Errorf("Unexpected update of an object without containers: %#v", a.Object)
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// DefaultResources gives containers that do not ask for memory or CPU the configured amounts,
+// in the units of api.Container. Zero amounts are not defaulted.
+type DefaultResources struct {
+	Memory int `json:"memory,omitempty"`
+	CPU    int `json:"cpu,omitempty"`
+}
+
+// MakeDefaultResources makes a DefaultResources plugin from a configuration such as
+// {"memory": 536870912, "cpu": 1000}.
+func MakeDefaultResources(config json.RawMessage) (Interface, error) {
+	plugin := &DefaultResources{}
+	if config != nil {
+		if err := json.Unmarshal(config, plugin); err != nil {
+			return nil, err
+		}
+	}
+	if plugin.Memory <= 0 && plugin.CPU <= 0 {
+		return nil, fmt.Errorf("no default memory or cpu is configured")
+	}
+	return plugin, nil
+}
+
+func (d *DefaultResources) Admit(a *Attributes) error {
+	return updateContainers(a, func(containers []api.Container) error {
+		for i := range containers {
+			if containers[i].Memory == 0 {
+				containers[i].Memory = d.Memory
+			}
+			if containers[i].CPU == 0 {
+				containers[i].CPU = d.CPU
+			}
+		}
+		return nil
+	})
+}
+
+// MinHostPort rejects containers that ask for host ports below Min, which are usually reserved
+// for the services of the host itself.
+type MinHostPort struct {
+	Min int `json:"min"`
+}
+
+// defaultMinHostPort is the lowest host port MinHostPort allows if none is configured: the first
+// port that is not privileged.
+const defaultMinHostPort = 1024
+
+// MakeMinHostPort makes a MinHostPort plugin from a configuration such as {"min": 1024}.
+func MakeMinHostPort(config json.RawMessage) (Interface, error) {
+	plugin := &MinHostPort{Min: defaultMinHostPort}
+	if config != nil {
+		if err := json.Unmarshal(config, plugin); err != nil {
+			return nil, err
+		}
+	}
+	return plugin, nil
+}
+
+func (m *MinHostPort) Admit(a *Attributes) error {
+	return updateContainers(a, func(containers []api.Container) error {
+		for _, container := range containers {
+			for _, port := range container.Ports {
+				if port.HostPort != 0 && port.HostPort < m.Min {
+					return fmt.Errorf("container %q asks for host port %d, host ports below %d are not allowed", container.Name, port.HostPort, m.Min)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// ImageRegistries only admits containers whose images come from one of Registries. The registry
+// of a Docker image is the host its name starts with, such as "registry.example.com:5000", and
+// the registry of a VM image URL is its scheme and host, such as "https://images.example.com".
+// Images without a registry, which come from the Docker index or the kubelet's image server,
+// have the registry "".
+type ImageRegistries struct {
+	Registries []string `json:"registries"`
+}
+
+// MakeImageRegistries makes an ImageRegistries plugin from a configuration such as
+// {"registries": ["registry.example.com:5000", "https://images.example.com"]}.
+func MakeImageRegistries(config json.RawMessage) (Interface, error) {
+	plugin := &ImageRegistries{}
+	if config != nil {
+		if err := json.Unmarshal(config, plugin); err != nil {
+			return nil, err
+		}
+	}
+	if len(plugin.Registries) == 0 {
+		return nil, fmt.Errorf("no registries are configured")
+	}
+	return plugin, nil
+}
+
+// imageRegistry returns the registry 'image' comes from.
+func imageRegistry(image string) string {
+	if strings.Contains(image, "://") {
+		if parsed, err := url.Parse(image); err == nil {
+			return parsed.Scheme + "://" + parsed.Host
+		}
+		return image
+	}
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[0]
+	}
+	return ""
+}
+
+func (r *ImageRegistries) Admit(a *Attributes) error {
+	return updateContainers(a, func(containers []api.Container) error {
+		for _, container := range containers {
+			registry := imageRegistry(container.Image)
+			allowed := false
+			for _, candidate := range r.Registries {
+				if candidate == registry {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				return fmt.Errorf("container %q uses image %q, which is not from an allowed registry", container.Name, container.Image)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+func makePod(containers ...api.Container) api.Pod {
+	pod := api.Pod{JSONBase: api.JSONBase{ID: "foo"}}
+	pod.DesiredState.Manifest.Containers = containers
+	return pod
+}
+
+func TestDefaultResources(t *testing.T) {
+	plugin := &DefaultResources{Memory: 1024, CPU: 100}
+	a := &Attributes{Object: makePod(api.Container{Name: "a"}, api.Container{Name: "b", Memory: 5, CPU: 7})}
+	if err := plugin.Admit(a); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	containers := a.Object.(api.Pod).DesiredState.Manifest.Containers
+	if containers[0].Memory != 1024 || containers[0].CPU != 100 || containers[1].Memory != 5 || containers[1].CPU != 7 {
+		t.Errorf("Unexpected containers: %#v", containers)
+	}
+
+	if _, err := MakeDefaultResources(nil); err == nil {
+		t.Errorf("Expected an error without defaults")
+	}
+}
+
+func TestMinHostPort(t *testing.T) {
+	plugin, err := MakeMinHostPort(nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	table := []struct {
+		ports []api.Port
+		ok    bool
+	}{
+		{nil, true},
+		{[]api.Port{{ContainerPort: 80}}, true},
+		{[]api.Port{{ContainerPort: 80, HostPort: 8080}}, true},
+		{[]api.Port{{ContainerPort: 80, HostPort: 8080}, {ContainerPort: 22, HostPort: 22}}, false},
+		{[]api.Port{{ContainerPort: 80, HostPort: 1023}}, false},
+	}
+	for _, item := range table {
+		err := plugin.Admit(&Attributes{Object: makePod(api.Container{Name: "a", Ports: item.ports})})
+		if (err == nil) != item.ok {
+			t.Errorf("Unexpected admission of %#v: %v", item.ports, err)
+		}
+	}
+}
+
+func TestImageRegistries(t *testing.T) {
+	plugin := &ImageRegistries{Registries: []string{"registry.example.com:5000", "https://images.example.com"}}
+	table := []struct {
+		image string
+		ok    bool
+	}{
+		{"registry.example.com:5000/foo", true},
+		{"registry.example.com:5000/team/foo:v1", true},
+		{"https://images.example.com/disks/foo.qcow2", true},
+		{"http://images.example.com/disks/foo.qcow2", false},
+		{"registry.example.com/foo", false},
+		{"ubuntu", false},
+		{"team/foo", false},
+	}
+	for _, item := range table {
+		err := plugin.Admit(&Attributes{Object: makePod(api.Container{Name: "a", Image: item.image})})
+		if (err == nil) != item.ok {
+			t.Errorf("Unexpected admission of %s: %v", item.image, err)
+		}
+	}
+}
+
+func TestImageRegistry(t *testing.T) {
+	table := map[string]string{
+		"ubuntu":                         "",
+		"dockerfile/nginx":               "",
+		"localhost/foo":                  "localhost",
+		"localhost:5000/foo":             "localhost:5000",
+		"registry.example.com/team/foo":  "registry.example.com",
+		"https://images.example.com/foo": "https://images.example.com",
+		"fedora.qcow2":                   "",
+	}
+	for image, expected := range table {
+		if registry := imageRegistry(image); registry != expected {
+			t.Errorf("Unexpected registry of %s: %q, expected %q", image, registry, expected)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/v1beta1"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/v1beta2"
+)
+
+func init() {
+	AddKnownTypes("v1beta1",
+		v1beta1.PodList{},
+		v1beta1.Pod{},
+		v1beta1.ReplicationControllerList{},
+		v1beta1.ReplicationController{},
+		v1beta1.ServiceList{},
+		v1beta1.Service{},
+		v1beta1.MinionList{},
+		v1beta1.Minion{},
+		v1beta1.Status{},
+		v1beta1.Operation{},
+		v1beta1.OperationList{},
+	)
+	AddKnownTypes("v1beta2",
+		v1beta2.PodList{},
+		v1beta2.Pod{},
+		v1beta2.ReplicationControllerList{},
+		v1beta2.ReplicationController{},
+		v1beta2.ServiceList{},
+		v1beta2.Service{},
+		v1beta2.MinionList{},
+		v1beta2.Minion{},
+		v1beta2.Status{},
+		v1beta2.Operation{},
+		v1beta2.OperationList{},
+	)
+	err := AddConversionFuncs(
+		// v1beta2 renamed ReplicasInSet to ReplicaSelector.
+		func(in *ReplicationControllerState, out *v1beta2.ReplicationControllerState) error {
+			out.Replicas = in.Replicas
+			if err := Convert(&in.ReplicasInSet, &out.ReplicaSelector); err != nil {
+				return err
+			}
+			return Convert(&in.PodTemplate, &out.PodTemplate)
+		},
+		func(in *v1beta2.ReplicationControllerState, out *ReplicationControllerState) error {
+			out.Replicas = in.Replicas
+			if err := Convert(&in.ReplicaSelector, &out.ReplicasInSet); err != nil {
+				return err
+			}
+			return Convert(&in.PodTemplate, &out.PodTemplate)
+		},
+		// v1beta2 renamed the Labels of a service, which select its pods, to Selector.
+		func(in *Service, out *v1beta2.Service) error {
+			if err := Convert(&in.JSONBase, &out.JSONBase); err != nil {
+				return err
+			}
+			out.Port = in.Port
+			out.CreateExternalLoadBalancer = in.CreateExternalLoadBalancer
+			return Convert(&in.Labels, &out.Selector)
+		},
+		func(in *v1beta2.Service, out *Service) error {
+			if err := Convert(&in.JSONBase, &out.JSONBase); err != nil {
+				return err
+			}
+			out.Port = in.Port
+			out.CreateExternalLoadBalancer = in.CreateExternalLoadBalancer
+			return Convert(&in.Selector, &out.Labels)
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type typePair struct {
+	source reflect.Type
+	dest   reflect.Type
+}
+
+// Converter copies objects between types that have the same shape, such as an internal type
+// and the same type in a version of the API. Structs are copied field by field, by name, and
+// pairs of types that differ are copied by conversion functions registered for them.
+type Converter struct {
+	funcs map[typePair]reflect.Value
+}
+
+// MakeConverter makes a Converter without conversion functions.
+func MakeConverter() *Converter {
+	return &Converter{
+		funcs: map[typePair]reflect.Value{},
+	}
+}
+
+// Register adds a conversion function, which must be of the form
+// func(in *A, out *B) error. It is called whenever an A is converted to a B.
+func (c *Converter) Register(conversionFunc interface{}) error {
+	fv := reflect.ValueOf(conversionFunc)
+	ft := fv.Type()
+	if ft.Kind() != reflect.Func || ft.NumIn() != 2 || ft.NumOut() != 1 {
+		return fmt.Errorf("expected func(in *A, out *B) error, got %v", ft)
+	}
+	if ft.In(0).Kind() != reflect.Ptr || ft.In(1).Kind() != reflect.Ptr {
+		return fmt.Errorf("expected pointer arguments, got %v", ft)
+	}
+	var err error
+	if ft.Out(0) != reflect.TypeOf(&err).Elem() {
+		return fmt.Errorf("expected an error result, got %v", ft)
+	}
+	c.funcs[typePair{ft.In(0).Elem(), ft.In(1).Elem()}] = fv
+	return nil
+}
+
+// Convert copies what 'src' points to into what 'dest' points to.
+func (c *Converter) Convert(src, dest interface{}) error {
+	sv := reflect.ValueOf(src)
+	dv := reflect.ValueOf(dest)
+	if sv.Kind() != reflect.Ptr || dv.Kind() != reflect.Ptr {
+		return fmt.Errorf("expected pointers, got %v and %v", sv.Type(), dv.Type())
+	}
+	return c.convert(sv.Elem(), dv.Elem())
+}
+
+func (c *Converter) convert(sv, dv reflect.Value) error {
+	st, dt := sv.Type(), dv.Type()
+	if fv, ok := c.funcs[typePair{st, dt}]; ok {
+		if !sv.CanAddr() {
+			// Map keys and values can't be addressed, so the function gets a copy.
+			copied := reflect.New(st).Elem()
+			copied.Set(sv)
+			sv = copied
+		}
+		result := fv.Call([]reflect.Value{sv.Addr(), dv.Addr()})[0]
+		if result.IsNil() {
+			return nil
+		}
+		return result.Interface().(error)
+	}
+	if st.Kind() != dt.Kind() {
+		return fmt.Errorf("can't convert %v to %v", st, dt)
+	}
+	switch st.Kind() {
+	case reflect.Struct:
+		if st.NumField() != dt.NumField() {
+			return fmt.Errorf("can't convert %v to %v: they have different fields", st, dt)
+		}
+		for i := 0; i < dt.NumField(); i++ {
+			name := dt.Field(i).Name
+			field, ok := st.FieldByName(name)
+			if !ok || len(field.Index) != 1 {
+				return fmt.Errorf("%v has no field %s to convert to %v", st, name, dt)
+			}
+			if err := c.convert(sv.FieldByIndex(field.Index), dv.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		if sv.IsNil() {
+			dv.Set(reflect.Zero(dt))
+			return nil
+		}
+		dv.Set(reflect.MakeSlice(dt, sv.Len(), sv.Len()))
+		for i := 0; i < sv.Len(); i++ {
+			if err := c.convert(sv.Index(i), dv.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if sv.IsNil() {
+			dv.Set(reflect.Zero(dt))
+			return nil
+		}
+		dv.Set(reflect.MakeMap(dt))
+		for _, sk := range sv.MapKeys() {
+			dk := reflect.New(dt.Key()).Elem()
+			if err := c.convert(sk, dk); err != nil {
+				return err
+			}
+			dElem := reflect.New(dt.Elem()).Elem()
+			if err := c.convert(sv.MapIndex(sk), dElem); err != nil {
+				return err
+			}
+			dv.SetMapIndex(dk, dElem)
+		}
+	case reflect.Ptr:
+		if sv.IsNil() {
+			dv.Set(reflect.Zero(dt))
+			return nil
+		}
+		dv.Set(reflect.New(dt.Elem()))
+		return c.convert(sv.Elem(), dv.Elem())
+	case reflect.Interface:
+		// The value of an interface field, such as PodState.Info, has no version.
+		dv.Set(sv)
+	default:
+		if !st.ConvertibleTo(dt) {
+			return fmt.Errorf("can't convert %v to %v", st, dt)
+		}
+		dv.Set(sv.Convert(dt))
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func expectNoError(t *testing.T, err error) {
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
+
+type convertA struct {
+	Name   string
+	Count  int
+	Tags   map[string]string
+	Items  []convertItemA
+	Next   *convertItemA
+	Info   interface{}
+	Status MinionCondition
+}
+
+type convertItemA struct {
+	Value string
+}
+
+type convertB struct {
+	Name   string
+	Count  int
+	Tags   map[string]string
+	Items  []convertItemB
+	Next   *convertItemB
+	Info   interface{}
+	Status string
+}
+
+type convertItemB struct {
+	Text string
+}
+
+func TestConverter(t *testing.T) {
+	c := MakeConverter()
+	err := c.Register(func(in *convertItemA, out *convertItemB) error {
+		out.Text = "converted " + in.Value
+		return nil
+	})
+	expectNoError(t, err)
+	in := convertA{
+		Name:   "foo",
+		Count:  3,
+		Tags:   map[string]string{"a": "b"},
+		Items:  []convertItemA{{"x"}, {"y"}},
+		Next:   &convertItemA{"z"},
+		Info:   []int{1},
+		Status: MinionReady,
+	}
+	var out convertB
+	expectNoError(t, c.Convert(&in, &out))
+	expected := convertB{
+		Name:   "foo",
+		Count:  3,
+		Tags:   map[string]string{"a": "b"},
+		Items:  []convertItemB{{"converted x"}, {"converted y"}},
+		Next:   &convertItemB{"converted z"},
+		Info:   []int{1},
+		Status: "Ready",
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, out)
+	}
+
+	var empty convertB
+	expectNoError(t, c.Convert(&convertA{}, &empty))
+	if !reflect.DeepEqual(empty, convertB{}) {
+		t.Errorf("Expected nil fields to stay nil, got %#v", empty)
+	}
+}
+
+func TestConverterErrors(t *testing.T) {
+	c := MakeConverter()
+	// Without a conversion function, the fields of the items don't match.
+	if err := c.Convert(&convertA{Items: []convertItemA{{"x"}}}, &convertB{}); err == nil {
+		t.Errorf("Expected an error")
+	}
+	if err := c.Convert(convertA{}, &convertB{}); err == nil {
+		t.Errorf("Expected an error for a non-pointer")
+	}
+	if err := c.Register(func(in convertItemA, out *convertItemB) error { return nil }); err == nil {
+		t.Errorf("Expected an error for a non-pointer argument")
+	}
+	if err := c.Register(func(in *convertItemA, out *convertItemB) {}); err == nil {
+		t.Errorf("Expected an error for a function without an error result")
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// StorageVersion is the version of the API objects are stored in, and the version of encoded
+// objects that don't say which version they are in.
+const StorageVersion = "v1beta1"
+
+// Versions are the versions of the API, oldest first.
+var Versions = []string{}
+
+// knownTypes holds the types of each version of the API, by name. Each of them is the
+// version's form of the internal type of the same name.
+var knownTypes = map[string]map[string]reflect.Type{}
+
+var converter = MakeConverter()
+
+// AddKnownTypes adds 'version' to the versions of the API, with the given types. They are
+// converted from and to the internal types of the same names.
+func AddKnownTypes(version string, types ...interface{}) {
+	known, ok := knownTypes[version]
+	if !ok {
+		known = map[string]reflect.Type{}
+		knownTypes[version] = known
+		Versions = append(Versions, version)
+	}
+	for _, obj := range types {
+		t := reflect.TypeOf(obj)
+		known[t.Name()] = t
+	}
+}
+
+// AddConversionFuncs registers functions that convert between types whose fields differ.
+// See Converter.Register.
+func AddConversionFuncs(conversionFuncs ...interface{}) error {
+	for _, f := range conversionFuncs {
+		if err := converter.Register(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Convert copies what 'src' points to into what 'dest' points to, which is of the same type
+// in another version. Conversion functions use it to convert the fields that don't differ.
+func Convert(src, dest interface{}) error {
+	return converter.Convert(src, dest)
+}
+
+// KnownTypes returns the types of API 'version' by name, or nil if there is no such version.
+func KnownTypes(version string) map[string]reflect.Type {
+	known, ok := knownTypes[version]
+	if !ok {
+		return nil
+	}
+	types := map[string]reflect.Type{}
+	for name, t := range known {
+		types[name] = t
+	}
+	return types
+}
+
+// externalType returns the type of 'version' that internal type 't' is converted to, or nil
+// if 't' has no versions.
+func externalType(t reflect.Type, version string) (reflect.Type, error) {
+	if t.PkgPath() != reflect.TypeOf(JSONBase{}).PkgPath() {
+		return nil, nil
+	}
+	known, ok := knownTypes[version]
+	if !ok {
+		return nil, fmt.Errorf("unknown api version %q", version)
+	}
+	return known[t.Name()], nil
+}
+
+// Encode returns the JSON of 'obj', an internal object, in the StorageVersion.
+func Encode(obj interface{}) ([]byte, error) {
+	return EncodeVersion(obj, StorageVersion)
+}
+
+// EncodeVersion returns the JSON of 'obj', an internal object, in 'version', which it
+// records in the apiVersion of the object. Objects of types that have no versions, such as
+// container manifests, are encoded as they are.
+func EncodeVersion(obj interface{}, version string) ([]byte, error) {
+	v := reflect.ValueOf(obj)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Kind() == reflect.Ptr {
+		return json.Marshal(obj)
+	}
+	external, err := externalType(v.Type(), version)
+	if err != nil {
+		return nil, err
+	}
+	if external == nil {
+		return json.Marshal(obj)
+	}
+	out := reflect.New(external)
+	if err := converter.convert(v, out.Elem()); err != nil {
+		return nil, err
+	}
+	setAPIVersion(out.Elem(), version)
+	return json.Marshal(out.Interface())
+}
+
+// DecodeInto decodes the JSON in 'data' into 'objPtr', which points to an internal object.
+// The JSON is in the version its apiVersion names, or in the StorageVersion if it has none.
+func DecodeInto(data []byte, objPtr interface{}) error {
+	return DecodeVersionInto(data, StorageVersion, objPtr)
+}
+
+// DecodeVersionInto is DecodeInto for JSON that is in 'version' if its apiVersion is empty.
+func DecodeVersionInto(data []byte, version string, objPtr interface{}) error {
+	v := reflect.ValueOf(objPtr)
+	if v.Kind() != reflect.Ptr {
+		return fmt.Errorf("expected a pointer, got %v", v.Type())
+	}
+	var base struct {
+		APIVersion string `json:"apiVersion"`
+	}
+	if err := json.Unmarshal(data, &base); err == nil && len(base.APIVersion) != 0 {
+		version = base.APIVersion
+	}
+	external, err := externalType(v.Elem().Type(), version)
+	if err != nil {
+		return err
+	}
+	if external == nil {
+		return json.Unmarshal(data, objPtr)
+	}
+	in := reflect.New(external)
+	if err := json.Unmarshal(data, in.Interface()); err != nil {
+		return err
+	}
+	if err := converter.convert(in.Elem(), v.Elem()); err != nil {
+		return err
+	}
+	setAPIVersion(v.Elem(), "")
+	return nil
+}
+
+func setAPIVersion(v reflect.Value, version string) {
+	field := v.FieldByName("APIVersion")
+	if field.IsValid() && field.CanSet() && field.Kind() == reflect.String {
+		field.SetString(version)
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func samplePod() Pod {
+	return Pod{
+		JSONBase: JSONBase{ID: "foo", Kind: "cluster#pod", ResourceVersion: 7},
+		Labels:   map[string]string{"name": "foo"},
+		DesiredState: PodState{
+			Manifest: ContainerManifest{
+				Version:    "v1beta1",
+				Volumes:    []Volume{{Name: "data"}},
+				Containers: []Container{{Name: "web", Image: "dockerfile/nginx", Ports: []Port{{ContainerPort: 80}}}},
+			},
+		},
+		CurrentState: PodState{Host: "machine", PodIP: "10.244.1.2"},
+	}
+}
+
+func sampleObjects() []interface{} {
+	pod := samplePod()
+	controller := ReplicationController{
+		JSONBase: JSONBase{ID: "foo"},
+		DesiredState: ReplicationControllerState{
+			Replicas:      2,
+			ReplicasInSet: map[string]string{"name": "foo"},
+			PodTemplate:   PodTemplate{DesiredState: pod.DesiredState, Labels: pod.Labels},
+		},
+	}
+	service := Service{JSONBase: JSONBase{ID: "foo"}, Port: 8080, Labels: map[string]string{"name": "foo"}}
+	minion := Minion{JSONBase: JSONBase{ID: "machine"}, Condition: MinionReady, Capacity: MinionResources{Memory: 1024}}
+	return []interface{}{
+		&pod,
+		&PodList{Items: []Pod{pod}},
+		&controller,
+		&ReplicationControllerList{Items: []ReplicationController{controller}},
+		&service,
+		&ServiceList{Items: []Service{service}},
+		&minion,
+		&MinionList{Items: []Minion{minion}},
+		&Operation{JSONBase: JSONBase{ID: "1"}, Status: StatusWorking},
+		&OperationList{Items: []Operation{{JSONBase: JSONBase{ID: "1"}, Status: StatusSuccess}}},
+		&Status{Status: StatusFailure, Reason: StatusReasonInvalid, Details: &StatusDetails{Causes: []StatusCause{{Field: "id"}}}},
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	for _, version := range Versions {
+		for _, obj := range sampleObjects() {
+			data, err := EncodeVersion(obj, version)
+			expectNoError(t, err)
+			if !strings.Contains(string(data), `"apiVersion":"`+version+`"`) {
+				t.Errorf("Expected %s to be in %s", string(data), version)
+			}
+			out := reflect.New(reflect.TypeOf(obj).Elem()).Interface()
+			expectNoError(t, DecodeInto(data, out))
+			if !reflect.DeepEqual(obj, out) {
+				t.Errorf("Expected %#v, got %#v", obj, out)
+			}
+		}
+	}
+}
+
+func TestVersionsAreRegistered(t *testing.T) {
+	if !reflect.DeepEqual(Versions, []string{"v1beta1", "v1beta2"}) {
+		t.Errorf("Unexpected versions: %v", Versions)
+	}
+	for _, version := range Versions {
+		for _, obj := range sampleObjects() {
+			external, err := externalType(reflect.TypeOf(obj).Elem(), version)
+			if err != nil || external == nil {
+				t.Errorf("Expected %T to have a type in %s: %v", obj, version, err)
+			}
+		}
+	}
+}
+
+func TestKnownTypes(t *testing.T) {
+	types := KnownTypes("v1beta2")
+	if types["Pod"] == nil || types["Pod"].PkgPath() != "github.com/GoogleCloudPlatform/kubernetes/pkg/api/v1beta2" {
+		t.Errorf("Unexpected types: %#v", types)
+	}
+	delete(types, "Pod")
+	if KnownTypes("v1beta2")["Pod"] == nil {
+		t.Errorf("Expected the known types not to change")
+	}
+	if types := KnownTypes("v0"); types != nil {
+		t.Errorf("Unexpected types: %#v", types)
+	}
+}
+
+func TestEncodeV1beta2(t *testing.T) {
+	objects := sampleObjects()
+	data, err := EncodeVersion(objects[2], "v1beta2")
+	expectNoError(t, err)
+	var controller map[string]interface{}
+	expectNoError(t, json.Unmarshal(data, &controller))
+	state := controller["desiredState"].(map[string]interface{})
+	if _, ok := state["replicasInSet"]; ok || state["replicaSelector"] == nil {
+		t.Errorf("Unexpected controller: %s", string(data))
+	}
+
+	data, err = EncodeVersion(objects[4], "v1beta2")
+	expectNoError(t, err)
+	if string(data) != `{"id":"foo","apiVersion":"v1beta2","port":8080,"selector":{"name":"foo"}}` {
+		t.Errorf("Unexpected service: %s", string(data))
+	}
+}
+
+func TestDecodeDefaultVersion(t *testing.T) {
+	var service Service
+	expectNoError(t, DecodeVersionInto([]byte(`{"id":"foo","selector":{"name":"foo"}}`), "v1beta2", &service))
+	if service.ID != "foo" || service.Labels["name"] != "foo" || service.APIVersion != "" {
+		t.Errorf("Unexpected service: %#v", service)
+	}
+	// The apiVersion of the object wins over the default.
+	service = Service{}
+	expectNoError(t, DecodeVersionInto([]byte(`{"id":"foo","apiVersion":"v1beta1","labels":{"name":"foo"}}`), "v1beta2", &service))
+	if service.Labels["name"] != "foo" {
+		t.Errorf("Unexpected service: %#v", service)
+	}
+	// Without a default, objects are in the StorageVersion.
+	service = Service{}
+	expectNoError(t, DecodeInto([]byte(`{"id":"foo","labels":{"name":"foo"}}`), &service))
+	if service.Labels["name"] != "foo" {
+		t.Errorf("Unexpected service: %#v", service)
+	}
+}
+
+func TestDecodeUnknownVersion(t *testing.T) {
+	var pod Pod
+	if err := DecodeInto([]byte(`{"id":"foo","apiVersion":"v0"}`), &pod); err == nil {
+		t.Errorf("Expected an error")
+	}
+	if _, err := EncodeVersion(&pod, "v0"); err == nil {
+		t.Errorf("Expected an error")
+	}
+}
+
+func TestEncodeUnversioned(t *testing.T) {
+	manifests := []ContainerManifest{{Id: "foo"}}
+	data, err := Encode(manifests)
+	expectNoError(t, err)
+	expected, _ := json.Marshal(manifests)
+	if string(data) != string(expected) {
+		t.Errorf("Expected %s, got %s", string(expected), string(data))
+	}
+	var out []ContainerManifest
+	expectNoError(t, DecodeInto(data, &out))
+	if !reflect.DeepEqual(manifests, out) {
+		t.Errorf("Expected %#v, got %#v", manifests, out)
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// StatusError is an error that carries the Status of a failed call. RESTStorage implementations
+// return it to pick the status of their response, and the client returns it for failed calls.
+type StatusError struct {
+	Status Status
+}
+
+func (e *StatusError) Error() string {
+	return e.Status.Message
+}
+
+func makeStatusError(reason StatusReason, code int, kind, id, message string) error {
+	return &StatusError{Status{
+		Status:  StatusFailure,
+		Message: message,
+		Reason:  reason,
+		Details: &StatusDetails{ID: id, Kind: kind},
+		Code:    code,
+	}}
+}
+
+// NewNotFound returns an error saying that the object 'id' of 'kind' does not exist.
+func NewNotFound(kind, id string) error {
+	return makeStatusError(StatusReasonNotFound, http.StatusNotFound, kind, id, fmt.Sprintf("%s %q not found", kind, id))
+}
+
+// NewAlreadyExists returns an error saying that the object 'id' of 'kind' can't be created
+// because it exists.
+func NewAlreadyExists(kind, id string) error {
+	return makeStatusError(StatusReasonAlreadyExists, http.StatusConflict, kind, id, fmt.Sprintf("%s %q already exists", kind, id))
+}
+
+// NewConflict returns an error saying that 'err' kept a call on the object 'id' of 'kind' from
+// succeeding in its current state.
+func NewConflict(kind, id string, err error) error {
+	return makeStatusError(StatusReasonConflict, http.StatusConflict, kind, id, fmt.Sprintf("%s %q cannot be changed: %v", kind, id, err))
+}
+
+// NewInvalid returns an error saying that the object 'id' of 'kind' in a request is malformed.
+// 'id' is empty if the object could not be read.
+func NewInvalid(kind, id string, err error) error {
+	if len(id) == 0 {
+		return makeStatusError(StatusReasonInvalid, 422, kind, id, fmt.Sprintf("%s is invalid: %v", kind, err))
+	}
+	return makeStatusError(StatusReasonInvalid, 422, kind, id, fmt.Sprintf("%s %q is invalid: %v", kind, id, err))
+}
+
+// NewInvalidFields returns an Invalid error that lists the problems with the fields of the
+// object 'id' of 'kind' as the causes of its Status.
+func NewInvalidFields(kind, id string, causes []StatusCause) error {
+	messages := make([]string, len(causes))
+	for ix, cause := range causes {
+		messages[ix] = fmt.Sprintf("%s: %s", cause.Field, cause.Message)
+	}
+	err := NewInvalid(kind, id, errors.New(strings.Join(messages, "; ")))
+	err.(*StatusError).Status.Details.Causes = causes
+	return err
+}
+
+// NewInternalError returns an error saying that 'err' kept the server from completing a call.
+func NewInternalError(err error) error {
+	return &StatusError{Status{
+		Status:  StatusFailure,
+		Message: fmt.Sprintf("internal error: %v", err),
+		Reason:  StatusReasonInternalError,
+		Code:    http.StatusInternalServerError,
+	}}
+}
+
+// NewTimeout returns an error saying that the operation 'id' did not finish in time.
+func NewTimeout(kind, id string) error {
+	return makeStatusError(StatusReasonTimeout, http.StatusGatewayTimeout, kind, id, fmt.Sprintf("%s %q timed out", kind, id))
+}
+
+// NewUnauthorized returns an error saying that a request did not carry valid credentials.
+func NewUnauthorized(message string) error {
+	return &StatusError{Status{
+		Status:  StatusFailure,
+		Message: message,
+		Reason:  StatusReasonUnauthorized,
+		Code:    http.StatusUnauthorized,
+	}}
+}
+
+// NewForbidden returns an error saying that 'err' keeps the user of a request from calling 'verb'
+// on 'kind'.
+func NewForbidden(kind, verb string, err error) error {
+	return makeStatusError(StatusReasonForbidden, http.StatusForbidden, kind, "", fmt.Sprintf("%s on %s is forbidden: %v", verb, kind, err))
+}
+
+// NewMethodNotAllowed returns an error saying that 'verb' is not supported for objects of 'kind'.
+func NewMethodNotAllowed(kind, verb string) error {
+	return makeStatusError(StatusReasonMethodNotAllowed, http.StatusMethodNotAllowed, kind, "", fmt.Sprintf("%s is not supported for %s", verb, kind))
+}
+
+// ErrorToStatus returns the Status of a call that failed with 'err'. Errors that aren't
+// StatusErrors are internal errors.
+func ErrorToStatus(err error) Status {
+	if statusErr, ok := err.(*StatusError); ok {
+		return statusErr.Status
+	}
+	return NewInternalError(err).(*StatusError).Status
+}
+
+func reasonForError(err error) StatusReason {
+	if statusErr, ok := err.(*StatusError); ok {
+		return statusErr.Status.Reason
+	}
+	return ""
+}
+
+// IsNotFound returns true if 'err' says that an object does not exist.
+func IsNotFound(err error) bool {
+	return reasonForError(err) == StatusReasonNotFound
+}
+
+// IsAlreadyExists returns true if 'err' says that an object to create already exists.
+func IsAlreadyExists(err error) bool {
+	return reasonForError(err) == StatusReasonAlreadyExists
+}
+
+// IsConflict returns true if 'err' says that a call conflicted with the state of an object.
+func IsConflict(err error) bool {
+	return reasonForError(err) == StatusReasonConflict
+}
+
+// IsInvalid returns true if 'err' says that a request was malformed.
+func IsInvalid(err error) bool {
+	return reasonForError(err) == StatusReasonInvalid
+}
+
+// IsTimeout returns true if 'err' says that an operation did not finish in time.
+func IsTimeout(err error) bool {
+	return reasonForError(err) == StatusReasonTimeout
+}
+
+// IsUnauthorized returns true if 'err' says that a request did not carry valid credentials.
+func IsUnauthorized(err error) bool {
+	return reasonForError(err) == StatusReasonUnauthorized
+}
+
+// IsForbidden returns true if 'err' says that the user of a request is not allowed to make it.
+func IsForbidden(err error) bool {
+	return reasonForError(err) == StatusReasonForbidden
+}
+
+// IsMethodNotAllowed returns true if 'err' says that a request's method is not supported.
+func IsMethodNotAllowed(err error) bool {
+	return reasonForError(err) == StatusReasonMethodNotAllowed
+}
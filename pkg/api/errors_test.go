@@ -0,0 +1,82 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestStatusErrors(t *testing.T) {
+	table := []struct {
+		err    error
+		reason StatusReason
+		code   int
+		test   func(error) bool
+	}{
+		{NewNotFound("pod", "foo"), StatusReasonNotFound, 404, IsNotFound},
+		{NewAlreadyExists("pod", "foo"), StatusReasonAlreadyExists, 409, IsAlreadyExists},
+		{NewConflict("pod", "foo", fmt.Errorf("busy")), StatusReasonConflict, 409, IsConflict},
+		{NewInvalid("pod", "foo", fmt.Errorf("no id")), StatusReasonInvalid, 422, IsInvalid},
+		{NewInternalError(fmt.Errorf("etcd down")), StatusReasonInternalError, 500, nil},
+		{NewTimeout("operation", "1"), StatusReasonTimeout, 504, IsTimeout},
+		{NewUnauthorized("no credentials"), StatusReasonUnauthorized, 401, IsUnauthorized},
+		{NewForbidden("pods", "delete", fmt.Errorf("read only")), StatusReasonForbidden, 403, IsForbidden},
+		{NewMethodNotAllowed("pods", "patch"), StatusReasonMethodNotAllowed, 405, IsMethodNotAllowed},
+	}
+	for _, item := range table {
+		status := ErrorToStatus(item.err)
+		if status.Status != StatusFailure || status.Reason != item.reason || status.Code != item.code {
+			t.Errorf("Unexpected status: %#v", status)
+		}
+		if item.test != nil && !item.test(item.err) {
+			t.Errorf("Expected %v to have reason %s", item.err, item.reason)
+		}
+		if IsNotFound(item.err) != (item.reason == StatusReasonNotFound) {
+			t.Errorf("Unexpected IsNotFound for %v", item.err)
+		}
+	}
+}
+
+func TestErrorToStatusUntyped(t *testing.T) {
+	status := ErrorToStatus(fmt.Errorf("etcd down"))
+	if status.Reason != StatusReasonInternalError || status.Code != 500 || status.Message != "internal error: etcd down" {
+		t.Errorf("Unexpected status: %#v", status)
+	}
+	if IsNotFound(fmt.Errorf("not found")) {
+		t.Errorf("Expected untyped errors to have no reason")
+	}
+}
+
+func TestNewInvalidFields(t *testing.T) {
+	causes := []StatusCause{
+		{Type: CauseTypeFieldValueRequired, Message: "required value", Field: "id"},
+		{Type: CauseTypeFieldValueInvalid, Message: "invalid value '0'", Field: "port"},
+	}
+	err := NewInvalidFields("service", "foo", causes)
+	if !IsInvalid(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	status := ErrorToStatus(err)
+	if status.Code != 422 || status.Details == nil || !reflect.DeepEqual(status.Details.Causes, causes) {
+		t.Errorf("Unexpected status: %#v", status)
+	}
+	if status.Message != `service "foo" is invalid: id: required value; port: invalid value '0'` {
+		t.Errorf("Unexpected message: %s", status.Message)
+	}
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"text/template"
+)
+
+// Resource is a collection of objects served by the API.
+type Resource struct {
+	// Path is the storage key the collection is served at, e.g. "pods".
+	Path string
+	// Name is what an object of the collection is called in documentation. The schema of the
+	// objects is doc/${name}-schema.json, and their examples are in examples/${name}.json.
+	Name        string
+	Kind        string
+	ListKind    string
+	Description string
+	// Examples is set if the resource has examples.
+	Examples bool
+	// Namespaced is set if the objects are in a namespace.
+	Namespaced bool
+}
+
+// Resources are the collections documented by Docs.
+var Resources = []Resource{
+	{
+		Path:        "pods",
+		Name:        "pod",
+		Kind:        "Pod",
+		ListKind:    "PodList",
+		Description: "Pod resource. A pod corresponds to a co-located group of containers, run as [Docker containers](http://docker.io) or as libvirt domains.",
+		Examples:    true,
+		Namespaced:  true,
+	},
+	{
+		Path:        "replicationControllers",
+		Name:        "controller",
+		Kind:        "ReplicationController",
+		ListKind:    "ReplicationControllerList",
+		Description: "A replicationController resource. A replicationController helps to create and manage a set of pods. It acts as a factory to create new pods based on a template. It ensures that there are a specific number of pods running. If fewer pods are running than `replicas` then the needed pods are generated using `podTemplate`. If more pods are running than `replicas`, then excess pods are deleted.",
+		Examples:    true,
+		Namespaced:  true,
+	},
+	{
+		Path:        "services",
+		Name:        "service",
+		Kind:        "Service",
+		ListKind:    "ServiceList",
+		Description: "A service resource. A service is a load-balanced target for the pods with its labels.",
+		Examples:    true,
+		Namespaced:  true,
+	},
+	{
+		Path:        "minions",
+		Name:        "minion",
+		Kind:        "Minion",
+		ListKind:    "MinionList",
+		Description: "A minion resource. A minion is a machine that pods are scheduled onto.",
+		Examples:    true,
+	},
+}
+
+// Docs returns the files that document API 'version', by their path under api/: the schemas
+// of the objects and lists of each of Resources and of Status in doc/, and kubernetes.raml.
+func Docs(version string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	add := func(name, kind, description string) error {
+		schema, err := ForKind(version, kind)
+		if err != nil {
+			return err
+		}
+		schema.Description = description
+		data, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return err
+		}
+		files["doc/"+name+"-schema.json"] = append(data, '\n')
+		return nil
+	}
+	for _, resource := range Resources {
+		if err := add(resource.Name, resource.Kind, resource.Description); err != nil {
+			return nil, err
+		}
+		if err := add(resource.Name+"-list", resource.ListKind, "A list of "+resource.Path+"."); err != nil {
+			return nil, err
+		}
+	}
+	if err := add("status", "Status", "The result of a call that does not return an object, and the body of every failed call."); err != nil {
+		return nil, err
+	}
+	var raml bytes.Buffer
+	err := ramlTemplate.Execute(&raml, struct {
+		Version   string
+		Resources []Resource
+	}{version, Resources})
+	if err != nil {
+		return nil, err
+	}
+	files["kubernetes.raml"] = raml.Bytes()
+	return files, nil
+}
+
+var ramlTemplate = template.Must(template.New("raml").Parse(`#%RAML 0.8
+baseUri: http://server/api/{version}
+title: Kubernetes
+version: {{.Version}}
+mediaType: application/json
+documentation:
+ - title: Overview
+   content: |
+     The Kubernetes API currently manages 4 main resources: ` + "`pods`" + `,
+     ` + "`replicationControllers`, `services` and `minions`" + `. Pods correspond to
+     colocated groups of [Docker containers](http://docker.io) or
+     libvirt domains with shared volumes, as supported by [Google Cloud
+     Platform's container-vm
+     images](https://developers.google.com/compute/docs/containers).
+     Singleton pods can be created directly via the ` + "`/pods`" + `
+     endpoint. Sets of pods may created, maintained, and scaled using
+     replicationControllers.  Services create load-balanced targets
+     for sets of pods. Minions are the machines pods run on.
+
+ - title: Resource identifiers
+   content: |
+     Each resource has a string ` + "`id`" + ` and list of key-value
+     ` + "`labels`" + `. The ` + "`id`" + ` is unique among the resources of its
+     kind in its namespace. ` + "`labels`" + `
+     is a map of string (key) to string (value). Each resource may
+     have at most one label with a particular key. Individual labels
+     are used to specify identifying metadata that can be used to
+     define sets of resources by specifying required labels. Examples
+     of typical pod label keys include ` + "`stage`, `service`, `name`" + `,
+     ` + "`tier`, `partition`, and `track`" + `, but you are free to develop
+     your own conventions.
+
+ - title: Namespaces
+   content: |
+     Pods, replicationControllers and services are in a namespace,
+     and are served under ` + "`/namespaces/{namespace}`" + `, e.g.
+     ` + "`/namespaces/{namespace}/pods`" + `. Outside of a namespace,
+     lists span every namespace, creates and updates are in the
+     namespace of their object, and other calls are in the ` + "`default`" + `
+     namespace. Minions are not in a namespace.
+
+ - title: Media types
+   content: |
+     Objects are sent and received as JSON. Request bodies may be
+     sent as YAML instead, with a ` + "`Content-Type`" + ` of
+     ` + "`application/yaml`" + `, and responses are YAML for requests
+     that prefer it in their ` + "`Accept`" + ` header. Watches are always
+     streamed as JSON.
+
+ - title: Creation semantics
+   content: |
+     Creation is currently not idempotent. We plan to add a
+     modification token to each resource. A unique value for the token
+     should be provided by the user during creation. If the user
+     specifies a duplicate token at creation time, the system should
+     return an error with a pointer to the exiting resource with that
+     token. In this way a user can deterministically recover from a
+     dropped connection during a resource creation request.
+
+ - title: Update semantics
+   content: |
+     Custom verbs are minimized and are used only for 'edge triggered'
+     actions such as a reboot. Resource descriptions are generally set
+     up with ` + "`desiredState`" + ` for the user provided parameters and
+     ` + "`currentState`" + ` for the actual system state. While consistent
+     terminology is used across these two stanzas they do not match
+     member for member.
+
+     When a new version of a resource is PUT the ` + "`desiredState`" + ` is
+     updated and available immediately. Over time the system will work
+     to bring the ` + "`currentState`" + ` into line with the ` + "`desiredState`" + `. The
+     system will drive toward the most recent ` + "`desiredState`" + ` regardless
+     of previous versions of that stanza. In other words, if a value
+     is changed from 2 to 5 in one PUT and then back down to 3 in
+     another PUT the system isn't required to 'touch base' at 5 before
+     making 3 the ` + "`currentState`" + `.
+
+     When doing an update, we assume that the entire ` + "`desiredState`" + `
+     stanza is specified. If a field is omitted it is assumed that the
+     user is looking to delete that field. It is viable for a user to
+     GET the resource, modify what they like in the ` + "`desiredState`" + ` or
+     labels stanzas and then PUT it back. If the ` + "`currentState`" + ` is
+     included in the PUT it will be silently ignored. To change only
+     some fields, PATCH the resource with a JSON merge patch of them.
+
+     Concurrent modification is handled with optimistic locking of
+     resources. Each resource has a ` + "`resourceVersion`" + `. If it is
+     included in a PUT the system verifies that there haven't been
+     other successful mutations to the resource during a
+     read/modify/write cycle, and fails with a 409 Conflict if there
+     were. The correct client action at this point is to GET the
+     resource again, apply the changes afresh and try submitting again.
+{{range .Resources}}
+/{{.Path}}:
+  get:
+    description: List all {{.Path}} on this cluster
+    responses:
+      200:
+        body:
+          schema: !include doc/{{.Name}}-list-schema.json{{if .Examples}}
+          example: !include examples/{{.Name}}-list.json{{end}}
+  post:
+    description: Create a new {{.Name}}
+    body:
+      schema: !include doc/{{.Name}}-schema.json{{if .Examples}}
+      example: !include examples/{{.Name}}.json{{end}}
+
+  /{{"{"}}{{.Name}}Id{{"}"}}:
+    get:
+      description: Get a specific {{.Name}}
+      responses:
+        200:
+          body:
+            schema: !include doc/{{.Name}}-schema.json{{if .Examples}}
+            example: !include examples/{{.Name}}.json{{end}}
+    put:
+      description: Update a {{.Name}}
+      body:
+        schema: !include doc/{{.Name}}-schema.json{{if .Examples}}
+        example: !include examples/{{.Name}}.json{{end}}
+    patch:
+      description: Update the fields of a {{.Name}} that are set in a JSON merge patch
+    delete:
+      description: Delete a specific {{.Name}}
+      responses:
+        200:
+          body:
+            schema: !include doc/status-schema.json
+{{end}}`))
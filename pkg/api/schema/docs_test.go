@@ -0,0 +1,86 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// apiDir is the directory of the documentation and examples of the API.
+const apiDir = "../../../api"
+
+// exampleKinds are the kinds of the files in api/examples.
+var exampleKinds = map[string]string{
+	"pod.json":              "Pod",
+	"pod-list.json":         "PodList",
+	"controller.json":       "ReplicationController",
+	"controller-list.json":  "ReplicationControllerList",
+	"service.json":          "Service",
+	"external-service.json": "Service",
+	"service-list.json":     "ServiceList",
+	"minion.json":           "Minion",
+	"minion-list.json":      "MinionList",
+}
+
+func TestExamplesAreValid(t *testing.T) {
+	files, err := ioutil.ReadDir(path.Join(apiDir, "examples"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, file := range files {
+		kind, ok := exampleKinds[file.Name()]
+		if !ok {
+			t.Errorf("Unexpected example %s, add it to exampleKinds", file.Name())
+			continue
+		}
+		schema, err := ForKind(api.StorageVersion, kind)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		data, err := ioutil.ReadFile(path.Join(apiDir, "examples", file.Name()))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var example interface{}
+		if err := json.Unmarshal(data, &example); err != nil {
+			t.Errorf("Unexpected error in %s: %v", file.Name(), err)
+			continue
+		}
+		for _, err := range Validate(schema, example) {
+			t.Errorf("Unexpected error in %s: %v", file.Name(), err)
+		}
+	}
+}
+
+func TestDocsAreUpToDate(t *testing.T) {
+	files, err := Docs(api.StorageVersion)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for name, expected := range files {
+		data, err := ioutil.ReadFile(path.Join(apiDir, name))
+		if err != nil || !bytes.Equal(data, expected) {
+			t.Errorf("Expected api/%s to be generated from the API types, run hack/update-docs.sh: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// Draft03 identifies the version of JSON Schema that schemas are written in.
+const Draft03 = "http://json-schema.org/draft-03/schema"
+
+// Schema is a JSON Schema of an API type, or of one of its fields.
+type Schema struct {
+	Schema      string `json:"$schema,omitempty" yaml:"$schema,omitempty"`
+	Type        string `json:"type,omitempty" yaml:"type,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Properties are the schemas of the fields of an object.
+	Properties map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	// AdditionalProperties is the schema of the values of a map, or false if an object may not
+	// have fields other than its Properties.
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+	// Items is the schema of the items of an array.
+	Items *Schema `json:"items,omitempty" yaml:"items,omitempty"`
+}
+
+// ForKind returns the schema of the type named 'kind' in API 'version'.
+func ForKind(version, kind string) (*Schema, error) {
+	t, ok := api.KnownTypes(version)[kind]
+	if !ok {
+		return nil, fmt.Errorf("no kind %q in api version %q", kind, version)
+	}
+	schema := ForType(t)
+	schema.Schema = Draft03
+	return schema, nil
+}
+
+// ForType returns the schema of the JSON encoding of values of 't'. Fields are described by
+// their "description" tags.
+func ForType(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return ForType(t.Elem())
+	case reflect.Struct:
+		schema := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: false}
+		addFields(schema, t)
+		return schema
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: ForType(t.Elem())}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: ForType(t.Elem())}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	}
+	// An interface{} holds any value.
+	return &Schema{}
+}
+
+// addFields adds the fields of struct type 't' to the properties of 'schema', following the
+// rules of encoding/json. The fields of embedded structs without a name are inlined.
+func addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) != 0 && !field.Anonymous {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && len(name) == 0 && field.Type.Kind() == reflect.Struct {
+			addFields(schema, field.Type)
+			continue
+		}
+		if len(name) == 0 {
+			name = field.Name
+		}
+		property := ForType(field.Type)
+		property.Description = field.Tag.Get("description")
+		schema.Properties[name] = property
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+type inner struct {
+	Name string `json:"name" description:"The name"`
+}
+
+type embedded struct {
+	Kind string `json:"kind,omitempty"`
+}
+
+type outer struct {
+	embedded `json:",inline"`
+	Inner    *inner            `json:"inner,omitempty"`
+	Labels   map[string]string `json:"labels"`
+	Ports    []int             `json:"ports"`
+	Ratio    float64           `json:"ratio"`
+	Enabled  bool              `json:"enabled"`
+	Info     interface{}       `json:"info"`
+	Untagged uint64
+	Skipped  string `json:"-"`
+	hidden   string
+}
+
+func TestForType(t *testing.T) {
+	schema := ForType(reflect.TypeOf(outer{}))
+	expected := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"kind": {Type: "string"},
+			"inner": {
+				Type:                 "object",
+				Properties:           map[string]*Schema{"name": {Type: "string", Description: "The name"}},
+				AdditionalProperties: false,
+			},
+			"labels":   {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+			"ports":    {Type: "array", Items: &Schema{Type: "integer"}},
+			"ratio":    {Type: "number"},
+			"enabled":  {Type: "boolean"},
+			"info":     {},
+			"Untagged": {Type: "integer"},
+		},
+		AdditionalProperties: false,
+	}
+	if !reflect.DeepEqual(schema, expected) {
+		t.Errorf("Unexpected schema: %#v", schema)
+	}
+}
+
+func TestForKind(t *testing.T) {
+	schema, err := ForKind("v1beta2", "Service")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if schema.Schema != Draft03 || schema.Properties["selector"] == nil || schema.Properties["labels"] != nil {
+		t.Errorf("Unexpected schema: %#v", schema)
+	}
+	if schema.Properties["id"] == nil || schema.Properties["selector"].Description == "" {
+		t.Errorf("Unexpected properties: %#v", schema.Properties)
+	}
+	if _, err := ForKind("v1beta2", "Foo"); err == nil {
+		t.Errorf("Expected an error for an unknown kind")
+	}
+	if _, err := ForKind("v0", "Pod"); err == nil {
+		t.Errorf("Expected an error for an unknown version")
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/validation"
+)
+
+// Validate checks 'data', a JSON value decoded by encoding/json into an interface{}, against
+// 'schema', and returns the problems with its fields.
+func Validate(schema *Schema, data interface{}) validation.ErrorList {
+	return validate(schema, data, "")
+}
+
+func validate(schema *Schema, data interface{}, field string) validation.ErrorList {
+	// encoding/json decodes null into any type.
+	if data == nil {
+		return nil
+	}
+	valid := true
+	switch schema.Type {
+	case "object":
+		object, ok := data.(map[string]interface{})
+		if !ok {
+			valid = false
+			break
+		}
+		return validateObject(schema, object, field)
+	case "array":
+		items, ok := data.([]interface{})
+		if !ok {
+			valid = false
+			break
+		}
+		errs := validation.ErrorList{}
+		for ix, item := range items {
+			errs = append(errs, validate(schema.Items, item, fmt.Sprintf("%s[%d]", field, ix))...)
+		}
+		return errs
+	case "string":
+		_, valid = data.(string)
+	case "boolean":
+		_, valid = data.(bool)
+	case "integer":
+		number, ok := data.(float64)
+		valid = ok && number == math.Trunc(number)
+	case "number":
+		_, valid = data.(float64)
+	}
+	if !valid {
+		return validation.ErrorList{validation.NewFieldInvalid(field, data)}
+	}
+	return nil
+}
+
+func validateObject(schema *Schema, object map[string]interface{}, field string) validation.ErrorList {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	errs := validation.ErrorList{}
+	for _, key := range keys {
+		path := key
+		if len(field) != 0 {
+			path = field + "." + key
+		}
+		if property, ok := schema.Properties[key]; ok {
+			errs = append(errs, validate(property, object[key], path)...)
+			continue
+		}
+		switch additional := schema.AdditionalProperties.(type) {
+		case *Schema:
+			errs = append(errs, validate(additional, object[key], path)...)
+		case bool:
+			if !additional {
+				errs = append(errs, validation.NewFieldNotSupported(path, object[key]))
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	schema := ForType(reflect.TypeOf(outer{}))
+	table := []struct {
+		data   string
+		fields []string
+	}{
+		{`{"kind": "a", "inner": {"name": "b"}, "labels": {"c": "d"}, "ports": [1, 2], "ratio": 0.5, "enabled": true, "info": [{}], "Untagged": 3}`, []string{}},
+		{`{"inner": null, "labels": null, "ports": null}`, []string{}},
+		{`{"kind": 1, "ratio": "a", "enabled": "true", "Untagged": 1.5}`, []string{"Untagged", "enabled", "kind", "ratio"}},
+		{`{"inner": {"name": "a", "image": "b"}, "Skipped": "c", "hidden": "d"}`, []string{"Skipped", "hidden", "inner.image"}},
+		{`{"labels": {"a": 1}, "ports": [1, "2", 3.5], "inner": []}`, []string{"inner", "labels.a", "ports[1]", "ports[2]"}},
+		{`[]`, []string{""}},
+	}
+	for _, item := range table {
+		var data interface{}
+		if err := json.Unmarshal([]byte(item.data), &data); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		fields := []string{}
+		for _, err := range Validate(schema, data) {
+			fields = append(fields, err.Field)
+		}
+		if !reflect.DeepEqual(fields, item.fields) {
+			t.Errorf("Unexpected invalid fields of %s: %#v", item.data, fields)
+		}
+	}
+}
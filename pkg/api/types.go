@@ -74,6 +74,15 @@ type Event struct {
 	Timestamp int64              `json:"timestamp"`
 }
 
+// PodMigration asks the kubelet running a pod to live migrate the pod's members to another
+// host, whose kubelet has prepared to receive them.
+type PodMigration struct {
+	PodID  string `json:"podID" yaml:"podID"`
+	Target string `json:"target" yaml:"target"`
+	// Members maps the name of each container in the pod to the name of its member on the target.
+	Members map[string]string `json:"members,omitempty" yaml:"members,omitempty"`
+}
+
 // The below types are used by kube_client and api_server.
 
 // JSONBase is shared by all objects sent to, or returned from the client
@@ -82,15 +91,32 @@ type JSONBase struct {
 	ID                string `json:"id,omitempty" yaml:"id,omitempty"`
 	CreationTimestamp string `json:"creationTimestamp,omitempty" yaml:"creationTimestamp,omitempty"`
 	SelfLink          string `json:"selfLink,omitempty" yaml:"selfLink,omitempty"`
+	// APIVersion is the version of the API an encoded object is in. It is empty on the
+	// internal types, which are converted from and to the versions by Encode and DecodeInto.
+	APIVersion string `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	// ResourceVersion is the version of the stored object the object was read from. An update
+	// with a version other than 0 fails with a Conflict if the object has changed since.
+	ResourceVersion uint64 `json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
+	// Namespace is the namespace the ID of the object is unique in. Minions are not in a namespace.
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
 }
 
+const (
+	// NamespaceDefault is the namespace of objects that are created without one.
+	NamespaceDefault = "default"
+	// NamespaceAll is the namespace to list and watch the objects of every namespace in.
+	NamespaceAll = ""
+)
+
 // PodState is the state of a pod, used as either input (desired state) or output (current state)
 type PodState struct {
 	Manifest ContainerManifest `json:"manifest,omitempty" yaml:"manifest,omitempty"`
 	Status   string            `json:"status,omitempty" yaml:"status,omitempty"`
 	Host     string            `json:"host,omitempty" yaml:"host,omitempty"`
 	HostIP   string            `json:"hostIP,omitempty" yaml:"hostIP,omitempty"`
-	Info     interface{}       `json:"info,omitempty" yaml:"info,omitempty"`
+	// Address of the pod on the pod network, if its runtime assigned one.
+	PodIP string      `json:"podIP,omitempty" yaml:"podIP,omitempty"`
+	Info  interface{} `json:"info,omitempty" yaml:"info,omitempty"`
 }
 
 type PodList struct {
@@ -146,9 +172,140 @@ type Service struct {
 	CreateExternalLoadBalancer bool              `json:"createExternalLoadBalancer,omitempty" yaml:"createExternalLoadBalancer,omitempty"`
 }
 
+// MinionCondition is whether a minion can currently run pods.
+type MinionCondition string
+
+const (
+	MinionReady    MinionCondition = "Ready"
+	MinionNotReady MinionCondition = "NotReady"
+)
+
+// MinionResources describes the resources of a minion, in the units of Container.Memory and Container.CPU.
+type MinionResources struct {
+	Memory int `json:"memory,omitempty" yaml:"memory,omitempty"`
+	CPU    int `json:"cpu,omitempty" yaml:"cpu,omitempty"`
+}
+
+// Minion is a worker machine that pods can be scheduled onto.
+type Minion struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	// Address of the minion, if known.
+	HostIP    string          `json:"hostIP,omitempty" yaml:"hostIP,omitempty"`
+	Capacity  MinionResources `json:"capacity,omitempty" yaml:"capacity,omitempty"`
+	Condition MinionCondition `json:"condition,omitempty" yaml:"condition,omitempty"`
+	// Unix time of the last heartbeat the master received from the minion's kubelet.
+	// Zero for minions whose kubelet never reported, which are always treated as ready.
+	LastHeartbeat int64 `json:"lastHeartbeat,omitempty" yaml:"lastHeartbeat,omitempty"`
+	// Subnet the minion's kubelet assigns pod IPs from, e.g. 10.244.1.0/24.
+	PodCIDR string `json:"podCIDR,omitempty" yaml:"podCIDR,omitempty"`
+}
+
+// MinionList is a list of minions.
+type MinionList struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	Items    []Minion `json:"items,omitempty" yaml:"items,omitempty"`
+}
+
+// Operation is an API call that started work which continues after the call returns, such as
+// starting a pod. Operations are served from /operations/{id}.
+type Operation struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	// One of StatusWorking, StatusSuccess or StatusFailure.
+	Status string `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// OperationList is a list of operations.
+type OperationList struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	Items    []Operation `json:"items,omitempty" yaml:"items,omitempty"`
+}
+
+// Status is a return value for calls that don't return other objects, and the body of
+// every failed call.
+type Status struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	// One of StatusSuccess or StatusFailure.
+	Status string `json:"status,omitempty" yaml:"status,omitempty"`
+	// A human readable description of the status.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Why the call failed. Clients should switch on the reason, not on the message.
+	Reason StatusReason `json:"reason,omitempty" yaml:"reason,omitempty"`
+	// The object the status is about, if there is one.
+	Details *StatusDetails `json:"details,omitempty" yaml:"details,omitempty"`
+	// The HTTP status code of the response.
+	Code int `json:"code,omitempty" yaml:"code,omitempty"`
+}
+
+// StatusDetails names the object a Status is about.
+type StatusDetails struct {
+	ID   string `json:"id,omitempty" yaml:"id,omitempty"`
+	Kind string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	// The problems with the fields of the object, for Invalid errors.
+	Causes []StatusCause `json:"causes,omitempty" yaml:"causes,omitempty"`
+}
+
+// StatusCause is a single problem with a field of an object.
+type StatusCause struct {
+	Type CauseType `json:"type,omitempty" yaml:"type,omitempty"`
+	// A human readable description of the problem.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// The path of the field, e.g. desiredState.manifest.containers[0].name.
+	Field string `json:"field,omitempty" yaml:"field,omitempty"`
+}
+
+// CauseType is the machine readable kind of a StatusCause.
+type CauseType string
+
+const (
+	// A required field is missing or empty.
+	CauseTypeFieldValueRequired CauseType = "FieldValueRequired"
+	// The value of a field is malformed or out of range.
+	CauseTypeFieldValueInvalid CauseType = "FieldValueInvalid"
+	// The value of a field must be unique, but is repeated.
+	CauseTypeFieldValueDuplicate CauseType = "FieldValueDuplicate"
+	// The value of a field refers to something that does not exist.
+	CauseTypeFieldValueNotFound CauseType = "FieldValueNotFound"
+	// The value of a field is well formed, but not one of the values that are supported.
+	CauseTypeFieldValueNotSupported CauseType = "FieldValueNotSupported"
+)
+
+// Values of Status.Status.
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+	// The call started an operation that is still working.
+	StatusWorking = "working"
+)
+
+// StatusReason is the machine readable reason a call failed.
+type StatusReason string
+
+const (
+	// The object does not exist.
+	StatusReasonNotFound StatusReason = "NotFound"
+	// The object to create already exists.
+	StatusReasonAlreadyExists StatusReason = "AlreadyExists"
+	// The call conflicts with the current state of the object, and may succeed if retried
+	// once the state changes.
+	StatusReasonConflict StatusReason = "Conflict"
+	// The request or the object in it is malformed.
+	StatusReasonInvalid StatusReason = "Invalid"
+	// The server failed to complete a valid request, for example because etcd is unreachable.
+	StatusReasonInternalError StatusReason = "InternalError"
+	// An operation did not finish in time.
+	StatusReasonTimeout StatusReason = "Timeout"
+	// The request did not carry valid credentials.
+	StatusReasonUnauthorized StatusReason = "Unauthorized"
+	// The user of the request is not allowed to make it.
+	StatusReasonForbidden StatusReason = "Forbidden"
+	// The method of the request is not supported for the object, such as PATCH of a pod.
+	StatusReasonMethodNotAllowed StatusReason = "MethodNotAllowed"
+)
+
 // Defines the endpoints that implement the actual service, for example:
 // Name: "mysql", Endpoints: ["10.10.1.1:1909", "10.10.2.2:8834"]
 type Endpoints struct {
 	Name      string
+	Namespace string `json:",omitempty"`
 	Endpoints []string
 }
@@ -0,0 +1,276 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+// ContainerManifest corresponds to the Container Manifest format, documented at:
+// https://developers.google.com/compute/docs/containers/container_vms#container_manifest
+// This is used as the representation of Kubernete's workloads.
+type ContainerManifest struct {
+	Version    string      `yaml:"version" json:"version"`
+	Volumes    []Volume    `yaml:"volumes" json:"volumes"`
+	Containers []Container `yaml:"containers" json:"containers"`
+	Id         string      `yaml:"id,omitempty" json:"id,omitempty"`
+}
+
+// Volume represents a named volume in a pod that may be accessed by any containers in the pod.
+type Volume struct {
+	Name string `yaml:"name" json:"name"`
+}
+
+// Port represents a network port in a single container
+type Port struct {
+	Name          string `yaml:"name,omitempty" json:"name,omitempty"`
+	HostPort      int    `yaml:"hostPort,omitempty" json:"hostPort,omitempty"`
+	ContainerPort int    `yaml:"containerPort,omitempty" json:"containerPort,omitempty"`
+	Protocol      string `yaml:"protocol,omitempty" json:"protocol,omitempty"`
+}
+
+// VolumeMount describes a mounting of a Volume within a container
+type VolumeMount struct {
+	// Name must match the Name of a volume [above]
+	Name      string `yaml:"name,omitempty" json:"name,omitempty"`
+	ReadOnly  bool   `yaml:"readOnly,omitempty" json:"readOnly,omitempty"`
+	MountPath string `yaml:"mountPath,omitempty" json:"mountPath,omitempty"`
+}
+
+// EnvVar represents an environment variable present in a Container
+type EnvVar struct {
+	Name  string `yaml:"name,omitempty" json:"name,omitempty"`
+	Value string `yaml:"value,omitempty" json:"value,omitempty"`
+}
+
+// Container represents a single container that is expected to be run on the host.
+type Container struct {
+	Name         string        `yaml:"name,omitempty" json:"name,omitempty"`
+	Image        string        `yaml:"image,omitempty" json:"image,omitempty"`
+	Command      string        `yaml:"command,omitempty" json:"command,omitempty"`
+	WorkingDir   string        `yaml:"workingDir,omitempty" json:"workingDir,omitempty"`
+	Ports        []Port        `yaml:"ports,omitempty" json:"ports,omitempty"`
+	Env          []EnvVar      `yaml:"env,omitempty" json:"env,omitempty"`
+	Memory       int           `yaml:"memory,omitempty" json:"memory,omitempty"`
+	CPU          int           `yaml:"cpu,omitempty" json:"cpu,omitempty"`
+	VolumeMounts []VolumeMount `yaml:"volumeMounts,omitempty" json:"volumeMounts,omitempty"`
+}
+
+// JSONBase is shared by all objects sent to, or returned from the client
+type JSONBase struct {
+	Kind              string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	ID                string `json:"id,omitempty" yaml:"id,omitempty"`
+	CreationTimestamp string `json:"creationTimestamp,omitempty" yaml:"creationTimestamp,omitempty"`
+	SelfLink          string `json:"selfLink,omitempty" yaml:"selfLink,omitempty"`
+	// APIVersion is the version of the API the object is in.
+	APIVersion string `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	// ResourceVersion is the version of the stored object the object was read from. An update
+	// with a version other than 0 fails with a Conflict if the object has changed since.
+	ResourceVersion uint64 `json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
+	// Namespace is the namespace the ID of the object is unique in. Minions are not in a namespace.
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+}
+
+// PodState is the state of a pod, used as either input (desired state) or output (current state)
+type PodState struct {
+	Manifest ContainerManifest `json:"manifest,omitempty" yaml:"manifest,omitempty" description:"Manifest describing the containers of the pod, compatible with the format used by [Google Cloud Platform's container-vm images](https://developers.google.com/compute/docs/containers)"`
+	Status   string            `json:"status,omitempty" yaml:"status,omitempty"`
+	Host     string            `json:"host,omitempty" yaml:"host,omitempty"`
+	HostIP   string            `json:"hostIP,omitempty" yaml:"hostIP,omitempty"`
+	// Address of the pod on the pod network, if its runtime assigned one.
+	PodIP string      `json:"podIP,omitempty" yaml:"podIP,omitempty"`
+	Info  interface{} `json:"info,omitempty" yaml:"info,omitempty"`
+}
+
+type PodList struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	Items    []Pod `json:"items" yaml:"items,omitempty"`
+}
+
+// Pod is a collection of containers, used as either input (create, update) or as output (list, get)
+type Pod struct {
+	JSONBase     `json:",inline" yaml:",inline"`
+	Labels       map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	DesiredState PodState          `json:"desiredState,omitempty" yaml:"desiredState,omitempty" description:"The desired configuration of the pod"`
+	CurrentState PodState          `json:"currentState,omitempty" yaml:"currentState,omitempty" description:"The current configuration and status of the pod. Fields in common with desiredState have the same meaning."`
+}
+
+// ReplicationControllerState is the state of a replication controller, either input (create, update) or as output (list, get)
+type ReplicationControllerState struct {
+	Replicas      int               `json:"replicas" yaml:"replicas" description:"Number of pods desired in the set"`
+	ReplicasInSet map[string]string `json:"replicasInSet,omitempty" yaml:"replicasInSet,omitempty" description:"Required labels used to identify pods in the set"`
+	PodTemplate   PodTemplate       `json:"podTemplate,omitempty" yaml:"podTemplate,omitempty" description:"Template from which to create new pods, as necessary"`
+}
+
+type ReplicationControllerList struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	Items    []ReplicationController `json:"items,omitempty" yaml:"items,omitempty"`
+}
+
+// ReplicationController represents the configuration of a replication controller
+type ReplicationController struct {
+	JSONBase     `json:",inline" yaml:",inline"`
+	DesiredState ReplicationControllerState `json:"desiredState,omitempty" yaml:"desiredState,omitempty" description:"The desired configuration of the replicationController"`
+	Labels       map[string]string          `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// PodTemplate holds the information used for creating pods
+type PodTemplate struct {
+	DesiredState PodState          `json:"desiredState,omitempty" yaml:"desiredState,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// ServiceList holds a list of services
+type ServiceList struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	Items    []Service `json:"items" yaml:"items"`
+}
+
+// Defines a service abstraction by a name (for example, mysql) consisting of local port
+// (for example 3306) that the proxy listens on, and the labels that define the service.
+type Service struct {
+	JSONBase                   `json:",inline" yaml:",inline"`
+	Port                       int               `json:"port,omitempty" yaml:"port,omitempty" description:"Port the service proxy listens on"`
+	Labels                     map[string]string `json:"labels,omitempty" yaml:"labels,omitempty" description:"Labels of the pods the service sends traffic to"`
+	CreateExternalLoadBalancer bool              `json:"createExternalLoadBalancer,omitempty" yaml:"createExternalLoadBalancer,omitempty" description:"Whether to create a load balancer that forwards external traffic to the service"`
+}
+
+// MinionCondition is whether a minion can currently run pods.
+type MinionCondition string
+
+const (
+	MinionReady    MinionCondition = "Ready"
+	MinionNotReady MinionCondition = "NotReady"
+)
+
+// MinionResources describes the resources of a minion, in the units of Container.Memory and Container.CPU.
+type MinionResources struct {
+	Memory int `json:"memory,omitempty" yaml:"memory,omitempty"`
+	CPU    int `json:"cpu,omitempty" yaml:"cpu,omitempty"`
+}
+
+// Minion is a worker machine that pods can be scheduled onto.
+type Minion struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	// Address of the minion, if known.
+	HostIP    string          `json:"hostIP,omitempty" yaml:"hostIP,omitempty"`
+	Capacity  MinionResources `json:"capacity,omitempty" yaml:"capacity,omitempty"`
+	Condition MinionCondition `json:"condition,omitempty" yaml:"condition,omitempty"`
+	// Unix time of the last heartbeat the master received from the minion's kubelet.
+	// Zero for minions whose kubelet never reported, which are always treated as ready.
+	LastHeartbeat int64 `json:"lastHeartbeat,omitempty" yaml:"lastHeartbeat,omitempty"`
+	// Subnet the minion's kubelet assigns pod IPs from, e.g. 10.244.1.0/24.
+	PodCIDR string `json:"podCIDR,omitempty" yaml:"podCIDR,omitempty"`
+}
+
+// MinionList is a list of minions.
+type MinionList struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	Items    []Minion `json:"items,omitempty" yaml:"items,omitempty"`
+}
+
+// Operation is an API call that started work which continues after the call returns, such as
+// starting a pod. Operations are served from /operations/{id}.
+type Operation struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	// One of StatusWorking, StatusSuccess or StatusFailure.
+	Status string `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// OperationList is a list of operations.
+type OperationList struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	Items    []Operation `json:"items,omitempty" yaml:"items,omitempty"`
+}
+
+// Status is a return value for calls that don't return other objects, and the body of
+// every failed call.
+type Status struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	// One of StatusSuccess or StatusFailure.
+	Status string `json:"status,omitempty" yaml:"status,omitempty"`
+	// A human readable description of the status.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Why the call failed. Clients should switch on the reason, not on the message.
+	Reason StatusReason `json:"reason,omitempty" yaml:"reason,omitempty"`
+	// The object the status is about, if there is one.
+	Details *StatusDetails `json:"details,omitempty" yaml:"details,omitempty"`
+	// The HTTP status code of the response.
+	Code int `json:"code,omitempty" yaml:"code,omitempty"`
+}
+
+// StatusDetails names the object a Status is about.
+type StatusDetails struct {
+	ID   string `json:"id,omitempty" yaml:"id,omitempty"`
+	Kind string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	// The problems with the fields of the object, for Invalid errors.
+	Causes []StatusCause `json:"causes,omitempty" yaml:"causes,omitempty"`
+}
+
+// StatusCause is a single problem with a field of an object.
+type StatusCause struct {
+	Type CauseType `json:"type,omitempty" yaml:"type,omitempty"`
+	// A human readable description of the problem.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// The path of the field, e.g. desiredState.manifest.containers[0].name.
+	Field string `json:"field,omitempty" yaml:"field,omitempty"`
+}
+
+// CauseType is the machine readable kind of a StatusCause.
+type CauseType string
+
+const (
+	// A required field is missing or empty.
+	CauseTypeFieldValueRequired CauseType = "FieldValueRequired"
+	// The value of a field is malformed or out of range.
+	CauseTypeFieldValueInvalid CauseType = "FieldValueInvalid"
+	// The value of a field must be unique, but is repeated.
+	CauseTypeFieldValueDuplicate CauseType = "FieldValueDuplicate"
+	// The value of a field refers to something that does not exist.
+	CauseTypeFieldValueNotFound CauseType = "FieldValueNotFound"
+	// The value of a field is well formed, but not one of the values that are supported.
+	CauseTypeFieldValueNotSupported CauseType = "FieldValueNotSupported"
+)
+
+// Values of Status.Status.
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+	// The call started an operation that is still working.
+	StatusWorking = "working"
+)
+
+// StatusReason is the machine readable reason a call failed.
+type StatusReason string
+
+const (
+	// The object does not exist.
+	StatusReasonNotFound StatusReason = "NotFound"
+	// The object to create already exists.
+	StatusReasonAlreadyExists StatusReason = "AlreadyExists"
+	// The call conflicts with the current state of the object, and may succeed if retried
+	// once the state changes.
+	StatusReasonConflict StatusReason = "Conflict"
+	// The request or the object in it is malformed.
+	StatusReasonInvalid StatusReason = "Invalid"
+	// The server failed to complete a valid request, for example because etcd is unreachable.
+	StatusReasonInternalError StatusReason = "InternalError"
+	// An operation did not finish in time.
+	StatusReasonTimeout StatusReason = "Timeout"
+	// The request did not carry valid credentials.
+	StatusReasonUnauthorized StatusReason = "Unauthorized"
+	// The user of the request is not allowed to make it.
+	StatusReasonForbidden StatusReason = "Forbidden"
+	// The method of the request is not supported for the object, such as PATCH of a pod.
+	StatusReasonMethodNotAllowed StatusReason = "MethodNotAllowed"
+)
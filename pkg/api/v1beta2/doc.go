@@ -0,0 +1,21 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1beta2 holds the types of version v1beta2 of the API, as they are sent to and from
+// the apiserver. Code works with the internal types in package api, which are converted
+// from and to these types by api.DecodeInto and api.Encode. It differs from v1beta1 in
+// naming the selectors of replication controllers and services after what they do.
+package v1beta2
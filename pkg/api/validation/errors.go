@@ -0,0 +1,113 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// ValidationError is a problem with the value of a single field of an object.
+type ValidationError struct {
+	Type api.CauseType
+	// The path of the field, e.g. desiredState.manifest.containers[0].name.
+	Field    string
+	BadValue interface{}
+}
+
+// NewFieldRequired returns an error saying that 'field' must be set.
+func NewFieldRequired(field string) ValidationError {
+	return ValidationError{api.CauseTypeFieldValueRequired, field, nil}
+}
+
+// NewFieldInvalid returns an error saying that 'value' of 'field' is malformed or out of range.
+func NewFieldInvalid(field string, value interface{}) ValidationError {
+	return ValidationError{api.CauseTypeFieldValueInvalid, field, value}
+}
+
+// NewFieldDuplicate returns an error saying that 'value' of 'field' is used more than once.
+func NewFieldDuplicate(field string, value interface{}) ValidationError {
+	return ValidationError{api.CauseTypeFieldValueDuplicate, field, value}
+}
+
+// NewFieldNotFound returns an error saying that 'value' of 'field' refers to something missing.
+func NewFieldNotFound(field string, value interface{}) ValidationError {
+	return ValidationError{api.CauseTypeFieldValueNotFound, field, value}
+}
+
+// NewFieldNotSupported returns an error saying that 'value' of 'field' is not supported.
+func NewFieldNotSupported(field string, value interface{}) ValidationError {
+	return ValidationError{api.CauseTypeFieldValueNotSupported, field, value}
+}
+
+// Message describes the problem, without the field.
+func (v ValidationError) Message() string {
+	switch v.Type {
+	case api.CauseTypeFieldValueRequired:
+		return "required value"
+	case api.CauseTypeFieldValueInvalid:
+		return fmt.Sprintf("invalid value '%v'", v.BadValue)
+	case api.CauseTypeFieldValueDuplicate:
+		return fmt.Sprintf("duplicate value '%v'", v.BadValue)
+	case api.CauseTypeFieldValueNotFound:
+		return fmt.Sprintf("'%v' not found", v.BadValue)
+	case api.CauseTypeFieldValueNotSupported:
+		return fmt.Sprintf("unsupported value '%v'", v.BadValue)
+	}
+	return fmt.Sprintf("%s '%v'", v.Type, v.BadValue)
+}
+
+func (v ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", v.Field, v.Message())
+}
+
+// ErrorList holds every problem found in an object. It is empty if the object is valid.
+type ErrorList []ValidationError
+
+// Prefix returns the errors in 'list' as errors of the fields under the field 'prefix'.
+func (list ErrorList) Prefix(prefix string) ErrorList {
+	result := make(ErrorList, len(list))
+	for ix, err := range list {
+		if strings.HasPrefix(err.Field, "[") {
+			err.Field = prefix + err.Field
+		} else {
+			err.Field = prefix + "." + err.Field
+		}
+		result[ix] = err
+	}
+	return result
+}
+
+// PrefixIndex returns the errors in 'list' as errors of the item at 'index' of a list.
+func (list ErrorList) PrefixIndex(index int) ErrorList {
+	return list.Prefix(fmt.Sprintf("[%d]", index))
+}
+
+// Causes returns the errors in 'list' as the causes of an api.Status.
+func (list ErrorList) Causes() []api.StatusCause {
+	causes := make([]api.StatusCause, len(list))
+	for ix, err := range list {
+		causes[ix] = api.StatusCause{
+			Type:    err.Type,
+			Message: err.Message(),
+			Field:   err.Field,
+		}
+	}
+	return causes
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+func TestErrorListPrefix(t *testing.T) {
+	errs := ErrorList{NewFieldRequired("name"), NewFieldInvalid("[1]", 0)}
+	fields := []string{}
+	for _, err := range errs.PrefixIndex(2).Prefix("containers") {
+		fields = append(fields, err.Field)
+	}
+	expected := []string{"containers[2].name", "containers[2][1]"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Unexpected fields: %#v", fields)
+	}
+	if errs[0].Field != "name" {
+		t.Errorf("Expected Prefix to copy the errors: %#v", errs)
+	}
+}
+
+func TestErrorListCauses(t *testing.T) {
+	causes := ErrorList{NewFieldNotFound("volumeMounts[0].name", "data")}.Causes()
+	expected := []api.StatusCause{
+		{Type: api.CauseTypeFieldValueNotFound, Message: "'data' not found", Field: "volumeMounts[0].name"},
+	}
+	if !reflect.DeepEqual(causes, expected) {
+		t.Errorf("Unexpected causes: %#v", causes)
+	}
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	table := map[string]ValidationError{
+		"id: required value":                 NewFieldRequired("id"),
+		"port: invalid value '0'":            NewFieldInvalid("port", 0),
+		"name: duplicate value 'foo'":        NewFieldDuplicate("name", "foo"),
+		"name: 'data' not found":             NewFieldNotFound("name", "data"),
+		"protocol: unsupported value 'sctp'": NewFieldNotSupported("protocol", "sctp"),
+	}
+	for expected, err := range table {
+		if err.Error() != expected {
+			t.Errorf("Expected %q, got %q", expected, err.Error())
+		}
+	}
+}
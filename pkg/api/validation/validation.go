@@ -0,0 +1,257 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"regexp"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+const (
+	maxIDLength    = 253
+	maxLabelLength = 63
+	maxPort        = 65535
+)
+
+var (
+	// IDs name objects in etcd keys and URLs, and containers on their host.
+	idRegexp = regexp.MustCompile("^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$")
+	// DNS labels (RFC 1123) name the parts of a pod, such as containers and volumes.
+	dnsLabelRegexp = regexp.MustCompile("^[a-z0-9]([-a-z0-9]*[a-z0-9])?$")
+	// Label keys and values may not contain the separators of label queries.
+	labelRegexp = regexp.MustCompile("^[A-Za-z0-9]([-A-Za-z0-9_./]*[A-Za-z0-9])?$")
+	// Environment variable names are C identifiers.
+	envVarNameRegexp = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*$")
+)
+
+var supportedManifestVersions = map[string]bool{"v1beta1": true}
+
+var supportedPortProtocols = map[string]bool{"tcp": true, "udp": true}
+
+func validateID(id string) ErrorList {
+	if len(id) == 0 {
+		return ErrorList{NewFieldRequired("id")}
+	}
+	if len(id) > maxIDLength || !idRegexp.MatchString(id) {
+		return ErrorList{NewFieldInvalid("id", id)}
+	}
+	return nil
+}
+
+// validateJSONBase checks the id and namespace of an object. An empty namespace is the default
+// namespace.
+func validateJSONBase(base *api.JSONBase) ErrorList {
+	errs := validateID(base.ID)
+	if len(base.Namespace) != 0 && !isDNSLabel(base.Namespace) {
+		errs = append(errs, NewFieldInvalid("namespace", base.Namespace))
+	}
+	return errs
+}
+
+func isDNSLabel(value string) bool {
+	return len(value) <= maxLabelLength && dnsLabelRegexp.MatchString(value)
+}
+
+func isLabelPart(value string) bool {
+	return len(value) <= maxLabelLength && labelRegexp.MatchString(value)
+}
+
+// validateLabels checks the keys and values of the labels or label selector in 'field'.
+// Values may be empty.
+func validateLabels(field string, set map[string]string) ErrorList {
+	errs := ErrorList{}
+	for key, value := range set {
+		if !isLabelPart(key) {
+			errs = append(errs, NewFieldInvalid(field, key))
+		}
+		if len(value) != 0 && !isLabelPart(value) {
+			errs = append(errs, NewFieldInvalid(field+"."+key, value))
+		}
+	}
+	return errs
+}
+
+// validateVolumes checks the volumes of a manifest, and returns the names they define.
+func validateVolumes(volumes []api.Volume) (map[string]bool, ErrorList) {
+	names := map[string]bool{}
+	errs := ErrorList{}
+	for ix, volume := range volumes {
+		volumeErrs := ErrorList{}
+		switch {
+		case len(volume.Name) == 0:
+			volumeErrs = append(volumeErrs, NewFieldRequired("name"))
+		case !isDNSLabel(volume.Name):
+			volumeErrs = append(volumeErrs, NewFieldInvalid("name", volume.Name))
+		case names[volume.Name]:
+			volumeErrs = append(volumeErrs, NewFieldDuplicate("name", volume.Name))
+		default:
+			names[volume.Name] = true
+		}
+		errs = append(errs, volumeErrs.PrefixIndex(ix)...)
+	}
+	return names, errs
+}
+
+func validatePorts(ports []api.Port) ErrorList {
+	names := map[string]bool{}
+	errs := ErrorList{}
+	for ix, port := range ports {
+		portErrs := ErrorList{}
+		if len(port.Name) != 0 {
+			if !isDNSLabel(port.Name) {
+				portErrs = append(portErrs, NewFieldInvalid("name", port.Name))
+			} else if names[port.Name] {
+				portErrs = append(portErrs, NewFieldDuplicate("name", port.Name))
+			}
+			names[port.Name] = true
+		}
+		if port.ContainerPort == 0 {
+			portErrs = append(portErrs, NewFieldRequired("containerPort"))
+		} else if port.ContainerPort < 0 || port.ContainerPort > maxPort {
+			portErrs = append(portErrs, NewFieldInvalid("containerPort", port.ContainerPort))
+		}
+		if port.HostPort < 0 || port.HostPort > maxPort {
+			portErrs = append(portErrs, NewFieldInvalid("hostPort", port.HostPort))
+		}
+		if len(port.Protocol) != 0 && !supportedPortProtocols[port.Protocol] {
+			portErrs = append(portErrs, NewFieldNotSupported("protocol", port.Protocol))
+		}
+		errs = append(errs, portErrs.PrefixIndex(ix)...)
+	}
+	return errs
+}
+
+func validateEnv(vars []api.EnvVar) ErrorList {
+	errs := ErrorList{}
+	for ix, envVar := range vars {
+		varErrs := ErrorList{}
+		if len(envVar.Name) == 0 {
+			varErrs = append(varErrs, NewFieldRequired("name"))
+		} else if !envVarNameRegexp.MatchString(envVar.Name) {
+			varErrs = append(varErrs, NewFieldInvalid("name", envVar.Name))
+		}
+		errs = append(errs, varErrs.PrefixIndex(ix)...)
+	}
+	return errs
+}
+
+func validateVolumeMounts(mounts []api.VolumeMount, volumes map[string]bool) ErrorList {
+	errs := ErrorList{}
+	for ix, mount := range mounts {
+		mountErrs := ErrorList{}
+		if len(mount.Name) == 0 {
+			mountErrs = append(mountErrs, NewFieldRequired("name"))
+		} else if !volumes[mount.Name] {
+			mountErrs = append(mountErrs, NewFieldNotFound("name", mount.Name))
+		}
+		if len(mount.MountPath) == 0 {
+			mountErrs = append(mountErrs, NewFieldRequired("mountPath"))
+		}
+		errs = append(errs, mountErrs.PrefixIndex(ix)...)
+	}
+	return errs
+}
+
+// validateContainers checks the containers of a manifest, whose volumes are named 'volumes'.
+// Containers may be unnamed, but their names must be unique, so only one of them can be.
+func validateContainers(containers []api.Container, volumes map[string]bool) ErrorList {
+	names := map[string]bool{}
+	errs := ErrorList{}
+	for ix, container := range containers {
+		containerErrs := ErrorList{}
+		if len(container.Name) != 0 && !isDNSLabel(container.Name) {
+			containerErrs = append(containerErrs, NewFieldInvalid("name", container.Name))
+		} else if names[container.Name] {
+			containerErrs = append(containerErrs, NewFieldDuplicate("name", container.Name))
+		}
+		names[container.Name] = true
+		if len(container.Image) == 0 {
+			containerErrs = append(containerErrs, NewFieldRequired("image"))
+		}
+		if container.Memory < 0 {
+			containerErrs = append(containerErrs, NewFieldInvalid("memory", container.Memory))
+		}
+		if container.CPU < 0 {
+			containerErrs = append(containerErrs, NewFieldInvalid("cpu", container.CPU))
+		}
+		containerErrs = append(containerErrs, validatePorts(container.Ports).Prefix("ports")...)
+		containerErrs = append(containerErrs, validateEnv(container.Env).Prefix("env")...)
+		containerErrs = append(containerErrs, validateVolumeMounts(container.VolumeMounts, volumes).Prefix("volumeMounts")...)
+		errs = append(errs, containerErrs.PrefixIndex(ix)...)
+	}
+	return errs
+}
+
+// ValidateManifest checks a container manifest. Its id is optional, since the apiserver
+// sets it to the id of the pod the manifest belongs to.
+func ValidateManifest(manifest *api.ContainerManifest) ErrorList {
+	errs := ErrorList{}
+	if len(manifest.Version) != 0 && !supportedManifestVersions[manifest.Version] {
+		errs = append(errs, NewFieldNotSupported("version", manifest.Version))
+	}
+	if len(manifest.Id) != 0 {
+		errs = append(errs, validateID(manifest.Id)...)
+	}
+	volumes, volumeErrs := validateVolumes(manifest.Volumes)
+	errs = append(errs, volumeErrs.Prefix("volumes")...)
+	errs = append(errs, validateContainers(manifest.Containers, volumes).Prefix("containers")...)
+	return errs
+}
+
+// ValidatePod checks a pod to be created or updated.
+func ValidatePod(pod *api.Pod) ErrorList {
+	errs := validateJSONBase(&pod.JSONBase)
+	errs = append(errs, validateLabels("labels", pod.Labels)...)
+	errs = append(errs, ValidateManifest(&pod.DesiredState.Manifest).Prefix("desiredState.manifest")...)
+	return errs
+}
+
+// ValidateReplicationController checks a replication controller to be created or updated.
+// The pods of its template must match its selector, or it would never find the pods it makes.
+func ValidateReplicationController(controller *api.ReplicationController) ErrorList {
+	errs := validateJSONBase(&controller.JSONBase)
+	errs = append(errs, validateLabels("labels", controller.Labels)...)
+	state := &controller.DesiredState
+	if state.Replicas < 0 {
+		errs = append(errs, NewFieldInvalid("desiredState.replicas", state.Replicas))
+	}
+	if len(state.ReplicasInSet) == 0 {
+		errs = append(errs, NewFieldRequired("desiredState.replicasInSet"))
+	} else {
+		errs = append(errs, validateLabels("desiredState.replicasInSet", state.ReplicasInSet)...)
+		if !labels.QueryFromSet(labels.Set(state.ReplicasInSet)).Matches(labels.Set(state.PodTemplate.Labels)) {
+			errs = append(errs, NewFieldInvalid("desiredState.podTemplate.labels", labels.Set(state.PodTemplate.Labels).String()))
+		}
+	}
+	errs = append(errs, validateLabels("desiredState.podTemplate.labels", state.PodTemplate.Labels)...)
+	errs = append(errs, ValidateManifest(&state.PodTemplate.DesiredState.Manifest).Prefix("desiredState.podTemplate.desiredState.manifest")...)
+	return errs
+}
+
+// ValidateService checks a service to be created or updated.
+func ValidateService(service *api.Service) ErrorList {
+	errs := validateJSONBase(&service.JSONBase)
+	if service.Port == 0 {
+		errs = append(errs, NewFieldRequired("port"))
+	} else if service.Port < 0 || service.Port > maxPort {
+		errs = append(errs, NewFieldInvalid("port", service.Port))
+	}
+	errs = append(errs, validateLabels("labels", service.Labels)...)
+	return errs
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// expectErrors checks that 'errs' are about exactly 'fields', in order.
+func expectErrors(t *testing.T, name string, errs ErrorList, fields ...string) {
+	if len(errs) != len(fields) {
+		t.Errorf("%s: expected errors for %v, got %v", name, fields, errs)
+		return
+	}
+	for ix, err := range errs {
+		if err.Field != fields[ix] {
+			t.Errorf("%s: expected errors for %v, got %v", name, fields, errs)
+			return
+		}
+	}
+}
+
+func validManifest() api.ContainerManifest {
+	return api.ContainerManifest{
+		Version: "v1beta1",
+		Volumes: []api.Volume{{Name: "data"}},
+		Containers: []api.Container{
+			{
+				Name:         "web",
+				Image:        "dockerfile/nginx",
+				Ports:        []api.Port{{Name: "http", ContainerPort: 80, HostPort: 8080, Protocol: "tcp"}},
+				Env:          []api.EnvVar{{Name: "SERVICE_HOST", Value: "10.0.0.1"}},
+				VolumeMounts: []api.VolumeMount{{Name: "data", MountPath: "/data"}},
+			},
+			{
+				Image: "dockerfile/redis",
+			},
+		},
+	}
+}
+
+func TestValidateManifest(t *testing.T) {
+	manifest := validManifest()
+	expectErrors(t, "valid", ValidateManifest(&manifest))
+	expectErrors(t, "empty", ValidateManifest(&api.ContainerManifest{}))
+
+	table := map[string]struct {
+		change func(*api.ContainerManifest)
+		fields []string
+	}{
+		"version": {
+			func(m *api.ContainerManifest) { m.Version = "v2" },
+			[]string{"version"},
+		},
+		"id": {
+			func(m *api.ContainerManifest) { m.Id = "a/b" },
+			[]string{"id"},
+		},
+		"volume names": {
+			func(m *api.ContainerManifest) {
+				m.Volumes = []api.Volume{{Name: "data"}, {Name: ""}, {Name: "Data"}, {Name: "data"}}
+			},
+			[]string{"volumes[1].name", "volumes[2].name", "volumes[3].name"},
+		},
+		"container names": {
+			func(m *api.ContainerManifest) {
+				m.Containers[0].Name = "web_1"
+				m.Containers = append(m.Containers, api.Container{Image: "busybox"})
+			},
+			[]string{"containers[0].name", "containers[2].name"},
+		},
+		"duplicate container names": {
+			func(m *api.ContainerManifest) { m.Containers[1].Name = "web" },
+			[]string{"containers[1].name"},
+		},
+		"image": {
+			func(m *api.ContainerManifest) { m.Containers[1].Image = "" },
+			[]string{"containers[1].image"},
+		},
+		"resources": {
+			func(m *api.ContainerManifest) {
+				m.Containers[0].Memory = -1
+				m.Containers[0].CPU = -1
+			},
+			[]string{"containers[0].memory", "containers[0].cpu"},
+		},
+		"ports": {
+			func(m *api.ContainerManifest) {
+				m.Containers[0].Ports = []api.Port{
+					{Name: "http", ContainerPort: 80},
+					{Name: "http", ContainerPort: 0, HostPort: 65536},
+					{ContainerPort: 70000, Protocol: "TCP"},
+				}
+			},
+			[]string{"containers[0].ports[1].name", "containers[0].ports[1].containerPort", "containers[0].ports[1].hostPort", "containers[0].ports[2].containerPort", "containers[0].ports[2].protocol"},
+		},
+		"env": {
+			func(m *api.ContainerManifest) { m.Containers[0].Env = []api.EnvVar{{Name: ""}, {Name: "1FOO"}} },
+			[]string{"containers[0].env[0].name", "containers[0].env[1].name"},
+		},
+		"volume mounts": {
+			func(m *api.ContainerManifest) {
+				m.Containers[0].VolumeMounts = []api.VolumeMount{{Name: "logs", MountPath: "/logs"}, {Name: "data"}}
+			},
+			[]string{"containers[0].volumeMounts[0].name", "containers[0].volumeMounts[1].mountPath"},
+		},
+	}
+	for name, item := range table {
+		manifest := validManifest()
+		item.change(&manifest)
+		expectErrors(t, name, ValidateManifest(&manifest), item.fields...)
+	}
+}
+
+func TestValidatePod(t *testing.T) {
+	pod := api.Pod{
+		JSONBase:     api.JSONBase{ID: "my-pod.1"},
+		Labels:       map[string]string{"name": "web", "tier": ""},
+		DesiredState: api.PodState{Manifest: validManifest()},
+	}
+	expectErrors(t, "valid", ValidatePod(&pod))
+
+	pod.ID = ""
+	pod.Labels = map[string]string{"name": "a=b"}
+	pod.DesiredState.Manifest.Containers[0].Image = ""
+	expectErrors(t, "invalid", ValidatePod(&pod), "id", "labels.name", "desiredState.manifest.containers[0].image")
+
+	pod.ID = "-foo"
+	pod.Labels = map[string]string{"a,b": "c"}
+	pod.DesiredState.Manifest = validManifest()
+	expectErrors(t, "invalid id and label key", ValidatePod(&pod), "id", "labels")
+
+	pod.ID = strings.Repeat("a", 254)
+	pod.Labels = nil
+	expectErrors(t, "long id", ValidatePod(&pod), "id")
+
+	pod.ID = "my-pod"
+	pod.Namespace = "team-a"
+	expectErrors(t, "namespace", ValidatePod(&pod))
+	pod.Namespace = "Team.A"
+	expectErrors(t, "invalid namespace", ValidatePod(&pod), "namespace")
+}
+
+func TestValidateReplicationController(t *testing.T) {
+	controller := api.ReplicationController{
+		JSONBase: api.JSONBase{ID: "nginxController"},
+		DesiredState: api.ReplicationControllerState{
+			Replicas:      2,
+			ReplicasInSet: map[string]string{"name": "nginx"},
+			PodTemplate: api.PodTemplate{
+				DesiredState: api.PodState{Manifest: validManifest()},
+				Labels:       map[string]string{"name": "nginx", "tier": "frontend"},
+			},
+		},
+	}
+	expectErrors(t, "valid", ValidateReplicationController(&controller))
+
+	controller.DesiredState.Replicas = -1
+	controller.DesiredState.PodTemplate.Labels = map[string]string{"name": "redis"}
+	controller.DesiredState.PodTemplate.DesiredState.Manifest.Containers[0].Ports[0].ContainerPort = 0
+	expectErrors(t, "invalid", ValidateReplicationController(&controller),
+		"desiredState.replicas", "desiredState.podTemplate.labels", "desiredState.podTemplate.desiredState.manifest.containers[0].ports[0].containerPort")
+
+	controller.DesiredState.Replicas = 0
+	controller.DesiredState.ReplicasInSet = nil
+	controller.DesiredState.PodTemplate.DesiredState.Manifest = validManifest()
+	expectErrors(t, "no selector", ValidateReplicationController(&controller), "desiredState.replicasInSet")
+}
+
+func TestValidateService(t *testing.T) {
+	service := api.Service{
+		JSONBase: api.JSONBase{ID: "redismaster"},
+		Port:     6379,
+		Labels:   map[string]string{"name": "redis-master"},
+	}
+	expectErrors(t, "valid", ValidateService(&service))
+
+	service.Port = 0
+	expectErrors(t, "no port", ValidateService(&service), "port")
+
+	service.ID = ""
+	service.Port = 65536
+	service.Labels = map[string]string{"name": "redis master"}
+	expectErrors(t, "invalid", ValidateService(&service), "id", "port", "labels.name")
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/admission"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth"
+)
+
+// SetAdmissionControl makes the server run every object it creates or updates by 'admit' before
+// it is stored. Objects are stored as 'admit' leaves them; rejected ones are answered with 403
+// Forbidden, unless the plugin picked another status.
+func (server *ApiServer) SetAdmissionControl(admit admission.Interface) {
+	server.admission = admit
+}
+
+// admit returns the object to store for a 'verb' of 'obj' in 'resource', or the error that
+// rejects it.
+func (server *ApiServer) admit(req *http.Request, verb, namespace, resource string, obj interface{}) (interface{}, error) {
+	if server.admission == nil {
+		return obj, nil
+	}
+	var user *auth.User
+	if server.requests != nil {
+		user = server.requests.User(req)
+	}
+	a := &admission.Attributes{User: user, Verb: verb, Namespace: namespace, Resource: resource, Object: obj}
+	if err := server.admission.Admit(a); err != nil {
+		if _, ok := err.(*api.StatusError); !ok {
+			err = api.NewForbidden(resource, verb, err)
+		}
+		return nil, err
+	}
+	return a.Object, nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/admission"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// renamingAdmission renames Simple objects to 'name', and rejects those called "bad".
+type renamingAdmission struct {
+	name       string
+	attributes []admission.Attributes
+}
+
+func (r *renamingAdmission) Admit(a *admission.Attributes) error {
+	r.attributes = append(r.attributes, *a)
+	simple := a.Object.(Simple)
+	if simple.Name == "bad" {
+		return fmt.Errorf("bad name")
+	}
+	simple.Name = r.name
+	a.Object = simple
+	return nil
+}
+
+func TestAdmission(t *testing.T) {
+	simpleStorage := &SimpleRESTStorage{}
+	handler := New(map[string]RESTStorage{"simple": simpleStorage}, "/prefix/version")
+	admit := &renamingAdmission{name: "admitted"}
+	handler.SetAdmissionControl(admit)
+	server := httptest.NewServer(handler)
+
+	table := []struct {
+		method string
+		path   string
+		body   string
+		verb   string
+		code   int
+	}{
+		{"PUT", "/simple/foo", `{"Name":"foo"}`, "update", 200},
+		{"PUT", "/simple/foo", `{"Name":"bad"}`, "update", http.StatusForbidden},
+		{"POST", "/namespaces/other/simple", `{"Name":"foo"}`, "create", 200},
+		{"POST", "/simple", `{"Name":"bad"}`, "create", http.StatusForbidden},
+		{"PATCH", "/simple/foo", `{"Name":"foo"}`, "patch", 200},
+		{"PATCH", "/simple/foo", `{"Name":"bad"}`, "patch", http.StatusForbidden},
+	}
+	for _, item := range table {
+		admit.attributes = nil
+		simpleStorage.updated = Simple{}
+		request, err := http.NewRequest(item.method, server.URL+"/prefix/version"+item.path, bytes.NewBufferString(item.body))
+		expectNoError(t, err)
+		response, err := http.DefaultClient.Do(request)
+		expectNoError(t, err)
+		var status api.Status
+		body, _ := extractBody(response, &status)
+		if response.StatusCode != item.code {
+			t.Errorf("Unexpected response to %#v: %d %s", item, response.StatusCode, body)
+		}
+		if len(admit.attributes) != 1 || admit.attributes[0].Verb != item.verb || admit.attributes[0].Resource != "simple" {
+			t.Errorf("Unexpected admission of %#v: %#v", item, admit.attributes)
+		}
+		if item.code == http.StatusForbidden && (status.Reason != api.StatusReasonForbidden || simpleStorage.updated.Name != "") {
+			t.Errorf("Unexpected response to %#v: %s", item, body)
+		}
+		if item.method != "POST" && item.code == 200 && simpleStorage.updated.Name != "admitted" {
+			t.Errorf("Unexpected update for %#v: %#v", item, simpleStorage.updated)
+		}
+	}
+}
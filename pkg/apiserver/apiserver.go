@@ -17,51 +17,140 @@ limitations under the License.
 package apiserver
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
 	"runtime/debug"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/admission"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/audit"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
-// RESTStorage is a generic interface for RESTful storage services
+// RESTStorage is a generic interface for RESTful storage services. Errors that are
+// *api.StatusErrors pick the status of the response; any other error is an internal error.
+// Objects are internal api types; the ApiServer converts them to the version it serves.
 type RESTStorage interface {
-	List(labels.Query) (interface{}, error)
-	Get(id string) (interface{}, error)
-	Delete(id string) error
-	Extract(body string) (interface{}, error)
+	// List returns the objects in 'namespace' that match the query, or those of every
+	// namespace if 'namespace' is api.NamespaceAll.
+	List(namespace string, query labels.Query) (interface{}, error)
+	Get(namespace, id string) (interface{}, error)
+	Delete(namespace, id string) error
+	// Extract decodes an object from a request body, which is in API 'version' unless it
+	// names its own, e.g. with api.DecodeVersionInto.
+	Extract(body string, version string) (interface{}, error)
+	// Create and Update store objects whose Namespace the ApiServer has set to the namespace
+	// of the request. Patches are applied to the object Get returns, and stored with Update.
 	Create(interface{}) error
 	Update(interface{}) error
 }
 
-// Status is a return value for calls that don't return other objects
-type Status struct {
-	Success bool
+// ClusterScoped is implemented by RESTStorage whose objects are not in a namespace, such as
+// minions. They are only served outside of namespaces, and are passed no namespace.
+type ClusterScoped interface {
+	ClusterScoped() bool
+}
+
+// Unpatchable is implemented by RESTStorage whose objects can't be updated in place, such as
+// pods. PATCH requests for them are refused.
+type Unpatchable interface {
+	Unpatchable() bool
+}
+
+// RESTStreamer is implemented by RESTStorage whose objects have streamed sub resources,
+// such as the console of a pod. Streams are served as plain text.
+type RESTStreamer interface {
+	// Stream opens the sub resource 'name' of the object 'id', or returns nil if there is no
+	// such object or sub resource. 'params' are the query parameters of the request.
+	Stream(namespace, id, name string, params url.Values) (io.ReadCloser, error)
+}
+
+// RESTActor is implemented by RESTStorage whose objects support actions, such as migrating a
+// pod to another host.
+type RESTActor interface {
+	// Act performs 'action' on the object 'id', and returns the result, or nil if there is no
+	// such object or action. 'params' are the query parameters of the request.
+	Act(namespace, id, action string, params url.Values) (interface{}, error)
+}
+
+// ResourceWatcher is implemented by RESTStorage whose objects can be watched.
+type ResourceWatcher interface {
+	// Watch returns the changes to the objects in 'namespace', or in every namespace if it is
+	// api.NamespaceAll, that match 'query'. If 'resourceVersion' is not 0, the changes after
+	// that version are returned, if the storage keeps a history.
+	Watch(namespace string, query labels.Query, resourceVersion uint64) (watch.Interface, error)
+}
+
+// ResourceLocator is implemented by RESTStorage whose objects serve HTTP, such as the kubelet of
+// a minion. Requests to ${prefix}/proxy/${storage_key}/${id}/${path} are proxied to ${path} there.
+type ResourceLocator interface {
+	// ResourceLocation returns the host:port that requests for the object 'id' are proxied to.
+	// Errors are like those of Get.
+	ResourceLocation(namespace, id string) (string, error)
+}
+
+// WatchEvent is how an event is sent to the clients of a watch: as one JSON object per line.
+// Object is in the API version of the watch.
+type WatchEvent struct {
+	Type   watch.EventType `json:"type" yaml:"type"`
+	Object interface{}     `json:"object" yaml:"object"`
 }
 
 // ApiServer is an HTTPHandler that delegates to RESTStorage objects.
 // It handles URLs of the form:
 // ${prefix}/${storage_key}[/${object_name}]
 // Where 'prefix' is an arbitrary string, and 'storage_key' points to a RESTStorage object stored in storage.
+// Objects in a namespace are served under ${prefix}/namespaces/${namespace}/${storage_key}. Outside
+// of a namespace, lists and watches span every namespace, creates and updates are in the namespace of
+// their object, and other calls are in the default namespace.
+// Changes to the objects of a ResourceWatcher are streamed from ${prefix}/watch/${storage_key}.
+// The objects of a ResourceLocator are proxied to at ${prefix}/proxy/${storage_key}/${object_name}.
+// Creates and updates that pass a "timeout" parameter are tracked as operations, which are
+// listed at ${prefix}/operations and waited on at ${prefix}/operations/${id}.
+// The JSON Schemas of the kinds of objects are served from ${prefix}/schemas/${kind}.
+// Objects are sent and received in one version of the API, as JSON, or as YAML in requests whose
+// Content-Type is YAML and in responses to requests that accept YAML. Watches stream JSON.
 //
 // TODO: consider migrating this to go-restful which is a more full-featured version of the same thing.
 type ApiServer struct {
 	prefix  string
+	version string
 	storage map[string]RESTStorage
+	ops     *Operations
+
+	authorizer auth.Authorizer
+	requests   *auth.Requests
+	admission  admission.Interface
+	auditSink  audit.Sink
 }
 
-// New creates a new ApiServer object.
+// New creates a new ApiServer object that serves the api.StorageVersion.
 // 'storage' contains a map of handlers.
 // 'prefix' is the hosting path prefix.
 func New(storage map[string]RESTStorage, prefix string) *ApiServer {
+	return NewVersioned(storage, MakeOperations(), prefix, api.StorageVersion)
+}
+
+// NewVersioned creates a new ApiServer object that serves API 'version' from 'storage'.
+// Servers of different versions of the same storage share 'ops'.
+func NewVersioned(storage map[string]RESTStorage, ops *Operations, prefix, version string) *ApiServer {
 	return &ApiServer{
 		storage: storage,
+		ops:     ops,
 		prefix:  prefix,
+		version: version,
 	}
 }
 
@@ -74,17 +163,17 @@ func (server *ApiServer) handleIndex(w http.ResponseWriter) {
 
 // HTTP Handler interface
 func (server *ApiServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w = negotiate(req, w)
 	defer func() {
 		if x := recover(); x != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprint(w, "apiserver panic. Look in log for details.")
+			server.error(api.NewInternalError(fmt.Errorf("apiserver panic. Look in log for details.")), w)
 			log.Printf("ApiServer panic'd: %#v\n%s\n", x, debug.Stack())
 		}
 	}()
 	log.Printf("%s %s", req.Method, req.RequestURI)
 	url, err := url.ParseRequestURI(req.RequestURI)
 	if err != nil {
-		server.error(err, w)
+		server.error(api.NewInvalid("request URI", "", err), w)
 		return
 	}
 	if url.Path == "/index.html" || url.Path == "/" || url.Path == "" {
@@ -100,38 +189,292 @@ func (server *ApiServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		server.notFound(req, w)
 		return
 	}
+	namespace := api.NamespaceAll
+	if requestParts[0] == "namespaces" {
+		if len(requestParts) < 3 || len(requestParts[1]) == 0 {
+			server.notFound(req, w)
+			return
+		}
+		namespace = requestParts[1]
+		requestParts = requestParts[2:]
+	}
+	if requestParts[0] == "watch" {
+		server.handleWatch(requestParts[1:], namespace, url, req, w)
+		return
+	}
+	if requestParts[0] == "proxy" {
+		server.handleProxy(requestParts[1:], namespace, url, req, w)
+		return
+	}
+	if requestParts[0] == "operations" && namespace == api.NamespaceAll {
+		server.handleOperation(requestParts[1:], url, req, w)
+		return
+	}
+	if requestParts[0] == "schemas" && namespace == api.NamespaceAll {
+		server.handleSchema(requestParts[1:], req, w)
+		return
+	}
 	storage := server.storage[requestParts[0]]
-	if storage == nil {
+	if storage == nil || (isClusterScoped(storage) && namespace != api.NamespaceAll) {
 		server.notFound(req, w)
 		return
 	} else {
-		server.handleREST(requestParts, url, req, w, storage)
+		server.handleREST(requestParts, namespace, url, req, w, storage)
 	}
 }
 
 func (server *ApiServer) notFound(req *http.Request, w http.ResponseWriter) {
-	w.WriteHeader(404)
-	fmt.Fprintf(w, "Not Found: %#v", req)
+	server.write(http.StatusNotFound, api.Status{
+		Status:  api.StatusFailure,
+		Message: fmt.Sprintf("%s %s not found", req.Method, req.URL.Path),
+		Reason:  api.StatusReasonNotFound,
+		Code:    http.StatusNotFound,
+	}, w)
+}
+
+// encode returns the indented JSON of 'object' in the version of the server.
+func (server *ApiServer) encode(object interface{}) ([]byte, error) {
+	data, err := api.EncodeVersion(object, server.version)
+	if err != nil {
+		return nil, err
+	}
+	var output bytes.Buffer
+	if err := json.Indent(&output, data, "", "    "); err != nil {
+		return nil, err
+	}
+	return output.Bytes(), nil
+}
+
+// encodeFor returns the encoding of 'object' that 'w' sends, and its content type: YAML if the
+// request accepts it, or else indented JSON.
+func (server *ApiServer) encodeFor(object interface{}, w http.ResponseWriter) ([]byte, string, error) {
+	output, err := server.encode(object)
+	if err != nil || !wantsYAML(w) {
+		return output, "application/json", err
+	}
+	output, err = jsonToYAML(output)
+	return output, "application/yaml", err
 }
 
 func (server *ApiServer) write(statusCode int, object interface{}, w http.ResponseWriter) {
-	w.WriteHeader(statusCode)
-	output, err := json.MarshalIndent(object, "", "    ")
+	output, contentType, err := server.encodeFor(object, w)
 	if err != nil {
 		server.error(err, w)
 		return
 	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
 	w.Write(output)
 }
 
+// error writes the Status of 'err' to the response, with its status code.
 func (server *ApiServer) error(err error, w http.ResponseWriter) {
-	w.WriteHeader(500)
-	fmt.Fprintf(w, "Internal Error: %#v", err)
+	status := api.ErrorToStatus(err)
+	if status.Reason == api.StatusReasonInternalError {
+		log.Printf("Internal error: %#v", err)
+	}
+	output, contentType, err := server.encodeFor(status, w)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "Internal Error: %#v", err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status.Code)
+	w.Write(output)
 }
 
+// extract reads the object in the body of 'req' with 'storage'. Errors reading the object
+// are reported as invalid requests, unless the storage picked another status.
+func (server *ApiServer) extract(storage RESTStorage, req *http.Request) (interface{}, error) {
+	body, err := server.readBody(req)
+	if err != nil {
+		return nil, api.NewInvalid("request body", "", err)
+	}
+	return server.decode(storage, body)
+}
+
+// decode reads the object in 'body' with 'storage', and reports errors like extract.
+func (server *ApiServer) decode(storage RESTStorage, body string) (interface{}, error) {
+	obj, err := storage.Extract(body, server.version)
+	if err != nil {
+		if _, ok := err.(*api.StatusError); !ok {
+			err = api.NewInvalid("request body", "", err)
+		}
+		return nil, err
+	}
+	return obj, nil
+}
+
+// stream copies a sub resource from 'streamer' to the response until it ends or the client goes away.
+func (server *ApiServer) stream(streamer RESTStreamer, namespace, id, name string, params url.Values, req *http.Request, w http.ResponseWriter) {
+	stream, err := streamer.Stream(namespace, id, name, params)
+	if err != nil {
+		server.error(err, w)
+		return
+	}
+	if stream == nil {
+		server.notFound(req, w)
+		return
+	}
+	defer stream.Close()
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(200)
+	if err := util.StreamResponse(w, stream); err != nil {
+		log.Printf("Error streaming %s of %s: %#v", name, id, err)
+	}
+}
+
+// handleWatch streams the changes to the objects in 'namespace' of the storage named by 'parts'
+// as WatchEvents, until the client goes away or the watch ends. The "labels" parameter selects
+// the objects, and "resourceVersion" the version to watch from.
+func (server *ApiServer) handleWatch(parts []string, namespace string, requestUrl *url.URL, req *http.Request, w http.ResponseWriter) {
+	if req.Method != "GET" || len(parts) != 1 {
+		server.notFound(req, w)
+		return
+	}
+	watcher, ok := server.storage[parts[0]].(ResourceWatcher)
+	if !ok || (isClusterScoped(server.storage[parts[0]]) && namespace != api.NamespaceAll) {
+		server.notFound(req, w)
+		return
+	}
+	if !server.authorize(req, "watch", namespace, parts[0], w) {
+		return
+	}
+	query, err := labels.ParseQuery(requestUrl.Query().Get("labels"))
+	if err != nil {
+		server.error(api.NewInvalid("label query", "", err), w)
+		return
+	}
+	resourceVersion := uint64(0)
+	if value := requestUrl.Query().Get("resourceVersion"); len(value) > 0 {
+		resourceVersion, err = strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			server.error(api.NewInvalid("resourceVersion", "", err), w)
+			return
+		}
+	}
+	watching, err := watcher.Watch(namespace, query, resourceVersion)
+	if err != nil {
+		server.error(err, w)
+		return
+	}
+	defer watching.Stop()
+
+	var gone <-chan bool
+	if notifier, ok := w.(http.CloseNotifier); ok {
+		gone = notifier.CloseNotify()
+	}
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case <-gone:
+			return
+		case event, ok := <-watching.ResultChan():
+			if !ok {
+				return
+			}
+			object, err := api.EncodeVersion(event.Object, server.version)
+			if err != nil {
+				log.Printf("Error encoding watch event: %#v", err)
+				return
+			}
+			if err := encoder.Encode(WatchEvent{event.Type, json.RawMessage(object)}); err != nil {
+				log.Printf("Error writing watch event: %#v", err)
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// timeout returns the "timeout" parameter of 'requestUrl', and whether there is one.
+func timeout(requestUrl *url.URL) (time.Duration, bool, error) {
+	value := requestUrl.Query().Get("timeout")
+	if len(value) == 0 {
+		return 0, false, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, false, api.NewInvalid("timeout", "", err)
+	}
+	return timeout, true, nil
+}
+
+// finish answers a create or update of 'obj' in 'storage'. If the request has a timeout, the
+// work it started is tracked as an operation, which is waited on for up to the timeout.
+func (server *ApiServer) finish(storage RESTStorage, obj interface{}, requestUrl *url.URL, w http.ResponseWriter) {
+	timeout, ok, err := timeout(requestUrl)
+	if err != nil {
+		server.error(err, w)
+		return
+	}
+	if !ok {
+		server.write(200, obj, w)
+		return
+	}
+	server.wait(server.ops.Start(storage, obj), timeout, w)
+}
+
+// wait waits for up to 'timeout' for 'op', and answers with its result if it finished, or
+// with a working status that names it if it did not.
+func (server *ApiServer) wait(op *Operation, timeout time.Duration, w http.ResponseWriter) {
+	if !op.Wait(timeout) {
+		server.write(http.StatusAccepted, op.workingStatus(), w)
+		return
+	}
+	result, err := op.Result()
+	if err != nil {
+		server.error(err, w)
+		return
+	}
+	server.write(200, result, w)
+}
+
+// handleOperation lists the operations of the server, or waits for the operation named by
+// 'parts' for up to the "timeout" parameter.
+func (server *ApiServer) handleOperation(parts []string, requestUrl *url.URL, req *http.Request, w http.ResponseWriter) {
+	if req.Method != "GET" || len(parts) > 1 {
+		server.notFound(req, w)
+		return
+	}
+	if len(parts) == 0 || len(parts[0]) == 0 {
+		if server.authorize(req, "list", api.NamespaceAll, "operations", w) {
+			server.write(200, server.ops.List(), w)
+		}
+		return
+	}
+	if !server.authorize(req, "get", api.NamespaceAll, "operations", w) {
+		return
+	}
+	op := server.ops.Get(parts[0])
+	if op == nil {
+		server.error(api.NewNotFound("operation", parts[0]), w)
+		return
+	}
+	timeout, _, err := timeout(requestUrl)
+	if err != nil {
+		server.error(err, w)
+		return
+	}
+	server.wait(op, timeout, w)
+}
+
+// readBody returns the JSON in the body of 'req'. Bodies sent as YAML are converted to JSON.
 func (server *ApiServer) readBody(req *http.Request) (string, error) {
 	defer req.Body.Close()
 	body, err := ioutil.ReadAll(req.Body)
+	if err == nil && isYAML(req.Header.Get("Content-Type")) {
+		body, err = yamlToJSON(body)
+	}
 	return string(body), err
 }
 
@@ -140,28 +483,61 @@ func (server *ApiServer) readBody(req *http.Request) (string, error) {
 //   Method     Path          Action
 //   GET        /foo          list
 //   GET        /foo/bar      get 'bar'
+//   GET        /foo/bar/baz  stream 'baz' of 'bar', if the storage is a RESTStreamer
 //   POST       /foo          create
+//   POST       /foo/bar/baz  perform action 'baz' on 'bar', if the storage is a RESTActor
 //   PUT        /foo/bar      update 'bar'
+//   PATCH      /foo/bar      apply a JSON merge patch to 'bar', unless the storage is Unpatchable
 //   DELETE     /foo/bar      delete 'bar'
-// Returns 404 if the method/pattern doesn't match one of these entries
-func (server *ApiServer) handleREST(parts []string, requestUrl *url.URL, req *http.Request, w http.ResponseWriter, storage RESTStorage) {
+// Returns 404 if the method/pattern doesn't match one of these entries. Failures are
+// answered with an api.Status. Creates and updates with a "timeout" parameter wait for the
+// work they start, and answer 202 with the operation if it is not done in time. Requests the
+// authorizer denies are answered with 403 before the storage is called, as are creates and
+// updates of objects the admission control rejects. Requests that change objects are audited,
+// if the server has an audit sink. Lists span every namespace if 'namespace' is
+// api.NamespaceAll; other calls are then in the default namespace. Creates and updates are
+// authorized, admitted and audited in the namespace of the object they store.
+func (server *ApiServer) handleREST(parts []string, namespace string, requestUrl *url.URL, req *http.Request, w http.ResponseWriter, storage RESTStorage) {
+	verb := restVerb(req.Method, parts)
+	var obj interface{}
+	var objErr error
+	if (verb == "create" && len(parts) == 1) || (verb == "update" && len(parts) == 2) {
+		obj, objErr = server.extract(storage, req)
+		if objErr == nil {
+			obj, objErr = inNamespace(storage, obj, namespace)
+		}
+	}
+	if verb != "list" {
+		namespace = objectNamespace(storage, namespace)
+	}
+	if objErr == nil && obj != nil && !isClusterScoped(storage) {
+		namespace = namespaceOf(obj, namespace)
+	}
+	audited := server.audit(req, verb, namespace, parts, w)
+	if audited != nil {
+		w = audited
+		defer audited.finish()
+	}
+	if !server.authorize(req, verb, namespace, parts[0], w) {
+		return
+	}
 	switch req.Method {
 	case "GET":
 		switch len(parts) {
 		case 1:
 			query, err := labels.ParseQuery(requestUrl.Query().Get("labels"))
 			if err != nil {
-				server.error(err, w)
+				server.error(api.NewInvalid("label query", "", err), w)
 				return
 			}
-			controllers, err := storage.List(query)
+			controllers, err := storage.List(namespace, query)
 			if err != nil {
 				server.error(err, w)
 				return
 			}
 			server.write(200, controllers, w)
 		case 2:
-			item, err := storage.Get(parts[1])
+			item, err := storage.Get(namespace, parts[1])
 			if err != nil {
 				server.error(err, w)
 				return
@@ -171,21 +547,47 @@ func (server *ApiServer) handleREST(parts []string, requestUrl *url.URL, req *ht
 				return
 			}
 			server.write(200, item, w)
+		case 3:
+			streamer, ok := storage.(RESTStreamer)
+			if !ok {
+				server.notFound(req, w)
+				return
+			}
+			server.stream(streamer, namespace, parts[1], parts[2], requestUrl.Query(), req, w)
 		default:
 			server.notFound(req, w)
 		}
 		return
 	case "POST":
+		if len(parts) == 3 {
+			actor, ok := storage.(RESTActor)
+			if !ok {
+				server.notFound(req, w)
+				return
+			}
+			result, err := actor.Act(namespace, parts[1], parts[2], requestUrl.Query())
+			if err != nil {
+				server.error(err, w)
+				return
+			}
+			if result == nil {
+				server.notFound(req, w)
+				return
+			}
+			server.write(200, result, w)
+			return
+		}
 		if len(parts) != 1 {
 			server.notFound(req, w)
 			return
 		}
-		body, err := server.readBody(req)
-		if err != nil {
-			server.error(err, w)
-			return
+		err := objErr
+		if err == nil && audited != nil {
+			audited.describe(obj)
+		}
+		if err == nil {
+			obj, err = server.admit(req, verb, namespace, parts[0], obj)
 		}
-		obj, err := storage.Extract(body)
 		if err != nil {
 			server.error(err, w)
 			return
@@ -194,7 +596,7 @@ func (server *ApiServer) handleREST(parts []string, requestUrl *url.URL, req *ht
 		if err != nil {
 			server.error(err, w)
 		} else {
-			server.write(200, obj, w)
+			server.finish(storage, obj, requestUrl, w)
 		}
 		return
 	case "DELETE":
@@ -202,33 +604,51 @@ func (server *ApiServer) handleREST(parts []string, requestUrl *url.URL, req *ht
 			server.notFound(req, w)
 			return
 		}
-		err := storage.Delete(parts[1])
+		err := storage.Delete(namespace, parts[1])
 		if err != nil {
 			server.error(err, w)
 			return
 		}
-		server.write(200, Status{Success: true}, w)
+		server.write(200, api.Status{Status: api.StatusSuccess, Code: http.StatusOK}, w)
 		return
 	case "PUT":
 		if len(parts) != 2 {
 			server.notFound(req, w)
 			return
 		}
-		body, err := server.readBody(req)
+		err := objErr
+		if err == nil && audited != nil {
+			audited.describe(obj)
+		}
+		if err == nil {
+			obj, err = server.admit(req, verb, namespace, parts[0], obj)
+		}
 		if err != nil {
 			server.error(err, w)
+			return
 		}
-		obj, err := storage.Extract(body)
+		err = storage.Update(obj)
 		if err != nil {
 			server.error(err, w)
 			return
 		}
-		err = storage.Update(obj)
+		server.finish(storage, obj, requestUrl, w)
+		return
+	case "PATCH":
+		if len(parts) != 2 {
+			server.notFound(req, w)
+			return
+		}
+		obj, err := server.patch(storage, namespace, parts[0], parts[1], req)
 		if err != nil {
 			server.error(err, w)
 			return
 		}
-		server.write(200, obj, w)
+		if obj == nil {
+			server.notFound(req, w)
+			return
+		}
+		server.finish(storage, obj, requestUrl, w)
 		return
 	default:
 		server.notFound(req, w)
@@ -19,13 +19,17 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
 // TODO: This doesn't reduce typing enough to make it worth the less readable errors. Remove.
@@ -44,30 +48,34 @@ type SimpleList struct {
 }
 
 type SimpleRESTStorage struct {
-	err     error
-	list    []Simple
-	item    Simple
-	deleted string
-	updated Simple
+	err       error
+	list      []Simple
+	item      Simple
+	deleted   string
+	updated   Simple
+	namespace string
 }
 
-func (storage *SimpleRESTStorage) List(labels.Query) (interface{}, error) {
+func (storage *SimpleRESTStorage) List(namespace string, query labels.Query) (interface{}, error) {
+	storage.namespace = namespace
 	result := SimpleList{
 		Items: storage.list,
 	}
 	return result, storage.err
 }
 
-func (storage *SimpleRESTStorage) Get(id string) (interface{}, error) {
+func (storage *SimpleRESTStorage) Get(namespace, id string) (interface{}, error) {
+	storage.namespace = namespace
 	return storage.item, storage.err
 }
 
-func (storage *SimpleRESTStorage) Delete(id string) error {
+func (storage *SimpleRESTStorage) Delete(namespace, id string) error {
+	storage.namespace = namespace
 	storage.deleted = id
 	return storage.err
 }
 
-func (storage *SimpleRESTStorage) Extract(body string) (interface{}, error) {
+func (storage *SimpleRESTStorage) Extract(body string, version string) (interface{}, error) {
 	var item Simple
 	json.Unmarshal([]byte(body), &item)
 	return item, storage.err
@@ -281,3 +289,308 @@ func TestCreate(t *testing.T) {
 		t.Errorf("Unexpected data: %#v, expected %#v (%s)", itemOut, simple, string(body))
 	}
 }
+
+func TestCreateInvalid(t *testing.T) {
+	handler := New(map[string]RESTStorage{
+		"foo": &SimpleRESTStorage{err: fmt.Errorf("bad body")},
+	}, "/prefix/version")
+	server := httptest.NewServer(handler)
+
+	response, err := http.Post(server.URL+"/prefix/version/foo", "application/json", bytes.NewBufferString("{"))
+	expectNoError(t, err)
+	var status api.Status
+	body, err := extractBody(response, &status)
+	expectNoError(t, err)
+	if response.StatusCode != 422 || status.Reason != api.StatusReasonInvalid || status.Code != 422 {
+		t.Errorf("Unexpected response: %d %s", response.StatusCode, body)
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	table := []struct {
+		err    error
+		code   int
+		reason api.StatusReason
+	}{
+		{api.NewNotFound("simple", "id"), 404, api.StatusReasonNotFound},
+		{api.NewAlreadyExists("simple", "id"), 409, api.StatusReasonAlreadyExists},
+		{api.NewConflict("simple", "id", fmt.Errorf("busy")), 409, api.StatusReasonConflict},
+		{api.NewInvalid("simple", "id", fmt.Errorf("no name")), 422, api.StatusReasonInvalid},
+		{fmt.Errorf("etcd down"), 500, api.StatusReasonInternalError},
+	}
+	for _, item := range table {
+		handler := New(map[string]RESTStorage{
+			"simple": &SimpleRESTStorage{err: item.err},
+		}, "/prefix/version")
+		server := httptest.NewServer(handler)
+
+		response, err := http.Get(server.URL + "/prefix/version/simple/id")
+		expectNoError(t, err)
+		var status api.Status
+		body, err := extractBody(response, &status)
+		expectNoError(t, err)
+		if response.StatusCode != item.code || status.Code != item.code || status.Reason != item.reason || status.Status != api.StatusFailure {
+			t.Errorf("Unexpected response for %v: %d %s", item.err, response.StatusCode, body)
+		}
+		server.Close()
+	}
+}
+
+func TestNotFoundStatus(t *testing.T) {
+	handler := New(map[string]RESTStorage{}, "/prefix/version")
+	server := httptest.NewServer(handler)
+
+	response, err := http.Get(server.URL + "/prefix/version/foobar")
+	expectNoError(t, err)
+	var status api.Status
+	body, err := extractBody(response, &status)
+	expectNoError(t, err)
+	if response.StatusCode != 404 || status.Reason != api.StatusReasonNotFound || status.Message != "GET /prefix/version/foobar not found" {
+		t.Errorf("Unexpected response: %d %s", response.StatusCode, body)
+	}
+}
+
+type StreamingRESTStorage struct {
+	SimpleRESTStorage
+	params url.Values
+}
+
+func (storage *StreamingRESTStorage) Stream(namespace, id, name string, params url.Values) (io.ReadCloser, error) {
+	storage.params = params
+	if id != "id" || name != "log" {
+		return nil, storage.err
+	}
+	return ioutil.NopCloser(bytes.NewBufferString("line 1\nline 2\n")), storage.err
+}
+
+func TestStream(t *testing.T) {
+	storage := map[string]RESTStorage{}
+	streamingStorage := StreamingRESTStorage{}
+	storage["simple"] = &streamingStorage
+	handler := New(storage, "/prefix/version")
+	server := httptest.NewServer(handler)
+
+	resp, err := http.Get(server.URL + "/prefix/version/simple/id/log?follow=true")
+	expectNoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	expectNoError(t, err)
+	if resp.StatusCode != 200 || string(body) != "line 1\nline 2\n" {
+		t.Errorf("Unexpected response: %d %s", resp.StatusCode, string(body))
+	}
+	if streamingStorage.params.Get("follow") != "true" {
+		t.Errorf("Unexpected params: %#v", streamingStorage.params)
+	}
+
+	resp, err = http.Get(server.URL + "/prefix/version/simple/id/other")
+	expectNoError(t, err)
+	resp.Body.Close()
+	if resp.StatusCode != 404 {
+		t.Errorf("Unexpected status: %d", resp.StatusCode)
+	}
+}
+
+func TestStreamNotSupported(t *testing.T) {
+	storage := map[string]RESTStorage{}
+	storage["simple"] = &SimpleRESTStorage{}
+	handler := New(storage, "/prefix/version")
+	server := httptest.NewServer(handler)
+
+	resp, err := http.Get(server.URL + "/prefix/version/simple/id/log")
+	expectNoError(t, err)
+	resp.Body.Close()
+	if resp.StatusCode != 404 {
+		t.Errorf("Unexpected status: %d", resp.StatusCode)
+	}
+}
+
+type ActingRESTStorage struct {
+	SimpleRESTStorage
+	params url.Values
+}
+
+func (storage *ActingRESTStorage) Act(namespace, id, action string, params url.Values) (interface{}, error) {
+	storage.params = params
+	if id != "id" || action != "restart" {
+		return nil, storage.err
+	}
+	return Simple{Name: "restarted"}, storage.err
+}
+
+func TestAct(t *testing.T) {
+	storage := map[string]RESTStorage{}
+	actingStorage := ActingRESTStorage{}
+	storage["simple"] = &actingStorage
+	handler := New(storage, "/prefix/version")
+	server := httptest.NewServer(handler)
+
+	resp, err := http.Post(server.URL+"/prefix/version/simple/id/restart?force=true", "application/json", nil)
+	expectNoError(t, err)
+	var itemOut Simple
+	body, err := extractBody(resp, &itemOut)
+	expectNoError(t, err)
+	if resp.StatusCode != 200 || itemOut.Name != "restarted" {
+		t.Errorf("Unexpected response: %d %s", resp.StatusCode, body)
+	}
+	if actingStorage.params.Get("force") != "true" {
+		t.Errorf("Unexpected params: %#v", actingStorage.params)
+	}
+
+	resp, err = http.Post(server.URL+"/prefix/version/simple/id/other", "application/json", nil)
+	expectNoError(t, err)
+	resp.Body.Close()
+	if resp.StatusCode != 404 {
+		t.Errorf("Unexpected status: %d", resp.StatusCode)
+	}
+
+	actingStorage.err = fmt.Errorf("test error")
+	resp, err = http.Post(server.URL+"/prefix/version/simple/id/restart", "application/json", nil)
+	expectNoError(t, err)
+	resp.Body.Close()
+	if resp.StatusCode != 500 {
+		t.Errorf("Unexpected status: %d", resp.StatusCode)
+	}
+}
+
+func TestActNotSupported(t *testing.T) {
+	storage := map[string]RESTStorage{}
+	storage["simple"] = &SimpleRESTStorage{}
+	handler := New(storage, "/prefix/version")
+	server := httptest.NewServer(handler)
+
+	resp, err := http.Post(server.URL+"/prefix/version/simple/id/restart", "application/json", nil)
+	expectNoError(t, err)
+	resp.Body.Close()
+	if resp.StatusCode != 404 {
+		t.Errorf("Unexpected status: %d", resp.StatusCode)
+	}
+}
+
+type WatchingRESTStorage struct {
+	SimpleRESTStorage
+	watcher         *watch.FakeWatcher
+	query           labels.Query
+	resourceVersion uint64
+}
+
+func (storage *WatchingRESTStorage) Watch(namespace string, query labels.Query, resourceVersion uint64) (watch.Interface, error) {
+	storage.query = query
+	storage.resourceVersion = resourceVersion
+	return storage.watcher, storage.err
+}
+
+func TestWatch(t *testing.T) {
+	watchingStorage := &WatchingRESTStorage{watcher: watch.MakeFakeWatcher()}
+	handler := New(map[string]RESTStorage{"simple": watchingStorage}, "/prefix/version")
+	server := httptest.NewServer(handler)
+
+	resp, err := http.Get(server.URL + "/prefix/version/watch/simple?labels=name%3Dfoo&resourceVersion=7")
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Errorf("Unexpected status: %d", resp.StatusCode)
+	}
+	if watchingStorage.query.String() != "name=foo" || watchingStorage.resourceVersion != 7 {
+		t.Errorf("Unexpected watch: %v %d", watchingStorage.query, watchingStorage.resourceVersion)
+	}
+
+	go func() {
+		watchingStorage.watcher.Add(Simple{Name: "foo"})
+		watchingStorage.watcher.Delete(Simple{Name: "bar"})
+		watchingStorage.watcher.Stop()
+	}()
+	decoder := json.NewDecoder(resp.Body)
+	events := []WatchEvent{}
+	for {
+		var event struct {
+			Type   watch.EventType
+			Object Simple
+		}
+		if err := decoder.Decode(&event); err != nil {
+			if err != io.EOF {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+			break
+		}
+		events = append(events, WatchEvent{event.Type, event.Object})
+	}
+	expected := []WatchEvent{{watch.Added, Simple{Name: "foo"}}, {watch.Deleted, Simple{Name: "bar"}}}
+	if !reflect.DeepEqual(expected, events) {
+		t.Errorf("Expected %#v, got %#v", expected, events)
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	handler := New(map[string]RESTStorage{
+		"simple":   &SimpleRESTStorage{},
+		"watching": &WatchingRESTStorage{watcher: watch.MakeFakeWatcher()},
+	}, "/prefix/version")
+	server := httptest.NewServer(handler)
+
+	table := map[string]int{
+		"/prefix/version/watch/simple":                          404,
+		"/prefix/version/watch/missing":                         404,
+		"/prefix/version/watch/watching/id":                     404,
+		"/prefix/version/watch/watching?resourceVersion=latest": 422,
+		"/prefix/version/watch/watching?labels=name":            422,
+	}
+	for path, code := range table {
+		resp, err := http.Get(server.URL + path)
+		expectNoError(t, err)
+		resp.Body.Close()
+		if resp.StatusCode != code {
+			t.Errorf("Unexpected status for %s: %d, expected %d", path, resp.StatusCode, code)
+		}
+	}
+}
+
+// ServiceRESTStorage holds an api.Service, to test that objects are sent and received in the
+// version of the ApiServer.
+type ServiceRESTStorage struct {
+	SimpleRESTStorage
+	service api.Service
+	created api.Service
+}
+
+func (storage *ServiceRESTStorage) Get(namespace, id string) (interface{}, error) {
+	return &storage.service, nil
+}
+
+func (storage *ServiceRESTStorage) Extract(body string, version string) (interface{}, error) {
+	var service api.Service
+	err := api.DecodeVersionInto([]byte(body), version, &service)
+	return service, err
+}
+
+func (storage *ServiceRESTStorage) Create(obj interface{}) error {
+	storage.created = obj.(api.Service)
+	return nil
+}
+
+func TestVersioned(t *testing.T) {
+	storage := &ServiceRESTStorage{
+		service: api.Service{JSONBase: api.JSONBase{ID: "foo"}, Port: 80, Labels: map[string]string{"name": "foo"}},
+	}
+	handler := NewVersioned(map[string]RESTStorage{"services": storage}, MakeOperations(), "/api/v1beta2", "v1beta2")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/api/v1beta2/services/foo")
+	expectNoError(t, err)
+	var service map[string]interface{}
+	body, err := extractBody(response, &service)
+	expectNoError(t, err)
+	if service["apiVersion"] != "v1beta2" || service["selector"] == nil || service["labels"] != nil {
+		t.Errorf("Unexpected service: %s", body)
+	}
+
+	response, err = http.Post(server.URL+"/api/v1beta2/services", "application/json", bytes.NewBufferString(`{"id":"bar","port":80,"selector":{"name":"bar"}}`))
+	expectNoError(t, err)
+	body, err = extractBody(response, &service)
+	expectNoError(t, err)
+	if response.StatusCode != 200 || !reflect.DeepEqual(storage.created.Labels, map[string]string{"name": "bar"}) {
+		t.Errorf("Unexpected response: %d %s %#v", response.StatusCode, body, storage.created)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/audit"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth"
+)
+
+// SetAuditSink makes the server record every request that changes objects in 'sink' once it is
+// answered, including the ones that are denied or fail.
+func (server *ApiServer) SetAuditSink(sink audit.Sink) {
+	server.auditSink = sink
+}
+
+// auditWriter is the response writer of an audited request. It remembers the status code of
+// the response.
+type auditWriter struct {
+	http.ResponseWriter
+	sink  audit.Sink
+	event audit.Event
+}
+
+// audit returns the response writer for a 'verb' of the object named by 'parts', if the request
+// is audited, or nil if it is not. The request is recorded when finish is called.
+func (server *ApiServer) audit(req *http.Request, verb, namespace string, parts []string, w http.ResponseWriter) *auditWriter {
+	if server.auditSink == nil || (auth.Attributes{Verb: verb}).IsReadOnly() {
+		return nil
+	}
+	event := audit.Event{
+		Time:      time.Now(),
+		SourceIP:  req.RemoteAddr,
+		Verb:      verb,
+		Namespace: namespace,
+		Resource:  parts[0],
+		Code:      http.StatusOK,
+	}
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		event.SourceIP = host
+	}
+	if server.requests != nil {
+		if user := server.requests.User(req); user != nil {
+			event.User = user.Name
+		}
+	}
+	if len(parts) > 1 {
+		event.ID = parts[1]
+	}
+	return &auditWriter{ResponseWriter: w, sink: server.auditSink, event: event}
+}
+
+func (w *auditWriter) WriteHeader(code int) {
+	w.event.Code = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// describe records the id and namespace of 'obj', the object a create or update stores.
+func (w *auditWriter) describe(obj interface{}) {
+	value := reflect.ValueOf(obj)
+	if value.Kind() != reflect.Struct {
+		return
+	}
+	if id := value.FieldByName("ID"); id.IsValid() && id.Kind() == reflect.String && len(id.String()) > 0 {
+		w.event.ID = id.String()
+	}
+	if namespace := value.FieldByName("Namespace"); namespace.IsValid() && namespace.Kind() == reflect.String && len(namespace.String()) > 0 {
+		w.event.Namespace = namespace.String()
+	}
+}
+
+// finish records the request.
+func (w *auditWriter) finish() {
+	w.event.Latency = time.Since(w.event.Time)
+	if err := w.sink.Record(w.event); err != nil {
+		log.Printf("Failed to record audit event %#v: %v", w.event, err)
+	}
+}
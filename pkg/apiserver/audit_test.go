@@ -0,0 +1,91 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/audit"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth"
+)
+
+type recordingSink struct {
+	events []audit.Event
+}
+
+func (r *recordingSink) Record(event audit.Event) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestAudit(t *testing.T) {
+	handler := New(map[string]RESTStorage{"simple": &NamespacedRESTStorage{}}, "/prefix/version")
+	requests := auth.MakeRequests()
+	handler.SetAuthorizer(auth.PolicyList{{User: "admin"}, {User: "viewer", ReadOnly: true}}, requests)
+	sink := &recordingSink{}
+	handler.SetAuditSink(sink)
+	server := httptest.NewServer(&auth.Handler{
+		Requests:      requests,
+		Authenticator: auth.BearerToken{Token: fakeTokens{"a": "admin", "v": "viewer"}},
+		Handler:       handler,
+	})
+
+	table := []struct {
+		token    string
+		method   string
+		path     string
+		body     string
+		expected *audit.Event
+	}{
+		{"a", "GET", "/simple", "", nil},
+		{"a", "GET", "/simple/foo", "", nil},
+		{"a", "DELETE", "/simple/foo", "", &audit.Event{User: "admin", Verb: "delete", Namespace: "default", Resource: "simple", ID: "foo", Code: 200}},
+		{"v", "DELETE", "/namespaces/team/simple/foo", "", &audit.Event{User: "viewer", Verb: "delete", Namespace: "team", Resource: "simple", ID: "foo", Code: 403}},
+		{"a", "POST", "/simple", `{"Name":"foo","Namespace":"team"}`, &audit.Event{User: "admin", Verb: "create", Namespace: "team", Resource: "simple", Code: 200}},
+		{"a", "PUT", "/namespaces/team/simple/foo", `{"Name":"foo","Namespace":"other"}`, &audit.Event{User: "admin", Verb: "update", Namespace: "team", Resource: "simple", ID: "foo", Code: 422}},
+	}
+	for _, item := range table {
+		sink.events = nil
+		request, err := http.NewRequest(item.method, server.URL+"/prefix/version"+item.path, bytes.NewBufferString(item.body))
+		expectNoError(t, err)
+		request.Header.Set("Authorization", "Bearer "+item.token)
+		response, err := http.DefaultClient.Do(request)
+		expectNoError(t, err)
+		response.Body.Close()
+		if item.expected == nil {
+			if len(sink.events) != 0 {
+				t.Errorf("Unexpected events for %#v: %#v", item, sink.events)
+			}
+			continue
+		}
+		if len(sink.events) != 1 {
+			t.Errorf("Unexpected events for %#v: %#v", item, sink.events)
+			continue
+		}
+		event := sink.events[0]
+		if event.Time.IsZero() || event.Latency <= 0 || event.SourceIP != "127.0.0.1" {
+			t.Errorf("Unexpected event for %#v: %#v", item, event)
+		}
+		event.Time, event.Latency, event.SourceIP = item.expected.Time, item.expected.Latency, item.expected.SourceIP
+		if event != *item.expected {
+			t.Errorf("Unexpected event for %#v: %#v", item, event)
+		}
+	}
+}
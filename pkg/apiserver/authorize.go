@@ -0,0 +1,67 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth"
+)
+
+// SetAuthorizer makes the server check every request with 'authorizer' before it is served. The
+// user of a request is the one 'requests' remembers for it.
+func (server *ApiServer) SetAuthorizer(authorizer auth.Authorizer, requests *auth.Requests) {
+	server.authorizer = authorizer
+	server.requests = requests
+}
+
+// authorize returns whether the user of 'req' may call 'verb' on 'resource' in 'namespace', and
+// answers 403 Forbidden if not.
+func (server *ApiServer) authorize(req *http.Request, verb, namespace, resource string, w http.ResponseWriter) bool {
+	if server.authorizer == nil {
+		return true
+	}
+	var user *auth.User
+	if server.requests != nil {
+		user = server.requests.User(req)
+	}
+	err := server.authorizer.Authorize(auth.Attributes{User: user, Verb: verb, Namespace: namespace, Resource: resource})
+	if err != nil {
+		server.error(api.NewForbidden(resource, verb, err), w)
+		return false
+	}
+	return true
+}
+
+// restVerb returns the verb that handleREST authorizes a request as.
+func restVerb(method string, parts []string) string {
+	switch {
+	case method == "GET" && len(parts) == 1:
+		return "list"
+	case method == "GET":
+		return "get"
+	case method == "POST" && len(parts) == 3:
+		return parts[2]
+	case method == "POST":
+		return "create"
+	case method == "PUT":
+		return "update"
+	}
+	return strings.ToLower(method)
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth"
+)
+
+type fakeTokens map[string]string
+
+func (f fakeTokens) AuthenticateToken(token string) (*auth.User, bool, error) {
+	if name, ok := f[token]; ok {
+		return &auth.User{Name: name}, true, nil
+	}
+	return nil, false, nil
+}
+
+type recordingAuthorizer struct {
+	attributes []auth.Attributes
+	policies   auth.PolicyList
+}
+
+func (r *recordingAuthorizer) Authorize(a auth.Attributes) error {
+	r.attributes = append(r.attributes, a)
+	return r.policies.Authorize(a)
+}
+
+func TestAuthorize(t *testing.T) {
+	simpleStorage := &ActingRESTStorage{}
+	handler := New(map[string]RESTStorage{"simple": simpleStorage}, "/prefix/version")
+	authorizer := &recordingAuthorizer{policies: auth.PolicyList{
+		{User: "admin"},
+		{User: "viewer", ReadOnly: true},
+	}}
+	requests := auth.MakeRequests()
+	handler.SetAuthorizer(authorizer, requests)
+	server := httptest.NewServer(&auth.Handler{
+		Requests:      requests,
+		Authenticator: auth.BearerToken{Token: fakeTokens{"a": "admin", "v": "viewer"}},
+		Handler:       handler,
+	})
+
+	table := []struct {
+		token     string
+		method    string
+		path      string
+		verb      string
+		namespace string
+		code      int
+	}{
+		{"v", "GET", "/simple", "list", "", 200},
+		{"v", "GET", "/simple/id", "get", "default", 200},
+		{"v", "GET", "/namespaces/other/simple", "list", "other", 200},
+		{"v", "DELETE", "/simple/id", "delete", "default", 403},
+		{"v", "POST", "/simple/id/restart", "restart", "default", 403},
+		{"v", "PATCH", "/simple/id", "patch", "default", 403},
+		{"v", "GET", "/operations", "list", "", 200},
+		{"a", "DELETE", "/simple/id", "delete", "default", 200},
+		{"a", "DELETE", "/namespaces/other/simple/id", "delete", "other", 200},
+		{"a", "POST", "/simple/id/restart", "restart", "default", 200},
+		{"a", "PUT", "/simple/id", "update", "default", 200},
+	}
+	for _, item := range table {
+		authorizer.attributes = nil
+		simpleStorage.deleted = ""
+		request, _ := http.NewRequest(item.method, server.URL+"/prefix/version"+item.path, nil)
+		request.Header.Set("Authorization", "Bearer "+item.token)
+		response, err := http.DefaultClient.Do(request)
+		expectNoError(t, err)
+		var status api.Status
+		body, _ := extractBody(response, &status)
+		if response.StatusCode != item.code {
+			t.Errorf("Unexpected response to %#v: %d %s", item, response.StatusCode, body)
+		}
+		if item.code == 403 && (status.Reason != api.StatusReasonForbidden || len(simpleStorage.deleted) > 0) {
+			t.Errorf("Unexpected response to %#v: %s", item, body)
+		}
+		if len(authorizer.attributes) != 1 || authorizer.attributes[0].Verb != item.verb || authorizer.attributes[0].Namespace != item.namespace || authorizer.attributes[0].User == nil {
+			t.Errorf("Unexpected authorization of %#v: %#v", item, authorizer.attributes)
+		}
+	}
+}
+
+func TestAuthorizeNamespaceOfBody(t *testing.T) {
+	simpleStorage := &NamespacedRESTStorage{}
+	handler := New(map[string]RESTStorage{"simple": simpleStorage}, "/prefix/version")
+	authorizer := &recordingAuthorizer{policies: auth.PolicyList{{Namespace: api.NamespaceDefault}}}
+	handler.SetAuthorizer(authorizer, nil)
+	server := httptest.NewServer(handler)
+
+	table := []struct {
+		body      string
+		namespace string
+		code      int
+	}{
+		{`{"Name":"foo","Namespace":"secret"}`, "secret", 403},
+		{`{"Name":"foo"}`, api.NamespaceDefault, 200},
+	}
+	for _, item := range table {
+		authorizer.attributes = nil
+		simpleStorage.created = Namespaced{}
+		response, err := http.Post(server.URL+"/prefix/version/simple", "application/json", bytes.NewBufferString(item.body))
+		expectNoError(t, err)
+		response.Body.Close()
+		if response.StatusCode != item.code {
+			t.Errorf("Unexpected response to %s: %d", item.body, response.StatusCode)
+		}
+		if len(authorizer.attributes) != 1 || authorizer.attributes[0].Namespace != item.namespace {
+			t.Errorf("Unexpected authorization of %s: %#v", item.body, authorizer.attributes)
+		}
+		if item.code == 200 && simpleStorage.created.Namespace != item.namespace {
+			t.Errorf("Unexpected created object: %#v", simpleStorage.created)
+		}
+		if item.code == 403 && simpleStorage.created != (Namespaced{}) {
+			t.Errorf("Unexpected created object: %#v", simpleStorage.created)
+		}
+	}
+}
+
+func TestAuthorizeWatch(t *testing.T) {
+	handler := New(map[string]RESTStorage{"simple": &WatchingRESTStorage{}}, "/prefix/version")
+	authorizer := &recordingAuthorizer{}
+	handler.SetAuthorizer(authorizer, nil)
+	server := httptest.NewServer(handler)
+
+	response, err := http.Get(server.URL + "/prefix/version/watch/simple")
+	expectNoError(t, err)
+	response.Body.Close()
+	expected := []auth.Attributes{{Verb: "watch", Resource: "simple"}}
+	if response.StatusCode != 403 || !reflect.DeepEqual(authorizer.attributes, expected) {
+		t.Errorf("Unexpected response: %d %#v", response.StatusCode, authorizer.attributes)
+	}
+}
+
+func TestNoAuthorizer(t *testing.T) {
+	handler := New(map[string]RESTStorage{"simple": &SimpleRESTStorage{err: fmt.Errorf("test error")}}, "/prefix/version")
+	if !handler.authorize(&http.Request{}, "delete", api.NamespaceDefault, "simple", httptest.NewRecorder()) {
+		t.Errorf("Expected requests to be allowed without an authorizer")
+	}
+}
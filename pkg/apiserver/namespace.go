@@ -0,0 +1,85 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+func isClusterScoped(storage RESTStorage) bool {
+	scoped, ok := storage.(ClusterScoped)
+	return ok && scoped.ClusterScoped()
+}
+
+// objectNamespace returns the namespace of the objects a request in 'namespace' to 'storage'
+// is for: the default namespace if the request is in none, and none for cluster storage.
+func objectNamespace(storage RESTStorage, namespace string) string {
+	if isClusterScoped(storage) {
+		return api.NamespaceAll
+	}
+	if namespace == api.NamespaceAll {
+		return api.NamespaceDefault
+	}
+	return namespace
+}
+
+// namespaceOf returns the namespace 'obj' is in, or 'namespace' if it has no Namespace field.
+func namespaceOf(obj interface{}, namespace string) string {
+	value := reflect.ValueOf(obj)
+	if value.Kind() != reflect.Struct {
+		return namespace
+	}
+	field := value.FieldByName("Namespace")
+	if !field.IsValid() || field.Kind() != reflect.String || len(field.String()) == 0 {
+		return namespace
+	}
+	return field.String()
+}
+
+// inNamespace returns a copy of 'obj' in 'namespace', for a create or update in 'namespace' of
+// 'storage'. Objects that are in another namespace are invalid. Outside of a namespace, objects
+// stay in their own namespace, or go in the default one. Objects of cluster storage, and
+// objects without a Namespace field, are left alone.
+func inNamespace(storage RESTStorage, obj interface{}, namespace string) (interface{}, error) {
+	if isClusterScoped(storage) {
+		return obj, nil
+	}
+	value := reflect.ValueOf(obj)
+	if value.Kind() != reflect.Struct {
+		return obj, nil
+	}
+	copy := reflect.New(value.Type()).Elem()
+	copy.Set(value)
+	field := copy.FieldByName("Namespace")
+	if !field.IsValid() || field.Kind() != reflect.String {
+		return obj, nil
+	}
+	current := field.String()
+	switch {
+	case namespace == api.NamespaceAll && len(current) == 0:
+		field.SetString(api.NamespaceDefault)
+	case namespace == api.NamespaceAll || current == namespace:
+	case len(current) == 0:
+		field.SetString(namespace)
+	default:
+		return nil, api.NewInvalid("namespace", current, fmt.Errorf("the request is in namespace %q", namespace))
+	}
+	return copy.Interface(), nil
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+type Namespaced struct {
+	Name      string
+	Namespace string
+}
+
+// NamespacedRESTStorage stores objects with a Namespace field.
+type NamespacedRESTStorage struct {
+	SimpleRESTStorage
+	created Namespaced
+}
+
+func (storage *NamespacedRESTStorage) Extract(body string, version string) (interface{}, error) {
+	var item Namespaced
+	err := json.Unmarshal([]byte(body), &item)
+	return item, err
+}
+
+func (storage *NamespacedRESTStorage) Create(obj interface{}) error {
+	storage.created = obj.(Namespaced)
+	return nil
+}
+
+type ClusterRESTStorage struct {
+	SimpleRESTStorage
+}
+
+func (storage *ClusterRESTStorage) ClusterScoped() bool {
+	return true
+}
+
+func TestInNamespace(t *testing.T) {
+	table := []struct {
+		obj       interface{}
+		namespace string
+		expected  interface{}
+		valid     bool
+	}{
+		{Namespaced{Name: "foo"}, "team", Namespaced{Name: "foo", Namespace: "team"}, true},
+		{Namespaced{Name: "foo", Namespace: "team"}, "team", Namespaced{Name: "foo", Namespace: "team"}, true},
+		{Namespaced{Name: "foo", Namespace: "other"}, "team", nil, false},
+		{Namespaced{Name: "foo"}, api.NamespaceAll, Namespaced{Name: "foo", Namespace: api.NamespaceDefault}, true},
+		{Namespaced{Name: "foo", Namespace: "other"}, api.NamespaceAll, Namespaced{Name: "foo", Namespace: "other"}, true},
+		{Simple{Name: "foo"}, "team", Simple{Name: "foo"}, true},
+	}
+	for _, item := range table {
+		obj, err := inNamespace(&SimpleRESTStorage{}, item.obj, item.namespace)
+		if item.valid != (err == nil) || (item.valid && obj != item.expected) {
+			t.Errorf("Unexpected result for %#v in %q: %#v %v", item.obj, item.namespace, obj, err)
+		}
+		if !item.valid && !api.IsInvalid(err) {
+			t.Errorf("Unexpected error: %#v", err)
+		}
+	}
+	obj, err := inNamespace(&ClusterRESTStorage{}, Namespaced{Name: "foo"}, api.NamespaceAll)
+	if err != nil || obj != (Namespaced{Name: "foo"}) {
+		t.Errorf("Unexpected result: %#v %v", obj, err)
+	}
+}
+
+func TestNamespacedPaths(t *testing.T) {
+	simpleStorage := &SimpleRESTStorage{}
+	handler := New(map[string]RESTStorage{"simple": simpleStorage}, "/prefix/version")
+	server := httptest.NewServer(handler)
+
+	table := []struct {
+		method    string
+		path      string
+		namespace string
+	}{
+		{"GET", "/simple", api.NamespaceAll},
+		{"GET", "/simple/id", api.NamespaceDefault},
+		{"DELETE", "/simple/id", api.NamespaceDefault},
+		{"GET", "/namespaces/team/simple", "team"},
+		{"GET", "/namespaces/team/simple/id", "team"},
+		{"DELETE", "/namespaces/team/simple/id", "team"},
+	}
+	for _, item := range table {
+		simpleStorage.namespace = "unset"
+		request, _ := http.NewRequest(item.method, server.URL+"/prefix/version"+item.path, nil)
+		response, err := http.DefaultClient.Do(request)
+		expectNoError(t, err)
+		response.Body.Close()
+		if response.StatusCode != 200 || simpleStorage.namespace != item.namespace {
+			t.Errorf("Unexpected namespace for %s %s: %d %q", item.method, item.path, response.StatusCode, simpleStorage.namespace)
+		}
+	}
+
+	for _, path := range []string{"/namespaces", "/namespaces/team", "/namespaces//simple", "/namespaces/team/operations"} {
+		response, err := http.Get(server.URL + "/prefix/version" + path)
+		expectNoError(t, err)
+		response.Body.Close()
+		if response.StatusCode != 404 {
+			t.Errorf("Unexpected status for %s: %d", path, response.StatusCode)
+		}
+	}
+}
+
+func TestNamespacedCreate(t *testing.T) {
+	storage := &NamespacedRESTStorage{}
+	handler := New(map[string]RESTStorage{"simple": storage}, "/prefix/version")
+	server := httptest.NewServer(handler)
+
+	table := []struct {
+		path      string
+		body      string
+		code      int
+		namespace string
+	}{
+		{"/simple", `{"Name":"foo"}`, 200, api.NamespaceDefault},
+		{"/namespaces/team/simple", `{"Name":"foo"}`, 200, "team"},
+		{"/namespaces/team/simple", `{"Name":"foo","Namespace":"team"}`, 200, "team"},
+		{"/namespaces/team/simple", `{"Name":"foo","Namespace":"other"}`, 422, ""},
+		{"/simple", `{"Name":"foo","Namespace":"other"}`, 200, "other"},
+	}
+	for _, item := range table {
+		storage.created = Namespaced{}
+		response, err := http.Post(server.URL+"/prefix/version"+item.path, "application/json", bytes.NewBufferString(item.body))
+		expectNoError(t, err)
+		response.Body.Close()
+		if response.StatusCode != item.code || storage.created.Namespace != item.namespace {
+			t.Errorf("Unexpected create of %s in %s: %d %#v", item.body, item.path, response.StatusCode, storage.created)
+		}
+	}
+}
+
+func TestClusterScoped(t *testing.T) {
+	storage := &ClusterRESTStorage{}
+	handler := New(map[string]RESTStorage{"minions": storage}, "/prefix/version")
+	server := httptest.NewServer(handler)
+
+	response, err := http.Get(server.URL + "/prefix/version/minions/id")
+	expectNoError(t, err)
+	response.Body.Close()
+	if response.StatusCode != 200 || storage.namespace != api.NamespaceAll {
+		t.Errorf("Unexpected response: %d %q", response.StatusCode, storage.namespace)
+	}
+	response, err = http.Get(server.URL + "/prefix/version/namespaces/team/minions/id")
+	expectNoError(t, err)
+	response.Body.Close()
+	if response.StatusCode != 404 {
+		t.Errorf("Unexpected status: %d", response.StatusCode)
+	}
+}
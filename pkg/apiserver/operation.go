@@ -0,0 +1,209 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// RESTCompleter is implemented by RESTStorage whose objects are not done changing when Create
+// or Update returns, such as pods, which still have to be scheduled and started.
+type RESTCompleter interface {
+	// Completed returns 'obj', which was created or updated, as it is now, and whether the work
+	// started by the change is done. It is called until the work is done or an error fails it.
+	Completed(obj interface{}) (interface{}, bool, error)
+}
+
+// Operation tracks the work started by a create or update until it is done.
+type Operation struct {
+	ID string
+
+	lock   sync.Mutex
+	done   chan struct{}
+	result interface{}
+	err    error
+}
+
+// Wait waits for up to 'timeout' for the operation to finish, and returns whether it did.
+func (op *Operation) Wait(timeout time.Duration) bool {
+	select {
+	case <-op.done:
+		return true
+	default:
+	}
+	if timeout <= 0 {
+		return false
+	}
+	select {
+	case <-op.done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Result returns the object the operation finished with, or the error it failed with.
+func (op *Operation) Result() (interface{}, error) {
+	op.lock.Lock()
+	defer op.lock.Unlock()
+	return op.result, op.err
+}
+
+func (op *Operation) finish(result interface{}, err error) {
+	op.lock.Lock()
+	defer op.lock.Unlock()
+	op.result = result
+	op.err = err
+	close(op.done)
+}
+
+// describe returns the api.Operation that describes the state of the operation.
+func (op *Operation) describe() api.Operation {
+	status := api.StatusWorking
+	if op.Wait(0) {
+		if _, err := op.Result(); err != nil {
+			status = api.StatusFailure
+		} else {
+			status = api.StatusSuccess
+		}
+	}
+	return api.Operation{
+		JSONBase: api.JSONBase{ID: op.ID, Kind: "cluster#operation"},
+		Status:   status,
+	}
+}
+
+// workingStatus returns the api.Status of a call whose operation is still working.
+func (op *Operation) workingStatus() api.Status {
+	return api.Status{
+		Status:  api.StatusWorking,
+		Message: fmt.Sprintf("operation %s is working", op.ID),
+		Details: &api.StatusDetails{ID: op.ID, Kind: "operation"},
+		Code:    http.StatusAccepted,
+	}
+}
+
+// Operations holds the operations of an apiserver. Operations that are still working after
+// 'timeout' fail, and finished operations are forgotten after 'expiry'.
+type Operations struct {
+	lock   sync.Mutex
+	ops    map[string]*Operation
+	lastID int64
+
+	pollInterval time.Duration
+	timeout      time.Duration
+	expiry       time.Duration
+}
+
+// MakeOperations makes an empty set of operations, which poll storage for completion every second.
+func MakeOperations() *Operations {
+	return &Operations{
+		ops:          map[string]*Operation{},
+		pollInterval: time.Second,
+		timeout:      10 * time.Minute,
+		expiry:       10 * time.Minute,
+	}
+}
+
+// Start starts an operation that finishes when the work started by creating or updating 'obj'
+// in 'storage' is done.
+func (ops *Operations) Start(storage RESTStorage, obj interface{}) *Operation {
+	ops.lock.Lock()
+	ops.lastID++
+	op := &Operation{
+		ID:   strconv.FormatInt(ops.lastID, 10),
+		done: make(chan struct{}),
+	}
+	ops.ops[op.ID] = op
+	ops.lock.Unlock()
+
+	go func() {
+		ops.complete(op, storage, obj)
+		time.AfterFunc(ops.expiry, func() {
+			ops.lock.Lock()
+			defer ops.lock.Unlock()
+			delete(ops.ops, op.ID)
+		})
+	}()
+	return op
+}
+
+func (ops *Operations) complete(op *Operation, storage RESTStorage, obj interface{}) {
+	completer, ok := storage.(RESTCompleter)
+	if !ok {
+		op.finish(obj, nil)
+		return
+	}
+	deadline := time.Now().Add(ops.timeout)
+	for {
+		result, done, err := completer.Completed(obj)
+		if err != nil {
+			op.finish(nil, err)
+			return
+		}
+		if done {
+			op.finish(result, nil)
+			return
+		}
+		if time.Now().After(deadline) {
+			op.finish(nil, api.NewTimeout("operation", op.ID))
+			return
+		}
+		time.Sleep(ops.pollInterval)
+	}
+}
+
+// Get returns the operation 'id', or nil if there is no such operation.
+func (ops *Operations) Get(id string) *Operation {
+	ops.lock.Lock()
+	defer ops.lock.Unlock()
+	return ops.ops[id]
+}
+
+// List returns every operation, ordered by id.
+func (ops *Operations) List() api.OperationList {
+	ops.lock.Lock()
+	all := make([]*Operation, 0, len(ops.ops))
+	for _, op := range ops.ops {
+		all = append(all, op)
+	}
+	ops.lock.Unlock()
+
+	sort.Sort(byID(all))
+	list := api.OperationList{JSONBase: api.JSONBase{Kind: "cluster#operationList"}}
+	for _, op := range all {
+		list.Items = append(list.Items, op.describe())
+	}
+	return list
+}
+
+type byID []*Operation
+
+func (ops byID) Len() int      { return len(ops) }
+func (ops byID) Swap(i, j int) { ops[i], ops[j] = ops[j], ops[i] }
+func (ops byID) Less(i, j int) bool {
+	a, _ := strconv.ParseInt(ops[i].ID, 10, 64)
+	b, _ := strconv.ParseInt(ops[j].ID, 10, 64)
+	return a < b
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+type CompletingRESTStorage struct {
+	SimpleRESTStorage
+	ready chan struct{}
+	err   error
+}
+
+func (storage *CompletingRESTStorage) Completed(obj interface{}) (interface{}, bool, error) {
+	select {
+	case <-storage.ready:
+		return Simple{Name: "done"}, true, storage.err
+	default:
+		return nil, false, nil
+	}
+}
+
+func makeCompletingServer(storage RESTStorage) (*ApiServer, *httptest.Server) {
+	ops := MakeOperations()
+	ops.pollInterval = time.Millisecond
+	handler := NewVersioned(map[string]RESTStorage{"foo": storage}, ops, "/prefix/version", api.StorageVersion)
+	return handler, httptest.NewServer(handler)
+}
+
+func postSimple(t *testing.T, url string) *http.Response {
+	data, _ := json.Marshal(Simple{Name: "foo"})
+	response, err := http.Post(url, "application/json", bytes.NewBuffer(data))
+	expectNoError(t, err)
+	return response
+}
+
+func TestCreateWaitsForCompletion(t *testing.T) {
+	storage := &CompletingRESTStorage{ready: make(chan struct{})}
+	close(storage.ready)
+	_, server := makeCompletingServer(storage)
+
+	response := postSimple(t, server.URL+"/prefix/version/foo?timeout=10s")
+	var itemOut Simple
+	body, err := extractBody(response, &itemOut)
+	expectNoError(t, err)
+	if response.StatusCode != 200 || itemOut.Name != "done" {
+		t.Errorf("Unexpected response: %d %s", response.StatusCode, body)
+	}
+}
+
+func TestCreateWithoutTimeoutDoesNotWait(t *testing.T) {
+	storage := &CompletingRESTStorage{ready: make(chan struct{})}
+	handler, server := makeCompletingServer(storage)
+
+	response := postSimple(t, server.URL+"/prefix/version/foo")
+	var itemOut Simple
+	body, err := extractBody(response, &itemOut)
+	expectNoError(t, err)
+	if response.StatusCode != 200 || itemOut.Name != "foo" {
+		t.Errorf("Unexpected response: %d %s", response.StatusCode, body)
+	}
+	if list := handler.ops.List(); len(list.Items) != 0 {
+		t.Errorf("Unexpected operations: %#v", list)
+	}
+}
+
+func TestCreateInvalidTimeout(t *testing.T) {
+	_, server := makeCompletingServer(&CompletingRESTStorage{ready: make(chan struct{})})
+
+	response := postSimple(t, server.URL+"/prefix/version/foo?timeout=soon")
+	var status api.Status
+	body, err := extractBody(response, &status)
+	expectNoError(t, err)
+	if response.StatusCode != 422 || status.Reason != api.StatusReasonInvalid {
+		t.Errorf("Unexpected response: %d %s", response.StatusCode, body)
+	}
+}
+
+func TestCreateReturnsWorkingOperation(t *testing.T) {
+	storage := &CompletingRESTStorage{ready: make(chan struct{})}
+	_, server := makeCompletingServer(storage)
+
+	response := postSimple(t, server.URL+"/prefix/version/foo?timeout=1ms")
+	var status api.Status
+	body, err := extractBody(response, &status)
+	expectNoError(t, err)
+	if response.StatusCode != 202 || status.Status != api.StatusWorking || status.Details == nil || status.Details.Kind != "operation" {
+		t.Fatalf("Unexpected response: %d %s", response.StatusCode, body)
+	}
+	id := status.Details.ID
+
+	response, err = http.Get(server.URL + "/prefix/version/operations")
+	expectNoError(t, err)
+	var list api.OperationList
+	body, err = extractBody(response, &list)
+	expectNoError(t, err)
+	if len(list.Items) != 1 || list.Items[0].ID != id || list.Items[0].Status != api.StatusWorking {
+		t.Errorf("Unexpected operations: %s", body)
+	}
+
+	response, err = http.Get(server.URL + "/prefix/version/operations/" + id)
+	expectNoError(t, err)
+	response.Body.Close()
+	if response.StatusCode != 202 {
+		t.Errorf("Unexpected status: %d", response.StatusCode)
+	}
+
+	close(storage.ready)
+	response, err = http.Get(server.URL + "/prefix/version/operations/" + id + "?timeout=10s")
+	expectNoError(t, err)
+	var itemOut Simple
+	body, err = extractBody(response, &itemOut)
+	expectNoError(t, err)
+	if response.StatusCode != 200 || itemOut.Name != "done" {
+		t.Errorf("Unexpected response: %d %s", response.StatusCode, body)
+	}
+}
+
+func TestOperationFailure(t *testing.T) {
+	storage := &CompletingRESTStorage{ready: make(chan struct{}), err: api.NewNotFound("foo", "bar")}
+	close(storage.ready)
+	_, server := makeCompletingServer(storage)
+
+	response := postSimple(t, server.URL+"/prefix/version/foo?timeout=10s")
+	var status api.Status
+	body, err := extractBody(response, &status)
+	expectNoError(t, err)
+	if response.StatusCode != 404 || status.Reason != api.StatusReasonNotFound {
+		t.Errorf("Unexpected response: %d %s", response.StatusCode, body)
+	}
+}
+
+func TestOperationTimeout(t *testing.T) {
+	ops := MakeOperations()
+	ops.pollInterval = time.Millisecond
+	ops.timeout = 5 * time.Millisecond
+	op := ops.Start(&CompletingRESTStorage{ready: make(chan struct{})}, Simple{})
+	if !op.Wait(time.Second) {
+		t.Fatalf("Operation did not time out")
+	}
+	if _, err := op.Result(); !api.IsTimeout(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
+
+func TestOperationNotFound(t *testing.T) {
+	_, server := makeCompletingServer(&SimpleRESTStorage{})
+
+	response, err := http.Get(server.URL + "/prefix/version/operations/42")
+	expectNoError(t, err)
+	var status api.Status
+	body, err := extractBody(response, &status)
+	expectNoError(t, err)
+	if response.StatusCode != 404 || status.Details == nil || status.Details.Kind != "operation" {
+		t.Errorf("Unexpected response: %d %s", response.StatusCode, body)
+	}
+}
+
+func TestOperationWithoutCompleter(t *testing.T) {
+	ops := MakeOperations()
+	op := ops.Start(&SimpleRESTStorage{}, Simple{Name: "foo"})
+	if !op.Wait(time.Second) {
+		t.Fatalf("Operation did not finish")
+	}
+	result, err := op.Result()
+	if err != nil || result.(Simple).Name != "foo" {
+		t.Errorf("Unexpected result: %#v %#v", result, err)
+	}
+	if ops.Get(op.ID) != op {
+		t.Errorf("Unexpected operation: %#v", ops.Get(op.ID))
+	}
+	if list := ops.List(); len(list.Items) != 1 || list.Items[0].Status != api.StatusSuccess {
+		t.Errorf("Unexpected operations: %#v", list)
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// maxPatchRetries is how often a patch is applied again when another writer changed the object
+// between the read and the update.
+const maxPatchRetries = 5
+
+// patch applies the JSON merge patch (RFC 7386) in the body of 'req' to the stored object 'id'
+// of 'resource', and updates it once it is admitted. It returns the updated object, or nil if
+// there is no such object. The update is made against the version of the object that was
+// patched. If another writer got in between, the patch is applied again to the new version,
+// unless the patch names a resourceVersion.
+func (server *ApiServer) patch(storage RESTStorage, namespace, resource, id string, req *http.Request) (interface{}, error) {
+	if unpatchable, ok := storage.(Unpatchable); ok && unpatchable.Unpatchable() {
+		return nil, api.NewMethodNotAllowed(resource, "patch")
+	}
+	body, err := server.readBody(req)
+	if err != nil {
+		return nil, api.NewInvalid("request body", "", err)
+	}
+	var changes map[string]interface{}
+	if err := decodeJSON([]byte(body), &changes); err != nil {
+		return nil, api.NewInvalid("request body", "", err)
+	}
+	if value, ok := changes["id"]; ok && value != id {
+		return nil, api.NewInvalid("id", fmt.Sprintf("%v", value), fmt.Errorf("the request is for %q", id))
+	}
+	_, versioned := changes["resourceVersion"]
+	for attempt := 0; ; attempt++ {
+		current, err := storage.Get(namespace, id)
+		if err != nil || current == nil {
+			return nil, err
+		}
+		data, err := api.EncodeVersion(current, server.version)
+		if err != nil {
+			return nil, err
+		}
+		var original interface{}
+		if err := decodeJSON(data, &original); err != nil {
+			return nil, err
+		}
+		patched, err := json.Marshal(mergePatch(original, changes))
+		if err != nil {
+			return nil, err
+		}
+		obj, err := server.decode(storage, string(patched))
+		if err == nil {
+			obj, err = inNamespace(storage, obj, namespace)
+		}
+		if err == nil {
+			obj, err = server.admit(req, "patch", namespace, resource, obj)
+		}
+		if err != nil {
+			return nil, err
+		}
+		err = storage.Update(obj)
+		if err == nil {
+			return obj, nil
+		}
+		if !api.IsConflict(err) || versioned || attempt == maxPatchRetries {
+			return nil, err
+		}
+	}
+}
+
+// decodeJSON unmarshals 'data' into 'target', keeping numbers as they were written, so that
+// versions and other large integers survive a round trip.
+func decodeJSON(data []byte, target interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(target)
+}
+
+// mergePatch returns 'target' with the changes in 'patch' applied as in RFC 7386: objects are
+// merged member by member, a null removes the member, and any other value replaces the target.
+// 'patch' is not modified.
+func mergePatch(target, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	result, ok := target.(map[string]interface{})
+	if !ok {
+		result = map[string]interface{}{}
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(result, key)
+			continue
+		}
+		result[key] = mergePatch(result[key], value)
+	}
+	return result
+}
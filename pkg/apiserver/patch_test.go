@@ -0,0 +1,213 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+type Versioned struct {
+	Name            string            `json:"id"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	ResourceVersion uint64            `json:"resourceVersion,omitempty"`
+}
+
+// VersionedRESTStorage keeps one object, and fails updates of stale versions with a conflict.
+// The first 'conflicts' updates find that another writer got in first.
+type VersionedRESTStorage struct {
+	SimpleRESTStorage
+	stored    Versioned
+	conflicts int
+	updates   int
+}
+
+func (storage *VersionedRESTStorage) Get(namespace, id string) (interface{}, error) {
+	if id != storage.stored.Name {
+		return nil, api.NewNotFound("versioned", id)
+	}
+	return storage.stored, nil
+}
+
+func (storage *VersionedRESTStorage) Extract(body string, version string) (interface{}, error) {
+	var item Versioned
+	err := json.Unmarshal([]byte(body), &item)
+	return item, err
+}
+
+func (storage *VersionedRESTStorage) Update(obj interface{}) error {
+	storage.updates++
+	item := obj.(Versioned)
+	if storage.conflicts > 0 {
+		storage.conflicts--
+		storage.stored.ResourceVersion++
+	}
+	if item.ResourceVersion != storage.stored.ResourceVersion {
+		return api.NewConflict("versioned", item.Name, fmt.Errorf("stale version %d", item.ResourceVersion))
+	}
+	item.ResourceVersion++
+	storage.stored = item
+	return nil
+}
+
+func TestMergePatch(t *testing.T) {
+	table := []struct {
+		target   string
+		patch    string
+		expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{`{"v":12345678901234567890}`, `{"a":1}`, `{"a":1,"v":12345678901234567890}`},
+	}
+	for _, item := range table {
+		var target, patch interface{}
+		expectNoError(t, decodeJSON([]byte(item.target), &target))
+		expectNoError(t, decodeJSON([]byte(item.patch), &patch))
+		data, err := json.Marshal(mergePatch(target, patch))
+		expectNoError(t, err)
+		if string(data) != item.expected {
+			t.Errorf("Unexpected result of %s on %s: %s, expected %s", item.patch, item.target, data, item.expected)
+		}
+	}
+}
+
+func patchRequest(t *testing.T, url, patch string) *http.Response {
+	request, err := http.NewRequest("PATCH", url, bytes.NewBufferString(patch))
+	expectNoError(t, err)
+	response, err := http.DefaultClient.Do(request)
+	expectNoError(t, err)
+	return response
+}
+
+func TestPatch(t *testing.T) {
+	storage := &VersionedRESTStorage{
+		stored: Versioned{Name: "foo", Labels: map[string]string{"a": "b", "c": "d"}, ResourceVersion: 1},
+	}
+	handler := New(map[string]RESTStorage{"versioned": storage}, "/prefix/version")
+	server := httptest.NewServer(handler)
+
+	response := patchRequest(t, server.URL+"/prefix/version/versioned/foo", `{"labels":{"a":"x","c":null,"e":"f"}}`)
+	if response.StatusCode != 200 {
+		t.Errorf("Unexpected status: %d", response.StatusCode)
+	}
+	var itemOut Versioned
+	body, err := extractBody(response, &itemOut)
+	expectNoError(t, err)
+	expected := Versioned{Name: "foo", Labels: map[string]string{"a": "x", "e": "f"}, ResourceVersion: 2}
+	if !reflect.DeepEqual(storage.stored, expected) || !reflect.DeepEqual(itemOut.Labels, expected.Labels) {
+		t.Errorf("Unexpected patch result: %#v %s, expected %#v", storage.stored, body, expected)
+	}
+}
+
+func TestPatchConflict(t *testing.T) {
+	storage := &VersionedRESTStorage{
+		stored:    Versioned{Name: "foo", ResourceVersion: 1},
+		conflicts: 2,
+	}
+	handler := New(map[string]RESTStorage{"versioned": storage}, "/prefix/version")
+	server := httptest.NewServer(handler)
+
+	response := patchRequest(t, server.URL+"/prefix/version/versioned/foo", `{"labels":{"a":"b"}}`)
+	if response.StatusCode != 200 {
+		t.Errorf("Unexpected status: %d", response.StatusCode)
+	}
+	if storage.updates != 3 || storage.stored.Labels["a"] != "b" {
+		t.Errorf("Unexpected patch result: %d %#v", storage.updates, storage.stored)
+	}
+
+	// A patch of a given version is not applied again to a newer one.
+	storage.conflicts = 1
+	storage.updates = 0
+	response = patchRequest(t, server.URL+"/prefix/version/versioned/foo", `{"labels":{"a":"c"},"resourceVersion":4}`)
+	if response.StatusCode != http.StatusConflict {
+		t.Errorf("Unexpected status: %d", response.StatusCode)
+	}
+	if storage.updates != 1 || storage.stored.Labels["a"] != "b" {
+		t.Errorf("Unexpected patch result: %d %#v", storage.updates, storage.stored)
+	}
+}
+
+// UnpatchableRESTStorage is VersionedRESTStorage whose objects can't be patched.
+type UnpatchableRESTStorage struct {
+	VersionedRESTStorage
+}
+
+func (storage *UnpatchableRESTStorage) Unpatchable() bool {
+	return true
+}
+
+func TestPatchUnpatchable(t *testing.T) {
+	storage := &UnpatchableRESTStorage{
+		VersionedRESTStorage{stored: Versioned{Name: "foo", ResourceVersion: 1}},
+	}
+	handler := New(map[string]RESTStorage{"versioned": storage}, "/prefix/version")
+	server := httptest.NewServer(handler)
+
+	response := patchRequest(t, server.URL+"/prefix/version/versioned/foo", `{"labels":{"a":"b"}}`)
+	if response.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Unexpected status: %d", response.StatusCode)
+	}
+	if storage.updates != 0 {
+		t.Errorf("Unexpected updates: %d", storage.updates)
+	}
+}
+
+func TestPatchErrors(t *testing.T) {
+	storage := &VersionedRESTStorage{
+		stored: Versioned{Name: "foo", ResourceVersion: 1},
+	}
+	handler := New(map[string]RESTStorage{"versioned": storage}, "/prefix/version")
+	server := httptest.NewServer(handler)
+
+	table := []struct {
+		path  string
+		patch string
+		code  int
+	}{
+		{"/prefix/version/versioned/bar", `{"labels":{"a":"b"}}`, http.StatusNotFound},
+		{"/prefix/version/versioned/foo", `{"id":"bar"}`, 422},
+		{"/prefix/version/versioned/foo", `["id"]`, 422},
+		{"/prefix/version/versioned/foo", `{"labels":`, 422},
+		{"/prefix/version/versioned", `{"labels":{"a":"b"}}`, http.StatusNotFound},
+	}
+	for _, item := range table {
+		response := patchRequest(t, server.URL+item.path, item.patch)
+		if response.StatusCode != item.code {
+			t.Errorf("Unexpected status for %s %s: %d, expected %d", item.path, item.patch, response.StatusCode, item.code)
+		}
+	}
+	if storage.updates != 0 {
+		t.Errorf("Unexpected updates: %d", storage.updates)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// handleProxy proxies requests for ${path} of the object named by 'parts', which are of the form
+// ${storage_key}/${id}/${path}, to the location its ResourceLocator storage gives. The request
+// is sent without the credentials it was authenticated with.
+func (server *ApiServer) handleProxy(parts []string, namespace string, requestUrl *url.URL, req *http.Request, w http.ResponseWriter) {
+	if len(parts) < 2 || len(parts[1]) == 0 {
+		server.notFound(req, w)
+		return
+	}
+	storage := server.storage[parts[0]]
+	locator, ok := storage.(ResourceLocator)
+	if !ok || (isClusterScoped(storage) && namespace != api.NamespaceAll) {
+		server.notFound(req, w)
+		return
+	}
+	prefix := server.prefix
+	if namespace != api.NamespaceAll {
+		prefix += "/namespaces/" + namespace
+	}
+	prefix += "/proxy/" + parts[0] + "/" + parts[1]
+	namespace = objectNamespace(storage, namespace)
+	if audited := server.audit(req, "proxy", namespace, parts, w); audited != nil {
+		w = audited
+		defer audited.finish()
+	}
+	if !server.authorize(req, "proxy", namespace, parts[0], w) {
+		return
+	}
+	location, err := locator.ResourceLocation(namespace, parts[1])
+	if err != nil {
+		server.error(err, w)
+		return
+	}
+	target := &url.URL{
+		Scheme:   "http",
+		Host:     location,
+		Path:     "/" + strings.Join(parts[2:], "/"),
+		RawQuery: requestUrl.RawQuery,
+	}
+	proxy := &httputil.ReverseProxy{
+		Director: func(out *http.Request) {
+			out.URL = target
+			out.Host = location
+			out.Header.Del("Authorization")
+		},
+		Transport: &proxyTransport{location: location, prefix: prefix},
+	}
+	proxy.ServeHTTP(w, req)
+}
+
+// proxyTransport sends proxied requests, and rewrites the redirects of the proxied object to
+// itself so that they go through the proxy too.
+type proxyTransport struct {
+	// location is the host:port requests are proxied to.
+	location string
+	// prefix is the path the object is proxied under.
+	prefix string
+}
+
+func (t *proxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	response, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if location := response.Header.Get("Location"); len(location) > 0 {
+		response.Header.Set("Location", t.rewriteLocation(location))
+	}
+	return response, nil
+}
+
+// rewriteLocation returns the URL of the proxy for 'location', if it is an absolute path or a
+// URL of the proxied object. Other URLs, including relative paths, are left alone.
+func (t *proxyTransport) rewriteLocation(location string) string {
+	parsed, err := url.Parse(location)
+	if err != nil || (len(parsed.Host) > 0 && parsed.Host != t.location) || !strings.HasPrefix(parsed.Path, "/") {
+		return location
+	}
+	rewritten := url.URL{Path: t.prefix + parsed.Path, RawQuery: parsed.RawQuery, Fragment: parsed.Fragment}
+	return rewritten.String()
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// LocatingRESTStorage proxies requests for every object to 'location', and records the last
+// object it was asked for.
+type LocatingRESTStorage struct {
+	SimpleRESTStorage
+	location string
+	id       string
+}
+
+func (storage *LocatingRESTStorage) ResourceLocation(namespace, id string) (string, error) {
+	storage.namespace = namespace
+	storage.id = id
+	return storage.location, storage.err
+}
+
+func TestProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/redirect" {
+			http.Redirect(w, req, "/moved?a=b", http.StatusFound)
+			return
+		}
+		fmt.Fprintf(w, "%s %s %s?%s auth=%s", req.Method, req.Host, req.URL.Path, req.URL.RawQuery, req.Header.Get("Authorization"))
+	}))
+	defer backend.Close()
+	location := strings.TrimPrefix(backend.URL, "http://")
+	storage := &LocatingRESTStorage{location: location}
+	handler := New(map[string]RESTStorage{"simple": storage}, "/prefix/version")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	table := []struct {
+		path      string
+		namespace string
+		id        string
+		body      string
+	}{
+		{"/proxy/simple/foo/containerInfo/bar?x=y", "default", "foo", "GET " + location + " /containerInfo/bar?x=y auth="},
+		{"/proxy/simple/foo", "default", "foo", "GET " + location + " /? auth="},
+		{"/proxy/simple/foo/", "default", "foo", "GET " + location + " /? auth="},
+		{"/namespaces/team/proxy/simple/foo:8080/a/", "team", "foo:8080", "GET " + location + " /a/? auth="},
+	}
+	for _, item := range table {
+		request, _ := http.NewRequest("GET", server.URL+"/prefix/version"+item.path, nil)
+		request.SetBasicAuth("user", "password")
+		response, err := http.DefaultClient.Do(request)
+		expectNoError(t, err)
+		body, _ := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if response.StatusCode != 200 || string(body) != item.body {
+			t.Errorf("Unexpected response for %s: %d %s", item.path, response.StatusCode, body)
+		}
+		if storage.namespace != item.namespace || storage.id != item.id {
+			t.Errorf("Unexpected location of %s: %s %s", item.path, storage.namespace, storage.id)
+		}
+	}
+
+	request, _ := http.NewRequest("GET", server.URL+"/prefix/version/namespaces/team/proxy/simple/foo/redirect", nil)
+	response, err := http.DefaultTransport.RoundTrip(request)
+	expectNoError(t, err)
+	response.Body.Close()
+	if location := response.Header.Get("Location"); response.StatusCode != http.StatusFound || location != "/prefix/version/namespaces/team/proxy/simple/foo/moved?a=b" {
+		t.Errorf("Unexpected redirect: %d %s", response.StatusCode, location)
+	}
+}
+
+func TestProxyErrors(t *testing.T) {
+	storage := map[string]RESTStorage{
+		"simple":  &SimpleRESTStorage{},
+		"failing": &LocatingRESTStorage{err: fmt.Errorf("test error")},
+		"cluster": &ClusterRESTStorage{},
+	}
+	handler := New(storage, "/prefix/version")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	table := map[string]int{
+		"/proxy/simple/foo/bar":                      http.StatusNotFound,
+		"/proxy/missing/foo/bar":                     http.StatusNotFound,
+		"/proxy/failing":                             http.StatusNotFound,
+		"/proxy/failing/foo/bar":                     http.StatusInternalServerError,
+		"/namespaces/team/proxy/cluster/foo/bar":     http.StatusNotFound,
+		"/namespaces/team/proxy/missing/foo/bar":     http.StatusNotFound,
+		"/namespaces/team/proxy/failing/foo/bar/baz": http.StatusInternalServerError,
+	}
+	for path, code := range table {
+		response, err := http.Get(server.URL + "/prefix/version" + path)
+		expectNoError(t, err)
+		response.Body.Close()
+		if response.StatusCode != code {
+			t.Errorf("Unexpected status for %s: %d, expected %d", path, response.StatusCode, code)
+		}
+	}
+}
+
+func TestRewriteLocation(t *testing.T) {
+	transport := &proxyTransport{location: "10.0.0.1:8080", prefix: "/api/v1beta1/proxy/pods/foo"}
+	table := map[string]string{
+		"/bar":                            "/api/v1beta1/proxy/pods/foo/bar",
+		"/bar?a=b#c":                      "/api/v1beta1/proxy/pods/foo/bar?a=b#c",
+		"http://10.0.0.1:8080/bar":        "/api/v1beta1/proxy/pods/foo/bar",
+		"http://10.0.0.2:8080/bar":        "http://10.0.0.2:8080/bar",
+		"bar":                             "bar",
+		"https://www.example.com/a?b=c#d": "https://www.example.com/a?b=c#d",
+	}
+	for location, expected := range table {
+		if rewritten := transport.rewriteLocation(location); rewritten != expected {
+			t.Errorf("Unexpected rewrite of %s: %s, expected %s", location, rewritten, expected)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/schema"
+)
+
+// handleSchema lists the kinds of the version of the server, or writes the JSON Schema of the
+// kind named by 'parts'.
+func (server *ApiServer) handleSchema(parts []string, req *http.Request, w http.ResponseWriter) {
+	if req.Method != "GET" || len(parts) > 1 {
+		server.notFound(req, w)
+		return
+	}
+	if len(parts) == 0 || len(parts[0]) == 0 {
+		if server.authorize(req, "list", api.NamespaceAll, "schemas", w) {
+			kinds := []string{}
+			for kind := range api.KnownTypes(server.version) {
+				kinds = append(kinds, kind)
+			}
+			sort.Strings(kinds)
+			server.write(http.StatusOK, kinds, w)
+		}
+		return
+	}
+	if !server.authorize(req, "get", api.NamespaceAll, "schemas", w) {
+		return
+	}
+	kindSchema, err := schema.ForKind(server.version, parts[0])
+	if err != nil {
+		server.error(api.NewNotFound("schema", parts[0]), w)
+		return
+	}
+	server.write(http.StatusOK, kindSchema, w)
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/schema"
+)
+
+func TestListSchemas(t *testing.T) {
+	server := httptest.NewServer(NewVersioned(map[string]RESTStorage{}, MakeOperations(), "/prefix/version", "v1beta2"))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/prefix/version/schemas")
+	expectNoError(t, err)
+	var kinds []string
+	body, err := extractBody(response, &kinds)
+	expectNoError(t, err)
+	if response.StatusCode != http.StatusOK || len(kinds) == 0 || kinds[0] != "Minion" {
+		t.Errorf("Unexpected response: %d %s", response.StatusCode, body)
+	}
+}
+
+func TestGetSchema(t *testing.T) {
+	server := httptest.NewServer(NewVersioned(map[string]RESTStorage{}, MakeOperations(), "/prefix/version", "v1beta2"))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/prefix/version/schemas/Service")
+	expectNoError(t, err)
+	var serviceSchema schema.Schema
+	body, err := extractBody(response, &serviceSchema)
+	expectNoError(t, err)
+	if response.StatusCode != http.StatusOK || serviceSchema.Properties["selector"] == nil {
+		t.Errorf("Unexpected response: %d %s", response.StatusCode, body)
+	}
+}
+
+func TestSchemaErrors(t *testing.T) {
+	server := httptest.NewServer(New(map[string]RESTStorage{}, "/prefix/version"))
+	defer server.Close()
+
+	table := map[string]int{
+		"/schemas/Foo":                 http.StatusNotFound,
+		"/schemas/Pod/foo":             http.StatusNotFound,
+		"/namespaces/team/schemas/Pod": http.StatusNotFound,
+	}
+	for path, code := range table {
+		response, err := http.Get(server.URL + "/prefix/version" + path)
+		expectNoError(t, err)
+		response.Body.Close()
+		if response.StatusCode != code {
+			t.Errorf("Unexpected status for %s: %d, expected %d", path, response.StatusCode, code)
+		}
+	}
+	response, err := http.Post(server.URL+"/prefix/version/schemas/Pod", "application/json", nil)
+	expectNoError(t, err)
+	response.Body.Close()
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("Unexpected status: %d", response.StatusCode)
+	}
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gopkg.in/v1/yaml"
+)
+
+// isYAML returns whether 'contentType' is one of the media types YAML is sent as.
+func isYAML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return true
+	}
+	return false
+}
+
+// acceptsYAML returns whether the Accept header 'accept' prefers YAML to JSON. Of types with
+// the same quality, the first one listed wins.
+func acceptsYAML(accept string) bool {
+	best, yaml := 0.0, false
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality <= best {
+			continue
+		}
+		switch {
+		case isYAML(mediaType):
+			best, yaml = quality, true
+		case mediaType == "application/json" || mediaType == "application/*" || mediaType == "*/*":
+			best, yaml = quality, false
+		}
+	}
+	return yaml
+}
+
+// yamlWriter is the response writer of a request that accepts YAML. Objects written to it
+// are sent as YAML rather than JSON.
+type yamlWriter struct {
+	http.ResponseWriter
+}
+
+// Flush sends buffered data to the client, for streams.
+func (w *yamlWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// CloseNotify returns a channel that receives a value when the client goes away, for streams.
+func (w *yamlWriter) CloseNotify() <-chan bool {
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// negotiate returns the response writer of 'req', which sends objects in the format the
+// request accepts.
+func negotiate(req *http.Request, w http.ResponseWriter) http.ResponseWriter {
+	if acceptsYAML(req.Header.Get("Accept")) {
+		return &yamlWriter{w}
+	}
+	return w
+}
+
+// wantsYAML returns whether objects written to 'w' are sent as YAML.
+func wantsYAML(w http.ResponseWriter) bool {
+	switch w := w.(type) {
+	case *yamlWriter:
+		return true
+	case *auditWriter:
+		return wantsYAML(w.ResponseWriter)
+	}
+	return false
+}
+
+// yamlToJSON returns the JSON of the YAML document 'data'.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	value, err := jsonValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// jsonValue returns 'value', decoded from YAML, with the maps keyed by strings, as JSON objects
+// are.
+func jsonValue(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		object := map[string]interface{}{}
+		for key, item := range value {
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("unsupported key %v, keys must be strings", key)
+			}
+			converted, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			object[name] = converted
+		}
+		return object, nil
+	case []interface{}:
+		array := make([]interface{}, len(value))
+		for ix, item := range value {
+			converted, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			array[ix] = converted
+		}
+		return array, nil
+	}
+	return value, nil
+}
+
+// jsonToYAML returns the YAML of the JSON document 'data'.
+func jsonToYAML(data []byte) ([]byte, error) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(yamlValue(value))
+}
+
+// yamlValue returns 'value', decoded from JSON with numbers kept as json.Number, with the
+// numbers as integers where they are whole so that YAML does not write them as floats.
+func yamlValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = yamlValue(item)
+		}
+	case []interface{}:
+		for ix, item := range value {
+			value[ix] = yamlValue(item)
+		}
+	case json.Number:
+		if integer, err := value.Int64(); err == nil {
+			return integer
+		}
+		float, _ := value.Float64()
+		return float
+	}
+	return value
+}
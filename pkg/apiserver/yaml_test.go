@@ -0,0 +1,130 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"gopkg.in/v1/yaml"
+)
+
+func TestAcceptsYAML(t *testing.T) {
+	table := map[string]bool{
+		"":                                       false,
+		"application/json":                       false,
+		"*/*":                                    false,
+		"application/yaml":                       true,
+		"text/yaml; charset=utf-8":               true,
+		"application/x-yaml, application/json":   true,
+		"application/json, application/yaml":     false,
+		"application/json;q=0.5, text/yaml":      true,
+		"application/yaml;q=0.1, */*;q=0.2":      false,
+		"application/yaml;q=0, application/json": false,
+		"text/html, application/yaml;q=0.9":      true,
+		"application/yaml;q=x":                   false,
+	}
+	for accept, expected := range table {
+		if acceptsYAML(accept) != expected {
+			t.Errorf("Expected acceptsYAML(%q) to be %v", accept, expected)
+		}
+	}
+}
+
+func TestYAMLToJSON(t *testing.T) {
+	data, err := yamlToJSON([]byte("id: foo\nport: 8080\nlabels:\n  name: foo\nitems:\n- a: 1\n- ~\n"))
+	expectNoError(t, err)
+	expected := `{"id":"foo","items":[{"a":1},null],"labels":{"name":"foo"},"port":8080}`
+	if string(data) != expected {
+		t.Errorf("Unexpected JSON: %s", data)
+	}
+	if _, err := yamlToJSON([]byte("1: foo\n")); err == nil {
+		t.Errorf("Expected an error for a key that is not a string")
+	}
+	if _, err := yamlToJSON([]byte("id: [foo\n")); err == nil {
+		t.Errorf("Expected an error for malformed YAML")
+	}
+}
+
+func TestJSONToYAML(t *testing.T) {
+	data, err := jsonToYAML([]byte(`{"resourceVersion": 12345678901, "ratio": 0.5, "items": [{"id": "foo"}]}`))
+	expectNoError(t, err)
+	expected := "items:\n- id: foo\nratio: 0.5\nresourceVersion: 12345678901\n"
+	if string(data) != expected {
+		t.Errorf("Unexpected YAML: %q", data)
+	}
+}
+
+func TestCreateYAML(t *testing.T) {
+	storage := &SimpleRESTStorage{}
+	server := httptest.NewServer(New(map[string]RESTStorage{"foo": storage}, "/prefix/version"))
+	defer server.Close()
+
+	request, err := http.NewRequest("PUT", server.URL+"/prefix/version/foo/bar", bytes.NewBufferString("Name: bar\n"))
+	expectNoError(t, err)
+	request.Header.Set("Content-Type", "application/yaml")
+	request.Header.Set("Accept", "application/yaml")
+	response, err := http.DefaultClient.Do(request)
+	expectNoError(t, err)
+	defer response.Body.Close()
+	if storage.updated.Name != "bar" {
+		t.Errorf("Unexpected update: %#v", storage.updated)
+	}
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "application/yaml" {
+		t.Errorf("Unexpected response: %#v", response)
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	expectNoError(t, err)
+	var item map[string]interface{}
+	expectNoError(t, yaml.Unmarshal(body, &item))
+	if !reflect.DeepEqual(item, map[string]interface{}{"Name": "bar"}) {
+		t.Errorf("Unexpected body: %s", body)
+	}
+}
+
+func TestErrorYAML(t *testing.T) {
+	server := httptest.NewServer(New(map[string]RESTStorage{"foo": &SimpleRESTStorage{}}, "/prefix/version"))
+	defer server.Close()
+
+	table := []struct {
+		method, path, body string
+		code               int
+	}{
+		{"GET", "/prefix/version/missing", "", http.StatusNotFound},
+		{"POST", "/prefix/version/foo", "Name: [bar\n", 422},
+	}
+	for _, item := range table {
+		request, err := http.NewRequest(item.method, server.URL+item.path, bytes.NewBufferString(item.body))
+		expectNoError(t, err)
+		request.Header.Set("Content-Type", "text/yaml")
+		request.Header.Set("Accept", "text/yaml")
+		response, err := http.DefaultClient.Do(request)
+		expectNoError(t, err)
+		body, err := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		expectNoError(t, err)
+		var status map[string]interface{}
+		expectNoError(t, yaml.Unmarshal(body, &status))
+		if response.StatusCode != item.code || status["code"] != item.code || response.Header.Get("Content-Type") != "application/yaml" {
+			t.Errorf("Unexpected response to %s %s: %d %s", item.method, item.path, response.StatusCode, body)
+		}
+	}
+}
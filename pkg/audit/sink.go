@@ -0,0 +1,139 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Event describes an API request that changes objects, once it is answered.
+type Event struct {
+	// Time is when the request arrived.
+	Time time.Time `json:"time"`
+	// User made the request. It is empty if the request was not authenticated.
+	User string `json:"user,omitempty"`
+	// SourceIP is the address the request came from.
+	SourceIP string `json:"sourceIP"`
+	// Verb is what the request does, such as "create" or "delete".
+	Verb      string `json:"verb"`
+	Namespace string `json:"namespace,omitempty"`
+	Resource  string `json:"resource"`
+	// ID is the id of the object the request is for.
+	ID string `json:"id,omitempty"`
+	// Code is the HTTP status code of the response.
+	Code int `json:"code"`
+	// Latency is how long the request took to answer, in nanoseconds.
+	Latency time.Duration `json:"latency"`
+}
+
+// Sink is where events are recorded.
+type Sink interface {
+	Record(event Event) error
+}
+
+// FileSink records events in a file, as one JSON object per line. Once the file would grow
+// beyond its maximum size, it is renamed to ${path}.1, the older files move up by one, and a
+// new file is started. Only the newest backups are kept.
+type FileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+// OpenFileSink opens the file 'path' to append events to. If 'maxSize' is 0, the file is never
+// rotated.
+func OpenFileSink(path string, maxSize int64, maxBackups int) (*FileSink, error) {
+	f := &FileSink{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *FileSink) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// backup returns the name of the n-th backup of the file; the 0th is the file itself.
+func (f *FileSink) backup(n int) string {
+	if n == 0 {
+		return f.path
+	}
+	return fmt.Sprintf("%s.%d", f.path, n)
+}
+
+// rotate moves the file and its backups up by one, dropping the oldest, and starts a new file.
+func (f *FileSink) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	if f.maxBackups == 0 {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	for n := f.maxBackups; n > 0; n-- {
+		if err := os.Rename(f.backup(n-1), f.backup(n)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return f.open()
+}
+
+func (f *FileSink) Record(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(data)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := f.file.Write(data)
+	f.size += int64(n)
+	return err
+}
+
+// Close closes the file. No more events can be recorded.
+func (f *FileSink) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.file.Close()
+}
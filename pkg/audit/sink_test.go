@@ -0,0 +1,130 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// readEvents returns the events in the file 'path', or nil if there is no such file.
+func readEvents(t *testing.T, path string) []Event {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer file.Close()
+	events := []Event{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Errorf("Unexpected line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func makeEvent(id string) Event {
+	return Event{
+		Time:     time.Date(2014, 6, 1, 12, 0, 0, 0, time.UTC),
+		User:     "alice",
+		SourceIP: "10.0.0.1",
+		Verb:     "delete",
+		Resource: "pods",
+		ID:       id,
+		Code:     200,
+		Latency:  5 * time.Millisecond,
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	sink, err := OpenFileSink(path, 0, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := sink.Record(makeEvent(id)); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	sink.Close()
+
+	// Events are appended to the events that are there.
+	sink, err = OpenFileSink(path, 0, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sink.Record(makeEvent("c"))
+	sink.Close()
+
+	expected := []Event{makeEvent("a"), makeEvent("b"), makeEvent("c")}
+	if events := readEvents(t, path); !reflect.DeepEqual(events, expected) {
+		t.Errorf("Unexpected events: %#v", events)
+	}
+}
+
+func TestFileSinkRotates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	data, _ := json.Marshal(makeEvent("a"))
+	// Room for two events per file.
+	sink, err := OpenFileSink(path, int64(2*(len(data)+1)), 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer sink.Close()
+	for _, id := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		if err := sink.Record(makeEvent(id)); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	table := map[string][]Event{
+		path:        {makeEvent("g")},
+		path + ".1": {makeEvent("e"), makeEvent("f")},
+		path + ".2": {makeEvent("c"), makeEvent("d")},
+		path + ".3": nil,
+	}
+	for file, expected := range table {
+		if events := readEvents(t, file); !reflect.DeepEqual(events, expected) {
+			t.Errorf("Unexpected events in %s: %#v", file, events)
+		}
+	}
+}
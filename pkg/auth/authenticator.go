@@ -0,0 +1,139 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// User is the identity of an authenticated caller.
+type User struct {
+	Name string
+}
+
+// Authenticator finds out who sent a request. It returns false if the request does not carry
+// credentials it accepts, and an error if it could not check them.
+type Authenticator interface {
+	AuthenticateRequest(req *http.Request) (*User, bool, error)
+}
+
+// Password checks user names and passwords.
+type Password interface {
+	AuthenticatePassword(name, password string) (*User, bool, error)
+}
+
+// Token checks bearer tokens.
+type Token interface {
+	AuthenticateToken(token string) (*User, bool, error)
+}
+
+// Union is a chain of authenticators. A request is authenticated by the first authenticator that
+// accepts it.
+type Union []Authenticator
+
+func (union Union) AuthenticateRequest(req *http.Request) (*User, bool, error) {
+	var lastErr error
+	for _, authenticator := range union {
+		user, ok, err := authenticator.AuthenticateRequest(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if ok {
+			return user, true, nil
+		}
+	}
+	return nil, false, lastErr
+}
+
+// BasicAuth authenticates requests by their basic auth user name and password.
+type BasicAuth struct {
+	Password Password
+}
+
+func (b BasicAuth) AuthenticateRequest(req *http.Request) (*User, bool, error) {
+	name, password, ok := basicAuth(req)
+	if !ok {
+		return nil, false, nil
+	}
+	return b.Password.AuthenticatePassword(name, password)
+}
+
+func basicAuth(req *http.Request) (name, password string, ok bool) {
+	header := req.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Basic ") {
+		return "", "", false
+	}
+	data, err := base64.StdEncoding.DecodeString(header[len("Basic "):])
+	if err != nil {
+		return "", "", false
+	}
+	parts := strings.SplitN(string(data), ":", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// BearerToken authenticates requests by the token in their "Authorization: Bearer" header.
+type BearerToken struct {
+	Token Token
+}
+
+func (b BearerToken) AuthenticateRequest(req *http.Request) (*User, bool, error) {
+	header := req.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil, false, nil
+	}
+	token := strings.TrimSpace(header[len("Bearer "):])
+	if len(token) == 0 {
+		return nil, false, nil
+	}
+	return b.Token.AuthenticateToken(token)
+}
+
+// ClientCert authenticates requests by the TLS client certificate they were sent with, which
+// must be signed by one of 'Roots'. The user is the common name of the certificate.
+type ClientCert struct {
+	Roots *x509.CertPool
+}
+
+func (c ClientCert) AuthenticateRequest(req *http.Request) (*User, bool, error) {
+	if req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+		return nil, false, nil
+	}
+	certs := req.TLS.PeerCertificates
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         c.Roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(certs[0].Subject.CommonName) == 0 {
+		return nil, false, nil
+	}
+	return &User{Name: certs[0].Subject.CommonName}, true, nil
+}
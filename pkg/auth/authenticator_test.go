@@ -0,0 +1,196 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type fakePassword map[string]string
+
+func (f fakePassword) AuthenticatePassword(name, password string) (*User, bool, error) {
+	if expected, ok := f[name]; ok && expected == password {
+		return &User{Name: name}, true, nil
+	}
+	return nil, false, nil
+}
+
+type fakeToken map[string]string
+
+func (f fakeToken) AuthenticateToken(token string) (*User, bool, error) {
+	if name, ok := f[token]; ok {
+		return &User{Name: name}, true, nil
+	}
+	return nil, false, nil
+}
+
+type failingAuthenticator struct{}
+
+func (failingAuthenticator) AuthenticateRequest(req *http.Request) (*User, bool, error) {
+	return nil, false, fmt.Errorf("unavailable")
+}
+
+func makeRequest(t *testing.T) *http.Request {
+	req, err := http.NewRequest("GET", "http://localhost/api/v1beta1/pods", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	return req
+}
+
+func expectUser(t *testing.T, user *User, ok bool, err error, name string) {
+	if err != nil || !ok || user == nil || user.Name != name {
+		t.Errorf("Unexpected authentication: %#v %v %#v, expected %s", user, ok, err, name)
+	}
+}
+
+func expectNoUser(t *testing.T, user *User, ok bool, err error) {
+	if err != nil || ok || user != nil {
+		t.Errorf("Unexpected authentication: %#v %v %#v", user, ok, err)
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	authenticator := BasicAuth{fakePassword{"alice": "secret"}}
+
+	req := makeRequest(t)
+	req.SetBasicAuth("alice", "secret")
+	user, ok, err := authenticator.AuthenticateRequest(req)
+	expectUser(t, user, ok, err, "alice")
+
+	req = makeRequest(t)
+	req.SetBasicAuth("alice", "wrong")
+	user, ok, err = authenticator.AuthenticateRequest(req)
+	expectNoUser(t, user, ok, err)
+
+	user, ok, err = authenticator.AuthenticateRequest(makeRequest(t))
+	expectNoUser(t, user, ok, err)
+
+	req = makeRequest(t)
+	req.Header.Set("Authorization", "Basic !!!")
+	user, ok, err = authenticator.AuthenticateRequest(req)
+	expectNoUser(t, user, ok, err)
+}
+
+func TestBearerToken(t *testing.T) {
+	authenticator := BearerToken{fakeToken{"abc123": "kubelet"}}
+
+	req := makeRequest(t)
+	req.Header.Set("Authorization", "Bearer abc123")
+	user, ok, err := authenticator.AuthenticateRequest(req)
+	expectUser(t, user, ok, err, "kubelet")
+
+	req.Header.Set("Authorization", "Bearer other")
+	user, ok, err = authenticator.AuthenticateRequest(req)
+	expectNoUser(t, user, ok, err)
+
+	req.SetBasicAuth("abc123", "")
+	user, ok, err = authenticator.AuthenticateRequest(req)
+	expectNoUser(t, user, ok, err)
+}
+
+func TestUnion(t *testing.T) {
+	authenticator := Union{
+		failingAuthenticator{},
+		BasicAuth{fakePassword{"alice": "secret"}},
+		BearerToken{fakeToken{"abc123": "kubelet"}},
+	}
+
+	req := makeRequest(t)
+	req.Header.Set("Authorization", "Bearer abc123")
+	user, ok, err := authenticator.AuthenticateRequest(req)
+	expectUser(t, user, ok, err, "kubelet")
+
+	req = makeRequest(t)
+	req.SetBasicAuth("alice", "secret")
+	user, ok, err = authenticator.AuthenticateRequest(req)
+	expectUser(t, user, ok, err, "alice")
+
+	user, ok, err = authenticator.AuthenticateRequest(makeRequest(t))
+	if ok || user != nil || err == nil {
+		t.Errorf("Unexpected authentication: %#v %v %#v", user, ok, err)
+	}
+
+	user, ok, err = Union{}.AuthenticateRequest(makeRequest(t))
+	expectNoUser(t, user, ok, err)
+}
+
+func makeCert(t *testing.T, name string, usage x509.ExtKeyUsage, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent, parentKey = template, key
+	}
+	data, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	cert, err := x509.ParseCertificate(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	return cert, key
+}
+
+func TestClientCert(t *testing.T) {
+	ca, caKey := makeCert(t, "ca", x509.ExtKeyUsageAny, nil, nil)
+	client, _ := makeCert(t, "alice", x509.ExtKeyUsageClientAuth, ca, caKey)
+	server, _ := makeCert(t, "apiserver", x509.ExtKeyUsageServerAuth, ca, caKey)
+	other, _ := makeCert(t, "other-ca", x509.ExtKeyUsageAny, nil, nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	authenticator := ClientCert{roots}
+
+	req := makeRequest(t)
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{client}}
+	user, ok, err := authenticator.AuthenticateRequest(req)
+	expectUser(t, user, ok, err, "alice")
+
+	req.TLS = &tls.ConnectionState{}
+	user, ok, err = authenticator.AuthenticateRequest(req)
+	expectNoUser(t, user, ok, err)
+
+	for _, cert := range []*x509.Certificate{server, other} {
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		user, ok, err = authenticator.AuthenticateRequest(req)
+		if ok || user != nil || err == nil {
+			t.Errorf("Unexpected authentication: %#v %v %#v", user, ok, err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+// Attributes describe an API request to authorize.
+type Attributes struct {
+	// User made the request. It is nil if the request was not authenticated.
+	User *User
+	// Verb is what the request does: "get", "list", "watch", "create", "update", "patch", "delete",
+	// or the name of an action, such as "migrate".
+	Verb string
+	// Namespace is the namespace of the objects the request is for. It is api.NamespaceAll for
+	// lists and watches of every namespace, and for objects that are not in a namespace.
+	Namespace string
+	// Resource is the kind of object the request is for, such as "pods".
+	Resource string
+}
+
+// IsReadOnly returns true if the request does not change anything.
+func (a Attributes) IsReadOnly() bool {
+	return a.Verb == "get" || a.Verb == "list" || a.Verb == "watch"
+}
+
+// Authorizer decides whether requests are allowed. It returns an error that says why if not.
+type Authorizer interface {
+	Authorize(a Attributes) error
+}
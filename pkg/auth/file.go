@@ -0,0 +1,103 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+)
+
+// PasswordFile is a Password that checks the passwords listed in a file.
+type PasswordFile struct {
+	passwords map[string]string
+}
+
+// LoadPasswordFile reads a password file, which has one "password,user" line per user.
+func LoadPasswordFile(path string) (*PasswordFile, error) {
+	records, err := readCSV(path)
+	if err != nil {
+		return nil, err
+	}
+	passwords := map[string]string{}
+	for _, record := range records {
+		passwords[record[1]] = record[0]
+	}
+	return &PasswordFile{passwords}, nil
+}
+
+func (f *PasswordFile) AuthenticatePassword(name, password string) (*User, bool, error) {
+	expected, ok := f.passwords[name]
+	if !ok || subtle.ConstantTimeCompare([]byte(expected), []byte(password)) != 1 {
+		return nil, false, nil
+	}
+	return &User{Name: name}, true, nil
+}
+
+// TokenFile is a Token that checks the tokens listed in a file.
+type TokenFile struct {
+	users map[string]string
+}
+
+// LoadTokenFile reads a token file, which has one "token,user" line per token.
+func LoadTokenFile(path string) (*TokenFile, error) {
+	records, err := readCSV(path)
+	if err != nil {
+		return nil, err
+	}
+	users := map[string]string{}
+	for _, record := range records {
+		users[record[0]] = record[1]
+	}
+	return &TokenFile{users}, nil
+}
+
+func (f *TokenFile) AuthenticateToken(token string) (*User, bool, error) {
+	name, ok := f.users[token]
+	if !ok {
+		return nil, false, nil
+	}
+	return &User{Name: name}, true, nil
+}
+
+// readCSV reads the lines of a credential file, which must have two non empty fields each.
+func readCSV(path string) ([][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	var records [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) != 2 || len(record[0]) == 0 || len(record[1]) == 0 {
+			return nil, fmt.Errorf("%s: expected 2 non empty fields, got %q", path, record)
+		}
+		records = append(records, record)
+	}
+}
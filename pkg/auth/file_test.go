@@ -0,0 +1,82 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func writeFile(t *testing.T, data string) string {
+	file, err := ioutil.TempFile("", "auth")
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	return file.Name()
+}
+
+func TestPasswordFile(t *testing.T) {
+	path := writeFile(t, "secret,alice\n\nhunter2, bob\n")
+	defer os.Remove(path)
+	passwords, err := LoadPasswordFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+
+	user, ok, err := passwords.AuthenticatePassword("alice", "secret")
+	expectUser(t, user, ok, err, "alice")
+	user, ok, err = passwords.AuthenticatePassword("bob", "hunter2")
+	expectUser(t, user, ok, err, "bob")
+	user, ok, err = passwords.AuthenticatePassword("alice", "hunter2")
+	expectNoUser(t, user, ok, err)
+	user, ok, err = passwords.AuthenticatePassword("carol", "")
+	expectNoUser(t, user, ok, err)
+}
+
+func TestTokenFile(t *testing.T) {
+	path := writeFile(t, "abc123,kubelet\ndef456,alice\n")
+	defer os.Remove(path)
+	tokens, err := LoadTokenFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+
+	user, ok, err := tokens.AuthenticateToken("abc123")
+	expectUser(t, user, ok, err, "kubelet")
+	user, ok, err = tokens.AuthenticateToken("def456")
+	expectUser(t, user, ok, err, "alice")
+	user, ok, err = tokens.AuthenticateToken("kubelet")
+	expectNoUser(t, user, ok, err)
+}
+
+func TestLoadInvalidFile(t *testing.T) {
+	for _, data := range []string{"secret\n", "secret,alice,extra\n", ",alice\n", "\"secret,alice\n"} {
+		path := writeFile(t, data)
+		if _, err := LoadPasswordFile(path); err == nil {
+			t.Errorf("Expected an error for %q", data)
+		}
+		os.Remove(path)
+	}
+	if _, err := LoadTokenFile("/non/existent"); err == nil {
+		t.Errorf("Expected an error")
+	}
+}
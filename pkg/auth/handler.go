@@ -0,0 +1,90 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// Requests remembers the user of each request while it is served, for the handlers that serve it.
+type Requests struct {
+	lock  sync.Mutex
+	users map[*http.Request]*User
+}
+
+// MakeRequests makes an empty set of requests.
+func MakeRequests() *Requests {
+	return &Requests{users: map[*http.Request]*User{}}
+}
+
+// User returns the user that sent 'req', or nil if it was not authenticated.
+func (r *Requests) User(req *http.Request) *User {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.users[req]
+}
+
+func (r *Requests) set(req *http.Request, user *User) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.users[req] = user
+}
+
+func (r *Requests) remove(req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.users, req)
+}
+
+// Handler is an http.Handler that passes requests authenticated by Authenticator to Handler,
+// and remembers their user in Requests. Other requests are answered with 401 Unauthorized.
+type Handler struct {
+	Requests      *Requests
+	Authenticator Authenticator
+	Handler       http.Handler
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	user, ok, err := h.Authenticator.AuthenticateRequest(req)
+	if err != nil {
+		log.Printf("Failed to authenticate %s %s: %v", req.Method, req.RequestURI, err)
+	}
+	if !ok {
+		unauthorized(w)
+		return
+	}
+	h.Requests.set(req, user)
+	defer h.Requests.remove(req)
+	h.Handler.ServeHTTP(w, req)
+}
+
+func unauthorized(w http.ResponseWriter) {
+	status := api.ErrorToStatus(api.NewUnauthorized("the request did not carry valid credentials"))
+	data, err := api.Encode(status)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", `Basic realm="kubernetes"`)
+	w.WriteHeader(status.Code)
+	w.Write(data)
+}
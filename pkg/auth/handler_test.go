@@ -0,0 +1,65 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+func TestHandler(t *testing.T) {
+	requests := MakeRequests()
+	var served *User
+	handler := &Handler{
+		Requests:      requests,
+		Authenticator: BasicAuth{fakePassword{"alice": "secret"}},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			served = requests.User(req)
+		}),
+	}
+
+	req := makeRequest(t)
+	req.SetBasicAuth("alice", "secret")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	if recorder.Code != 200 || served == nil || served.Name != "alice" {
+		t.Errorf("Unexpected response: %d %#v", recorder.Code, served)
+	}
+	if user := requests.User(req); user != nil {
+		t.Errorf("Unexpected user after the request: %#v", user)
+	}
+
+	served = nil
+	req = makeRequest(t)
+	req.SetBasicAuth("alice", "wrong")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	var status api.Status
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if recorder.Code != 401 || status.Reason != api.StatusReasonUnauthorized || served != nil {
+		t.Errorf("Unexpected response: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if len(recorder.HeaderMap.Get("WWW-Authenticate")) == 0 {
+		t.Errorf("Expected a WWW-Authenticate header")
+	}
+}
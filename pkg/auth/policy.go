@@ -0,0 +1,146 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Policy allows the requests it matches. Empty fields, and "*", match anything.
+type Policy struct {
+	User     string `json:"user,omitempty"`
+	Verb     string `json:"verb,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	// Namespace is the namespace requests must be in. Lists and watches of every namespace,
+	// and objects that are not in a namespace, are only matched by "*" or an empty namespace.
+	Namespace string `json:"namespace,omitempty"`
+	// If ReadOnly is set, only requests that do not change anything match.
+	ReadOnly bool `json:"readonly,omitempty"`
+}
+
+func matches(pattern, value string) bool {
+	return len(pattern) == 0 || pattern == "*" || pattern == value
+}
+
+// Matches returns true if the policy allows the request described by 'a'.
+func (p Policy) Matches(a Attributes) bool {
+	if len(p.User) > 0 && p.User != "*" && (a.User == nil || a.User.Name != p.User) {
+		return false
+	}
+	if p.ReadOnly && !a.IsReadOnly() {
+		return false
+	}
+	return matches(p.Verb, a.Verb) && matches(p.Resource, a.Resource) && matches(p.Namespace, a.Namespace)
+}
+
+// PolicyList is an Authorizer that allows the requests matched by any of its policies.
+type PolicyList []Policy
+
+func (list PolicyList) Authorize(a Attributes) error {
+	for _, policy := range list {
+		if policy.Matches(a) {
+			return nil
+		}
+	}
+	if a.User == nil {
+		return fmt.Errorf("no policy allows anonymous requests")
+	}
+	return fmt.Errorf("no policy allows user %q", a.User.Name)
+}
+
+// ParsePolicies reads policies, one JSON object per line. Empty lines and lines that start with
+// '#' are ignored.
+func ParsePolicies(data []byte) (PolicyList, error) {
+	list := PolicyList{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+		var policy Policy
+		if err := json.Unmarshal(text, &policy); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		list = append(list, policy)
+	}
+	return list, scanner.Err()
+}
+
+// PolicyFile is an Authorizer that allows the requests matched by the policies in a file. The
+// file is read again by Reload when it changes.
+type PolicyFile struct {
+	path string
+
+	lock     sync.RWMutex
+	policies PolicyList
+	modified time.Time
+}
+
+// LoadPolicyFile reads the policies in the file 'path'.
+func LoadPolicyFile(path string) (*PolicyFile, error) {
+	f := &PolicyFile{path: path}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload reads the policies again if the file changed since it was last read. If it can't be
+// read, the policies are kept as they are.
+func (f *PolicyFile) Reload() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	f.lock.RLock()
+	unchanged := f.policies != nil && info.ModTime().Equal(f.modified)
+	f.lock.RUnlock()
+	if unchanged {
+		return nil
+	}
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	policies, err := ParsePolicies(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", f.path, err)
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.policies != nil {
+		log.Printf("Reloaded %d policies from %s", len(policies), f.path)
+	}
+	f.policies = policies
+	f.modified = info.ModTime()
+	return nil
+}
+
+func (f *PolicyFile) Authorize(a Attributes) error {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.policies.Authorize(a)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPolicyListAuthorize(t *testing.T) {
+	policies, err := ParsePolicies([]byte(`
+# Admins can do anything.
+{"user": "admin"}
+{"user": "alice", "resource": "pods"}
+{"user": "bob", "readonly": true}
+{"user": "kubelet", "verb": "update", "resource": "minions"}
+{"user": "*", "verb": "get", "resource": "services"}
+{"user": "dave", "namespace": "team"}
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	table := []struct {
+		user      string
+		verb      string
+		resource  string
+		namespace string
+		allowed   bool
+	}{
+		{"admin", "delete", "services", "default", true},
+		{"alice", "create", "pods", "default", true},
+		{"alice", "list", "services", "default", false},
+		{"bob", "list", "pods", "default", true},
+		{"bob", "watch", "replicationControllers", "default", true},
+		{"bob", "delete", "pods", "default", false},
+		{"bob", "migrate", "pods", "default", false},
+		{"kubelet", "update", "minions", "", true},
+		{"kubelet", "create", "minions", "", false},
+		{"carol", "get", "services", "default", true},
+		{"", "get", "services", "default", true},
+		{"", "get", "pods", "default", false},
+		{"dave", "delete", "pods", "team", true},
+		{"dave", "delete", "pods", "default", false},
+		{"dave", "list", "pods", "", false},
+	}
+	for _, item := range table {
+		a := Attributes{Verb: item.verb, Namespace: item.namespace, Resource: item.resource}
+		if len(item.user) > 0 {
+			a.User = &User{Name: item.user}
+		}
+		err := policies.Authorize(a)
+		if (err == nil) != item.allowed {
+			t.Errorf("Unexpected authorization of %#v: %v", item, err)
+		}
+	}
+}
+
+func TestParseInvalidPolicies(t *testing.T) {
+	if _, err := ParsePolicies([]byte("{\"user\": \"admin\"}\n{user}\n")); err == nil {
+		t.Errorf("Expected an error")
+	}
+}
+
+func TestPolicyFileReload(t *testing.T) {
+	path := writeFile(t, `{"user": "alice"}`)
+	defer os.Remove(path)
+	policies, err := LoadPolicyFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	alice := Attributes{User: &User{Name: "alice"}, Verb: "delete", Resource: "pods"}
+	bob := Attributes{User: &User{Name: "bob"}, Verb: "delete", Resource: "pods"}
+	if err := policies.Authorize(alice); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if err := policies.Authorize(bob); err == nil {
+		t.Errorf("Expected bob to be denied")
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"user": "bob"}`), 0600); err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if err := policies.Reload(); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if err := policies.Authorize(alice); err == nil {
+		t.Errorf("Expected alice to be denied")
+	}
+	if err := policies.Authorize(bob); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	// A broken file keeps the policies that were loaded.
+	ioutil.WriteFile(path, []byte(`{`), 0600)
+	later = later.Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if err := policies.Reload(); err == nil {
+		t.Errorf("Expected an error")
+	}
+	if err := policies.Authorize(bob); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
@@ -19,7 +19,6 @@ package client
 import (
 	"bytes"
 	"crypto/tls"
-	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -31,6 +30,9 @@ import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 )
 
+// apiVersion is the version of the API the client speaks.
+const apiVersion = "v1beta1"
+
 // ClientInterface holds the methods for clients of Kubenetes, an interface to allow mock testing
 type ClientInterface interface {
 	ListPods(labelQuery map[string]string) (api.PodList, error)
@@ -42,24 +44,50 @@ type ClientInterface interface {
 	GetReplicationController(name string) (api.ReplicationController, error)
 	CreateReplicationController(api.ReplicationController) (api.ReplicationController, error)
 	UpdateReplicationController(api.ReplicationController) (api.ReplicationController, error)
+	PatchReplicationController(name string, patch []byte) (api.ReplicationController, error)
 	DeleteReplicationController(string) error
 
 	GetService(name string) (api.Service, error)
 	CreateService(api.Service) (api.Service, error)
 	UpdateService(api.Service) (api.Service, error)
+	PatchService(name string, patch []byte) (api.Service, error)
 	DeleteService(string) error
+
+	ListMinions() (api.MinionList, error)
+	GetMinion(name string) (api.Minion, error)
+	CreateMinion(api.Minion) (api.Minion, error)
+	UpdateMinion(api.Minion) (api.Minion, error)
+	PatchMinion(name string, patch []byte) (api.Minion, error)
+	DeleteMinion(string) error
+
+	InNamespace(namespace string) ClientInterface
 }
 
-// AuthInfo is used to store authorization information
+// AuthInfo is used to store authorization information. If BearerToken is set, it is sent
+// instead of the user and password.
 type AuthInfo struct {
-	User     string
-	Password string
+	User        string
+	Password    string
+	BearerToken string `json:",omitempty"`
+}
+
+// SetAuth adds the credentials in 'auth' to 'request'.
+func (auth *AuthInfo) SetAuth(request *http.Request) {
+	if len(auth.BearerToken) > 0 {
+		request.Header.Set("Authorization", "Bearer "+auth.BearerToken)
+		return
+	}
+	request.SetBasicAuth(auth.User, auth.Password)
 }
 
 // Client is the actual implementation of a Kubernetes client.
 // Host is the http://... base for the URL
+// Namespace, if set, is the namespace of the objects the client reads and writes. Otherwise,
+// lists span every namespace, and other calls are in the default namespace. Minions are in no
+// namespace.
 type Client struct {
 	Host       string
+	Namespace  string
 	Auth       *AuthInfo
 	httpClient *http.Client
 }
@@ -70,23 +98,7 @@ type Client struct {
 // requestBody is the body of the request. Can be nil.
 // target the interface to marshal the JSON response into.  Can be nil.
 func (client Client) rawRequest(method, path string, requestBody io.Reader, target interface{}) ([]byte, error) {
-	request, err := http.NewRequest(method, client.makeURL(path), requestBody)
-	if err != nil {
-		return []byte{}, err
-	}
-	if client.Auth != nil {
-		request.SetBasicAuth(client.Auth.User, client.Auth.Password)
-	}
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	var httpClient *http.Client
-	if client.httpClient != nil {
-		httpClient = client.httpClient
-	} else {
-		httpClient = &http.Client{Transport: tr}
-	}
-	response, err := httpClient.Do(request)
+	response, err := client.do(method, path, requestBody)
 	if err != nil {
 		return nil, err
 	}
@@ -96,10 +108,10 @@ func (client Client) rawRequest(method, path string, requestBody io.Reader, targ
 		return body, err
 	}
 	if response.StatusCode != 200 {
-		return nil, fmt.Errorf("request [%s %s] failed (%d) %s: %s", method, client.makeURL(path), response.StatusCode, response.Status, string(body))
+		return nil, decodeError(method, client.makeURL(path), response, body)
 	}
 	if target != nil {
-		err = json.Unmarshal(body, target)
+		err = api.DecodeVersionInto(body, apiVersion, target)
 	}
 	if err != nil {
 		log.Printf("Failed to parse: %s\n", string(body))
@@ -108,8 +120,45 @@ func (client Client) rawRequest(method, path string, requestBody io.Reader, targ
 	return body, err
 }
 
+// decodeError returns the error of a failed request. If the server answered with an api.Status,
+// the error is an *api.StatusError, which can be inspected with api.IsNotFound and friends.
+func decodeError(method, url string, response *http.Response, body []byte) error {
+	var status api.Status
+	if err := api.DecodeVersionInto(body, apiVersion, &status); err == nil && status.Status == api.StatusFailure {
+		if status.Code == 0 {
+			status.Code = response.StatusCode
+		}
+		return &api.StatusError{Status: status}
+	}
+	return fmt.Errorf("request [%s %s] failed (%d) %s: %s", method, url, response.StatusCode, response.Status, string(body))
+}
+
+// do sends a request to the API server, and returns the unread response.
+func (client Client) do(method, path string, requestBody io.Reader) (*http.Response, error) {
+	request, err := http.NewRequest(method, client.makeURL(path), requestBody)
+	if err != nil {
+		return nil, err
+	}
+	if client.Auth != nil {
+		client.Auth.SetAuth(request)
+	}
+	tr := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	var httpClient *http.Client
+	if client.httpClient != nil {
+		httpClient = client.httpClient
+	} else {
+		httpClient = &http.Client{Transport: tr}
+	}
+	return httpClient.Do(request)
+}
+
 func (client Client) makeURL(path string) string {
-	return client.Host + "/api/v1beta1/" + path
+	if len(client.Namespace) > 0 && !strings.HasPrefix(path, "minions") {
+		path = "namespaces/" + client.Namespace + "/" + path
+	}
+	return client.Host + "/api/" + apiVersion + "/" + path
 }
 
 // EncodeLabelQuery transforms a label query expressed as a key/value map, into a
@@ -141,6 +190,12 @@ func DecodeLabelQuery(labelQuery string) map[string]string {
 	return result
 }
 
+// InNamespace returns a copy of the client that reads and writes the objects in 'namespace'.
+func (client Client) InNamespace(namespace string) ClientInterface {
+	client.Namespace = namespace
+	return client
+}
+
 // ListPods takes a label query, and returns the list of pods that match that query
 func (client Client) ListPods(labelQuery map[string]string) (api.PodList, error) {
 	path := "pods"
@@ -168,7 +223,7 @@ func (client Client) DeletePod(name string) error {
 // CreatePod takes the representation of a pod.  Returns the server's representation of the pod, and an error, if it occurs
 func (client Client) CreatePod(pod api.Pod) (api.Pod, error) {
 	var result api.Pod
-	body, err := json.Marshal(pod)
+	body, err := api.EncodeVersion(pod, apiVersion)
 	if err == nil {
 		_, err = client.rawRequest("POST", "pods", bytes.NewBuffer(body), &result)
 	}
@@ -178,13 +233,45 @@ func (client Client) CreatePod(pod api.Pod) (api.Pod, error) {
 // UpdatePod takes the representation of a pod to update.  Returns the server's representation of the pod, and an error, if it occurs
 func (client Client) UpdatePod(pod api.Pod) (api.Pod, error) {
 	var result api.Pod
-	body, err := json.Marshal(pod)
+	body, err := api.EncodeVersion(pod, apiVersion)
 	if err == nil {
 		_, err = client.rawRequest("PUT", "pods/"+pod.ID, bytes.NewBuffer(body), &result)
 	}
 	return result, err
 }
 
+// GetPodConsole returns the console output of 'container' in pod 'name', or of the pod's first
+// container if 'container' is empty. If 'follow' is set, the output continues until the container
+// stops or the returned reader is closed.
+func (client Client) GetPodConsole(name, container string, follow bool) (io.ReadCloser, error) {
+	query := url.Values{}
+	if len(container) > 0 {
+		query.Set("container", container)
+	}
+	if follow {
+		query.Set("follow", "true")
+	}
+	response, err := client.do("GET", "pods/"+name+"/console?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != 200 {
+		defer response.Body.Close()
+		body, _ := ioutil.ReadAll(response.Body)
+		return nil, decodeError("GET", response.Request.URL.String(), response, body)
+	}
+	return response.Body, nil
+}
+
+// MigratePod live migrates pod 'name' to minion 'target'. It returns the moved pod once it runs there.
+func (client Client) MigratePod(name, target string) (api.Pod, error) {
+	var result api.Pod
+	query := url.Values{}
+	query.Set("target", target)
+	_, err := client.rawRequest("POST", "pods/"+name+"/migrate?"+query.Encode(), nil, &result)
+	return result, err
+}
+
 // GetReplicationController returns information about a particular replication controller
 func (client Client) GetReplicationController(name string) (api.ReplicationController, error) {
 	var result api.ReplicationController
@@ -195,7 +282,7 @@ func (client Client) GetReplicationController(name string) (api.ReplicationContr
 // CreateReplicationController creates a new replication controller
 func (client Client) CreateReplicationController(controller api.ReplicationController) (api.ReplicationController, error) {
 	var result api.ReplicationController
-	body, err := json.Marshal(controller)
+	body, err := api.EncodeVersion(controller, apiVersion)
 	if err == nil {
 		_, err = client.rawRequest("POST", "replicationControllers", bytes.NewBuffer(body), &result)
 	}
@@ -205,13 +292,20 @@ func (client Client) CreateReplicationController(controller api.ReplicationContr
 // UpdateReplicationController updates an existing replication controller
 func (client Client) UpdateReplicationController(controller api.ReplicationController) (api.ReplicationController, error) {
 	var result api.ReplicationController
-	body, err := json.Marshal(controller)
+	body, err := api.EncodeVersion(controller, apiVersion)
 	if err == nil {
 		_, err = client.rawRequest("PUT", "replicationControllers/"+controller.ID, bytes.NewBuffer(body), &result)
 	}
 	return result, err
 }
 
+// PatchReplicationController applies a JSON merge patch to an existing replication controller
+func (client Client) PatchReplicationController(name string, patch []byte) (api.ReplicationController, error) {
+	var result api.ReplicationController
+	_, err := client.rawRequest("PATCH", "replicationControllers/"+name, bytes.NewBuffer(patch), &result)
+	return result, err
+}
+
 func (client Client) DeleteReplicationController(name string) error {
 	_, err := client.rawRequest("DELETE", "replicationControllers/"+name, nil, nil)
 	return err
@@ -227,7 +321,7 @@ func (client Client) GetService(name string) (api.Service, error) {
 // CreateReplicationController creates a new replication controller
 func (client Client) CreateService(svc api.Service) (api.Service, error) {
 	var result api.Service
-	body, err := json.Marshal(svc)
+	body, err := api.EncodeVersion(svc, apiVersion)
 	if err == nil {
 		_, err = client.rawRequest("POST", "services", bytes.NewBuffer(body), &result)
 	}
@@ -237,14 +331,74 @@ func (client Client) CreateService(svc api.Service) (api.Service, error) {
 // UpdateReplicationController updates an existing replication controller
 func (client Client) UpdateService(svc api.Service) (api.Service, error) {
 	var result api.Service
-	body, err := json.Marshal(svc)
+	body, err := api.EncodeVersion(svc, apiVersion)
 	if err == nil {
 		_, err = client.rawRequest("PUT", "services/"+svc.ID, bytes.NewBuffer(body), &result)
 	}
 	return result, err
 }
 
+// PatchService applies a JSON merge patch to an existing service
+func (client Client) PatchService(name string, patch []byte) (api.Service, error) {
+	var result api.Service
+	_, err := client.rawRequest("PATCH", "services/"+name, bytes.NewBuffer(patch), &result)
+	return result, err
+}
+
 func (client Client) DeleteService(name string) error {
 	_, err := client.rawRequest("DELETE", "services/"+name, nil, nil)
 	return err
 }
+
+// ListMinions returns all of the minions known to the master
+func (client Client) ListMinions() (api.MinionList, error) {
+	var result api.MinionList
+	_, err := client.rawRequest("GET", "minions", nil, &result)
+	return result, err
+}
+
+// GetMinion returns information about a particular minion
+func (client Client) GetMinion(name string) (api.Minion, error) {
+	var result api.Minion
+	_, err := client.rawRequest("GET", "minions/"+name, nil, &result)
+	return result, err
+}
+
+// CreateMinion registers a new minion
+func (client Client) CreateMinion(minion api.Minion) (api.Minion, error) {
+	var result api.Minion
+	body, err := api.EncodeVersion(minion, apiVersion)
+	if err == nil {
+		_, err = client.rawRequest("POST", "minions", bytes.NewBuffer(body), &result)
+	}
+	return result, err
+}
+
+// UpdateMinion updates an existing minion
+func (client Client) UpdateMinion(minion api.Minion) (api.Minion, error) {
+	var result api.Minion
+	body, err := api.EncodeVersion(minion, apiVersion)
+	if err == nil {
+		_, err = client.rawRequest("PUT", "minions/"+minion.ID, bytes.NewBuffer(body), &result)
+	}
+	return result, err
+}
+
+// MinionHeartbeat reports that the kubelet of minion 'name' is alive. It returns the minion.
+func (client Client) MinionHeartbeat(name string) (api.Minion, error) {
+	var result api.Minion
+	_, err := client.rawRequest("POST", "minions/"+name+"/heartbeat", nil, &result)
+	return result, err
+}
+
+// PatchMinion applies a JSON merge patch to an existing minion
+func (client Client) PatchMinion(name string, patch []byte) (api.Minion, error) {
+	var result api.Minion
+	_, err := client.rawRequest("PATCH", "minions/"+name, bytes.NewBuffer(patch), &result)
+	return result, err
+}
+
+func (client Client) DeleteMinion(name string) error {
+	_, err := client.rawRequest("DELETE", "minions/"+name, nil, nil)
+	return err
+}
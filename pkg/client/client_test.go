@@ -17,12 +17,16 @@ package client
 
 import (
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"testing"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 )
 
@@ -163,6 +167,113 @@ func TestGetPod(t *testing.T) {
 	testServer.Close()
 }
 
+func TestGetPodNotFound(t *testing.T) {
+	fakeHandler := util.FakeHandler{
+		StatusCode:   404,
+		ResponseBody: util.MakeJSONString(api.ErrorToStatus(api.NewNotFound("pod", "foo"))),
+	}
+	testServer := httptest.NewTLSServer(&fakeHandler)
+	client := Client{
+		Host: testServer.URL,
+	}
+	_, err := client.GetPod("foo")
+	if !api.IsNotFound(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if err != nil && err.Error() != `pod "foo" not found` {
+		t.Errorf("Unexpected message: %v", err)
+	}
+	testServer.Close()
+}
+
+func TestUpdateControllerConflict(t *testing.T) {
+	fakeHandler := util.FakeHandler{
+		StatusCode:   409,
+		ResponseBody: util.MakeJSONString(api.ErrorToStatus(api.NewConflict("replicationController", "foo", fmt.Errorf("resourceVersion 1 is out of date")))),
+	}
+	testServer := httptest.NewTLSServer(&fakeHandler)
+	client := Client{
+		Host: testServer.URL,
+	}
+	_, err := client.UpdateReplicationController(api.ReplicationController{JSONBase: api.JSONBase{ID: "foo", ResourceVersion: 1}})
+	if !api.IsConflict(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	testServer.Close()
+}
+
+func TestRequestErrorWithoutStatus(t *testing.T) {
+	fakeHandler := util.FakeHandler{
+		StatusCode:   500,
+		ResponseBody: "apiserver panic",
+	}
+	testServer := httptest.NewTLSServer(&fakeHandler)
+	client := Client{
+		Host: testServer.URL,
+	}
+	err := client.DeletePod("foo")
+	if err == nil {
+		t.Fatal("Unexpected non-error")
+	}
+	if _, ok := err.(*api.StatusError); ok {
+		t.Errorf("Unexpected status error: %#v", err)
+	}
+	testServer.Close()
+}
+
+func TestGetPodConsole(t *testing.T) {
+	fakeHandler := util.FakeHandler{
+		StatusCode:   200,
+		ResponseBody: "booting\n",
+	}
+	testServer := httptest.NewTLSServer(&fakeHandler)
+	client := Client{
+		Host: testServer.URL,
+	}
+	console, err := client.GetPodConsole("foo", "bar", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	data, err := ioutil.ReadAll(console)
+	console.Close()
+	fakeHandler.ValidateRequest(t, makeUrl("/pods/foo/console"), "GET", nil)
+	if err != nil || string(data) != "booting\n" {
+		t.Errorf("Unexpected console: %s %#v", string(data), err)
+	}
+	if query := fakeHandler.RequestReceived.URL.Query(); query.Get("container") != "bar" || query.Get("follow") != "true" {
+		t.Errorf("Unexpected query: %#v", query)
+	}
+
+	fakeHandler.StatusCode = 404
+	fakeHandler.ResponseBody = util.MakeJSONString(api.ErrorToStatus(api.NewNotFound("pod", "foo")))
+	if _, err := client.GetPodConsole("foo", "", false); !api.IsNotFound(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
+
+func TestMigratePod(t *testing.T) {
+	requestPod := api.Pod{
+		JSONBase:     api.JSONBase{ID: "foo"},
+		CurrentState: api.PodState{Host: "other"},
+	}
+	fakeHandler := util.FakeHandler{
+		StatusCode:   200,
+		ResponseBody: util.MakeJSONString(requestPod),
+	}
+	testServer := httptest.NewTLSServer(&fakeHandler)
+	client := Client{
+		Host: testServer.URL,
+	}
+	receivedPod, err := client.MigratePod("foo", "other")
+	fakeHandler.ValidateRequest(t, makeUrl("/pods/foo/migrate"), "POST", nil)
+	if err != nil || !reflect.DeepEqual(requestPod, receivedPod) {
+		t.Errorf("Unexpected response: %#v %#v", receivedPod, err)
+	}
+	if target := fakeHandler.RequestReceived.URL.Query().Get("target"); target != "other" {
+		t.Errorf("Unexpected target: %s", target)
+	}
+}
+
 func TestDeletePod(t *testing.T) {
 	fakeHandler := util.FakeHandler{
 		StatusCode:   200,
@@ -180,6 +291,25 @@ func TestDeletePod(t *testing.T) {
 	testServer.Close()
 }
 
+func TestNamespacedClient(t *testing.T) {
+	fakeHandler := util.FakeHandler{
+		StatusCode:   200,
+		ResponseBody: `{"success": true}`,
+	}
+	testServer := httptest.NewTLSServer(&fakeHandler)
+	client := Client{
+		Host:      testServer.URL,
+		Namespace: "team",
+	}
+	err := client.DeletePod("foo")
+	expectNoError(t, err)
+	fakeHandler.ValidateRequest(t, makeUrl("/namespaces/team/pods/foo"), "DELETE", nil)
+	err = client.DeleteMinion("foo")
+	expectNoError(t, err)
+	fakeHandler.ValidateRequest(t, makeUrl("/minions/foo"), "DELETE", nil)
+	testServer.Close()
+}
+
 func TestCreatePod(t *testing.T) {
 	requestPod := api.Pod{
 		CurrentState: api.PodState{
@@ -311,6 +441,34 @@ func TestUpdateController(t *testing.T) {
 	testServer.Close()
 }
 
+func TestPatchController(t *testing.T) {
+	expectedController := api.ReplicationController{
+		JSONBase: api.JSONBase{
+			ID: "foo",
+		},
+		DesiredState: api.ReplicationControllerState{
+			Replicas: 3,
+		},
+	}
+	body, _ := json.Marshal(expectedController)
+	fakeHandler := util.FakeHandler{
+		StatusCode:   200,
+		ResponseBody: string(body),
+	}
+	testServer := httptest.NewTLSServer(&fakeHandler)
+	client := Client{
+		Host: testServer.URL,
+	}
+	patch := `{"desiredState":{"replicas":3}}`
+	receivedController, err := client.PatchReplicationController("foo", []byte(patch))
+	expectNoError(t, err)
+	if !reflect.DeepEqual(expectedController, receivedController) {
+		t.Errorf("Unexpected controller, expected: %#v, received %#v", expectedController, receivedController)
+	}
+	fakeHandler.ValidateRequest(t, makeUrl("/replicationControllers/foo"), "PATCH", &patch)
+	testServer.Close()
+}
+
 func TestDeleteController(t *testing.T) {
 	fakeHandler := util.FakeHandler{
 		StatusCode:   200,
@@ -362,3 +520,136 @@ func TestCreateController(t *testing.T) {
 	fakeHandler.ValidateRequest(t, makeUrl("/replicationControllers"), "POST", nil)
 	testServer.Close()
 }
+
+func TestCreateMinion(t *testing.T) {
+	expectedMinion := api.Minion{
+		JSONBase: api.JSONBase{
+			ID: "foo",
+		},
+		HostIP: "10.0.0.1",
+	}
+	body, _ := json.Marshal(expectedMinion)
+	fakeHandler := util.FakeHandler{
+		StatusCode:   200,
+		ResponseBody: string(body),
+	}
+	testServer := httptest.NewTLSServer(&fakeHandler)
+	client := Client{
+		Host: testServer.URL,
+	}
+	receivedMinion, err := client.CreateMinion(expectedMinion)
+	expectNoError(t, err)
+	if !reflect.DeepEqual(expectedMinion, receivedMinion) {
+		t.Errorf("Unexpected minion, expected: %#v, received %#v", expectedMinion, receivedMinion)
+	}
+	fakeHandler.ValidateRequest(t, makeUrl("/minions"), "POST", nil)
+	testServer.Close()
+}
+
+func TestUpdateMinion(t *testing.T) {
+	expectedMinion := api.Minion{
+		JSONBase: api.JSONBase{
+			ID: "foo",
+		},
+		Condition: api.MinionReady,
+	}
+	body, _ := json.Marshal(expectedMinion)
+	fakeHandler := util.FakeHandler{
+		StatusCode:   200,
+		ResponseBody: string(body),
+	}
+	testServer := httptest.NewTLSServer(&fakeHandler)
+	client := Client{
+		Host: testServer.URL,
+	}
+	receivedMinion, err := client.UpdateMinion(api.Minion{
+		JSONBase: api.JSONBase{
+			ID: "foo",
+		},
+	})
+	expectNoError(t, err)
+	if !reflect.DeepEqual(expectedMinion, receivedMinion) {
+		t.Errorf("Unexpected minion, expected: %#v, received %#v", expectedMinion, receivedMinion)
+	}
+	fakeHandler.ValidateRequest(t, makeUrl("/minions/foo"), "PUT", nil)
+	testServer.Close()
+}
+
+func TestMinionHeartbeat(t *testing.T) {
+	expectedMinion := api.Minion{JSONBase: api.JSONBase{ID: "foo"}, Condition: api.MinionReady}
+	fakeHandler := util.FakeHandler{
+		StatusCode:   200,
+		ResponseBody: util.MakeJSONString(expectedMinion),
+	}
+	testServer := httptest.NewTLSServer(&fakeHandler)
+	defer testServer.Close()
+	client := Client{
+		Host: testServer.URL,
+	}
+	receivedMinion, err := client.MinionHeartbeat("foo")
+	fakeHandler.ValidateRequest(t, makeUrl("/minions/foo/heartbeat"), "POST", nil)
+	if err != nil || !reflect.DeepEqual(expectedMinion, receivedMinion) {
+		t.Errorf("Unexpected response: %#v %#v", receivedMinion, err)
+	}
+}
+
+func TestAuthInfoSetAuth(t *testing.T) {
+	request, _ := http.NewRequest("GET", "http://localhost/api/v1beta1/pods", nil)
+	(&AuthInfo{User: "user", Password: "pass"}).SetAuth(request)
+	if header := request.Header.Get("Authorization"); header != "Basic dXNlcjpwYXNz" {
+		t.Errorf("Unexpected authorization: %s", request.Header.Get("Authorization"))
+	}
+	(&AuthInfo{User: "user", Password: "pass", BearerToken: "abc123"}).SetAuth(request)
+	if header := request.Header.Get("Authorization"); header != "Bearer abc123" {
+		t.Errorf("Unexpected authorization: %s", header)
+	}
+}
+
+type fakeCredentials map[string]string
+
+func (f fakeCredentials) AuthenticatePassword(name, password string) (*auth.User, bool, error) {
+	if expected, ok := f[name]; ok && expected == password {
+		return &auth.User{Name: name}, true, nil
+	}
+	return nil, false, nil
+}
+
+func (f fakeCredentials) AuthenticateToken(token string) (*auth.User, bool, error) {
+	if name, ok := f[token]; ok {
+		return &auth.User{Name: name}, true, nil
+	}
+	return nil, false, nil
+}
+
+func TestAuthenticatedClient(t *testing.T) {
+	credentials := fakeCredentials{"controller": "secret", "abc123": "controller"}
+	testServer := httptest.NewTLSServer(&auth.Handler{
+		Requests:      auth.MakeRequests(),
+		Authenticator: auth.Union{auth.BasicAuth{Password: credentials}, auth.BearerToken{Token: credentials}},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(w, `{"items": []}`)
+		}),
+	})
+	defer testServer.Close()
+
+	table := []struct {
+		auth *AuthInfo
+		ok   bool
+	}{
+		{&AuthInfo{BearerToken: "abc123"}, true},
+		{&AuthInfo{User: "controller", Password: "secret"}, true},
+		{&AuthInfo{BearerToken: "wrong"}, false},
+		{&AuthInfo{User: "controller", Password: "wrong"}, false},
+		{nil, false},
+	}
+	for _, item := range table {
+		client := Client{Host: testServer.URL, Auth: item.auth}
+		_, err := client.ListPods(nil)
+		if item.ok && err != nil {
+			t.Errorf("Unexpected error for %#v: %v", item.auth, err)
+		}
+		if !item.ok && !api.IsUnauthorized(err) {
+			t.Errorf("Expected an unauthorized error for %#v, got %#v", item.auth, err)
+		}
+	}
+}
@@ -58,10 +58,10 @@ func (c *HTTPContainerInfo) GetContainerInfo(host, name string) (interface{}, er
 
 // Useful for testing.
 type FakeContainerInfo struct {
-	data interface{}
-	err  error
+	Data interface{}
+	Err  error
 }
 
 func (c *FakeContainerInfo) GetContainerInfo(host, name string) (interface{}, error) {
-	return c.data, c.err
+	return c.Data, c.Err
 }
@@ -0,0 +1,83 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PodConsole is an interface for things that can read the consoles of pods.
+// Injectable for easy testing.
+type PodConsole interface {
+	// GetPodConsole returns the console output of 'container' in pod 'podID' on 'host', or nil if
+	// there is no such pod. If 'follow' is set, the output continues until the container stops.
+	GetPodConsole(host, podID, container string, follow bool) (io.ReadCloser, error)
+}
+
+// The default implementation, accesses the kubelet over HTTP
+type HTTPPodConsole struct {
+	Client *http.Client
+	Port   uint
+}
+
+func (c *HTTPPodConsole) GetPodConsole(host, podID, container string, follow bool) (io.ReadCloser, error) {
+	query := url.Values{}
+	query.Set("podID", podID)
+	if len(container) > 0 {
+		query.Set("container", container)
+	}
+	if follow {
+		query.Set("follow", "true")
+	}
+	response, err := c.Client.Get(fmt.Sprintf("http://%s:%d/podConsole?%s", host, c.Port, query.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	return checkStreamResponse(response)
+}
+
+// checkStreamResponse returns the body of a successful streamed response, nil if the response
+// is a 404, and an error for any other response.
+func checkStreamResponse(response *http.Response) (io.ReadCloser, error) {
+	if response.StatusCode == http.StatusOK {
+		return response.Body, nil
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	body, _ := ioutil.ReadAll(response.Body)
+	return nil, fmt.Errorf("request for %s failed (%d) %s: %s", response.Request.URL, response.StatusCode, response.Status, string(body))
+}
+
+// Useful for testing.
+type FakePodConsole struct {
+	Data string
+	Err  error
+}
+
+func (c *FakePodConsole) GetPodConsole(host, podID, container string, follow bool) (io.ReadCloser, error) {
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	return ioutil.NopCloser(strings.NewReader(c.Data)), nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+)
+
+// makeTestKubelet starts a server for 'handler', and returns its host and port.
+func makeTestKubelet(t *testing.T, handler http.Handler) (string, uint, *httptest.Server) {
+	testServer := httptest.NewServer(handler)
+	hostUrl, err := url.Parse(testServer.URL)
+	expectNoError(t, err)
+	parts := strings.Split(hostUrl.Host, ":")
+	port, err := strconv.Atoi(parts[1])
+	expectNoError(t, err)
+	return parts[0], uint(port), testServer
+}
+
+func makeTestPodConsole(t *testing.T, handler http.Handler) (*HTTPPodConsole, string, *httptest.Server) {
+	host, port, testServer := makeTestKubelet(t, handler)
+	return &HTTPPodConsole{Client: http.DefaultClient, Port: port}, host, testServer
+}
+
+func TestHTTPPodConsole(t *testing.T) {
+	fakeHandler := util.FakeHandler{
+		StatusCode:   200,
+		ResponseBody: "booting\n",
+	}
+	podConsole, host, testServer := makeTestPodConsole(t, &fakeHandler)
+	defer testServer.Close()
+	console, err := podConsole.GetPodConsole(host, "foo", "bar", true)
+	expectNoError(t, err)
+	data, err := ioutil.ReadAll(console)
+	expectNoError(t, err)
+	console.Close()
+	if string(data) != "booting\n" {
+		t.Errorf("Unexpected console: %s", string(data))
+	}
+	query := fakeHandler.RequestReceived.URL.Query()
+	if fakeHandler.RequestReceived.URL.Path != "/podConsole" || query.Get("podID") != "foo" || query.Get("container") != "bar" || query.Get("follow") != "true" {
+		t.Errorf("Unexpected request: %#v", fakeHandler.RequestReceived.URL)
+	}
+}
+
+func TestHTTPPodConsoleNotFound(t *testing.T) {
+	podConsole, host, testServer := makeTestPodConsole(t, &util.FakeHandler{StatusCode: 404})
+	defer testServer.Close()
+	console, err := podConsole.GetPodConsole(host, "foo", "", false)
+	if console != nil || err != nil {
+		t.Errorf("Unexpected result: %#v %#v", console, err)
+	}
+}
+
+func TestHTTPPodConsoleError(t *testing.T) {
+	podConsole, host, testServer := makeTestPodConsole(t, &util.FakeHandler{StatusCode: 500, ResponseBody: "broken"})
+	defer testServer.Close()
+	if _, err := podConsole.GetPodConsole(host, "foo", "", false); err == nil {
+		t.Error("Unexpected non-error")
+	}
+}
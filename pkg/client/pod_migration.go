@@ -0,0 +1,115 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// PodMigration is an interface for things that can move pods between kubelets.
+// Injectable for easy testing.
+type PodMigration interface {
+	// PrepareMigration asks the kubelet on 'host' to prepare to receive the pod described by
+	// 'manifest', and returns the names its members will have there, by container name.
+	PrepareMigration(host string, manifest api.ContainerManifest) (map[string]string, error)
+	// MigratePod asks the kubelet on 'host' to live migrate a pod, and returns once it's done.
+	MigratePod(host string, migration api.PodMigration) error
+	// AbortMigration asks the kubelet on 'host' to give up on receiving pod 'podID', and to
+	// stop the members that arrived.
+	AbortMigration(host, podID string) error
+}
+
+// The default implementation, accesses the kubelet over HTTP
+type HTTPPodMigration struct {
+	Client *http.Client
+	Port   uint
+}
+
+// post sends 'obj' as JSON to 'path' on the kubelet at 'host', and returns the response body.
+func (c *HTTPPodMigration) post(host, path string, obj interface{}) ([]byte, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.Client.Post(fmt.Sprintf("http://%s:%d%s", host, c.Port, path), "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request for %s failed (%d) %s: %s", response.Request.URL, response.StatusCode, response.Status, string(body))
+	}
+	return body, nil
+}
+
+func (c *HTTPPodMigration) PrepareMigration(host string, manifest api.ContainerManifest) (map[string]string, error) {
+	body, err := c.post(host, "/prepareMigration", manifest)
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]string{}
+	err = json.Unmarshal(body, &names)
+	return names, err
+}
+
+func (c *HTTPPodMigration) MigratePod(host string, migration api.PodMigration) error {
+	_, err := c.post(host, "/migrate", migration)
+	return err
+}
+
+func (c *HTTPPodMigration) AbortMigration(host, podID string) error {
+	_, err := c.post(host, "/abortMigration", api.PodMigration{PodID: podID})
+	return err
+}
+
+// Useful for testing.
+type FakePodMigration struct {
+	// Names is returned by PrepareMigration.
+	Names      map[string]string
+	PrepareErr error
+	MigrateErr error
+	AbortErr   error
+	// Actions records the calls made, e.g. "prepare-migration:machine".
+	Actions    []string
+	Migrations []api.PodMigration
+}
+
+func (c *FakePodMigration) PrepareMigration(host string, manifest api.ContainerManifest) (map[string]string, error) {
+	c.Actions = append(c.Actions, "prepare-migration:"+host)
+	return c.Names, c.PrepareErr
+}
+
+func (c *FakePodMigration) MigratePod(host string, migration api.PodMigration) error {
+	c.Actions = append(c.Actions, "migrate:"+host)
+	c.Migrations = append(c.Migrations, migration)
+	return c.MigrateErr
+}
+
+func (c *FakePodMigration) AbortMigration(host, podID string) error {
+	c.Actions = append(c.Actions, "abort-migration:"+host)
+	return c.AbortErr
+}
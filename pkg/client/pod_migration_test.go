@@ -0,0 +1,71 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+)
+
+func TestHTTPPrepareMigration(t *testing.T) {
+	fakeHandler := util.FakeHandler{
+		StatusCode:   200,
+		ResponseBody: `{"bar": "bar--foo--1234"}`,
+	}
+	host, port, testServer := makeTestKubelet(t, &fakeHandler)
+	defer testServer.Close()
+	migration := &HTTPPodMigration{Client: http.DefaultClient, Port: port}
+	manifest := api.ContainerManifest{Id: "foo"}
+	names, err := migration.PrepareMigration(host, manifest)
+	expectNoError(t, err)
+	if names["bar"] != "bar--foo--1234" {
+		t.Errorf("Unexpected names: %#v", names)
+	}
+	data, _ := json.Marshal(manifest)
+	body := string(data)
+	fakeHandler.ValidateRequest(t, "/prepareMigration", "POST", &body)
+}
+
+func TestHTTPMigratePod(t *testing.T) {
+	fakeHandler := util.FakeHandler{StatusCode: 200}
+	host, port, testServer := makeTestKubelet(t, &fakeHandler)
+	defer testServer.Close()
+	migration := &HTTPPodMigration{Client: http.DefaultClient, Port: port}
+	err := migration.MigratePod(host, api.PodMigration{PodID: "foo", Target: "machine"})
+	expectNoError(t, err)
+	body := `{"podID":"foo","target":"machine"}`
+	fakeHandler.ValidateRequest(t, "/migrate", "POST", &body)
+
+	fakeHandler.StatusCode = 500
+	if err := migration.MigratePod(host, api.PodMigration{PodID: "foo", Target: "machine"}); err == nil {
+		t.Error("Unexpected non-error")
+	}
+}
+
+func TestHTTPAbortMigration(t *testing.T) {
+	fakeHandler := util.FakeHandler{StatusCode: 200}
+	host, port, testServer := makeTestKubelet(t, &fakeHandler)
+	defer testServer.Close()
+	migration := &HTTPPodMigration{Client: http.DefaultClient, Port: port}
+	expectNoError(t, migration.AbortMigration(host, "foo"))
+	body := `{"podID":"foo","target":""}`
+	fakeHandler.ValidateRequest(t, "/abortMigration", "POST", &body)
+}
@@ -115,7 +115,7 @@ func RequestWithBodyData(data []byte, url, method string) (*http.Request, error)
 // Execute a request, adds authentication (if auth != nil), and HTTPS cert ignoring.
 func DoRequest(request *http.Request, auth *client.AuthInfo) (string, error) {
 	if auth != nil {
-		request.SetBasicAuth(auth.User, auth.Password)
+		auth.SetAuth(request)
 	}
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
@@ -86,6 +86,11 @@ func (client *FakeKubeClient) UpdateReplicationController(controller api.Replica
 	return api.ReplicationController{}, nil
 }
 
+func (client *FakeKubeClient) PatchReplicationController(name string, patch []byte) (api.ReplicationController, error) {
+	client.actions = append(client.actions, Action{action: "patch-controller", value: string(patch)})
+	return api.ReplicationController{}, nil
+}
+
 func (client *FakeKubeClient) DeleteReplicationController(controller string) error {
 	client.actions = append(client.actions, Action{action: "delete-controller", value: controller})
 	return nil
@@ -106,11 +111,50 @@ func (client *FakeKubeClient) UpdateService(controller api.Service) (api.Service
 	return api.Service{}, nil
 }
 
+func (client *FakeKubeClient) PatchService(name string, patch []byte) (api.Service, error) {
+	client.actions = append(client.actions, Action{action: "patch-service", value: string(patch)})
+	return api.Service{}, nil
+}
+
 func (client *FakeKubeClient) DeleteService(controller string) error {
 	client.actions = append(client.actions, Action{action: "delete-service", value: controller})
 	return nil
 }
 
+func (client *FakeKubeClient) ListMinions() (api.MinionList, error) {
+	client.actions = append(client.actions, Action{action: "list-minions"})
+	return api.MinionList{}, nil
+}
+
+func (client *FakeKubeClient) GetMinion(name string) (api.Minion, error) {
+	client.actions = append(client.actions, Action{action: "get-minion", value: name})
+	return api.Minion{}, nil
+}
+
+func (client *FakeKubeClient) CreateMinion(minion api.Minion) (api.Minion, error) {
+	client.actions = append(client.actions, Action{action: "create-minion", value: minion})
+	return api.Minion{}, nil
+}
+
+func (client *FakeKubeClient) UpdateMinion(minion api.Minion) (api.Minion, error) {
+	client.actions = append(client.actions, Action{action: "update-minion", value: minion})
+	return api.Minion{}, nil
+}
+
+func (client *FakeKubeClient) PatchMinion(name string, patch []byte) (api.Minion, error) {
+	client.actions = append(client.actions, Action{action: "patch-minion", value: string(patch)})
+	return api.Minion{}, nil
+}
+
+func (client *FakeKubeClient) DeleteMinion(minion string) error {
+	client.actions = append(client.actions, Action{action: "delete-minion", value: minion})
+	return nil
+}
+
+func (client *FakeKubeClient) InNamespace(namespace string) client.ClientInterface {
+	return client
+}
+
 func validateAction(expectedAction, actualAction Action, t *testing.T) {
 	if expectedAction != actualAction {
 		t.Errorf("Unexpected action: %#v, expected: %#v", actualAction, expectedAction)
@@ -16,10 +16,16 @@ limitations under the License.
 
 package cloudprovider
 
+import (
+	"net"
+)
+
 // CloudInterface is an abstract, pluggable interface for cloud providers
 type Interface interface {
 	// TCPLoadBalancer returns a balancer interface, or nil if none is supported.  Returns an error if one occurs.
 	TCPLoadBalancer() (TCPLoadBalancer, error)
+	// Instances returns an instances interface, or nil if none is supported.  Returns an error if one occurs.
+	Instances() (Instances, error)
 }
 
 type TCPLoadBalancer interface {
@@ -29,3 +35,11 @@ type TCPLoadBalancer interface {
 	UpdateTCPLoadBalancer(name, region string, hosts []string) error
 	DeleteTCPLoadBalancer(name, region string) error
 }
+
+// Instances is an abstract interface over the machines a cloud provider knows about.
+type Instances interface {
+	// List lists the names of instances that match 'filter', a regular expression which must match the entire name.
+	List(filter string) ([]string, error)
+	// IPAddress returns the address of the named instance.
+	IPAddress(name string) (net.IP, error)
+}
@@ -82,6 +82,10 @@ func (gce *GCECloud) TCPLoadBalancer() (TCPLoadBalancer, error) {
 	return gce, nil
 }
 
+func (gce *GCECloud) Instances() (Instances, error) {
+	return nil, nil
+}
+
 func makeHostLink(projectID, zone, host string) string {
 	ix := strings.Index(host, ".")
 	if ix != -1 {
@@ -0,0 +1,302 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/libvirt"
+)
+
+// The libvirt URI used to reach the hypervisor on a host.
+const libvirtHostURIFormat = "qemu+ssh://%s/system"
+
+// iptables limits chain names to 28 characters.
+const maxChainNameLength = 28
+
+// CommandRunner runs a command on a remote host. It is an interface for testability.
+type CommandRunner interface {
+	Run(host string, args ...string) ([]byte, error)
+}
+
+// SSHCommandRunner implements CommandRunner using ssh.
+type SSHCommandRunner struct{}
+
+func (SSHCommandRunner) Run(host string, args ...string) ([]byte, error) {
+	out, err := exec.Command("ssh", append([]string{host}, args...)...).CombinedOutput()
+	if err != nil {
+		return out, fmt.Errorf("%s on %s failed: %v: %s", strings.Join(args, " "), host, err, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+// LibvirtCloud is a cloud provider for bare-metal hosts running libvirt.  Each
+// hypervisor host is an instance, and TCP load balancers are implemented with
+// iptables forwarding rules on a single balancer host.
+type LibvirtCloud struct {
+	hosts        map[string]libvirt.Connection
+	balancerHost string
+	runner       CommandRunner
+	lookupIP     func(host string) ([]net.IP, error)
+}
+
+// NewLibvirtCloud creates a provider for 'hosts', which are reached with libvirt over ssh.
+// Load balancers are created on 'balancerHost', or on the first host if it is empty.
+func NewLibvirtCloud(hosts []string, balancerHost string) (*LibvirtCloud, error) {
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no libvirt hosts specified")
+	}
+	if len(balancerHost) == 0 {
+		balancerHost = hosts[0]
+	}
+	conns := map[string]libvirt.Connection{}
+	for _, host := range hosts {
+		conns[host] = libvirt.MakeVirshConnection(fmt.Sprintf(libvirtHostURIFormat, host))
+	}
+	return &LibvirtCloud{
+		hosts:        conns,
+		balancerHost: balancerHost,
+		runner:       SSHCommandRunner{},
+		lookupIP:     net.LookupIP,
+	}, nil
+}
+
+func (l *LibvirtCloud) TCPLoadBalancer() (TCPLoadBalancer, error) {
+	return l, nil
+}
+
+func (l *LibvirtCloud) Instances() (Instances, error) {
+	return l, nil
+}
+
+// List returns the hosts whose name matches 'filter' and whose libvirt daemon is reachable.
+func (l *LibvirtCloud) List(filter string) ([]string, error) {
+	exp, err := regexp.Compile("^(?:" + filter + ")$")
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for name := range l.hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := []string{}
+	for _, name := range names {
+		if !exp.MatchString(name) {
+			continue
+		}
+		if _, err := l.hosts[name].ListDomains(); err != nil {
+			log.Printf("Skipping unreachable libvirt host %s: %v", name, err)
+			continue
+		}
+		result = append(result, name)
+	}
+	return result, nil
+}
+
+// IPAddress returns the address of a host, preferring IPv4.
+func (l *LibvirtCloud) IPAddress(name string) (net.IP, error) {
+	if _, ok := l.hosts[name]; !ok {
+		return nil, fmt.Errorf("unknown libvirt host: %s", name)
+	}
+	if ip := net.ParseIP(name); ip != nil {
+		return ip, nil
+	}
+	ips, err := l.lookupIP(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no addresses for host: %s", name)
+	}
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip, nil
+		}
+	}
+	return ips[0], nil
+}
+
+func (l *LibvirtCloud) iptables(args ...string) ([]byte, error) {
+	return l.runner.Run(l.balancerHost, append([]string{"iptables", "-t", "nat"}, args...)...)
+}
+
+// balancerChain returns the iptables chain of load balancer 'name'. Names that are too long are
+// truncated and end in a hash of the full name, so that they stay distinct.
+func balancerChain(name string) string {
+	chain := "KUBE-LB-" + name
+	if len(chain) > maxChainNameLength {
+		hash := sha1.Sum([]byte(name))
+		suffix := "-" + hex.EncodeToString(hash[:4])
+		chain = chain[:maxChainNameLength-len(suffix)] + suffix
+	}
+	return chain
+}
+
+func preroutingRule(chain string, port int) []string {
+	return []string{"PREROUTING", "-p", "tcp", "--dport", strconv.Itoa(port), "-j", chain}
+}
+
+// Without masquerading, backends would answer clients directly and the connection would break.
+func postroutingRule(port int) []string {
+	return []string{"POSTROUTING", "-p", "tcp", "-m", "conntrack", "--ctstate", "DNAT", "--ctorigdstport", strconv.Itoa(port), "-j", "MASQUERADE"}
+}
+
+// balancerPort finds the port forwarded to 'chain' in the PREROUTING rules of the balancer host.
+// It returns 0 if there is no such rule.
+func (l *LibvirtCloud) balancerPort(chain string) (int, error) {
+	out, err := l.iptables("-S", "PREROUTING")
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		port, target := "", ""
+		for i := 0; i+1 < len(fields); i++ {
+			switch fields[i] {
+			case "--dport":
+				port = fields[i+1]
+			case "-j":
+				target = fields[i+1]
+			}
+		}
+		if target == chain && len(port) > 0 {
+			return strconv.Atoi(port)
+		}
+	}
+	return 0, nil
+}
+
+// setBackends replaces the rules in 'chain' with ones that spread connections evenly over 'hosts'.
+func (l *LibvirtCloud) setBackends(chain string, port int, hosts []string) error {
+	if len(hosts) == 0 {
+		return fmt.Errorf("no hosts for load balancer %s", chain)
+	}
+	var destinations []string
+	for _, host := range hosts {
+		ip, err := l.IPAddress(host)
+		if err != nil {
+			return err
+		}
+		destinations = append(destinations, net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+	}
+	if _, err := l.iptables("-F", chain); err != nil {
+		return err
+	}
+	for i, destination := range destinations {
+		rule := []string{"-A", chain, "-p", "tcp"}
+		// Rule i sees the connections that rules before it didn't take, and takes 1/(n-i) of them.
+		if remaining := len(destinations) - i; remaining > 1 {
+			rule = append(rule, "-m", "statistic", "--mode", "nth", "--every", strconv.Itoa(remaining), "--packet", "0")
+		}
+		rule = append(rule, "-j", "DNAT", "--to-destination", destination)
+		if _, err := l.iptables(rule...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TCPLoadBalancerExists checks whether a load balancer named 'name' exists.  Region is ignored.
+func (l *LibvirtCloud) TCPLoadBalancerExists(name, region string) (bool, error) {
+	port, err := l.balancerPort(balancerChain(name))
+	return port != 0, err
+}
+
+// CreateTCPLoadBalancer forwards 'port' on the balancer host to 'port' on each of 'hosts'.  Region is ignored.
+func (l *LibvirtCloud) CreateTCPLoadBalancer(name, region string, port int, hosts []string) error {
+	chain := balancerChain(name)
+	existing, err := l.balancerPort(chain)
+	if err != nil {
+		return err
+	}
+	if existing != 0 {
+		return fmt.Errorf("load balancer %s already exists", name)
+	}
+	if _, err := l.iptables("-N", chain); err != nil {
+		return err
+	}
+	// Without the PREROUTING rule the balancer doesn't exist, so a failure removes what was
+	// created, and the chain doesn't get in the way of a retry.
+	err = l.setBackends(chain, port, hosts)
+	if err == nil {
+		if _, err = l.iptables(append([]string{"-A"}, postroutingRule(port)...)...); err == nil {
+			if _, err = l.iptables(append([]string{"-A"}, preroutingRule(chain, port)...)...); err != nil {
+				l.cleanup(append([]string{"-D"}, postroutingRule(port)...))
+			}
+		}
+	}
+	if err != nil {
+		l.cleanup([]string{"-F", chain}, []string{"-X", chain})
+	}
+	return err
+}
+
+// cleanup runs iptables 'commands' after a failure, logging the ones that fail.
+func (l *LibvirtCloud) cleanup(commands ...[]string) {
+	for _, command := range commands {
+		if _, err := l.iptables(command...); err != nil {
+			log.Printf("Failed to clean up load balancer rules: %v", err)
+		}
+	}
+}
+
+// UpdateTCPLoadBalancer changes the set of hosts behind a load balancer.  Region is ignored.
+func (l *LibvirtCloud) UpdateTCPLoadBalancer(name, region string, hosts []string) error {
+	chain := balancerChain(name)
+	port, err := l.balancerPort(chain)
+	if err != nil {
+		return err
+	}
+	if port == 0 {
+		return fmt.Errorf("load balancer %s does not exist", name)
+	}
+	return l.setBackends(chain, port, hosts)
+}
+
+// DeleteTCPLoadBalancer removes all of the forwarding rules for a load balancer.  Region is ignored.
+func (l *LibvirtCloud) DeleteTCPLoadBalancer(name, region string) error {
+	chain := balancerChain(name)
+	port, err := l.balancerPort(chain)
+	if err != nil {
+		return err
+	}
+	if port == 0 {
+		return fmt.Errorf("load balancer %s does not exist", name)
+	}
+	commands := [][]string{
+		append([]string{"-D"}, preroutingRule(chain, port)...),
+		append([]string{"-D"}, postroutingRule(port)...),
+		{"-F", chain},
+		{"-X", chain},
+	}
+	for _, command := range commands {
+		if _, err := l.iptables(command...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
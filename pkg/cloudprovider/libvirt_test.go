@@ -0,0 +1,265 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/libvirt"
+)
+
+// fakeRunner records commands and keeps the PREROUTING rules of the balancer host.
+type fakeRunner struct {
+	host       string
+	commands   []string
+	prerouting []string
+	err        error
+}
+
+func (f *fakeRunner) Run(host string, args ...string) ([]byte, error) {
+	f.host = host
+	command := strings.Join(args, " ")
+	f.commands = append(f.commands, command)
+	if f.err != nil {
+		return nil, f.err
+	}
+	switch {
+	case strings.HasPrefix(command, "iptables -t nat -S PREROUTING"):
+		return []byte(strings.Join(append([]string{"-P PREROUTING ACCEPT"}, f.prerouting...), "\n")), nil
+	case strings.HasPrefix(command, "iptables -t nat -A PREROUTING "):
+		f.prerouting = append(f.prerouting, strings.TrimPrefix(command, "iptables -t nat "))
+	case strings.HasPrefix(command, "iptables -t nat -D PREROUTING "):
+		rule := "-A " + strings.TrimPrefix(command, "iptables -t nat -D ")
+		for i := range f.prerouting {
+			if f.prerouting[i] == rule {
+				f.prerouting = append(f.prerouting[:i], f.prerouting[i+1:]...)
+				break
+			}
+		}
+	}
+	return nil, nil
+}
+
+func makeTestLibvirtCloud(hosts ...string) (*LibvirtCloud, map[string]*libvirt.FakeConnection, *fakeRunner) {
+	conns := map[string]libvirt.Connection{}
+	fakes := map[string]*libvirt.FakeConnection{}
+	for _, host := range hosts {
+		fake := libvirt.MakeFakeConnection()
+		conns[host] = fake
+		fakes[host] = fake
+	}
+	runner := &fakeRunner{}
+	cloud := &LibvirtCloud{
+		hosts:        conns,
+		balancerHost: hosts[0],
+		runner:       runner,
+		lookupIP: func(host string) ([]net.IP, error) {
+			switch host {
+			case "host1":
+				return []net.IP{net.ParseIP("fe80::1"), net.ParseIP("10.0.0.1")}, nil
+			case "host2":
+				return []net.IP{net.ParseIP("10.0.0.2")}, nil
+			}
+			return nil, fmt.Errorf("no such host: %s", host)
+		},
+	}
+	return cloud, fakes, runner
+}
+
+func TestNewLibvirtCloud(t *testing.T) {
+	if _, err := NewLibvirtCloud([]string{}, ""); err == nil {
+		t.Errorf("Expected error for no hosts")
+	}
+	cloud, err := NewLibvirtCloud([]string{"host1", "host2"}, "")
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if cloud.balancerHost != "host1" {
+		t.Errorf("Unexpected balancer host: %s", cloud.balancerHost)
+	}
+	conn, ok := cloud.hosts["host2"].(*libvirt.VirshConnection)
+	if !ok || conn.URI != "qemu+ssh://host2/system" {
+		t.Errorf("Unexpected connection: %#v", cloud.hosts["host2"])
+	}
+}
+
+func TestLibvirtCloudList(t *testing.T) {
+	cloud, fakes, _ := makeTestLibvirtCloud("host1", "host2", "other")
+	instances, err := cloud.List("host.*")
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if !reflect.DeepEqual(instances, []string{"host1", "host2"}) {
+		t.Errorf("Unexpected instances: %#v", instances)
+	}
+
+	fakes["host1"].Err = fmt.Errorf("test error")
+	instances, err = cloud.List(".*")
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if !reflect.DeepEqual(instances, []string{"host2", "other"}) {
+		t.Errorf("Unexpected instances: %#v", instances)
+	}
+
+	if _, err := cloud.List("("); err == nil {
+		t.Errorf("Expected error for invalid filter")
+	}
+}
+
+func TestLibvirtCloudIPAddress(t *testing.T) {
+	cloud, _, _ := makeTestLibvirtCloud("host1", "10.0.0.9", "missing")
+	ip, err := cloud.IPAddress("host1")
+	if err != nil || ip.String() != "10.0.0.1" {
+		t.Errorf("Unexpected result: %v %#v", ip, err)
+	}
+	ip, err = cloud.IPAddress("10.0.0.9")
+	if err != nil || ip.String() != "10.0.0.9" {
+		t.Errorf("Unexpected result: %v %#v", ip, err)
+	}
+	if _, err := cloud.IPAddress("missing"); err == nil {
+		t.Errorf("Expected error for unresolvable host")
+	}
+	if _, err := cloud.IPAddress("host2"); err == nil {
+		t.Errorf("Expected error for unknown host")
+	}
+}
+
+func TestLibvirtCloudCreateTCPLoadBalancer(t *testing.T) {
+	cloud, _, runner := makeTestLibvirtCloud("host1", "host2")
+	err := cloud.CreateTCPLoadBalancer("foo", "region", 8080, []string{"host1", "host2"})
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if runner.host != "host1" {
+		t.Errorf("Unexpected balancer host: %s", runner.host)
+	}
+	expected := []string{
+		"iptables -t nat -S PREROUTING",
+		"iptables -t nat -N KUBE-LB-foo",
+		"iptables -t nat -F KUBE-LB-foo",
+		"iptables -t nat -A KUBE-LB-foo -p tcp -m statistic --mode nth --every 2 --packet 0 -j DNAT --to-destination 10.0.0.1:8080",
+		"iptables -t nat -A KUBE-LB-foo -p tcp -j DNAT --to-destination 10.0.0.2:8080",
+		"iptables -t nat -A POSTROUTING -p tcp -m conntrack --ctstate DNAT --ctorigdstport 8080 -j MASQUERADE",
+		"iptables -t nat -A PREROUTING -p tcp --dport 8080 -j KUBE-LB-foo",
+	}
+	if !reflect.DeepEqual(runner.commands, expected) {
+		t.Errorf("Unexpected commands: %#v", runner.commands)
+	}
+
+	exists, err := cloud.TCPLoadBalancerExists("foo", "region")
+	if err != nil || !exists {
+		t.Errorf("Unexpected result: %v %#v", exists, err)
+	}
+	if err := cloud.CreateTCPLoadBalancer("foo", "region", 8080, []string{"host1"}); err == nil {
+		t.Errorf("Expected error for existing load balancer")
+	}
+}
+
+func TestLibvirtCloudCreateTCPLoadBalancerFailure(t *testing.T) {
+	cloud, _, runner := makeTestLibvirtCloud("host1", "host2")
+	if err := cloud.CreateTCPLoadBalancer("foo", "region", 8080, []string{"host1", "missing"}); err == nil {
+		t.Errorf("Unexpected non-error")
+	}
+	expected := []string{
+		"iptables -t nat -S PREROUTING",
+		"iptables -t nat -N KUBE-LB-foo",
+		"iptables -t nat -F KUBE-LB-foo",
+		"iptables -t nat -X KUBE-LB-foo",
+	}
+	if !reflect.DeepEqual(runner.commands, expected) {
+		t.Errorf("Unexpected commands: %#v", runner.commands)
+	}
+}
+
+func TestLibvirtCloudTCPLoadBalancerExists(t *testing.T) {
+	cloud, _, runner := makeTestLibvirtCloud("host1")
+	exists, err := cloud.TCPLoadBalancerExists("foo", "region")
+	if err != nil || exists {
+		t.Errorf("Unexpected result: %v %#v", exists, err)
+	}
+	runner.err = fmt.Errorf("test error")
+	if _, err := cloud.TCPLoadBalancerExists("foo", "region"); err == nil {
+		t.Errorf("Expected error")
+	}
+}
+
+func TestLibvirtCloudUpdateTCPLoadBalancer(t *testing.T) {
+	cloud, _, runner := makeTestLibvirtCloud("host1", "host2")
+	if err := cloud.UpdateTCPLoadBalancer("foo", "region", []string{"host2"}); err == nil {
+		t.Errorf("Expected error for missing load balancer")
+	}
+	// iptables -S prints the implicit tcp match.
+	runner.prerouting = []string{"-A PREROUTING -p tcp -m tcp --dport 9000 -j KUBE-LB-foo"}
+	runner.commands = nil
+	if err := cloud.UpdateTCPLoadBalancer("foo", "region", []string{"host2"}); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	expected := []string{
+		"iptables -t nat -S PREROUTING",
+		"iptables -t nat -F KUBE-LB-foo",
+		"iptables -t nat -A KUBE-LB-foo -p tcp -j DNAT --to-destination 10.0.0.2:9000",
+	}
+	if !reflect.DeepEqual(runner.commands, expected) {
+		t.Errorf("Unexpected commands: %#v", runner.commands)
+	}
+}
+
+func TestLibvirtCloudDeleteTCPLoadBalancer(t *testing.T) {
+	cloud, _, runner := makeTestLibvirtCloud("host1")
+	if err := cloud.CreateTCPLoadBalancer("foo", "region", 8080, []string{"host1"}); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	runner.commands = nil
+	if err := cloud.DeleteTCPLoadBalancer("foo", "region"); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	expected := []string{
+		"iptables -t nat -S PREROUTING",
+		"iptables -t nat -D PREROUTING -p tcp --dport 8080 -j KUBE-LB-foo",
+		"iptables -t nat -D POSTROUTING -p tcp -m conntrack --ctstate DNAT --ctorigdstport 8080 -j MASQUERADE",
+		"iptables -t nat -F KUBE-LB-foo",
+		"iptables -t nat -X KUBE-LB-foo",
+	}
+	if !reflect.DeepEqual(runner.commands, expected) {
+		t.Errorf("Unexpected commands: %#v", runner.commands)
+	}
+	exists, err := cloud.TCPLoadBalancerExists("foo", "region")
+	if err != nil || exists {
+		t.Errorf("Unexpected result: %v %#v", exists, err)
+	}
+}
+
+func TestBalancerChain(t *testing.T) {
+	if chain := balancerChain("foo"); chain != "KUBE-LB-foo" {
+		t.Errorf("Unexpected chain: %s", chain)
+	}
+	chain := balancerChain("a-very-long-service-name-indeed")
+	if len(chain) != maxChainNameLength || !strings.HasPrefix(chain, "KUBE-LB-a-very") {
+		t.Errorf("Unexpected chain: %s", chain)
+	}
+	if chain == balancerChain("a-very-long-service-name-too") {
+		t.Errorf("Expected long names with the same prefix to get different chains: %s", chain)
+	}
+	if chain != balancerChain("a-very-long-service-name-indeed") {
+		t.Errorf("Expected the same chain for the same name: %s", chain)
+	}
+}
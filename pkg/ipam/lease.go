@@ -0,0 +1,184 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// LeaseStore hands out the addresses of a subnet to owners, e.g. the members of pods.
+// Leases are saved to a file, so they survive restarts.
+type LeaseStore struct {
+	lock   sync.Mutex
+	subnet *net.IPNet
+	file   string
+	// leases maps owners to their IP addresses.
+	leases map[string]string
+}
+
+// leaseFile is the on disk format of a LeaseStore.
+type leaseFile struct {
+	Subnet string            `json:"subnet"`
+	Leases map[string]string `json:"leases"`
+}
+
+// MakeLeaseStore returns a store for the addresses of 'subnet', loading any leases saved
+// in 'file'. Saved leases that are outside 'subnet' are dropped. If 'file' is empty,
+// leases are only kept in memory.
+func MakeLeaseStore(subnet *net.IPNet, file string) (*LeaseStore, error) {
+	ones, bits := subnet.Mask.Size()
+	if bits != 32 || ones > 30 {
+		return nil, fmt.Errorf("subnet %s has no room for leases", subnet)
+	}
+	store := &LeaseStore{
+		subnet: &net.IPNet{IP: subnet.IP.Mask(subnet.Mask), Mask: subnet.Mask},
+		file:   file,
+		leases: map[string]string{},
+	}
+	if len(file) == 0 {
+		return store, nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved leaseFile
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+	for owner, value := range saved.Leases {
+		ip := net.ParseIP(value)
+		if ip == nil || !store.available(ip) {
+			log.Printf("Dropping lease of %s for %s outside of %s", value, owner, store.subnet)
+			continue
+		}
+		store.leases[owner] = ip.String()
+	}
+	return store, nil
+}
+
+// Subnet returns the subnet leases are made from.
+func (l *LeaseStore) Subnet() *net.IPNet {
+	return l.subnet
+}
+
+// available returns whether 'ip' is a host address of the subnet that can be leased.
+// The network, gateway and broadcast addresses are reserved.
+func (l *LeaseStore) available(ip net.IP) bool {
+	if !l.subnet.Contains(ip) {
+		return false
+	}
+	ones, _ := l.subnet.Mask.Size()
+	offset := ipToUint32(ip) - ipToUint32(l.subnet.IP)
+	return offset > 1 && offset < (uint32(1)<<uint(32-ones))-1
+}
+
+// Allocate returns the address leased to 'owner', leasing a free one if it has none.
+func (l *LeaseStore) Allocate(owner string) (net.IP, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if value, ok := l.leases[owner]; ok {
+		return net.ParseIP(value).To4(), nil
+	}
+	used := map[string]bool{}
+	for _, value := range l.leases {
+		used[value] = true
+	}
+	ones, _ := l.subnet.Mask.Size()
+	base := ipToUint32(l.subnet.IP)
+	last := base + (uint32(1) << uint(32-ones)) - 1
+	for value := base + 2; value < last; value++ {
+		ip := uint32ToIP(value)
+		if used[ip.String()] {
+			continue
+		}
+		l.leases[owner] = ip.String()
+		if err := l.save(); err != nil {
+			delete(l.leases, owner)
+			return nil, err
+		}
+		return ip, nil
+	}
+	return nil, fmt.Errorf("no free addresses left in %s", l.subnet)
+}
+
+// Release frees the address leased to 'owner', if any.
+func (l *LeaseStore) Release(owner string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	value, ok := l.leases[owner]
+	if !ok {
+		return nil
+	}
+	delete(l.leases, owner)
+	if err := l.save(); err != nil {
+		l.leases[owner] = value
+		return err
+	}
+	return nil
+}
+
+// Owners returns the owners of all leases.
+func (l *LeaseStore) Owners() []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	owners := []string{}
+	for owner := range l.leases {
+		owners = append(owners, owner)
+	}
+	return owners
+}
+
+// Lookup returns the address leased to 'owner', or nil if it has none.
+func (l *LeaseStore) Lookup(owner string) net.IP {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	value, ok := l.leases[owner]
+	if !ok {
+		return nil
+	}
+	return net.ParseIP(value).To4()
+}
+
+// save atomically replaces the lease file with the current leases.
+func (l *LeaseStore) save() error {
+	if len(l.file) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(leaseFile{Subnet: l.subnet.String(), Leases: l.leases})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.file), 0755); err != nil {
+		return err
+	}
+	tmp := l.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.file)
+}
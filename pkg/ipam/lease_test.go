@@ -0,0 +1,98 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestLeaseStoreAllocate(t *testing.T) {
+	store, err := MakeLeaseStore(parseCIDR(t, "10.244.1.0/24"), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	ip, err := store.Allocate("foo")
+	if err != nil || ip.String() != "10.244.1.2" {
+		t.Errorf("Unexpected lease: %s %#v", ip, err)
+	}
+	again, _ := store.Allocate("foo")
+	if !again.Equal(ip) {
+		t.Errorf("Expected the same lease, got %s", again)
+	}
+	other, _ := store.Allocate("bar")
+	if other.String() != "10.244.1.3" {
+		t.Errorf("Unexpected lease: %s", other)
+	}
+	if owners := store.Owners(); len(owners) != 2 {
+		t.Errorf("Unexpected owners: %#v", owners)
+	}
+	if err := store.Release("foo"); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if ip := store.Lookup("foo"); ip != nil {
+		t.Errorf("Unexpected lease after release: %s", ip)
+	}
+	reused, _ := store.Allocate("baz")
+	if reused.String() != "10.244.1.2" {
+		t.Errorf("Unexpected lease: %s", reused)
+	}
+}
+
+func TestLeaseStoreExhausted(t *testing.T) {
+	store, _ := MakeLeaseStore(parseCIDR(t, "10.244.1.0/30"), "")
+	ip, err := store.Allocate("foo")
+	if err != nil || ip.String() != "10.244.1.2" {
+		t.Errorf("Unexpected lease: %s %#v", ip, err)
+	}
+	if _, err := store.Allocate("bar"); err == nil {
+		t.Error("Unexpected non-error")
+	}
+}
+
+func TestLeaseStorePersists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ipam")
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := path.Join(dir, "leases", "pods.json")
+	store, _ := MakeLeaseStore(parseCIDR(t, "10.244.1.0/24"), file)
+	store.Allocate("foo")
+	bar, _ := store.Allocate("bar")
+
+	restarted, err := MakeLeaseStore(parseCIDR(t, "10.244.1.0/24"), file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	if ip := restarted.Lookup("bar"); !ip.Equal(bar) {
+		t.Errorf("Expected %s, got %s", bar, ip)
+	}
+	if ip, _ := restarted.Allocate("baz"); ip.String() != "10.244.1.4" {
+		t.Errorf("Unexpected lease: %s", ip)
+	}
+
+	moved, err := MakeLeaseStore(parseCIDR(t, "10.244.2.0/24"), file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	if ip := moved.Lookup("bar"); ip != nil {
+		t.Errorf("Unexpected lease outside of the subnet: %s", ip)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"fmt"
+	"net"
+)
+
+// SubnetAllocator carves fixed size subnets out of a cluster network.
+type SubnetAllocator struct {
+	network   *net.IPNet
+	prefixLen int
+}
+
+// MakeSubnetAllocator returns an allocator of /prefixLen subnets of 'network'.
+func MakeSubnetAllocator(network *net.IPNet, prefixLen int) (*SubnetAllocator, error) {
+	ones, bits := network.Mask.Size()
+	if bits != 32 || network.IP.To4() == nil {
+		return nil, fmt.Errorf("only IPv4 networks are supported: %s", network)
+	}
+	if prefixLen < ones || prefixLen > 30 {
+		return nil, fmt.Errorf("invalid subnet prefix length %d for network %s", prefixLen, network)
+	}
+	return &SubnetAllocator{
+		network:   network,
+		prefixLen: prefixLen,
+	}, nil
+}
+
+// Allocate returns the first subnet that doesn't overlap any of the CIDRs in 'used'.
+// Entries of 'used' that don't parse are ignored.
+func (s *SubnetAllocator) Allocate(used []string) (string, error) {
+	var usedNets []*net.IPNet
+	for _, cidr := range used {
+		_, usedNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		usedNets = append(usedNets, usedNet)
+	}
+	ones, _ := s.network.Mask.Size()
+	base := ipToUint32(s.network.IP)
+	size := uint32(1) << uint(32-s.prefixLen)
+	count := uint32(1) << uint(s.prefixLen-ones)
+	for i := uint32(0); i < count; i++ {
+		subnet := &net.IPNet{
+			IP:   uint32ToIP(base + i*size),
+			Mask: net.CIDRMask(s.prefixLen, 32),
+		}
+		if !overlapsAny(subnet, usedNets) {
+			return subnet.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no free subnets left in %s", s.network)
+}
+
+// Overlaps returns whether the CIDRs 'a' and 'b' share any addresses. CIDRs that don't parse
+// overlap nothing.
+func Overlaps(a, b string) bool {
+	_, aNet, err := net.ParseCIDR(a)
+	if err != nil {
+		return false
+	}
+	_, bNet, err := net.ParseCIDR(b)
+	if err != nil {
+		return false
+	}
+	return overlapsAny(aNet, []*net.IPNet{bNet})
+}
+
+func overlapsAny(subnet *net.IPNet, nets []*net.IPNet) bool {
+	for _, other := range nets {
+		if subnet.Contains(other.IP) || other.Contains(subnet.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// GatewayIP returns the first host address of 'subnet', which is reserved for the minion.
+func GatewayIP(subnet *net.IPNet) net.IP {
+	return uint32ToIP(ipToUint32(subnet.IP.Mask(subnet.Mask)) + 1)
+}
+
+func ipToUint32(ip net.IP) uint32 {
+	ip = ip.To4()
+	return uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3])
+}
+
+func uint32ToIP(value uint32) net.IP {
+	return net.IPv4(byte(value>>24), byte(value>>16), byte(value>>8), byte(value)).To4()
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"net"
+	"testing"
+)
+
+func parseCIDR(t *testing.T, cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	return network
+}
+
+func TestSubnetAllocatorSkipsUsed(t *testing.T) {
+	allocator, err := MakeSubnetAllocator(parseCIDR(t, "10.244.0.0/16"), 24)
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	subnet, err := allocator.Allocate([]string{"10.244.0.0/24", "", "junk", "10.244.2.0/24"})
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if subnet != "10.244.1.0/24" {
+		t.Errorf("Unexpected subnet: %s", subnet)
+	}
+}
+
+func TestSubnetAllocatorOverlap(t *testing.T) {
+	allocator, _ := MakeSubnetAllocator(parseCIDR(t, "10.0.0.0/22"), 24)
+	subnet, err := allocator.Allocate([]string{"10.0.0.0/23", "10.0.2.128/25"})
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if subnet != "10.0.3.0/24" {
+		t.Errorf("Unexpected subnet: %s", subnet)
+	}
+}
+
+func TestSubnetAllocatorFull(t *testing.T) {
+	allocator, _ := MakeSubnetAllocator(parseCIDR(t, "10.0.0.0/23"), 24)
+	_, err := allocator.Allocate([]string{"10.0.0.0/24", "10.0.1.0/24"})
+	if err == nil {
+		t.Error("Unexpected non-error")
+	}
+}
+
+func TestMakeSubnetAllocatorInvalid(t *testing.T) {
+	if _, err := MakeSubnetAllocator(parseCIDR(t, "10.0.0.0/24"), 16); err == nil {
+		t.Error("Unexpected non-error for a prefix shorter than the network")
+	}
+	if _, err := MakeSubnetAllocator(parseCIDR(t, "fd00::/64"), 80); err == nil {
+		t.Error("Unexpected non-error for an IPv6 network")
+	}
+}
+
+func TestGatewayIP(t *testing.T) {
+	if ip := GatewayIP(parseCIDR(t, "10.244.3.0/24")); ip.String() != "10.244.3.1" {
+		t.Errorf("Unexpected gateway: %s", ip)
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	table := []struct {
+		a, b     string
+		overlaps bool
+	}{
+		{"10.0.0.0/24", "10.0.0.0/24", true},
+		{"10.0.0.0/16", "10.0.3.0/24", true},
+		{"10.0.3.0/24", "10.0.0.0/16", true},
+		{"10.0.0.0/24", "10.0.1.0/24", false},
+		{"10.0.0.0/24", "", false},
+	}
+	for _, item := range table {
+		if Overlaps(item.a, item.b) != item.overlaps {
+			t.Errorf("Unexpected result for %s and %s, expected %v", item.a, item.b, item.overlaps)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"gopkg.in/v1/yaml"
+)
+
+// The volume label cloud-init looks for to find a NoCloud data source.
+const configDriveLabel = "cidata"
+
+// The file in the guest that holds the environment variables of the container.
+const guestEnvironmentFile = "/etc/kubernetes/environment"
+
+var envVarName = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*$")
+
+// ConfigDriveWriter builds the ISO images that VMs read their cloud-init configuration from.
+// It is an interface for testability.
+type ConfigDriveWriter interface {
+	// WriteConfigDrive writes an image of the files in 'dir', labelled 'label', to 'path'.
+	WriteConfigDrive(dir, label, path string) error
+}
+
+// GenisoimageConfigDriveWriter builds ISO images with genisoimage.
+type GenisoimageConfigDriveWriter struct{}
+
+func (GenisoimageConfigDriveWriter) WriteConfigDrive(dir, label, path string) error {
+	cmd := exec.Command("genisoimage", "-output", path, "-volid", label, "-joliet", "-rock", dir)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("genisoimage failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// cloudConfig is the part of the #cloud-config user data format used by the kubelet.
+// See http://cloudinit.readthedocs.org/en/latest/topics/examples.html
+type cloudConfig struct {
+	WriteFiles []cloudConfigFile `yaml:"write_files,omitempty"`
+	RunCmd     [][]string        `yaml:"runcmd,omitempty"`
+}
+
+type cloudConfigFile struct {
+	Path        string `yaml:"path"`
+	Permissions string `yaml:"permissions"`
+	Content     string `yaml:"content"`
+}
+
+// shellQuote quotes 'value' for a POSIX shell.
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+// makeGuestEnvironment renders the environment variables of 'container' as a file that a shell
+// can source. The manifests from the master already include the service environment variables.
+func makeGuestEnvironment(container *api.Container) string {
+	var buf bytes.Buffer
+	for _, env := range container.Env {
+		if !envVarName.MatchString(env.Name) {
+			log.Printf("Invalid environment variable name %q in %s, skipping.", env.Name, container.Name)
+			continue
+		}
+		fmt.Fprintf(&buf, "%s=%s\n", env.Name, shellQuote(env.Value))
+	}
+	return buf.String()
+}
+
+// makeUserData renders the cloud-init user data for 'container'. Like a Docker container, the
+// guest gets the container's environment, and runs its command in its working directory, once.
+func makeUserData(container *api.Container) ([]byte, error) {
+	config := cloudConfig{
+		WriteFiles: []cloudConfigFile{
+			{
+				Path:        guestEnvironmentFile,
+				Permissions: "0644",
+				Content:     makeGuestEnvironment(container),
+			},
+		},
+	}
+	if len(container.Command) > 0 {
+		script := "set -a; . " + guestEnvironmentFile + "; set +a; "
+		if len(container.WorkingDir) > 0 {
+			script += "cd " + shellQuote(container.WorkingDir) + " && "
+		}
+		script += "exec " + container.Command
+		config.RunCmd = [][]string{{"sh", "-c", script}}
+	}
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("#cloud-config\n"), data...), nil
+}
+
+// makeCloudInit returns the files of the NoCloud data source for 'container' in the pod described
+// by 'manifest', by name. The instance id changes with the user data, so that cloud-init applies
+// a changed manifest when the guest next boots.
+func makeCloudInit(manifest *api.ContainerManifest, container *api.Container) (map[string][]byte, error) {
+	userData, err := makeUserData(container)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha1.Sum(userData)
+	metaData := fmt.Sprintf("instance-id: %s-%s\nlocal-hostname: %s\n",
+		podNetworkKey(manifest.Id, container.Name), hex.EncodeToString(hash[:4]), manifest.Id)
+	return map[string][]byte{
+		"user-data": userData,
+		"meta-data": []byte(metaData),
+	}, nil
+}
+
+// sameFiles returns whether 'dir' holds exactly 'files'.
+func sameFiles(dir string, files map[string][]byte) bool {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil || len(infos) != len(files) {
+		return false
+	}
+	for name, data := range files {
+		existing, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil || !bytes.Equal(existing, data) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeConfigDrive makes the config drive at 'path' hold 'files'. The files are also kept in
+// 'dir', and the drive is only rebuilt if they changed. It returns whether it was rebuilt.
+func writeConfigDrive(writer ConfigDriveWriter, dir, path string, files map[string][]byte) (bool, error) {
+	if _, err := os.Stat(path); err == nil && sameFiles(dir, files) {
+		return false, nil
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, err
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return false, err
+		}
+	}
+	// The drive is replaced rather than rewritten, so a running VM keeps reading the old
+	// one until it is given the new one.
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+	err := writer.WriteConfigDrive(dir, configDriveLabel, tmp)
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		// Without the files, the next attempt doesn't take the old drive to be up to date.
+		os.RemoveAll(dir)
+		os.Remove(tmp)
+		return false, err
+	}
+	return true, nil
+}
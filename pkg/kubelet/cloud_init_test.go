@@ -0,0 +1,141 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"gopkg.in/v1/yaml"
+)
+
+// FakeConfigDriveWriter writes a listing of the files instead of an ISO image.
+type FakeConfigDriveWriter struct {
+	err error
+}
+
+func (f *FakeConfigDriveWriter) WriteConfigDrive(dir, label, path string) error {
+	if f.err != nil {
+		return f.err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	names := []string{}
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return ioutil.WriteFile(path, []byte(fmt.Sprintf("%s: %s", label, strings.Join(names, " "))), 0644)
+}
+
+func TestMakeUserData(t *testing.T) {
+	container := api.Container{
+		Name:       "bar",
+		Command:    "/bin/server --port 80",
+		WorkingDir: "/srv",
+		Env: []api.EnvVar{
+			{Name: "GREETING", Value: "it's here"},
+			{Name: "SERVICE_HOST", Value: "10.0.0.1"},
+			{Name: "not valid", Value: "skipped"},
+		},
+	}
+	data, err := makeUserData(&container)
+	expectNoError(t, err)
+	if !strings.HasPrefix(string(data), "#cloud-config\n") {
+		t.Errorf("Unexpected user data: %s", string(data))
+	}
+	var config cloudConfig
+	expectNoError(t, yaml.Unmarshal(data, &config))
+	expected := cloudConfig{
+		WriteFiles: []cloudConfigFile{
+			{
+				Path:        "/etc/kubernetes/environment",
+				Permissions: "0644",
+				Content:     "GREETING='it'\\''s here'\nSERVICE_HOST='10.0.0.1'\n",
+			},
+		},
+		RunCmd: [][]string{
+			{"sh", "-c", "set -a; . /etc/kubernetes/environment; set +a; cd '/srv' && exec /bin/server --port 80"},
+		},
+	}
+	if !reflect.DeepEqual(expected, config) {
+		t.Errorf("Expected %#v, got %#v", expected, config)
+	}
+}
+
+func TestMakeCloudInit(t *testing.T) {
+	manifest := api.ContainerManifest{Id: "foo"}
+	container := api.Container{Name: "bar"}
+	files, err := makeCloudInit(&manifest, &container)
+	expectNoError(t, err)
+	metaData := string(files["meta-data"])
+	if !strings.HasPrefix(metaData, "instance-id: foo--bar-") || !strings.HasSuffix(metaData, "\nlocal-hostname: foo\n") {
+		t.Errorf("Unexpected meta data: %s", metaData)
+	}
+	if !strings.HasPrefix(string(files["user-data"]), "#cloud-config\n") {
+		t.Errorf("Unexpected user data: %s", string(files["user-data"]))
+	}
+
+	container.Env = []api.EnvVar{{Name: "FOO", Value: "bar"}}
+	changed, err := makeCloudInit(&manifest, &container)
+	expectNoError(t, err)
+	if string(changed["meta-data"]) == metaData {
+		t.Error("Expected the instance id to change with the user data")
+	}
+}
+
+func TestWriteConfigDrive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cloudinit")
+	expectNoError(t, err)
+	defer os.RemoveAll(dir)
+	dataDir := filepath.Join(dir, "data")
+	iso := filepath.Join(dir, "drive.iso")
+	writer := &FakeConfigDriveWriter{}
+	files := map[string][]byte{"meta-data": []byte("a"), "user-data": []byte("b")}
+
+	changed, err := writeConfigDrive(writer, dataDir, iso, files)
+	expectNoError(t, err)
+	verifyBoolean(t, true, changed)
+	data, err := ioutil.ReadFile(iso)
+	expectNoError(t, err)
+	verifyStringEquals(t, string(data), "cidata: meta-data user-data")
+
+	changed, err = writeConfigDrive(writer, dataDir, iso, files)
+	expectNoError(t, err)
+	verifyBoolean(t, false, changed)
+
+	files["user-data"] = []byte("c")
+	writer.err = fmt.Errorf("test error")
+	_, err = writeConfigDrive(writer, dataDir, iso, files)
+	verifyError(t, err)
+	writer.err = nil
+	changed, err = writeConfigDrive(writer, dataDir, iso, files)
+	expectNoError(t, err)
+	verifyBoolean(t, true, changed)
+	data, err = ioutil.ReadFile(filepath.Join(dataDir, "user-data"))
+	expectNoError(t, err)
+	verifyStringEquals(t, string(data), "c")
+}
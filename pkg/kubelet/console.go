@@ -0,0 +1,99 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// How often a followed console is checked for new output.
+var consolePollInterval = 500 * time.Millisecond
+
+// fileFollower reads a file that is still being written, like 'tail -f'. At the end of the
+// file, reads wait for more data until the follower is closed, or until 'done' reports that
+// the writer has finished.
+type fileFollower struct {
+	file      *os.File
+	done      func() bool
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func followFile(file *os.File, done func() bool) *fileFollower {
+	return &fileFollower{
+		file:   file,
+		done:   done,
+		closed: make(chan struct{}),
+	}
+}
+
+func (f *fileFollower) Read(data []byte) (int, error) {
+	for {
+		n, err := f.file.Read(data)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		if f.done() {
+			// Pick up anything written between the last read and the writer finishing.
+			return f.file.Read(data)
+		}
+		select {
+		case <-f.closed:
+			return 0, io.EOF
+		case <-time.After(consolePollInterval):
+		}
+	}
+}
+
+func (f *fileFollower) Close() error {
+	f.closeOnce.Do(func() { close(f.closed) })
+	return f.file.Close()
+}
+
+// GetPodConsole returns the console output of the member running 'containerName' in pod 'podID'.
+// If 'containerName' is empty, the pod's first member is used. It returns nil if there is no such member.
+func (kl *Kubelet) GetPodConsole(podID, containerName string, follow bool) (io.ReadCloser, error) {
+	consoles, ok := kl.Runtime.(ConsoleRuntime)
+	if !ok {
+		return nil, fmt.Errorf("the runtime doesn't support consoles")
+	}
+	members, err := kl.Runtime.ListPodMembers()
+	if err != nil {
+		return nil, err
+	}
+	var found *PodMember
+	for ix := range members {
+		member := &members[ix]
+		if !inPod(*member, podID) || (len(containerName) > 0 && member.ContainerName != containerName) {
+			continue
+		}
+		// While a stopped member is being replaced there are two, prefer the running one.
+		if found == nil {
+			found = member
+		} else if status, _ := kl.Runtime.PodMemberStatus(member.ID); status == PodMemberRunning {
+			found = member
+		}
+	}
+	if found == nil {
+		return nil, nil
+	}
+	return consoles.PodMemberConsole(found.ID, follow)
+}
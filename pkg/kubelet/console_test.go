@@ -0,0 +1,136 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/libvirt"
+)
+
+func TestFileFollower(t *testing.T) {
+	defer func(interval time.Duration) { consolePollInterval = interval }(consolePollInterval)
+	consolePollInterval = time.Millisecond
+	file, err := ioutil.TempFile("", "console")
+	expectNoError(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString("booting\n")
+
+	var lock sync.Mutex
+	finished := false
+	in, err := os.Open(file.Name())
+	expectNoError(t, err)
+	follower := followFile(in, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return finished
+	})
+	defer follower.Close()
+	output := make(chan []byte)
+	go func() {
+		data, _ := ioutil.ReadAll(follower)
+		output <- data
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	file.WriteString("login: ")
+	file.Close()
+	lock.Lock()
+	finished = true
+	lock.Unlock()
+	select {
+	case data := <-output:
+		verifyStringEquals(t, string(data), "booting\nlogin: ")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Follower didn't finish")
+	}
+}
+
+func TestFileFollowerClose(t *testing.T) {
+	defer func(interval time.Duration) { consolePollInterval = interval }(consolePollInterval)
+	consolePollInterval = time.Millisecond
+	file, err := ioutil.TempFile("", "console")
+	expectNoError(t, err)
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	follower := followFile(file, func() bool { return false })
+	done := make(chan error)
+	go func() {
+		_, err := ioutil.ReadAll(follower)
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	follower.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Follower didn't stop when closed")
+	}
+}
+
+func TestGetPodConsoleLibvirt(t *testing.T) {
+	runtime, conn, cleanup := makeTestLibvirtRuntime(t)
+	defer cleanup()
+	kubelet := Kubelet{
+		Runtime: runtime,
+	}
+	member, err := runtime.StartPodMember(&api.ContainerManifest{Id: "qux"}, &api.Container{Name: "foo", Image: "base"})
+	expectNoError(t, err)
+	expectNoError(t, ioutil.WriteFile(runtime.consolePath(member.ID), []byte("booting\n"), 0644))
+
+	console, err := kubelet.GetPodConsole("qux", "", false)
+	expectNoError(t, err)
+	data, err := ioutil.ReadAll(console)
+	expectNoError(t, err)
+	console.Close()
+	verifyStringEquals(t, string(data), "booting\n")
+
+	// A followed console ends when the domain stops.
+	conn.States[member.ID] = libvirt.DomainShutoff
+	console, err = kubelet.GetPodConsole("qux", "foo", true)
+	expectNoError(t, err)
+	var out bytes.Buffer
+	_, err = out.ReadFrom(console)
+	expectNoError(t, err)
+	verifyStringEquals(t, out.String(), "booting\n")
+
+	console, err = kubelet.GetPodConsole("qux", "other", false)
+	if console != nil || err != nil {
+		t.Errorf("Unexpected console for a missing member: %#v %#v", console, err)
+	}
+
+	expectNoError(t, runtime.StopPodMember(member))
+	if _, err := os.Stat(runtime.consolePath(member.ID)); !os.IsNotExist(err) {
+		t.Errorf("Expected the console log to be removed: %#v", err)
+	}
+}
+
+func TestGetPodConsoleUnsupported(t *testing.T) {
+	kubelet := Kubelet{
+		Runtime: MakeDockerRuntime(&FakeDockerClient{}),
+	}
+	if _, err := kubelet.GetPodConsole("qux", "", false); err == nil {
+		t.Error("Unexpected non-error")
+	}
+}
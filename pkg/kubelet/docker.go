@@ -0,0 +1,242 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/fsouza/go-dockerclient"
+)
+
+// Interface for testability
+type DockerInterface interface {
+	ListContainers(options docker.ListContainersOptions) ([]docker.APIContainers, error)
+	InspectContainer(id string) (*docker.Container, error)
+	CreateContainer(docker.CreateContainerOptions) (*docker.Container, error)
+	StartContainer(id string, hostConfig *docker.HostConfig) error
+	StopContainer(id string, timeout uint) error
+}
+
+// DockerRuntime is a Runtime that runs each member of a pod as a Docker container.
+type DockerRuntime struct {
+	client   DockerInterface
+	pullLock sync.Mutex
+}
+
+// MakeDockerRuntime creates a DockerRuntime that talks to Docker through 'client'.
+func MakeDockerRuntime(client DockerInterface) *DockerRuntime {
+	return &DockerRuntime{
+		client: client,
+	}
+}
+
+// ListPodMembers returns the containers running on this host, including any that were
+// started by hand.
+func (d *DockerRuntime) ListPodMembers() ([]PodMember, error) {
+	result := []PodMember{}
+	containerList, err := d.client.ListContainers(docker.ListContainersOptions{})
+	if err != nil {
+		return result, err
+	}
+	for _, value := range containerList {
+		name := value.Names[0]
+		manifestId, containerName := dockerNameToManifestAndContainer(name)
+		result = append(result, PodMember{
+			ID:            value.ID,
+			Name:          name,
+			PodID:         manifestId,
+			ContainerName: containerName,
+		})
+	}
+	return result, nil
+}
+
+func (d *DockerRuntime) StartPodMember(manifest *api.ContainerManifest, container *api.Container) (PodMember, error) {
+	name := manifestAndContainerToDockerName(manifest, container)
+
+	envVariables := makeEnvironmentVariables(container)
+	volumes, binds := makeVolumesAndBinds(container)
+	exposedPorts, portBindings := makePortsAndBindings(container)
+
+	opts := docker.CreateContainerOptions{
+		Name: name,
+		Config: &docker.Config{
+			Image:        container.Image,
+			ExposedPorts: exposedPorts,
+			Env:          envVariables,
+			Volumes:      volumes,
+			WorkingDir:   container.WorkingDir,
+			Cmd:          makeCommandLine(container),
+		},
+	}
+	dockerContainer, err := d.client.CreateContainer(opts)
+	if err != nil {
+		return PodMember{}, err
+	}
+	member := PodMember{
+		ID: dockerContainer.ID,
+		// For some reason, list gives back names that start with '/'
+		Name:          "/" + name,
+		PodID:         manifest.Id,
+		ContainerName: container.Name,
+	}
+	return member, d.client.StartContainer(dockerContainer.ID, &docker.HostConfig{
+		PortBindings: portBindings,
+		Binds:        binds,
+	})
+}
+
+func (d *DockerRuntime) StopPodMember(member PodMember) error {
+	return d.client.StopContainer(member.ID, 10)
+}
+
+// PullImage runs 'docker pull'. Only one pull runs at a time.
+func (d *DockerRuntime) PullImage(image string) error {
+	d.pullLock.Lock()
+	defer d.pullLock.Unlock()
+	cmd := exec.Command("docker", "pull", image)
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+	return cmd.Wait()
+}
+
+// InspectPodMember returns the *docker.Container for the container with the given id.
+func (d *DockerRuntime) InspectPodMember(id string) (interface{}, error) {
+	return d.client.InspectContainer(id)
+}
+
+func (d *DockerRuntime) PodMemberStatus(id string) (PodMemberStatus, error) {
+	container, err := d.client.InspectContainer(id)
+	if err != nil {
+		return PodMemberUnknown, err
+	}
+	if container == nil {
+		return PodMemberUnknown, nil
+	}
+	if container.State.Running {
+		return PodMemberRunning, nil
+	}
+	return PodMemberStopped, nil
+}
+
+// Converts "-" to "_-_" and "_" to "___" so that we can use "--" to meaningfully separate parts of a docker name.
+func escapeDash(in string) (out string) {
+	out = strings.Replace(in, "_", "___", -1)
+	out = strings.Replace(out, "-", "_-_", -1)
+	return
+}
+
+// Reverses the transformation of escapeDash.
+func unescapeDash(in string) (out string) {
+	out = strings.Replace(in, "_-_", "-", -1)
+	out = strings.Replace(out, "___", "_", -1)
+	return
+}
+
+// Creates a name which can be reversed to identify both manifest id and container name.
+func manifestAndContainerToDockerName(manifest *api.ContainerManifest, container *api.Container) string {
+	// Note, manifest.Id could be blank.
+	return fmt.Sprintf("%s--%s--%x", escapeDash(container.Name), escapeDash(manifest.Id), rand.Uint32())
+}
+
+// Upacks a container name, returning the manifest id and container name we would have used to
+// construct the docker name. If the docker name isn't one we created, we may return empty strings.
+func dockerNameToManifestAndContainer(name string) (manifestId, containerName string) {
+	// For some reason docker appears to be appending '/' to names.
+	// If its there, strip it.
+	if name[0] == '/' {
+		name = name[1:]
+	}
+	parts := strings.Split(name, "--")
+	if len(parts) > 0 {
+		containerName = unescapeDash(parts[0])
+	}
+	if len(parts) > 1 {
+		manifestId = unescapeDash(parts[1])
+	}
+	return
+}
+
+func makeEnvironmentVariables(container *api.Container) []string {
+	var result []string
+	for _, value := range container.Env {
+		result = append(result, fmt.Sprintf("%s=%s", value.Name, value.Value))
+	}
+	return result
+}
+
+func makeVolumesAndBinds(container *api.Container) (map[string]struct{}, []string) {
+	volumes := map[string]struct{}{}
+	binds := []string{}
+	for _, volume := range container.VolumeMounts {
+		volumes[volume.MountPath] = struct{}{}
+		basePath := "/exports/" + volume.Name + ":" + volume.MountPath
+		if volume.ReadOnly {
+			basePath += ":ro"
+		}
+		binds = append(binds, basePath)
+	}
+	return volumes, binds
+}
+
+func makePortsAndBindings(container *api.Container) (map[docker.Port]struct{}, map[docker.Port][]docker.PortBinding) {
+	exposedPorts := map[docker.Port]struct{}{}
+	portBindings := map[docker.Port][]docker.PortBinding{}
+	for _, port := range container.Ports {
+		interiorPort := port.ContainerPort
+		exteriorPort := port.HostPort
+		// Some of this port stuff is under-documented voodoo.
+		// See http://stackoverflow.com/questions/20428302/binding-a-port-to-a-host-interface-using-the-rest-api
+		var protocol string
+		switch port.Protocol {
+		case "udp":
+			protocol = "/udp"
+		case "tcp":
+			protocol = "/tcp"
+		default:
+			if len(port.Protocol) != 0 {
+				log.Printf("Unknown protocol: %s, defaulting to tcp.", port.Protocol)
+			}
+			protocol = "/tcp"
+		}
+		dockerPort := docker.Port(strconv.Itoa(interiorPort) + protocol)
+		exposedPorts[dockerPort] = struct{}{}
+		portBindings[dockerPort] = []docker.PortBinding{
+			{
+				HostPort: strconv.Itoa(exteriorPort),
+			},
+		}
+	}
+	return exposedPorts, portBindings
+}
+
+func makeCommandLine(container *api.Container) []string {
+	var cmdList []string
+	if len(container.Command) > 0 {
+		cmdList = strings.Split(container.Command, " ")
+	}
+	return cmdList
+}
@@ -0,0 +1,276 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/fsouza/go-dockerclient"
+)
+
+func verifyPackUnpack(t *testing.T, manifestId, containerName string) {
+	name := manifestAndContainerToDockerName(
+		&api.ContainerManifest{Id: manifestId},
+		&api.Container{Name: containerName},
+	)
+	returnedManifestId, returnedContainerName := dockerNameToManifestAndContainer(name)
+	if manifestId != returnedManifestId || containerName != returnedContainerName {
+		t.Errorf("For (%s, %s), unpacked (%s, %s)", manifestId, containerName, returnedManifestId, returnedContainerName)
+	}
+}
+
+func TestContainerManifestNaming(t *testing.T) {
+	verifyPackUnpack(t, "manifest1234", "container5678")
+	verifyPackUnpack(t, "manifest--", "container__")
+	verifyPackUnpack(t, "--manifest", "__container")
+	verifyPackUnpack(t, "m___anifest_", "container-_-")
+	verifyPackUnpack(t, "_m___anifest", "-_-container")
+}
+
+func TestMakeCommandLine(t *testing.T) {
+	expected := []string{"echo", "hello", "world"}
+	container := api.Container{
+		Command: strings.Join(expected, " "),
+	}
+	cmdLine := makeCommandLine(&container)
+	if !reflect.DeepEqual(expected, cmdLine) {
+		t.Errorf("Unexpected command line.  Expected %#v, got %#v", expected, cmdLine)
+	}
+}
+
+func TestMakeEnvVariables(t *testing.T) {
+	container := api.Container{
+		Env: []api.EnvVar{
+			{
+				Name:  "foo",
+				Value: "bar",
+			},
+			{
+				Name:  "baz",
+				Value: "blah",
+			},
+		},
+	}
+	vars := makeEnvironmentVariables(&container)
+	if len(vars) != len(container.Env) {
+		t.Errorf("Vars don't match.  Expected: %#v Found: %#v", container.Env, vars)
+	}
+	for ix, env := range container.Env {
+		value := fmt.Sprintf("%s=%s", env.Name, env.Value)
+		if value != vars[ix] {
+			t.Errorf("Unexpected value: %s.  Expected: %s", vars[ix], value)
+		}
+	}
+}
+
+func TestMakeVolumesAndBinds(t *testing.T) {
+	container := api.Container{
+		VolumeMounts: []api.VolumeMount{
+			{
+				MountPath: "/mnt/path",
+				Name:      "disk",
+				ReadOnly:  false,
+			},
+			{
+				MountPath: "/mnt/path2",
+				Name:      "disk2",
+				ReadOnly:  true,
+			},
+		},
+	}
+	volumes, binds := makeVolumesAndBinds(&container)
+	if len(volumes) != len(container.VolumeMounts) ||
+		len(binds) != len(container.VolumeMounts) {
+		t.Errorf("Unexpected volumes and binds: %#v %#v.  Container was: %#v", volumes, binds, container)
+	}
+	for ix, volume := range container.VolumeMounts {
+		expectedBind := "/exports/" + volume.Name + ":" + volume.MountPath
+		if volume.ReadOnly {
+			expectedBind = expectedBind + ":ro"
+		}
+		if binds[ix] != expectedBind {
+			t.Errorf("Unexpected bind.  Expected %s.  Found %s", expectedBind, binds[ix])
+		}
+		if _, ok := volumes[volume.MountPath]; !ok {
+			t.Errorf("Map is missing key: %s. %#v", volume.MountPath, volumes)
+		}
+	}
+}
+
+func TestMakePortsAndBindings(t *testing.T) {
+	container := api.Container{
+		Ports: []api.Port{
+			{
+				ContainerPort: 80,
+				HostPort:      8080,
+			},
+			{
+				ContainerPort: 443,
+				HostPort:      443,
+				Protocol:      "tcp",
+			},
+			{
+				ContainerPort: 444,
+				HostPort:      444,
+				Protocol:      "udp",
+			},
+			{
+				ContainerPort: 445,
+				HostPort:      445,
+				Protocol:      "foobar",
+			},
+		},
+	}
+	exposedPorts, bindings := makePortsAndBindings(&container)
+	if len(container.Ports) != len(exposedPorts) ||
+		len(container.Ports) != len(bindings) {
+		t.Errorf("Unexpected ports and bindings, %#v %#v %#v", container, exposedPorts, bindings)
+	}
+	for key, value := range bindings {
+		switch value[0].HostPort {
+		case "8080":
+			if !reflect.DeepEqual(docker.Port("80/tcp"), key) {
+				t.Errorf("Unexpected docker port: %#v", key)
+			}
+		case "443":
+			if !reflect.DeepEqual(docker.Port("443/tcp"), key) {
+				t.Errorf("Unexpected docker port: %#v", key)
+			}
+		case "444":
+			if !reflect.DeepEqual(docker.Port("444/udp"), key) {
+				t.Errorf("Unexpected docker port: %#v", key)
+			}
+		case "445":
+			if !reflect.DeepEqual(docker.Port("445/tcp"), key) {
+				t.Errorf("Unexpected docker port: %#v", key)
+			}
+		}
+	}
+
+}
+
+func TestDockerRuntimeListPodMembers(t *testing.T) {
+	fakeDocker := FakeDockerClient{
+		err: nil,
+	}
+	runtime := MakeDockerRuntime(&fakeDocker)
+	fakeDocker.containerList = []docker.APIContainers{
+		{
+			Names: []string{"/foo--qux--1234"},
+			ID:    "1234",
+		},
+		{
+			Names: []string{"/bar--qux--1234"},
+			ID:    "4567",
+		},
+		{
+			// Started by hand, but still listed.
+			Names: []string{"/manual"},
+			ID:    "8910",
+		},
+	}
+
+	members, err := runtime.ListPodMembers()
+	verifyNoError(t, err)
+	verifyCalls(t, fakeDocker, []string{"list"})
+	expected := []PodMember{
+		{ID: "1234", Name: "/foo--qux--1234", PodID: "qux", ContainerName: "foo"},
+		{ID: "4567", Name: "/bar--qux--1234", PodID: "qux", ContainerName: "bar"},
+		{ID: "8910", Name: "/manual", ContainerName: "manual"},
+	}
+	if !reflect.DeepEqual(expected, members) {
+		t.Errorf("Expected %#v, got %#v", expected, members)
+	}
+}
+
+func TestDockerRuntimeListPodMembersWithError(t *testing.T) {
+	fakeDocker := FakeDockerClient{
+		err: fmt.Errorf("sample error"),
+	}
+	runtime := MakeDockerRuntime(&fakeDocker)
+	_, err := runtime.ListPodMembers()
+	verifyError(t, err)
+}
+
+func TestDockerRuntimeStartPodMember(t *testing.T) {
+	fakeDocker := FakeDockerClient{
+		err: nil,
+	}
+	runtime := MakeDockerRuntime(&fakeDocker)
+	manifest := api.ContainerManifest{Id: "qux"}
+	container := api.Container{Name: "foo"}
+
+	member, err := runtime.StartPodMember(&manifest, &container)
+	verifyNoError(t, err)
+	verifyCalls(t, fakeDocker, []string{"create", "start"})
+	if len(fakeDocker.created) != 1 || !strings.HasPrefix(fakeDocker.created[0], "foo--qux--") {
+		t.Errorf("Unexpected container created: %#v", fakeDocker.created)
+	}
+	if member.ID != fakeDocker.created[0] || member.Name != "/"+fakeDocker.created[0] ||
+		member.PodID != "qux" || member.ContainerName != "foo" {
+		t.Errorf("Unexpected member: %#v", member)
+	}
+}
+
+func TestDockerRuntimeStopPodMember(t *testing.T) {
+	fakeDocker := FakeDockerClient{
+		err: nil,
+	}
+	runtime := MakeDockerRuntime(&fakeDocker)
+	err := runtime.StopPodMember(PodMember{ID: "foobar", Name: "/foo--qux--1234"})
+	verifyNoError(t, err)
+	verifyCalls(t, fakeDocker, []string{"stop"})
+	verifyStringEquals(t, fakeDocker.stopped, "foobar")
+}
+
+func TestDockerRuntimePodMemberStatus(t *testing.T) {
+	fakeDocker := FakeDockerClient{
+		err: nil,
+	}
+	runtime := MakeDockerRuntime(&fakeDocker)
+
+	fakeDocker.container = &docker.Container{
+		ID:    "foobar",
+		State: docker.State{Running: true},
+	}
+	status, err := runtime.PodMemberStatus("foobar")
+	verifyNoError(t, err)
+	verifyCalls(t, fakeDocker, []string{"inspect"})
+	if status != PodMemberRunning {
+		t.Errorf("Expected %s, got %s", PodMemberRunning, status)
+	}
+
+	fakeDocker.container = &docker.Container{
+		ID: "foobar",
+	}
+	status, err = runtime.PodMemberStatus("foobar")
+	verifyNoError(t, err)
+	if status != PodMemberStopped {
+		t.Errorf("Expected %s, got %s", PodMemberStopped, status)
+	}
+
+	fakeDocker.container = nil
+	status, err = runtime.PodMemberStatus("foobar")
+	verifyNoError(t, err)
+	if status != PodMemberUnknown {
+		t.Errorf("Expected %s, got %s", PodMemberUnknown, status)
+	}
+}
@@ -0,0 +1,338 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// The directory in an image store that holds references to base images.
+const imageRefsDir = ".refs"
+
+// qcow2 images start with "QFI\xfb".
+var qcow2Magic = []byte{'Q', 'F', 'I', 0xfb}
+
+// ImageFetcher downloads VM disk images. It is an interface for testability.
+type ImageFetcher interface {
+	Fetch(image string, out io.Writer) error
+}
+
+// HTTPImageFetcher fetches images that are http(s) URLs directly, and other images
+// relative to BaseURL.
+type HTTPImageFetcher struct {
+	Client  *http.Client
+	BaseURL string
+}
+
+func (h *HTTPImageFetcher) Fetch(image string, out io.Writer) error {
+	url := image
+	if !isImageURL(image) {
+		if len(h.BaseURL) == 0 {
+			return fmt.Errorf("image %s is not cached and no image server is configured", image)
+		}
+		url = strings.TrimRight(h.BaseURL, "/") + "/" + image
+	}
+	response, err := h.Client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("error fetching %s: %s", url, response.Status)
+	}
+	_, err = io.Copy(out, response.Body)
+	return err
+}
+
+// OverlayCreator creates copy-on-write disks on top of base images. It is an interface for testability.
+type OverlayCreator interface {
+	CreateOverlay(base, baseFormat, overlay string) error
+}
+
+// QEMUImgOverlayCreator creates qcow2 overlays with qemu-img.
+type QEMUImgOverlayCreator struct{}
+
+func (QEMUImgOverlayCreator) CreateOverlay(base, baseFormat, overlay string) error {
+	cmd := exec.Command("qemu-img", "create", "-f", "qcow2", "-b", base, "-F", baseFormat, overlay)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("qemu-img failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// ImageStore is a per-host cache of VM base images. Each image is fetched once and shared
+// by the pod members that use it through copy-on-write overlays. References from overlays
+// are kept on disk, so they survive kubelet restarts, and base images without references
+// are removed by GarbageCollect.
+type ImageStore struct {
+	// lock guards the references, the base images and the fields below. It isn't held while
+	// an image is fetched, which can take long.
+	lock sync.Mutex
+	// pulls serializes the pulls of each image, by key.
+	pulls map[string]*sync.Mutex
+	// fetching holds the temporary files of the fetches in progress.
+	fetching map[string]bool
+	dir      string
+	fetcher  ImageFetcher
+	overlays OverlayCreator
+}
+
+// MakeImageStore creates an image store that keeps base images in 'dir'.
+func MakeImageStore(dir string, fetcher ImageFetcher, overlays OverlayCreator) *ImageStore {
+	return &ImageStore{
+		pulls:    map[string]*sync.Mutex{},
+		fetching: map[string]bool{},
+		dir:      dir,
+		fetcher:  fetcher,
+		overlays: overlays,
+	}
+}
+
+func isImageURL(image string) bool {
+	return strings.HasPrefix(image, "http://") || strings.HasPrefix(image, "https://")
+}
+
+// imageKey returns the file name an image is cached under. Plain image names are used as they
+// are, so images can also be put in the store by hand. URLs are named by their hash.
+func imageKey(image string) (string, error) {
+	if isImageURL(image) {
+		hash := sha1.Sum([]byte(image))
+		return hex.EncodeToString(hash[:8]) + "-" + path.Base(image), nil
+	}
+	if len(image) == 0 || strings.Contains(image, "/") || strings.HasPrefix(image, ".") {
+		return "", fmt.Errorf("invalid image name: %q", image)
+	}
+	return image, nil
+}
+
+func (s *ImageStore) basePath(key string) string {
+	return filepath.Join(s.dir, key)
+}
+
+func (s *ImageStore) refsPath(key string) string {
+	return filepath.Join(s.dir, imageRefsDir, key)
+}
+
+// Pull fetches 'image' into the store, unless it is already there.
+func (s *ImageStore) Pull(image string) error {
+	key, err := imageKey(image)
+	if err != nil {
+		return err
+	}
+	return s.pull(image, key)
+}
+
+// pullLock returns the lock that serializes the pulls of 'key'.
+func (s *ImageStore) pullLock(key string) *sync.Mutex {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	lock, found := s.pulls[key]
+	if !found {
+		lock = &sync.Mutex{}
+		s.pulls[key] = lock
+	}
+	return lock
+}
+
+// pull fetches 'image' under 'key' if it is absent. Callers must not hold the lock.
+func (s *ImageStore) pull(image, key string) error {
+	pullLock := s.pullLock(key)
+	pullLock.Lock()
+	defer pullLock.Unlock()
+	_, err := os.Stat(s.basePath(key))
+	if err == nil || !os.IsNotExist(err) {
+		return err
+	}
+	log.Printf("Fetching image %s", image)
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	// Fetch to a temporary file, so a failed fetch never leaves a partial base image.
+	tmp, err := ioutil.TempFile(s.dir, ".pull-")
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	s.fetching[filepath.Base(tmp.Name())] = true
+	s.lock.Unlock()
+	err = s.fetcher.Fetch(image, tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.fetching, filepath.Base(tmp.Name()))
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.basePath(key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func imageFormat(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	header := make([]byte, len(qcow2Magic))
+	if _, err := io.ReadFull(f, header); err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if bytes.Equal(header, qcow2Magic) {
+		return "qcow2", nil
+	}
+	return "raw", nil
+}
+
+// CreateOverlay creates a copy-on-write disk at 'overlay' backed by 'image', pulling the image
+// if needed, and records that 'owner' references the image until it is released.
+func (s *ImageStore) CreateOverlay(image, overlay, owner string) error {
+	key, err := imageKey(image)
+	if err != nil {
+		return err
+	}
+	// Record the reference first, so the base is never collected while an overlay uses it.
+	if err := s.addReference(key, owner, overlay); err != nil {
+		return err
+	}
+	err = s.pull(image, key)
+	if err == nil {
+		err = s.createOverlay(key, overlay)
+	}
+	if err != nil {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		os.Remove(filepath.Join(s.refsPath(key), owner))
+	}
+	return err
+}
+
+func (s *ImageStore) addReference(key, owner, overlay string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := os.MkdirAll(s.refsPath(key), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(s.refsPath(key), owner), []byte(overlay), 0644)
+}
+
+func (s *ImageStore) createOverlay(key, overlay string) error {
+	base := s.basePath(key)
+	format, err := imageFormat(base)
+	if err != nil {
+		return err
+	}
+	return s.overlays.CreateOverlay(base, format, overlay)
+}
+
+// Release drops the references 'owner' holds on base images.
+func (s *ImageStore) Release(owner string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	keys, err := ioutil.ReadDir(filepath.Join(s.dir, imageRefsDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, key := range keys {
+		err := os.Remove(filepath.Join(s.refsPath(key.Name()), owner))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ImageStore) references(key string) (int, error) {
+	refs, err := ioutil.ReadDir(s.refsPath(key))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	return len(refs), err
+}
+
+// References returns the number of overlays that use 'image'.
+func (s *ImageStore) References(image string) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key, err := imageKey(image)
+	if err != nil {
+		return 0, err
+	}
+	return s.references(key)
+}
+
+// GarbageCollect removes the base images that no overlay references, and returns their names.
+func (s *ImageStore) GarbageCollect() ([]string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	removed := []string{}
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return removed, nil
+		}
+		return removed, err
+	}
+	for _, file := range files {
+		key := file.Name()
+		// Temporary files that no fetch is writing are from interrupted pulls.
+		if strings.HasPrefix(key, ".pull-") {
+			if !s.fetching[key] {
+				os.Remove(filepath.Join(s.dir, key))
+			}
+			continue
+		}
+		if file.IsDir() || strings.HasPrefix(key, ".") {
+			continue
+		}
+		count, err := s.references(key)
+		if err != nil {
+			return removed, err
+		}
+		if count > 0 {
+			continue
+		}
+		log.Printf("Removing unused image %s", key)
+		if err := os.Remove(s.basePath(key)); err != nil {
+			return removed, err
+		}
+		os.Remove(s.refsPath(key))
+		removed = append(removed, key)
+	}
+	return removed, nil
+}
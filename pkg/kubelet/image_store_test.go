@@ -0,0 +1,251 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type FakeImageFetcher struct {
+	images  map[string]string
+	fetched []string
+}
+
+func (f *FakeImageFetcher) Fetch(image string, out io.Writer) error {
+	f.fetched = append(f.fetched, image)
+	data, ok := f.images[image]
+	if !ok {
+		return fmt.Errorf("no such image: %s", image)
+	}
+	_, err := io.WriteString(out, data)
+	return err
+}
+
+// FakeOverlayCreator writes a description of the overlay instead of a qcow2 file.
+type FakeOverlayCreator struct {
+	err error
+}
+
+func (f *FakeOverlayCreator) CreateOverlay(base, baseFormat, overlay string) error {
+	if f.err != nil {
+		return f.err
+	}
+	return ioutil.WriteFile(overlay, []byte(fmt.Sprintf("overlay of %s (%s)", base, baseFormat)), 0644)
+}
+
+func makeTestImageStore(t *testing.T) (*ImageStore, *FakeImageFetcher, *FakeOverlayCreator, string) {
+	dir, err := ioutil.TempDir("", "images")
+	expectNoError(t, err)
+	fetcher := &FakeImageFetcher{
+		images: map[string]string{
+			"raw":                      "raw data",
+			"qcow":                     "QFI\xfbqcow data",
+			"http://images/debian.img": "debian data",
+		},
+	}
+	overlays := &FakeOverlayCreator{}
+	return MakeImageStore(filepath.Join(dir, "store"), fetcher, overlays), fetcher, overlays, dir
+}
+
+func TestImageKey(t *testing.T) {
+	key, err := imageKey("debian")
+	expectNoError(t, err)
+	verifyStringEquals(t, key, "debian")
+	key, err = imageKey("http://images/debian.img")
+	expectNoError(t, err)
+	if !strings.HasSuffix(key, "-debian.img") || strings.Contains(key, "/") {
+		t.Errorf("Unexpected key: %s", key)
+	}
+	for _, image := range []string{"", "../etc/passwd", ".refs", "a/b"} {
+		if _, err := imageKey(image); err == nil {
+			t.Errorf("Expected error for %q", image)
+		}
+	}
+}
+
+func TestImageStorePullIfAbsent(t *testing.T) {
+	store, fetcher, _, dir := makeTestImageStore(t)
+	defer os.RemoveAll(dir)
+	expectNoError(t, store.Pull("raw"))
+	expectNoError(t, store.Pull("raw"))
+	verifyStringArrayEquals(t, fetcher.fetched, []string{"raw"})
+	data, err := ioutil.ReadFile(filepath.Join(store.dir, "raw"))
+	expectNoError(t, err)
+	verifyStringEquals(t, string(data), "raw data")
+}
+
+func TestImageStorePullFailure(t *testing.T) {
+	store, _, _, dir := makeTestImageStore(t)
+	defer os.RemoveAll(dir)
+	if err := store.Pull("missing"); err == nil {
+		t.Error("Unexpected non-error")
+	}
+	// No partial image is left behind.
+	files, err := ioutil.ReadDir(store.dir)
+	expectNoError(t, err)
+	if len(files) != 0 {
+		t.Errorf("Unexpected files: %#v", files)
+	}
+}
+
+func TestImageStoreOverlaysAndGarbageCollection(t *testing.T) {
+	store, fetcher, _, dir := makeTestImageStore(t)
+	defer os.RemoveAll(dir)
+	overlay1 := filepath.Join(dir, "one.qcow2")
+	overlay2 := filepath.Join(dir, "two.qcow2")
+	expectNoError(t, store.CreateOverlay("qcow", overlay1, "one"))
+	expectNoError(t, store.CreateOverlay("qcow", overlay2, "two"))
+	expectNoError(t, store.Pull("raw"))
+	verifyStringArrayEquals(t, fetcher.fetched, []string{"qcow", "raw"})
+
+	data, err := ioutil.ReadFile(overlay1)
+	expectNoError(t, err)
+	verifyStringEquals(t, string(data), "overlay of "+filepath.Join(store.dir, "qcow")+" (qcow2)")
+	refs, err := store.References("qcow")
+	expectNoError(t, err)
+	if refs != 2 {
+		t.Errorf("Unexpected references: %d", refs)
+	}
+
+	// Only the unreferenced image is collected.
+	removed, err := store.GarbageCollect()
+	expectNoError(t, err)
+	if !reflect.DeepEqual(removed, []string{"raw"}) {
+		t.Errorf("Unexpected removed images: %#v", removed)
+	}
+
+	expectNoError(t, store.Release("one"))
+	removed, err = store.GarbageCollect()
+	expectNoError(t, err)
+	if len(removed) != 0 {
+		t.Errorf("Unexpected removed images: %#v", removed)
+	}
+
+	// References are on disk, so a new store for the same directory sees them.
+	store = MakeImageStore(store.dir, fetcher, &FakeOverlayCreator{})
+	expectNoError(t, store.Release("two"))
+	removed, err = store.GarbageCollect()
+	expectNoError(t, err)
+	if !reflect.DeepEqual(removed, []string{"qcow"}) {
+		t.Errorf("Unexpected removed images: %#v", removed)
+	}
+}
+
+// blockingImageFetcher fetches 'image' only once 'unblock' is closed, and passes other images
+// to FakeImageFetcher.
+type blockingImageFetcher struct {
+	*FakeImageFetcher
+	image   string
+	started chan bool
+	unblock chan bool
+}
+
+func (f *blockingImageFetcher) Fetch(image string, out io.Writer) error {
+	if image == f.image {
+		f.started <- true
+		<-f.unblock
+		_, err := io.WriteString(out, "slow data")
+		return err
+	}
+	return f.FakeImageFetcher.Fetch(image, out)
+}
+
+func TestImageStoreFetchDoesNotBlockStore(t *testing.T) {
+	store, fetcher, _, dir := makeTestImageStore(t)
+	defer os.RemoveAll(dir)
+	blocking := &blockingImageFetcher{
+		FakeImageFetcher: fetcher,
+		image:            "slow",
+		started:          make(chan bool),
+		unblock:          make(chan bool),
+	}
+	store.fetcher = blocking
+	done := make(chan error)
+	go func() {
+		done <- store.CreateOverlay("slow", filepath.Join(dir, "slow.qcow2"), "slow")
+	}()
+	<-blocking.started
+
+	// Other images, releases and garbage collection go ahead while "slow" is fetched.
+	expectNoError(t, store.CreateOverlay("raw", filepath.Join(dir, "one.qcow2"), "one"))
+	expectNoError(t, store.Release("one"))
+	removed, err := store.GarbageCollect()
+	expectNoError(t, err)
+	if !reflect.DeepEqual(removed, []string{"raw"}) {
+		t.Errorf("Unexpected removed images: %#v", removed)
+	}
+
+	close(blocking.unblock)
+	expectNoError(t, <-done)
+	refs, err := store.References("slow")
+	expectNoError(t, err)
+	if refs != 1 {
+		t.Errorf("Unexpected references: %d", refs)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(store.dir, "slow"))
+	expectNoError(t, err)
+	verifyStringEquals(t, string(data), "slow data")
+}
+
+func TestImageStoreOverlayFailureDropsReference(t *testing.T) {
+	store, _, overlays, dir := makeTestImageStore(t)
+	defer os.RemoveAll(dir)
+	overlays.err = fmt.Errorf("test error")
+	if err := store.CreateOverlay("raw", filepath.Join(dir, "one.qcow2"), "one"); err == nil {
+		t.Error("Unexpected non-error")
+	}
+	refs, err := store.References("raw")
+	expectNoError(t, err)
+	if refs != 0 {
+		t.Errorf("Unexpected references: %d", refs)
+	}
+}
+
+func TestHTTPImageFetcher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/images/debian.img" {
+			http.NotFound(w, req)
+			return
+		}
+		io.WriteString(w, "debian data")
+	}))
+	defer server.Close()
+	fetcher := &HTTPImageFetcher{Client: http.DefaultClient, BaseURL: server.URL + "/images/"}
+
+	var out bytes.Buffer
+	expectNoError(t, fetcher.Fetch("debian.img", &out))
+	verifyStringEquals(t, out.String(), "debian data")
+	out.Reset()
+	expectNoError(t, fetcher.Fetch(server.URL+"/images/debian.img", &out))
+	verifyStringEquals(t, out.String(), "debian data")
+	if err := fetcher.Fetch("missing.img", &out); err == nil {
+		t.Error("Unexpected non-error")
+	}
+	if err := (&HTTPImageFetcher{Client: http.DefaultClient}).Fetch("debian.img", &out); err == nil {
+		t.Error("Unexpected non-error")
+	}
+}
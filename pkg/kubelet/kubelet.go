@@ -23,55 +23,36 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
-	"math/rand"
 	"net/http"
 	"os"
-	"os/exec"
-	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/registry"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/coreos/go-etcd/etcd"
-	"github.com/fsouza/go-dockerclient"
 	"gopkg.in/v1/yaml"
 )
 
-// State, sub object of the Docker JSON data
-type State struct {
-	Running bool
-}
-
-// The structured representation of the JSON object returned by Docker inspect
-type DockerContainerData struct {
-	state State
-}
-
-// Interface for testability
-type DockerInterface interface {
-	ListContainers(options docker.ListContainersOptions) ([]docker.APIContainers, error)
-	InspectContainer(id string) (*docker.Container, error)
-	CreateContainer(docker.CreateContainerOptions) (*docker.Container, error)
-	StartContainer(id string, hostConfig *docker.HostConfig) error
-	StopContainer(id string, timeout uint) error
-}
-
 // The main kubelet implementation
 type Kubelet struct {
 	Hostname           string
+	HostIP             string
+	Capacity           api.MinionResources
 	Client             registry.EtcdClient
-	DockerClient       DockerInterface
+	Master             MasterInterface
+	Runtime            Runtime
+	PodNetwork         *PodNetwork
 	FileCheckFrequency time.Duration
 	SyncFrequency      time.Duration
 	HTTPCheckFrequency time.Duration
-	pullLock           sync.Mutex
+	HeartbeatFrequency time.Duration
+	migrations         podMigrations
 }
 
 // Starts background goroutines. If file, manifest_url, or address are empty,
-// they are not watched. Never returns.
+// they are not watched. If Master is set, the kubelet registers with it. Never returns.
 func (kl *Kubelet) RunKubelet(file, manifest_url, etcd_servers, address string, port uint) {
 	fileChannel := make(chan api.ContainerManifest)
 	etcdChannel := make(chan []api.ContainerManifest)
@@ -88,18 +69,22 @@ func (kl *Kubelet) RunKubelet(file, manifest_url, etcd_servers, address string,
 		kl.Client = etcd.NewClient(servers)
 		go util.Forever(func() { kl.SyncAndSetupEtcdWatch(etcdChannel) }, 20*time.Second)
 	}
+	if kl.Master != nil {
+		go util.Forever(func() { kl.RunHeartbeats() }, 20*time.Second)
+	}
 	if address != "" {
 		log.Printf("Starting to listen on %s:%d", address, port)
 		handler := KubeletServer{
 			Kubelet:       kl,
 			UpdateChannel: serverChannel,
 		}
+		// There is no write timeout, because followed pod consoles stream for as long as the
+		// pod runs, and migrations take as long as copying the pod's disks and memory.
 		s := &http.Server{
 			// TODO: This is broken if address is an ipv6 address.
 			Addr:           fmt.Sprintf("%s:%d", address, port),
 			Handler:        &handler,
 			ReadTimeout:    10 * time.Second,
-			WriteTimeout:   10 * time.Second,
 			MaxHeaderBytes: 1 << 20,
 		}
 		go util.Forever(func() { s.ListenAndServe() }, 0)
@@ -135,226 +120,54 @@ func (kl *Kubelet) LogEvent(event *api.Event) error {
 	return err
 }
 
-// Does this container exist on this host? Returns true if so, and the name under which the container is running.
-// Returns an error if one occurs.
-func (kl *Kubelet) ContainerExists(manifest *api.ContainerManifest, container *api.Container) (exists bool, foundName string, err error) {
-	containers, err := kl.ListContainers()
-	if err != nil {
-		return false, "", err
-	}
-	for _, name := range containers {
-		manifestId, containerName := dockerNameToManifestAndContainer(name)
-		if manifestId == manifest.Id && containerName == container.Name {
-			// TODO(bburns) : This leads to an extra list.  Convert this to use the returned ID and a straight call
-			// to inspect
-			data, err := kl.GetContainerByName(name)
-			return data != nil, name, err
-		}
-	}
-	return false, "", nil
-}
-
-// GetContainerID looks at the list of containers on the machine and returns the ID of the container whose name
-// matches 'name'.  It returns the name of the container, or empty string, if the container isn't found.
-// it returns true if the container is found, false otherwise, and any error that occurs.
+// GetContainerID looks at the list of pod members on the machine and returns the ID of the member whose pod ID
+// or container name is 'name'.  It returns the ID of the member, or empty string, if the member isn't found.
+// it returns true if the member is found, false otherwise, and any error that occurs.
 func (kl *Kubelet) GetContainerID(name string) (string, bool, error) {
-	containerList, err := kl.DockerClient.ListContainers(docker.ListContainersOptions{})
+	members, err := kl.Runtime.ListPodMembers()
 	if err != nil {
 		return "", false, err
 	}
-	for _, value := range containerList {
-		if strings.Contains(value.Names[0], name) {
-			return value.ID, true, nil
+	for _, member := range members {
+		if inPod(member, name) || member.ContainerName == name {
+			return member.ID, true, nil
 		}
 	}
 	return "", false, nil
 }
 
-// Get a container by name.
-// returns the container data from Docker, or an error if one exists.
-func (kl *Kubelet) GetContainerByName(name string) (*docker.Container, error) {
-	id, found, err := kl.GetContainerID(name)
-	if err != nil {
-		return nil, err
-	}
-	if !found {
-		return nil, nil
-	}
-	return kl.DockerClient.InspectContainer(id)
-}
-
-func (kl *Kubelet) ListContainers() ([]string, error) {
-	result := []string{}
-	containerList, err := kl.DockerClient.ListContainers(docker.ListContainersOptions{})
-	if err != nil {
-		return result, err
-	}
-	for _, value := range containerList {
-		result = append(result, value.Names[0])
-	}
-	return result, err
-}
-
-func (kl *Kubelet) pullImage(image string) error {
-	kl.pullLock.Lock()
-	defer kl.pullLock.Unlock()
-	cmd := exec.Command("docker", "pull", image)
-	err := cmd.Start()
-	if err != nil {
-		return err
-	}
-	return cmd.Wait()
-}
-
-// Converts "-" to "_-_" and "_" to "___" so that we can use "--" to meaningfully separate parts of a docker name.
-func escapeDash(in string) (out string) {
-	out = strings.Replace(in, "_", "___", -1)
-	out = strings.Replace(out, "-", "_-_", -1)
-	return
-}
-
-// Reverses the transformation of escapeDash.
-func unescapeDash(in string) (out string) {
-	out = strings.Replace(in, "_-_", "-", -1)
-	out = strings.Replace(out, "___", "_", -1)
-	return
-}
-
-// Creates a name which can be reversed to identify both manifest id and container name.
-func manifestAndContainerToDockerName(manifest *api.ContainerManifest, container *api.Container) string {
-	// Note, manifest.Id could be blank.
-	return fmt.Sprintf("%s--%s--%x", escapeDash(container.Name), escapeDash(manifest.Id), rand.Uint32())
-}
-
-// Upacks a container name, returning the manifest id and container name we would have used to
-// construct the docker name. If the docker name isn't one we created, we may return empty strings.
-func dockerNameToManifestAndContainer(name string) (manifestId, containerName string) {
-	// For some reason docker appears to be appending '/' to names.
-	// If its there, strip it.
-	if name[0] == '/' {
-		name = name[1:]
-	}
-	parts := strings.Split(name, "--")
-	if len(parts) > 0 {
-		containerName = unescapeDash(parts[0])
-	}
-	if len(parts) > 1 {
-		manifestId = unescapeDash(parts[1])
-	}
-	return
-}
-
-func makeEnvironmentVariables(container *api.Container) []string {
-	var result []string
-	for _, value := range container.Env {
-		result = append(result, fmt.Sprintf("%s=%s", value.Name, value.Value))
+// inPod returns whether 'member' runs in the pod with manifest ID 'manifestID'. Manifest IDs
+// name the namespace of their pod after a dot. Members started before namespaces were added
+// are named by the ID of their pod alone, which is now in the default namespace.
+func inPod(member PodMember, manifestID string) bool {
+	if member.PodID == manifestID {
+		return true
 	}
-	return result
+	return !strings.Contains(member.PodID, ".") && member.PodID+"."+api.NamespaceDefault == manifestID
 }
 
-func makeVolumesAndBinds(container *api.Container) (map[string]struct{}, []string) {
-	volumes := map[string]struct{}{}
-	binds := []string{}
-	for _, volume := range container.VolumeMounts {
-		volumes[volume.MountPath] = struct{}{}
-		basePath := "/exports/" + volume.Name + ":" + volume.MountPath
-		if volume.ReadOnly {
-			basePath += ":ro"
+// findPodMember returns the member in 'members' that runs 'container' from 'manifest', if there is one.
+func findPodMember(members []PodMember, manifest *api.ContainerManifest, container *api.Container) (PodMember, bool) {
+	for _, member := range members {
+		if inPod(member, manifest.Id) && member.ContainerName == container.Name {
+			return member, true
 		}
-		binds = append(binds, basePath)
 	}
-	return volumes, binds
+	return PodMember{}, false
 }
 
-func makePortsAndBindings(container *api.Container) (map[docker.Port]struct{}, map[docker.Port][]docker.PortBinding) {
-	exposedPorts := map[docker.Port]struct{}{}
-	portBindings := map[docker.Port][]docker.PortBinding{}
-	for _, port := range container.Ports {
-		interiorPort := port.ContainerPort
-		exteriorPort := port.HostPort
-		// Some of this port stuff is under-documented voodoo.
-		// See http://stackoverflow.com/questions/20428302/binding-a-port-to-a-host-interface-using-the-rest-api
-		var protocol string
-		switch port.Protocol {
-		case "udp":
-			protocol = "/udp"
-		case "tcp":
-			protocol = "/tcp"
-		default:
-			if len(port.Protocol) != 0 {
-				log.Printf("Unknown protocol: %s, defaulting to tcp.", port.Protocol)
-			}
-			protocol = "/tcp"
-		}
-		dockerPort := docker.Port(strconv.Itoa(interiorPort) + protocol)
-		exposedPorts[dockerPort] = struct{}{}
-		portBindings[dockerPort] = []docker.PortBinding{
-			{
-				HostPort: strconv.Itoa(exteriorPort),
-			},
-		}
-	}
-	return exposedPorts, portBindings
-}
-
-func makeCommandLine(container *api.Container) []string {
-	var cmdList []string
-	if len(container.Command) > 0 {
-		cmdList = strings.Split(container.Command, " ")
-	}
-	return cmdList
-}
-
-func (kl *Kubelet) RunContainer(manifest *api.ContainerManifest, container *api.Container) (name string, err error) {
-	name = manifestAndContainerToDockerName(manifest, container)
-
-	envVariables := makeEnvironmentVariables(container)
-	volumes, binds := makeVolumesAndBinds(container)
-	exposedPorts, portBindings := makePortsAndBindings(container)
-
-	opts := docker.CreateContainerOptions{
-		Name: name,
-		Config: &docker.Config{
-			Image:        container.Image,
-			ExposedPorts: exposedPorts,
-			Env:          envVariables,
-			Volumes:      volumes,
-			WorkingDir:   container.WorkingDir,
-			Cmd:          makeCommandLine(container),
-		},
-	}
-	dockerContainer, err := kl.DockerClient.CreateContainer(opts)
-	if err != nil {
-		return "", err
-	}
-	return name, kl.DockerClient.StartContainer(dockerContainer.ID, &docker.HostConfig{
-		PortBindings: portBindings,
-		Binds:        binds,
-	})
-}
-
-func (kl *Kubelet) KillContainer(name string) error {
-	id, found, err := kl.GetContainerID(name)
-	if err != nil {
-		return err
-	}
-	if !found {
-		// This is weird, but not an error, so yell and then return nil
-		log.Printf("Couldn't find container: %s", name)
-		return nil
-	}
-	err = kl.DockerClient.StopContainer(id, 10)
-	manifestId, containerName := dockerNameToManifestAndContainer(name)
+// Stop a pod member, and log an event recording that it was stopped.
+func (kl *Kubelet) killPodMember(member PodMember) error {
+	err := kl.Runtime.StopPodMember(member)
 	kl.LogEvent(&api.Event{
 		Event: "STOP",
 		Manifest: &api.ContainerManifest{
-			Id: manifestId,
+			Id: member.PodID,
 		},
 		Container: &api.Container{
-			Name: containerName,
+			Name: member.ContainerName,
 		},
 	})
-
 	return err
 }
 
@@ -548,50 +361,59 @@ func (kl *Kubelet) WatchEtcd(watchChannel <-chan *etcd.Response, changeChannel c
 // Sync the configured list of containers (desired state) with the host current state
 func (kl *Kubelet) SyncManifests(config []api.ContainerManifest) error {
 	log.Printf("Desired:%#v", config)
-	var err error
+	kl.syncMigrations(config)
+	existingMembers, err := kl.Runtime.ListPodMembers()
+	if err != nil {
+		return err
+	}
 	desired := map[string]bool{}
 	for _, manifest := range config {
+		if kl.migrations.migrating(manifest.Id) {
+			log.Printf("%s is migrating, skipping", manifest.Id)
+			continue
+		}
 		for _, element := range manifest.Containers {
-			var exists bool
-			exists, actualName, err := kl.ContainerExists(&manifest, &element)
-			if err != nil {
-				log.Printf("Error detecting container: %#v skipping.", err)
-				continue
-			}
-			if !exists {
-				log.Printf("%#v doesn't exist, creating", element)
-				err = kl.pullImage(element.Image)
-				if err != nil {
-					log.Printf("Error pulling container: %#v", err)
+			member, exists := findPodMember(existingMembers, &manifest, &element)
+			if exists {
+				if _, err := kl.Runtime.PodMemberStatus(member.ID); err != nil {
+					log.Printf("Error detecting container: %#v skipping.", err)
 					continue
 				}
-				actualName, err = kl.RunContainer(&manifest, &element)
-				// For some reason, list gives back names that start with '/'
-				actualName = "/" + actualName
-
-				if err != nil {
-					// TODO(bburns) : Perhaps blacklist a container after N failures?
-					log.Printf("Error creating container: %#v", err)
-					desired[actualName] = true
-					continue
+				log.Printf("%#v exists as %v", element.Name, member.Name)
+				desired[member.ID] = true
+				if updater, ok := kl.Runtime.(UpdatingRuntime); ok {
+					if err := updater.UpdatePodMember(member, &manifest, &element); err != nil {
+						log.Printf("Error updating container: %#v", err)
+					}
 				}
-			} else {
-				log.Printf("%#v exists as %v", element.Name, actualName)
+				continue
+			}
+			log.Printf("%#v doesn't exist, creating", element)
+			if err := kl.Runtime.PullImage(element.Image); err != nil {
+				log.Printf("Error pulling container: %#v", err)
+				continue
+			}
+			member, err := kl.Runtime.StartPodMember(&manifest, &element)
+			if err != nil {
+				// TODO(bburns) : Perhaps blacklist a container after N failures?
+				log.Printf("Error creating container: %#v", err)
+				continue
+			}
+			if len(member.ID) > 0 {
+				desired[member.ID] = true
 			}
-			desired[actualName] = true
 		}
 	}
-	existingContainers, _ := kl.ListContainers()
-	log.Printf("Existing:\n%#v Desired: %#v", existingContainers, desired)
-	for _, container := range existingContainers {
-		// This is slightly hacky, but we ignore containers that lack '--' in their name
+	log.Printf("Existing:\n%#v Desired: %#v", existingMembers, desired)
+	for _, member := range existingMembers {
+		// This is slightly hacky, but we ignore members that lack '--' in their name
 		// to allow users to manually spin up their own containers if they want.
-		if !strings.Contains(container, "--") {
+		if !strings.Contains(member.Name, "--") {
 			continue
 		}
-		if !desired[container] {
-			log.Printf("Killing: %s", container)
-			err = kl.KillContainer(container)
+		if !desired[member.ID] && !kl.migrations.migrating(member.PodID) {
+			log.Printf("Killing: %s", member.Name)
+			err = kl.killPodMember(member)
 			if err != nil {
 				log.Printf("Error killing container: %#v", err)
 			}
@@ -636,8 +458,9 @@ func (kl *Kubelet) RunSyncLoop(etcdChannel <-chan []api.ContainerManifest, fileC
 	}
 }
 
+// GetContainerInfo returns the runtime's description of the pod member with id 'name', as JSON.
 func (kl *Kubelet) GetContainerInfo(name string) (string, error) {
-	info, err := kl.DockerClient.InspectContainer(name)
+	info, err := kl.Runtime.InspectPodMember(name)
 	if err != nil {
 		return "{}", err
 	}
@@ -17,12 +17,16 @@ limitations under the License.
 package kubelet
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"net/url"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"gopkg.in/v1/yaml"
 )
 
@@ -36,6 +40,10 @@ type KubeletServer struct {
 type kubeletInterface interface {
 	GetContainerID(name string) (string, bool, error)
 	GetContainerInfo(name string) (string, error)
+	GetPodConsole(podID, containerName string, follow bool) (io.ReadCloser, error)
+	PrepareMigration(manifest api.ContainerManifest) (map[string]string, error)
+	MigratePod(migration api.PodMigration) error
+	AbortMigration(podID string) error
 }
 
 func (s *KubeletServer) error(w http.ResponseWriter, err error) {
@@ -86,8 +94,88 @@ func (s *KubeletServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		w.Header().Add("Content-type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprint(w, body)
+	case u.Path == "/podConsole":
+		s.handlePodConsole(w, u.Query())
+	case u.Path == "/prepareMigration" && req.Method == "POST":
+		var manifest api.ContainerManifest
+		if err := s.readBody(req, &manifest); err != nil {
+			s.error(w, err)
+			return
+		}
+		names, err := s.Kubelet.PrepareMigration(manifest)
+		if err != nil {
+			s.error(w, err)
+			return
+		}
+		data, err := json.Marshal(names)
+		if err != nil {
+			s.error(w, err)
+			return
+		}
+		w.Header().Add("Content-type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	case u.Path == "/migrate" && req.Method == "POST":
+		var migration api.PodMigration
+		if err := s.readBody(req, &migration); err != nil {
+			s.error(w, err)
+			return
+		}
+		if err := s.Kubelet.MigratePod(migration); err != nil {
+			s.error(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case u.Path == "/abortMigration" && req.Method == "POST":
+		var migration api.PodMigration
+		if err := s.readBody(req, &migration); err != nil {
+			s.error(w, err)
+			return
+		}
+		if err := s.Kubelet.AbortMigration(migration.PodID); err != nil {
+			s.error(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
 	default:
 		w.WriteHeader(http.StatusNotFound)
 		fmt.Fprint(w, "Not found.")
 	}
 }
+
+// readBody decodes the YAML or JSON request body into 'obj'.
+func (s *KubeletServer) readBody(req *http.Request, obj interface{}) error {
+	defer req.Body.Close()
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, obj)
+}
+
+// handlePodConsole streams the console of a pod member. With follow=true, the response
+// continues with new output until the member stops or the client goes away.
+func (s *KubeletServer) handlePodConsole(w http.ResponseWriter, query url.Values) {
+	podID := query.Get("podID")
+	if len(podID) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "Missing podID query arg.")
+		return
+	}
+	console, err := s.Kubelet.GetPodConsole(podID, query.Get("container"), query.Get("follow") == "true")
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+	if console == nil {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "Not found.")
+		return
+	}
+	defer console.Close()
+	w.Header().Add("Content-type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	if err := util.StreamResponse(w, console); err != nil {
+		log.Printf("Error streaming console of %s: %#v", podID, err)
+	}
+}
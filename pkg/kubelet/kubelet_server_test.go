@@ -3,10 +3,12 @@ package kubelet
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 
@@ -15,8 +17,28 @@ import (
 )
 
 type fakeKubelet struct {
-	infoFunc func(name string) (string, error)
-	idFunc   func(name string) (string, bool, error)
+	infoFunc    func(name string) (string, error)
+	idFunc      func(name string) (string, bool, error)
+	consoleFunc func(podID, containerName string, follow bool) (io.ReadCloser, error)
+	prepareFunc func(manifest api.ContainerManifest) (map[string]string, error)
+	migrateFunc func(migration api.PodMigration) error
+	abortFunc   func(podID string) error
+}
+
+func (fk *fakeKubelet) PrepareMigration(manifest api.ContainerManifest) (map[string]string, error) {
+	return fk.prepareFunc(manifest)
+}
+
+func (fk *fakeKubelet) MigratePod(migration api.PodMigration) error {
+	return fk.migrateFunc(migration)
+}
+
+func (fk *fakeKubelet) AbortMigration(podID string) error {
+	return fk.abortFunc(podID)
+}
+
+func (fk *fakeKubelet) GetPodConsole(podID, containerName string, follow bool) (io.ReadCloser, error) {
+	return fk.consoleFunc(podID, containerName, follow)
 }
 
 func (fk *fakeKubelet) GetContainerInfo(name string) (string, error) {
@@ -129,3 +151,114 @@ func TestContainerInfo(t *testing.T) {
 		t.Errorf("Expected: '%v', got: '%v'", expected, got)
 	}
 }
+
+func TestPodConsole(t *testing.T) {
+	fw := makeServerTest()
+	fw.fakeKubelet.consoleFunc = func(podID, containerName string, follow bool) (io.ReadCloser, error) {
+		if podID != "foo" {
+			return nil, nil
+		}
+		if containerName != "bar" || !follow {
+			t.Errorf("Unexpected arguments: %s %v", containerName, follow)
+		}
+		return ioutil.NopCloser(strings.NewReader("booting\n")), nil
+	}
+	resp, err := http.Get(fw.testHttpServer.URL + "/podConsole?podID=foo&container=bar&follow=true")
+	if err != nil {
+		t.Errorf("Got error GETing: %v", err)
+	}
+	got, err := readResp(resp)
+	if err != nil {
+		t.Errorf("Error reading body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || got != "booting\n" {
+		t.Errorf("Unexpected response: %d %s", resp.StatusCode, got)
+	}
+
+	resp, err = http.Get(fw.testHttpServer.URL + "/podConsole?podID=missing")
+	if err != nil {
+		t.Errorf("Got error GETing: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Unexpected status: %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(fw.testHttpServer.URL + "/podConsole")
+	if err != nil {
+		t.Errorf("Got error GETing: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Unexpected status: %d", resp.StatusCode)
+	}
+}
+
+func TestServePrepareMigration(t *testing.T) {
+	fw := makeServerTest()
+	fw.fakeKubelet.prepareFunc = func(manifest api.ContainerManifest) (map[string]string, error) {
+		if manifest.Id != "foo" {
+			t.Errorf("Unexpected manifest: %#v", manifest)
+		}
+		return map[string]string{"bar": "bar--foo--1234"}, nil
+	}
+	resp, err := http.Post(fw.testHttpServer.URL+"/prepareMigration", "application/json", strings.NewReader(`{"id": "foo"}`))
+	if err != nil {
+		t.Errorf("Got error POSTing: %v", err)
+	}
+	got, err := readResp(resp)
+	if err != nil {
+		t.Errorf("Error reading body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || got != `{"bar":"bar--foo--1234"}` {
+		t.Errorf("Unexpected response: %d %s", resp.StatusCode, got)
+	}
+}
+
+func TestServeMigratePod(t *testing.T) {
+	fw := makeServerTest()
+	fw.fakeKubelet.migrateFunc = func(migration api.PodMigration) error {
+		if migration.PodID != "foo" || migration.Target != "machine" || migration.Members["bar"] != "bar--foo--1234" {
+			t.Errorf("Unexpected migration: %#v", migration)
+		}
+		return nil
+	}
+	body := `{"podID": "foo", "target": "machine", "members": {"bar": "bar--foo--1234"}}`
+	resp, err := http.Post(fw.testHttpServer.URL+"/migrate", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Errorf("Got error POSTing: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Unexpected status: %d", resp.StatusCode)
+	}
+
+	fw.fakeKubelet.migrateFunc = func(migration api.PodMigration) error {
+		return fmt.Errorf("migration failed")
+	}
+	resp, err = http.Post(fw.testHttpServer.URL+"/migrate", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Errorf("Got error POSTing: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Unexpected status: %d", resp.StatusCode)
+	}
+}
+
+func TestServeAbortMigration(t *testing.T) {
+	fw := makeServerTest()
+	aborted := ""
+	fw.fakeKubelet.abortFunc = func(podID string) error {
+		aborted = podID
+		return nil
+	}
+	resp, err := http.Post(fw.testHttpServer.URL+"/abortMigration", "application/json", strings.NewReader(`{"podID": "foo"}`))
+	if err != nil {
+		t.Errorf("Got error POSTing: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || aborted != "foo" {
+		t.Errorf("Unexpected response: %d %s", resp.StatusCode, aborted)
+	}
+}
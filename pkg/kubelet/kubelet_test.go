@@ -22,7 +22,6 @@ import (
 	"io/ioutil"
 	"net/http/httptest"
 	"reflect"
-	"strings"
 	"sync"
 	"testing"
 
@@ -91,6 +90,7 @@ type FakeDockerClient struct {
 	container     *docker.Container
 	err           error
 	called        []string
+	created       []string
 	stopped       string
 }
 
@@ -112,9 +112,10 @@ func (f *FakeDockerClient) InspectContainer(id string) (*docker.Container, error
 	return f.container, f.err
 }
 
-func (f *FakeDockerClient) CreateContainer(docker.CreateContainerOptions) (*docker.Container, error) {
+func (f *FakeDockerClient) CreateContainer(opts docker.CreateContainerOptions) (*docker.Container, error) {
 	f.appendCall("create")
-	return nil, nil
+	f.created = append(f.created, opts.Name)
+	return &docker.Container{ID: opts.Name}, f.err
 }
 
 func (f *FakeDockerClient) StartContainer(id string, hostConfig *docker.HostConfig) error {
@@ -144,89 +145,31 @@ func verifyStringArrayEquals(t *testing.T, actual, expected []string) {
 	}
 }
 
-func verifyPackUnpack(t *testing.T, manifestId, containerName string) {
-	name := manifestAndContainerToDockerName(
-		&api.ContainerManifest{Id: manifestId},
-		&api.Container{Name: containerName},
-	)
-	returnedManifestId, returnedContainerName := dockerNameToManifestAndContainer(name)
-	if manifestId != returnedManifestId || containerName != returnedContainerName {
-		t.Errorf("For (%s, %s), unpacked (%s, %s)", manifestId, containerName, returnedManifestId, returnedContainerName)
-	}
-}
-
 func verifyBoolean(t *testing.T, expected, value bool) {
 	if expected != value {
 		t.Errorf("Unexpected boolean.  Expected %s.  Found %s", expected, value)
 	}
 }
 
-func TestContainerManifestNaming(t *testing.T) {
-	verifyPackUnpack(t, "manifest1234", "container5678")
-	verifyPackUnpack(t, "manifest--", "container__")
-	verifyPackUnpack(t, "--manifest", "__container")
-	verifyPackUnpack(t, "m___anifest_", "container-_-")
-	verifyPackUnpack(t, "_m___anifest", "-_-container")
-}
-
-func TestContainerExists(t *testing.T) {
+func TestGetContainerID(t *testing.T) {
 	fakeDocker := FakeDockerClient{
 		err: nil,
 	}
 	kubelet := Kubelet{
-		DockerClient: &fakeDocker,
-	}
-	manifest := api.ContainerManifest{
-		Id: "qux",
-	}
-	container := api.Container{
-		Name: "foo",
+		Runtime: MakeDockerRuntime(&fakeDocker),
 	}
 	fakeDocker.containerList = []docker.APIContainers{
 		{
 			Names: []string{"foo--qux--1234"},
+			ID:    "1234",
 		},
 		{
 			Names: []string{"bar--qux--1234"},
-		},
-	}
-	fakeDocker.container = &docker.Container{
-		ID: "foobar",
-	}
-
-	exists, _, err := kubelet.ContainerExists(&manifest, &container)
-	verifyCalls(t, fakeDocker, []string{"list", "list", "inspect"})
-	if !exists {
-		t.Errorf("Failed to find container %#v", container)
-	}
-	if err != nil {
-		t.Errorf("Unexpected error: %#v", err)
-	}
-
-	fakeDocker.clearCalls()
-	missingManifest := api.ContainerManifest{Id: "foobar"}
-	exists, _, err = kubelet.ContainerExists(&missingManifest, &container)
-	verifyCalls(t, fakeDocker, []string{"list"})
-	if exists {
-		t.Errorf("Failed to not find container %#v, missingManifest")
-	}
-}
-
-func TestGetContainerID(t *testing.T) {
-	fakeDocker := FakeDockerClient{
-		err: nil,
-	}
-	kubelet := Kubelet{
-		DockerClient: &fakeDocker,
-	}
-	fakeDocker.containerList = []docker.APIContainers{
-		{
-			Names: []string{"foo"},
-			ID:    "1234",
+			ID:    "4567",
 		},
 		{
-			Names: []string{"bar"},
-			ID:    "4567",
+			Names: []string{"/manual"},
+			ID:    "8910",
 		},
 	}
 
@@ -244,104 +187,68 @@ func TestGetContainerID(t *testing.T) {
 	verifyCalls(t, fakeDocker, []string{"list"})
 	fakeDocker.clearCalls()
 
+	id, found, err = kubelet.GetContainerID("manual")
+	verifyBoolean(t, true, found)
+	verifyStringEquals(t, id, "8910")
+	verifyNoError(t, err)
+	verifyCalls(t, fakeDocker, []string{"list"})
+	fakeDocker.clearCalls()
+
+	id, found, err = kubelet.GetContainerID("qux")
+	verifyBoolean(t, true, found)
+	verifyStringEquals(t, id, "1234")
+	verifyNoError(t, err)
+	verifyCalls(t, fakeDocker, []string{"list"})
+	fakeDocker.clearCalls()
+
+	// Only an exact match counts.
+	id, found, err = kubelet.GetContainerID("qu")
+	verifyBoolean(t, false, found)
+	verifyNoError(t, err)
+	verifyCalls(t, fakeDocker, []string{"list"})
+	fakeDocker.clearCalls()
+
 	id, found, err = kubelet.GetContainerID("NotFound")
 	verifyBoolean(t, false, found)
 	verifyNoError(t, err)
 	verifyCalls(t, fakeDocker, []string{"list"})
 }
 
-func TestGetContainerByName(t *testing.T) {
+func TestGetContainerInfo(t *testing.T) {
 	fakeDocker := FakeDockerClient{
 		err: nil,
 	}
 	kubelet := Kubelet{
-		DockerClient: &fakeDocker,
-	}
-	fakeDocker.containerList = []docker.APIContainers{
-		{
-			Names: []string{"foo"},
-		},
-		{
-			Names: []string{"bar"},
-		},
+		Runtime: MakeDockerRuntime(&fakeDocker),
 	}
 	fakeDocker.container = &docker.Container{
 		ID: "foobar",
 	}
 
-	container, err := kubelet.GetContainerByName("foo")
-	verifyCalls(t, fakeDocker, []string{"list", "inspect"})
-	if container == nil {
-		t.Errorf("Unexpected nil container")
-	}
-	verifyStringEquals(t, container.ID, "foobar")
+	info, err := kubelet.GetContainerInfo("foobar")
 	verifyNoError(t, err)
+	verifyCalls(t, fakeDocker, []string{"inspect"})
+	var container docker.Container
+	expectNoError(t, json.Unmarshal([]byte(info), &container))
+	verifyStringEquals(t, container.ID, "foobar")
 }
 
-func TestListContainers(t *testing.T) {
+func TestKillPodMember(t *testing.T) {
 	fakeDocker := FakeDockerClient{
 		err: nil,
 	}
+	fakeEtcd := registry.MakeFakeEtcdClient(t)
 	kubelet := Kubelet{
-		DockerClient: &fakeDocker,
-	}
-	fakeDocker.containerList = []docker.APIContainers{
-		{
-			Names: []string{"foo"},
-		},
-		{
-			Names: []string{"bar"},
-		},
+		Client:  fakeEtcd,
+		Runtime: MakeDockerRuntime(&fakeDocker),
 	}
-
-	containers, err := kubelet.ListContainers()
-	verifyStringArrayEquals(t, containers, []string{"foo", "bar"})
+	err := kubelet.killPodMember(PodMember{ID: "1234", Name: "/foo--bar--1", PodID: "bar", ContainerName: "foo"})
 	verifyNoError(t, err)
-	verifyCalls(t, fakeDocker, []string{"list"})
-}
-
-func TestKillContainerWithError(t *testing.T) {
-	fakeDocker := FakeDockerClient{
-		err: fmt.Errorf("sample error"),
-		containerList: []docker.APIContainers{
-			{
-				Names: []string{"foo"},
-			},
-			{
-				Names: []string{"bar"},
-			},
-		},
-	}
-	kubelet := Kubelet{
-		DockerClient: &fakeDocker,
-	}
-	err := kubelet.KillContainer("foo")
-	verifyError(t, err)
-	verifyCalls(t, fakeDocker, []string{"list"})
-}
-
-func TestKillContainer(t *testing.T) {
-	fakeDocker := FakeDockerClient{
-		err: nil,
-	}
-	kubelet := Kubelet{
-		DockerClient: &fakeDocker,
-	}
-	fakeDocker.containerList = []docker.APIContainers{
-		{
-			Names: []string{"foo"},
-		},
-		{
-			Names: []string{"bar"},
-		},
-	}
-	fakeDocker.container = &docker.Container{
-		ID: "foobar",
+	verifyCalls(t, fakeDocker, []string{"stop"})
+	verifyStringEquals(t, fakeDocker.stopped, "1234")
+	if fakeEtcd.Ix != 1 {
+		t.Errorf("Expected a STOP event to be logged, saw %d events", fakeEtcd.Ix)
 	}
-
-	err := kubelet.KillContainer("foo")
-	verifyNoError(t, err)
-	verifyCalls(t, fakeDocker, []string{"list", "stop"})
 }
 
 func TestResponseToContainersNil(t *testing.T) {
@@ -501,10 +408,11 @@ func TestSyncManifestsDoesNothing(t *testing.T) {
 		},
 	}
 	fakeDocker.container = &docker.Container{
-		ID: "1234",
+		ID:    "1234",
+		State: docker.State{Running: true},
 	}
 	kubelet := Kubelet{
-		DockerClient: &fakeDocker,
+		Runtime: MakeDockerRuntime(&fakeDocker),
 	}
 	err := kubelet.SyncManifests([]api.ContainerManifest{
 		{
@@ -515,11 +423,9 @@ func TestSyncManifestsDoesNothing(t *testing.T) {
 		},
 	})
 	expectNoError(t, err)
-	if len(fakeDocker.called) != 4 ||
+	if len(fakeDocker.called) != 2 ||
 		fakeDocker.called[0] != "list" ||
-		fakeDocker.called[1] != "list" ||
-		fakeDocker.called[2] != "inspect" ||
-		fakeDocker.called[3] != "list" {
+		fakeDocker.called[1] != "inspect" {
 		t.Errorf("Unexpected call sequence: %#v", fakeDocker.called)
 	}
 }
@@ -540,19 +446,185 @@ func TestSyncManifestsDeletes(t *testing.T) {
 		},
 	}
 	kubelet := Kubelet{
-		DockerClient: &fakeDocker,
+		Runtime: MakeDockerRuntime(&fakeDocker),
 	}
 	err := kubelet.SyncManifests([]api.ContainerManifest{})
 	expectNoError(t, err)
-	if len(fakeDocker.called) != 3 ||
+	if len(fakeDocker.called) != 2 ||
 		fakeDocker.called[0] != "list" ||
-		fakeDocker.called[1] != "list" ||
-		fakeDocker.called[2] != "stop" ||
+		fakeDocker.called[1] != "stop" ||
 		fakeDocker.stopped != "1234" {
 		t.Errorf("Unexpected call sequence: %#v", fakeDocker.called)
 	}
 }
 
+// FakeRuntime is a Runtime that records the calls made against it.
+type FakeRuntime struct {
+	members  []PodMember
+	status   PodMemberStatus
+	err      error
+	startErr error
+	called   []string
+	pulled   []string
+	started  []PodMember
+	stopped  []PodMember
+}
+
+func (f *FakeRuntime) ListPodMembers() ([]PodMember, error) {
+	f.called = append(f.called, "list")
+	return f.members, f.err
+}
+
+func (f *FakeRuntime) StartPodMember(manifest *api.ContainerManifest, container *api.Container) (PodMember, error) {
+	f.called = append(f.called, "start")
+	member := PodMember{
+		ID:            manifest.Id + "/" + container.Name,
+		Name:          container.Name + "--" + manifest.Id,
+		PodID:         manifest.Id,
+		ContainerName: container.Name,
+	}
+	f.started = append(f.started, member)
+	if f.startErr != nil {
+		return member, f.startErr
+	}
+	return member, f.err
+}
+
+func (f *FakeRuntime) StopPodMember(member PodMember) error {
+	f.called = append(f.called, "stop")
+	f.stopped = append(f.stopped, member)
+	return f.err
+}
+
+func (f *FakeRuntime) PullImage(image string) error {
+	f.called = append(f.called, "pull")
+	f.pulled = append(f.pulled, image)
+	return f.err
+}
+
+func (f *FakeRuntime) InspectPodMember(id string) (interface{}, error) {
+	f.called = append(f.called, "inspect")
+	return nil, f.err
+}
+
+func (f *FakeRuntime) PodMemberStatus(id string) (PodMemberStatus, error) {
+	f.called = append(f.called, "status")
+	return f.status, f.err
+}
+
+func TestSyncManifestsCreates(t *testing.T) {
+	fakeRuntime := &FakeRuntime{}
+	kubelet := Kubelet{
+		Runtime: fakeRuntime,
+	}
+	err := kubelet.SyncManifests([]api.ContainerManifest{
+		{
+			Id: "foo",
+			Containers: []api.Container{
+				{Name: "bar", Image: "dockerfile/nginx"},
+			},
+		},
+	})
+	expectNoError(t, err)
+	verifyStringArrayEquals(t, fakeRuntime.called, []string{"list", "pull", "start"})
+	verifyStringArrayEquals(t, fakeRuntime.pulled, []string{"dockerfile/nginx"})
+	if len(fakeRuntime.started) != 1 || fakeRuntime.started[0].PodID != "foo" || fakeRuntime.started[0].ContainerName != "bar" {
+		t.Errorf("Unexpected started members: %#v", fakeRuntime.started)
+	}
+}
+
+func TestSyncManifestsKeepsExisting(t *testing.T) {
+	fakeRuntime := &FakeRuntime{
+		members: []PodMember{
+			{ID: "1234", Name: "bar--foo--1", PodID: "foo", ContainerName: "bar"},
+			{ID: "4567", Name: "manual", ContainerName: "manual"},
+		},
+		status: PodMemberStopped,
+	}
+	kubelet := Kubelet{
+		Runtime: fakeRuntime,
+	}
+	err := kubelet.SyncManifests([]api.ContainerManifest{
+		{
+			Id: "foo",
+			Containers: []api.Container{
+				{Name: "bar"},
+			},
+		},
+	})
+	expectNoError(t, err)
+	verifyStringArrayEquals(t, fakeRuntime.called, []string{"list", "status"})
+	if len(fakeRuntime.stopped) != 0 {
+		t.Errorf("Unexpected stopped members: %#v", fakeRuntime.stopped)
+	}
+}
+
+func TestSyncManifestsKeepsLegacy(t *testing.T) {
+	fakeRuntime := &FakeRuntime{
+		members: []PodMember{
+			{ID: "1234", Name: "bar--foo--1", PodID: "foo", ContainerName: "bar"},
+		},
+	}
+	kubelet := Kubelet{
+		Runtime: fakeRuntime,
+	}
+	err := kubelet.SyncManifests([]api.ContainerManifest{
+		{
+			Id: "foo.default",
+			Containers: []api.Container{
+				{Name: "bar"},
+			},
+		},
+	})
+	expectNoError(t, err)
+	verifyStringArrayEquals(t, fakeRuntime.called, []string{"list", "status"})
+}
+
+func TestInPod(t *testing.T) {
+	table := []struct {
+		podID      string
+		manifestID string
+		expected   bool
+	}{
+		{"foo.default", "foo.default", true},
+		{"foo.team", "foo.team", true},
+		{"foo", "foo.default", true},
+		{"foo", "foo.team", false},
+		{"foo.team", "foo.team.default", false},
+		{"foo.default", "foo", false},
+	}
+	for _, item := range table {
+		if inPod(PodMember{PodID: item.podID}, item.manifestID) != item.expected {
+			t.Errorf("Expected inPod(%s, %s) to be %v", item.podID, item.manifestID, item.expected)
+		}
+	}
+}
+
+func TestSyncManifestsStartFailure(t *testing.T) {
+	// The member that failed to start has the ID of a member that isn't wanted any more.
+	unwanted := PodMember{ID: "foo/bar", Name: "baz--foo--1", PodID: "foo", ContainerName: "baz"}
+	fakeRuntime := &FakeRuntime{
+		members:  []PodMember{unwanted},
+		startErr: fmt.Errorf("sample error"),
+	}
+	kubelet := Kubelet{
+		Runtime: fakeRuntime,
+	}
+	err := kubelet.SyncManifests([]api.ContainerManifest{
+		{
+			Id: "foo",
+			Containers: []api.Container{
+				{Name: "bar"},
+			},
+		},
+	})
+	expectNoError(t, err)
+	verifyStringArrayEquals(t, fakeRuntime.called, []string{"list", "pull", "start", "stop"})
+	if len(fakeRuntime.stopped) != 1 || fakeRuntime.stopped[0] != unwanted {
+		t.Errorf("Unexpected stopped members: %#v", fakeRuntime.stopped)
+	}
+}
+
 func TestEventWriting(t *testing.T) {
 	fakeEtcd := registry.MakeFakeEtcdClient(t)
 	kubelet := &Kubelet{
@@ -597,128 +669,6 @@ func TestEventWritingError(t *testing.T) {
 	}
 }
 
-func TestMakeCommandLine(t *testing.T) {
-	expected := []string{"echo", "hello", "world"}
-	container := api.Container{
-		Command: strings.Join(expected, " "),
-	}
-	cmdLine := makeCommandLine(&container)
-	if !reflect.DeepEqual(expected, cmdLine) {
-		t.Error("Unexpected command line.  Expected %#v, got %#v", expected, cmdLine)
-	}
-}
-
-func TestMakeEnvVariables(t *testing.T) {
-	container := api.Container{
-		Env: []api.EnvVar{
-			{
-				Name:  "foo",
-				Value: "bar",
-			},
-			{
-				Name:  "baz",
-				Value: "blah",
-			},
-		},
-	}
-	vars := makeEnvironmentVariables(&container)
-	if len(vars) != len(container.Env) {
-		t.Errorf("Vars don't match.  Expected: %#v Found: %#v", container.Env, vars)
-	}
-	for ix, env := range container.Env {
-		value := fmt.Sprintf("%s=%s", env.Name, env.Value)
-		if value != vars[ix] {
-			t.Errorf("Unexpected value: %s.  Expected: %s", vars[ix], value)
-		}
-	}
-}
-
-func TestMakeVolumesAndBinds(t *testing.T) {
-	container := api.Container{
-		VolumeMounts: []api.VolumeMount{
-			{
-				MountPath: "/mnt/path",
-				Name:      "disk",
-				ReadOnly:  false,
-			},
-			{
-				MountPath: "/mnt/path2",
-				Name:      "disk2",
-				ReadOnly:  true,
-			},
-		},
-	}
-	volumes, binds := makeVolumesAndBinds(&container)
-	if len(volumes) != len(container.VolumeMounts) ||
-		len(binds) != len(container.VolumeMounts) {
-		t.Errorf("Unexpected volumes and binds: %#v %#v.  Container was: %#v", volumes, binds, container)
-	}
-	for ix, volume := range container.VolumeMounts {
-		expectedBind := "/exports/" + volume.Name + ":" + volume.MountPath
-		if volume.ReadOnly {
-			expectedBind = expectedBind + ":ro"
-		}
-		if binds[ix] != expectedBind {
-			t.Errorf("Unexpected bind.  Expected %s.  Found %s", expectedBind, binds[ix])
-		}
-		if _, ok := volumes[volume.MountPath]; !ok {
-			t.Errorf("Map is missing key: %s. %#v", volume.MountPath, volumes)
-		}
-	}
-}
-
-func TestMakePortsAndBindings(t *testing.T) {
-	container := api.Container{
-		Ports: []api.Port{
-			{
-				ContainerPort: 80,
-				HostPort:      8080,
-			},
-			{
-				ContainerPort: 443,
-				HostPort:      443,
-				Protocol:      "tcp",
-			},
-			{
-				ContainerPort: 444,
-				HostPort:      444,
-				Protocol:      "udp",
-			},
-			{
-				ContainerPort: 445,
-				HostPort:      445,
-				Protocol:      "foobar",
-			},
-		},
-	}
-	exposedPorts, bindings := makePortsAndBindings(&container)
-	if len(container.Ports) != len(exposedPorts) ||
-		len(container.Ports) != len(bindings) {
-		t.Errorf("Unexpected ports and bindings, %#v %#v %#v", container, exposedPorts, bindings)
-	}
-	for key, value := range bindings {
-		switch value[0].HostPort {
-		case "8080":
-			if !reflect.DeepEqual(docker.Port("80/tcp"), key) {
-				t.Errorf("Unexpected docker port: %#v", key)
-			}
-		case "443":
-			if !reflect.DeepEqual(docker.Port("443/tcp"), key) {
-				t.Errorf("Unexpected docker port: %#v", key)
-			}
-		case "444":
-			if !reflect.DeepEqual(docker.Port("444/udp"), key) {
-				t.Errorf("Unexpected docker port: %#v", key)
-			}
-		case "445":
-			if !reflect.DeepEqual(docker.Port("445/tcp"), key) {
-				t.Errorf("Unexpected docker port: %#v", key)
-			}
-		}
-	}
-
-}
-
 func TestExtractFromNonExistentFile(t *testing.T) {
 	kubelet := Kubelet{}
 	changeChannel := make(chan api.ContainerManifest)
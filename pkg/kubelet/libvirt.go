@@ -0,0 +1,521 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/libvirt"
+)
+
+// The amount of memory given to a domain whose container doesn't specify any, in KiB.
+const defaultDomainMemory = 512 * 1024
+
+// The drive that holds the config drive of a domain.
+const configDriveTarget = "hdc"
+
+// LibvirtRuntime is a Runtime that runs each member of a pod as a libvirt domain.
+// Container.Image names a base disk image in the image store, and the root disk of
+// the domain is a copy-on-write overlay on top of it. The rest of the container is
+// passed to the guest through a cloud-init config drive.
+type LibvirtRuntime struct {
+	conn         libvirt.Connection
+	images       *ImageStore
+	configDrives ConfigDriveWriter
+	// network, if set, gives each domain an address on the pod network.
+	network *PodNetwork
+	// diskDir holds the root disks and console logs of running domains.
+	diskDir string
+	// migrationURI formats the libvirt URI of another host from its name, e.g. qemu+ssh://%s/system.
+	migrationURI string
+}
+
+// MakeLibvirtRuntime creates a LibvirtRuntime that manages domains through 'conn'. If 'network'
+// is nil, domains are only reachable through their host ports. Domains are migrated to the
+// libvirt daemon at fmt.Sprintf(migrationURI, host).
+func MakeLibvirtRuntime(conn libvirt.Connection, images *ImageStore, configDrives ConfigDriveWriter, network *PodNetwork, diskDir, migrationURI string) *LibvirtRuntime {
+	return &LibvirtRuntime{
+		conn:         conn,
+		images:       images,
+		configDrives: configDrives,
+		network:      network,
+		diskDir:      diskDir,
+		migrationURI: migrationURI,
+	}
+}
+
+// LibvirtDomainInfo is the description of a pod member returned by LibvirtRuntime.
+// State has the same shape as in Docker's inspect output, so that the master can
+// determine pod status the same way for both runtimes.
+type LibvirtDomainInfo struct {
+	State struct {
+		Running bool
+	}
+	Domain *libvirt.Domain
+	// PodIP is the address of the domain on the pod network, if it has one.
+	PodIP string `json:",omitempty"`
+}
+
+// ListPodMembers returns the domains on this host that were started by the kubelet.
+// Domains that lack '--' in their name are ignored.
+func (l *LibvirtRuntime) ListPodMembers() ([]PodMember, error) {
+	result := []PodMember{}
+	names, err := l.conn.ListDomains()
+	if err != nil {
+		return result, err
+	}
+	for _, name := range names {
+		if !strings.Contains(name, "--") {
+			continue
+		}
+		manifestId, containerName := dockerNameToManifestAndContainer(name)
+		result = append(result, PodMember{
+			ID:            name,
+			Name:          name,
+			PodID:         manifestId,
+			ContainerName: containerName,
+		})
+	}
+	return result, nil
+}
+
+func (l *LibvirtRuntime) diskPath(name string) string {
+	return filepath.Join(l.diskDir, name+".qcow2")
+}
+
+// consolePath is the file that the serial console output of the domain is written to.
+func (l *LibvirtRuntime) consolePath(name string) string {
+	return filepath.Join(l.diskDir, name+".console.log")
+}
+
+// configDrivePath is the ISO image that cloud-init in the domain reads its configuration from.
+func (l *LibvirtRuntime) configDrivePath(name string) string {
+	return filepath.Join(l.diskDir, name+".cidata.iso")
+}
+
+// configDataDir keeps the files on the config drive of the domain.
+func (l *LibvirtRuntime) configDataDir(name string) string {
+	return filepath.Join(l.diskDir, name+".cidata")
+}
+
+// writeConfigDrive brings the config drive of domain 'name' up to date with 'container' from
+// 'manifest'. It returns whether the drive changed.
+func (l *LibvirtRuntime) writeConfigDrive(name string, manifest *api.ContainerManifest, container *api.Container) (bool, error) {
+	files, err := makeCloudInit(manifest, container)
+	if err != nil {
+		return false, err
+	}
+	return writeConfigDrive(l.configDrives, l.configDataDir(name), l.configDrivePath(name), files)
+}
+
+// makeInterface leases an address on the pod network for 'member', and returns the interface
+// that connects its domain to the network. It returns nil if there is no pod network.
+func (l *LibvirtRuntime) makeInterface(member PodMember) (*libvirt.Interface, error) {
+	if l.network == nil {
+		return nil, nil
+	}
+	_, mac, err := l.network.Allocate(podNetworkKey(member.PodID, member.ContainerName))
+	if err != nil {
+		return nil, err
+	}
+	return &libvirt.Interface{
+		Type:   "network",
+		MAC:    &libvirt.InterfaceMAC{Address: mac},
+		Source: libvirt.InterfaceSource{Network: l.network.Name()},
+		Model:  libvirt.InterfaceModel{Type: "virtio"},
+	}, nil
+}
+
+// releaseAddress frees the pod network address of 'member', unless another domain runs the
+// same container of the same pod. SyncManifests starts the replacement of a stopped member
+// before it removes the old one, and the replacement keeps the address.
+func (l *LibvirtRuntime) releaseAddress(member PodMember) error {
+	if l.network == nil {
+		return nil
+	}
+	members, err := l.ListPodMembers()
+	if err != nil {
+		return err
+	}
+	for _, other := range members {
+		if other.ID != member.ID && other.PodID == member.PodID && other.ContainerName == member.ContainerName {
+			return nil
+		}
+	}
+	return l.network.Release(podNetworkKey(member.PodID, member.ContainerName))
+}
+
+// makePodMember returns a new member, with a fresh name, for 'container' from 'manifest'.
+func makePodMember(manifest *api.ContainerManifest, container *api.Container) PodMember {
+	name := manifestAndContainerToDockerName(manifest, container)
+	return PodMember{
+		ID:            name,
+		Name:          name,
+		PodID:         manifest.Id,
+		ContainerName: container.Name,
+	}
+}
+
+func (l *LibvirtRuntime) StartPodMember(manifest *api.ContainerManifest, container *api.Container) (PodMember, error) {
+	member := makePodMember(manifest, container)
+	name := member.Name
+	iface, err := l.makeInterface(member)
+	if err != nil {
+		return PodMember{}, err
+	}
+	disk := l.diskPath(name)
+	if err := l.images.CreateOverlay(container.Image, disk, name); err != nil {
+		l.releaseAddress(member)
+		return PodMember{}, err
+	}
+	if _, err := l.writeConfigDrive(name, manifest, container); err != nil {
+		l.removeMember(member)
+		return PodMember{}, err
+	}
+	domain := makeDomain(name, container, disk, l.consolePath(name), iface)
+	domain.Devices.Disks = append(domain.Devices.Disks, makeConfigDriveDisk(l.configDrivePath(name)))
+	if err := l.conn.DefineDomain(domain); err != nil {
+		l.removeMember(member)
+		return PodMember{}, err
+	}
+	if err := l.conn.StartDomain(name); err != nil {
+		l.conn.UndefineDomain(name)
+		l.removeMember(member)
+		return PodMember{}, err
+	}
+	return member, nil
+}
+
+// UpdatePodMember rebuilds the config drive of a running domain if the manifest changed, and
+// swaps it into the domain. cloud-init in the guest applies it on the next boot.
+func (l *LibvirtRuntime) UpdatePodMember(member PodMember, manifest *api.ContainerManifest, container *api.Container) error {
+	changed, err := l.writeConfigDrive(member.ID, manifest, container)
+	if err != nil || !changed {
+		return err
+	}
+	domain, err := l.conn.LookupDomain(member.ID)
+	if err != nil || domain == nil {
+		return err
+	}
+	for _, disk := range domain.Devices.Disks {
+		if disk.Target.Dev == configDriveTarget {
+			return l.conn.ChangeMedia(member.ID, configDriveTarget, l.configDrivePath(member.ID))
+		}
+	}
+	return nil
+}
+
+// StopPodMember powers off the domain and removes it, along with its root disk, its console
+// log, its reference to its base image and its pod network address.
+func (l *LibvirtRuntime) StopPodMember(member PodMember) error {
+	state, err := l.conn.DomainState(member.ID)
+	if err != nil {
+		return err
+	}
+	if state == libvirt.DomainRunning || state == libvirt.DomainPaused {
+		if err := l.conn.DestroyDomain(member.ID); err != nil {
+			return err
+		}
+	}
+	if err := l.conn.UndefineDomain(member.ID); err != nil {
+		return err
+	}
+	return l.removeMember(member)
+}
+
+// removeMember removes what is left of a member once its domain is gone from this host.
+func (l *LibvirtRuntime) removeMember(member PodMember) error {
+	for _, file := range []string{l.diskPath(member.ID), l.consolePath(member.ID), l.configDrivePath(member.ID)} {
+		err := os.Remove(file)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.RemoveAll(l.configDataDir(member.ID)); err != nil {
+		return err
+	}
+	if err := l.images.Release(member.ID); err != nil {
+		return err
+	}
+	return l.releaseAddress(member)
+}
+
+// PrepareMigration pulls the image of 'container', creates the root disk and config drive that
+// the migrated domain will run with, and leases the domain's address on this host's pod network.
+func (l *LibvirtRuntime) PrepareMigration(manifest *api.ContainerManifest, container *api.Container) (PodMember, error) {
+	member := makePodMember(manifest, container)
+	if err := l.images.Pull(container.Image); err != nil {
+		return PodMember{}, err
+	}
+	if _, err := l.makeInterface(member); err != nil {
+		return PodMember{}, err
+	}
+	if err := l.images.CreateOverlay(container.Image, l.diskPath(member.ID), member.ID); err != nil {
+		l.releaseAddress(member)
+		return PodMember{}, err
+	}
+	if _, err := l.writeConfigDrive(member.ID, manifest, container); err != nil {
+		l.removeMember(member)
+		return PodMember{}, err
+	}
+	return member, nil
+}
+
+// AbortMigration removes the disks and address prepared for 'member', unless its
+// domain arrived after all.
+func (l *LibvirtRuntime) AbortMigration(member PodMember) error {
+	domain, err := l.conn.LookupDomain(member.ID)
+	if err != nil || domain != nil {
+		return err
+	}
+	return l.removeMember(member)
+}
+
+// MigratePodMember live migrates the domain of 'member' to 'host', where it is renamed to
+// 'name' and runs on the disks prepared for it. The pod network is assumed to have the
+// same name on both hosts, and the interface keeps its MAC address, so that the guest keeps
+// its configuration and gets its new address through DHCP.
+func (l *LibvirtRuntime) MigratePodMember(member PodMember, host, name string) error {
+	domain, err := l.conn.LookupDomain(member.ID)
+	if err != nil {
+		return err
+	}
+	if domain == nil {
+		return fmt.Errorf("domain not found: %s", member.ID)
+	}
+	migrated := *domain
+	migrated.Name = name
+	migrated.Devices.Disks = append([]libvirt.Disk{}, domain.Devices.Disks...)
+	for ix := range migrated.Devices.Disks {
+		source := &migrated.Devices.Disks[ix].Source
+		switch source.File {
+		case l.diskPath(member.ID):
+			source.File = l.diskPath(name)
+		case l.configDrivePath(member.ID):
+			source.File = l.configDrivePath(name)
+		}
+	}
+	migrated.Devices.Serials = append([]libvirt.Serial{}, domain.Devices.Serials...)
+	for ix := range migrated.Devices.Serials {
+		source := migrated.Devices.Serials[ix].Source
+		if source != nil && source.Path == l.consolePath(member.ID) {
+			migrated.Devices.Serials[ix].Source = &libvirt.SerialSource{Path: l.consolePath(name)}
+		}
+	}
+	if err := l.conn.MigrateDomain(member.ID, fmt.Sprintf(l.migrationURI, host), &migrated); err != nil {
+		return err
+	}
+	return l.removeMember(member)
+}
+
+// PullImage fetches 'image' into the image store, if it isn't there already.
+func (l *LibvirtRuntime) PullImage(image string) error {
+	return l.images.Pull(image)
+}
+
+// InspectPodMember returns a *LibvirtDomainInfo for the domain with the given name.
+func (l *LibvirtRuntime) InspectPodMember(id string) (interface{}, error) {
+	domain, err := l.conn.LookupDomain(id)
+	if err != nil {
+		return nil, err
+	}
+	if domain == nil {
+		return nil, nil
+	}
+	state, err := l.conn.DomainState(id)
+	if err != nil {
+		return nil, err
+	}
+	info := &LibvirtDomainInfo{Domain: domain}
+	info.State.Running = state == libvirt.DomainRunning
+	if l.network != nil {
+		podID, containerName := dockerNameToManifestAndContainer(id)
+		if ip := l.network.Lookup(podNetworkKey(podID, containerName)); ip != nil {
+			info.PodIP = ip.String()
+		}
+	}
+	return info, nil
+}
+
+// PodMemberConsole returns the output of the domain's serial console. A followed console
+// ends when the domain stops running.
+func (l *LibvirtRuntime) PodMemberConsole(id string, follow bool) (io.ReadCloser, error) {
+	file, err := os.Open(l.consolePath(id))
+	if err != nil {
+		return nil, err
+	}
+	if !follow {
+		return file, nil
+	}
+	return followFile(file, func() bool {
+		state, err := l.conn.DomainState(id)
+		return err != nil || state != libvirt.DomainRunning
+	}), nil
+}
+
+func (l *LibvirtRuntime) PodMemberStatus(id string) (PodMemberStatus, error) {
+	state, err := l.conn.DomainState(id)
+	if err != nil {
+		return PodMemberUnknown, err
+	}
+	switch state {
+	case libvirt.DomainRunning:
+		return PodMemberRunning, nil
+	case libvirt.DomainShutoff, libvirt.DomainCrashed:
+		return PodMemberStopped, nil
+	default:
+		return PodMemberUnknown, nil
+	}
+}
+
+// Container.Memory is in bytes, libvirt wants KiB.
+func makeDomainMemory(container *api.Container) libvirt.Memory {
+	if container.Memory <= 0 {
+		return libvirt.Memory{Unit: "KiB", Value: defaultDomainMemory}
+	}
+	return libvirt.Memory{Unit: "KiB", Value: uint64(container.Memory) / 1024}
+}
+
+// Container.CPU is in thousandths of a core. A domain gets enough whole vcpus to cover it.
+func makeVCPUs(container *api.Container) int {
+	vcpus := (container.CPU + 999) / 1000
+	if vcpus < 1 {
+		vcpus = 1
+	}
+	return vcpus
+}
+
+// The root disk is vda, volumes are attached in order from vdb. Volumes are disk images
+// in /exports named after the volume.
+func makeDisks(container *api.Container, rootDisk string) []libvirt.Disk {
+	disks := []libvirt.Disk{
+		{
+			Type:   "file",
+			Device: "disk",
+			Driver: libvirt.DiskDriver{Name: "qemu", Type: "qcow2"},
+			Source: libvirt.DiskSource{File: rootDisk},
+			Target: libvirt.DiskTarget{Dev: "vda", Bus: "virtio"},
+		},
+	}
+	for ix, volume := range container.VolumeMounts {
+		disk := libvirt.Disk{
+			Type:   "file",
+			Device: "disk",
+			Driver: libvirt.DiskDriver{Name: "qemu", Type: "raw"},
+			Source: libvirt.DiskSource{File: "/exports/" + volume.Name + ".img"},
+			Target: libvirt.DiskTarget{Dev: fmt.Sprintf("vd%c", 'b'+ix), Bus: "virtio"},
+		}
+		if volume.ReadOnly {
+			disk.ReadOnly = &struct{}{}
+		}
+		disks = append(disks, disk)
+	}
+	return disks
+}
+
+// makeConfigDriveDisk returns the read only drive that holds the config drive 'path'.
+func makeConfigDriveDisk(path string) libvirt.Disk {
+	return libvirt.Disk{
+		Type:     "file",
+		Device:   "cdrom",
+		Driver:   libvirt.DiskDriver{Name: "qemu", Type: "raw"},
+		Source:   libvirt.DiskSource{File: path},
+		Target:   libvirt.DiskTarget{Dev: configDriveTarget, Bus: "ide"},
+		ReadOnly: &struct{}{},
+	}
+}
+
+// hasHostPorts returns whether any of the container's ports is exposed on the host.
+func hasHostPorts(container *api.Container) bool {
+	for _, port := range container.Ports {
+		if port.HostPort != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Ports are forwarded from the host into the guest by QEMU's user mode network stack.
+func makeHostForwards(container *api.Container) []libvirt.QEMUArg {
+	netdev := "user,id=net0"
+	for _, port := range container.Ports {
+		var protocol string
+		switch port.Protocol {
+		case "udp":
+			protocol = "udp"
+		case "tcp":
+			protocol = "tcp"
+		default:
+			if len(port.Protocol) != 0 {
+				log.Printf("Unknown protocol: %s, defaulting to tcp.", port.Protocol)
+			}
+			protocol = "tcp"
+		}
+		if port.HostPort == 0 {
+			log.Printf("No host port for container port %d, not forwarding.", port.ContainerPort)
+			continue
+		}
+		netdev += fmt.Sprintf(",hostfwd=%s::%d-:%d", protocol, port.HostPort, port.ContainerPort)
+	}
+	return []libvirt.QEMUArg{
+		{Value: "-netdev"},
+		{Value: netdev},
+		{Value: "-device"},
+		{Value: "virtio-net-pci,netdev=net0"},
+	}
+}
+
+// makeDomain describes the domain for 'container', whose serial console is logged to 'consoleLog'.
+// If 'iface' is set the domain is attached to the pod network through it, and QEMU's user mode
+// network is only added for host ports.
+func makeDomain(name string, container *api.Container, rootDisk, consoleLog string, iface *libvirt.Interface) *libvirt.Domain {
+	domain := &libvirt.Domain{
+		Type:   "kvm",
+		Name:   name,
+		Memory: makeDomainMemory(container),
+		VCPU:   makeVCPUs(container),
+		OS: libvirt.OS{
+			Type: libvirt.OSType{Value: "hvm"},
+			Boot: []libvirt.Boot{{Dev: "hd"}},
+		},
+		Devices: libvirt.Devices{
+			Disks: makeDisks(container, rootDisk),
+			Serials: []libvirt.Serial{
+				{
+					Type:   "file",
+					Source: &libvirt.SerialSource{Path: consoleLog},
+					Target: libvirt.SerialTarget{Port: 0},
+				},
+			},
+		},
+	}
+	if iface != nil {
+		domain.Devices.Interfaces = []libvirt.Interface{*iface}
+	}
+	if iface == nil || hasHostPorts(container) {
+		domain.QEMUCommandLine = &libvirt.QEMUCommandLine{
+			Args: makeHostForwards(container),
+		}
+	}
+	return domain
+}
@@ -0,0 +1,395 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/libvirt"
+)
+
+// makeTestLibvirtRuntime returns a runtime backed by a fake connection, with an image
+// store containing a single image named "base", and no images to fetch.
+func makeTestLibvirtRuntime(t *testing.T) (*LibvirtRuntime, *libvirt.FakeConnection, func()) {
+	dir, err := ioutil.TempDir("", "libvirt")
+	expectNoError(t, err)
+	imageDir := filepath.Join(dir, "images")
+	diskDir := filepath.Join(dir, "disks")
+	expectNoError(t, os.MkdirAll(imageDir, 0755))
+	expectNoError(t, os.MkdirAll(diskDir, 0755))
+	expectNoError(t, ioutil.WriteFile(filepath.Join(imageDir, "base"), []byte("image data"), 0644))
+	conn := libvirt.MakeFakeConnection()
+	images := MakeImageStore(imageDir, &FakeImageFetcher{}, &FakeOverlayCreator{})
+	return MakeLibvirtRuntime(conn, images, &FakeConfigDriveWriter{}, nil, diskDir, "qemu+ssh://%s/system"), conn, func() { os.RemoveAll(dir) }
+}
+
+func TestMakeVCPUs(t *testing.T) {
+	table := map[int]int{0: 1, 1: 1, 1000: 1, 1001: 2, 4000: 4}
+	for cpu, expected := range table {
+		if vcpus := makeVCPUs(&api.Container{CPU: cpu}); vcpus != expected {
+			t.Errorf("For CPU %d expected %d vcpus, got %d", cpu, expected, vcpus)
+		}
+	}
+}
+
+func TestMakeDomain(t *testing.T) {
+	container := api.Container{
+		Name:   "foo",
+		Image:  "base",
+		Memory: 1024 * 1024 * 1024,
+		CPU:    2000,
+		VolumeMounts: []api.VolumeMount{
+			{Name: "disk", MountPath: "/mnt/path"},
+			{Name: "disk2", MountPath: "/mnt/path2", ReadOnly: true},
+		},
+		Ports: []api.Port{
+			{ContainerPort: 80, HostPort: 8080},
+			{ContainerPort: 53, HostPort: 5353, Protocol: "udp"},
+			{ContainerPort: 443},
+		},
+	}
+	domain := makeDomain("foo--bar--1", &container, "/disks/foo--bar--1.qcow2", "/disks/foo--bar--1.console.log", nil)
+	if domain.Name != "foo--bar--1" || domain.Type != "kvm" {
+		t.Errorf("Unexpected domain: %#v", domain)
+	}
+	if domain.Memory.Unit != "KiB" || domain.Memory.Value != 1024*1024 {
+		t.Errorf("Unexpected memory: %#v", domain.Memory)
+	}
+	if domain.VCPU != 2 {
+		t.Errorf("Unexpected vcpus: %d", domain.VCPU)
+	}
+	disks := domain.Devices.Disks
+	if len(disks) != 3 {
+		t.Fatalf("Unexpected disks: %#v", disks)
+	}
+	if disks[0].Source.File != "/disks/foo--bar--1.qcow2" || disks[0].Target.Dev != "vda" || disks[0].ReadOnly != nil {
+		t.Errorf("Unexpected root disk: %#v", disks[0])
+	}
+	if disks[1].Source.File != "/exports/disk.img" || disks[1].Target.Dev != "vdb" || disks[1].ReadOnly != nil {
+		t.Errorf("Unexpected volume disk: %#v", disks[1])
+	}
+	if disks[2].Source.File != "/exports/disk2.img" || disks[2].Target.Dev != "vdc" || disks[2].ReadOnly == nil {
+		t.Errorf("Unexpected volume disk: %#v", disks[2])
+	}
+	if len(domain.Devices.Serials) != 1 || domain.Devices.Serials[0].Source.Path != "/disks/foo--bar--1.console.log" {
+		t.Errorf("Unexpected serial ports: %#v", domain.Devices.Serials)
+	}
+	expectedArgs := []libvirt.QEMUArg{
+		{Value: "-netdev"},
+		{Value: "user,id=net0,hostfwd=tcp::8080-:80,hostfwd=udp::5353-:53"},
+		{Value: "-device"},
+		{Value: "virtio-net-pci,netdev=net0"},
+	}
+	if !reflect.DeepEqual(expectedArgs, domain.QEMUCommandLine.Args) {
+		t.Errorf("Expected %#v, got %#v", expectedArgs, domain.QEMUCommandLine.Args)
+	}
+}
+
+func TestMakeDomainDefaultMemory(t *testing.T) {
+	domain := makeDomain("foo", &api.Container{}, "/disk", "/console", nil)
+	if domain.Memory.Value != defaultDomainMemory || domain.VCPU != 1 {
+		t.Errorf("Unexpected domain: %#v", domain)
+	}
+}
+
+func TestMakeDomainPodNetwork(t *testing.T) {
+	iface := &libvirt.Interface{Type: "network", Source: libvirt.InterfaceSource{Network: "pods"}}
+	domain := makeDomain("foo", &api.Container{Ports: []api.Port{{ContainerPort: 80}}}, "/disk", "/console", iface)
+	if len(domain.Devices.Interfaces) != 1 || domain.Devices.Interfaces[0].Source.Network != "pods" {
+		t.Errorf("Unexpected interfaces: %#v", domain.Devices.Interfaces)
+	}
+	if domain.QEMUCommandLine != nil {
+		t.Errorf("Unexpected command line without host ports: %#v", domain.QEMUCommandLine)
+	}
+	domain = makeDomain("foo", &api.Container{Ports: []api.Port{{ContainerPort: 80, HostPort: 8080}}}, "/disk", "/console", iface)
+	if len(domain.Devices.Interfaces) != 1 || domain.QEMUCommandLine == nil {
+		t.Errorf("Expected both networks: %#v", domain)
+	}
+}
+
+func TestLibvirtRuntimePullImage(t *testing.T) {
+	runtime, _, cleanup := makeTestLibvirtRuntime(t)
+	defer cleanup()
+	expectNoError(t, runtime.PullImage("base"))
+	if err := runtime.PullImage("missing"); err == nil {
+		t.Error("Unexpected non-error")
+	}
+}
+
+func TestLibvirtRuntimeStartAndStop(t *testing.T) {
+	runtime, conn, cleanup := makeTestLibvirtRuntime(t)
+	defer cleanup()
+	manifest := api.ContainerManifest{Id: "qux"}
+	container := api.Container{Name: "foo", Image: "base"}
+
+	member, err := runtime.StartPodMember(&manifest, &container)
+	expectNoError(t, err)
+	verifyStringArrayEquals(t, conn.Called, []string{"define", "start"})
+	if member.PodID != "qux" || member.ContainerName != "foo" {
+		t.Errorf("Unexpected member: %#v", member)
+	}
+	data, err := ioutil.ReadFile(runtime.diskPath(member.ID))
+	expectNoError(t, err)
+	verifyStringEquals(t, string(data), "overlay of "+filepath.Join(runtime.images.dir, "base")+" (raw)")
+	if refs, _ := runtime.images.References("base"); refs != 1 {
+		t.Errorf("Unexpected references: %d", refs)
+	}
+
+	members, err := runtime.ListPodMembers()
+	expectNoError(t, err)
+	if !reflect.DeepEqual([]PodMember{member}, members) {
+		t.Errorf("Expected %#v, got %#v", []PodMember{member}, members)
+	}
+
+	status, err := runtime.PodMemberStatus(member.ID)
+	expectNoError(t, err)
+	if status != PodMemberRunning {
+		t.Errorf("Unexpected status: %s", status)
+	}
+
+	info, err := runtime.InspectPodMember(member.ID)
+	expectNoError(t, err)
+	if !info.(*LibvirtDomainInfo).State.Running || info.(*LibvirtDomainInfo).Domain.Name != member.ID {
+		t.Errorf("Unexpected info: %#v", info)
+	}
+
+	conn.ClearCalls()
+	expectNoError(t, runtime.StopPodMember(member))
+	verifyStringArrayEquals(t, conn.Called, []string{"state", "destroy", "undefine"})
+	if len(conn.Domains) != 0 {
+		t.Errorf("Unexpected domains: %#v", conn.Domains)
+	}
+	if _, err := os.Stat(runtime.diskPath(member.ID)); !os.IsNotExist(err) {
+		t.Errorf("Expected root disk to be removed: %#v", err)
+	}
+	if refs, _ := runtime.images.References("base"); refs != 0 {
+		t.Errorf("Unexpected references: %d", refs)
+	}
+}
+
+func TestLibvirtRuntimeStartMissingImage(t *testing.T) {
+	runtime, conn, cleanup := makeTestLibvirtRuntime(t)
+	defer cleanup()
+	_, err := runtime.StartPodMember(&api.ContainerManifest{Id: "qux"}, &api.Container{Name: "foo", Image: "missing"})
+	if err == nil {
+		t.Error("Unexpected non-error")
+	}
+	if len(conn.Called) != 0 {
+		t.Errorf("Unexpected calls: %#v", conn.Called)
+	}
+}
+
+// failingStartConnection is a fake connection whose domains never start.
+type failingStartConnection struct {
+	*libvirt.FakeConnection
+}
+
+func (f failingStartConnection) StartDomain(name string) error {
+	return fmt.Errorf("sample error")
+}
+
+func TestLibvirtRuntimeStartFailure(t *testing.T) {
+	runtime, conn, cleanup := makeTestLibvirtRuntime(t)
+	defer cleanup()
+	runtime.conn = failingStartConnection{conn}
+	runtime.network = MakePodNetwork(conn, "pods", "", "")
+	expectNoError(t, runtime.network.Configure("10.244.1.0/24"))
+	_, err := runtime.StartPodMember(&api.ContainerManifest{Id: "qux"}, &api.Container{Name: "foo", Image: "base"})
+	if err == nil {
+		t.Error("Unexpected non-error")
+	}
+	if len(conn.Domains) != 0 {
+		t.Errorf("Unexpected domains: %#v", conn.Domains)
+	}
+	if files, _ := filepath.Glob(filepath.Join(runtime.diskDir, "*")); len(files) != 0 {
+		t.Errorf("Unexpected files: %#v", files)
+	}
+	if refs, _ := runtime.images.References("base"); refs != 0 {
+		t.Errorf("Unexpected references: %d", refs)
+	}
+	if ip := runtime.network.Lookup(podNetworkKey("qux", "foo")); ip != nil {
+		t.Errorf("Unexpected address: %s", ip)
+	}
+}
+
+func TestLibvirtRuntimeIgnoresUnmanagedDomains(t *testing.T) {
+	runtime, conn, cleanup := makeTestLibvirtRuntime(t)
+	defer cleanup()
+	expectNoError(t, conn.DefineDomain(&libvirt.Domain{Name: "manual"}))
+	members, err := runtime.ListPodMembers()
+	expectNoError(t, err)
+	if len(members) != 0 {
+		t.Errorf("Unexpected members: %#v", members)
+	}
+}
+
+func TestSyncManifestsLibvirt(t *testing.T) {
+	runtime, conn, cleanup := makeTestLibvirtRuntime(t)
+	defer cleanup()
+	kubelet := Kubelet{
+		Runtime: runtime,
+	}
+	manifests := []api.ContainerManifest{
+		{
+			Id: "foo",
+			Containers: []api.Container{
+				{Name: "bar", Image: "base"},
+				{Name: "baz", Image: "base"},
+			},
+		},
+	}
+	expectNoError(t, kubelet.SyncManifests(manifests))
+	if len(conn.Domains) != 2 {
+		t.Errorf("Unexpected domains: %#v", conn.Domains)
+	}
+	for name, state := range conn.States {
+		if state != libvirt.DomainRunning {
+			t.Errorf("Domain %s is %s", name, state)
+		}
+	}
+
+	// A second sync with the same manifests changes nothing.
+	conn.ClearCalls()
+	expectNoError(t, kubelet.SyncManifests(manifests))
+	verifyStringArrayEquals(t, conn.Called, []string{"list", "state", "state"})
+
+	// Removing the manifest removes its domains.
+	expectNoError(t, kubelet.SyncManifests([]api.ContainerManifest{}))
+	if len(conn.Domains) != 0 {
+		t.Errorf("Unexpected domains: %#v", conn.Domains)
+	}
+}
+
+func TestGetContainerInfoLibvirt(t *testing.T) {
+	runtime, _, cleanup := makeTestLibvirtRuntime(t)
+	defer cleanup()
+	kubelet := Kubelet{
+		Runtime: runtime,
+	}
+	member, err := runtime.StartPodMember(&api.ContainerManifest{Id: "qux"}, &api.Container{Name: "foo", Image: "base"})
+	expectNoError(t, err)
+	id, found, err := kubelet.GetContainerID("qux")
+	expectNoError(t, err)
+	if !found || id != member.ID {
+		t.Errorf("Unexpected id: %s %v", id, found)
+	}
+	info, err := kubelet.GetContainerInfo(id)
+	expectNoError(t, err)
+	// The master looks for State.Running to determine pod status.
+	var data map[string]interface{}
+	expectNoError(t, json.Unmarshal([]byte(info), &data))
+	if running := data["State"].(map[string]interface{})["Running"]; running != true {
+		t.Errorf("Unexpected info: %s", info)
+	}
+}
+
+func TestLibvirtRuntimePodNetwork(t *testing.T) {
+	runtime, conn, cleanup := makeTestLibvirtRuntime(t)
+	defer cleanup()
+	runtime.network = MakePodNetwork(conn, "pods", "", "")
+	expectNoError(t, runtime.network.Configure("10.244.1.0/24"))
+	kubelet := Kubelet{
+		Runtime: runtime,
+	}
+	manifests := []api.ContainerManifest{
+		{
+			Id:         "foo",
+			Containers: []api.Container{{Name: "bar", Image: "base"}},
+		},
+	}
+	expectNoError(t, kubelet.SyncManifests(manifests))
+	members, err := runtime.ListPodMembers()
+	expectNoError(t, err)
+	if len(members) != 1 {
+		t.Fatalf("Unexpected members: %#v", members)
+	}
+	domain := conn.Domains[members[0].ID]
+	if len(domain.Devices.Interfaces) != 1 || domain.Devices.Interfaces[0].MAC.Address != "52:54:00:ad:23:cb" {
+		t.Errorf("Unexpected interfaces: %#v", domain.Devices.Interfaces)
+	}
+	info, err := runtime.InspectPodMember(members[0].ID)
+	expectNoError(t, err)
+	if info.(*LibvirtDomainInfo).PodIP != "10.244.1.2" {
+		t.Errorf("Unexpected info: %#v", info)
+	}
+
+	// Removing the pod releases its address.
+	expectNoError(t, kubelet.SyncManifests([]api.ContainerManifest{}))
+	if ip := runtime.network.Lookup(podNetworkKey("foo", "bar")); ip != nil {
+		t.Errorf("Unexpected address after removal: %s", ip)
+	}
+	if hosts := conn.Networks["pods"].IPs[0].DHCP.Hosts; len(hosts) != 0 {
+		t.Errorf("Unexpected DHCP hosts: %#v", hosts)
+	}
+}
+
+func TestLibvirtRuntimeConfigDrive(t *testing.T) {
+	runtime, conn, cleanup := makeTestLibvirtRuntime(t)
+	defer cleanup()
+	kubelet := Kubelet{
+		Runtime: runtime,
+	}
+	manifests := []api.ContainerManifest{
+		{
+			Id:         "foo",
+			Containers: []api.Container{{Name: "bar", Image: "base", Env: []api.EnvVar{{Name: "A", Value: "1"}}}},
+		},
+	}
+	expectNoError(t, kubelet.SyncManifests(manifests))
+	members, err := runtime.ListPodMembers()
+	expectNoError(t, err)
+	if len(members) != 1 {
+		t.Fatalf("Unexpected members: %#v", members)
+	}
+	name := members[0].ID
+	disks := conn.Domains[name].Devices.Disks
+	drive := disks[len(disks)-1]
+	if drive.Device != "cdrom" || drive.Target.Dev != "hdc" || drive.Source.File != runtime.configDrivePath(name) {
+		t.Errorf("Unexpected config drive: %#v", drive)
+	}
+	if _, err := os.Stat(runtime.configDrivePath(name)); err != nil {
+		t.Errorf("Expected a config drive: %#v", err)
+	}
+
+	// A changed manifest rebuilds the drive and gives it to the running domain.
+	manifests[0].Containers[0].Env[0].Value = "2"
+	conn.ClearCalls()
+	expectNoError(t, kubelet.SyncManifests(manifests))
+	verifyStringArrayEquals(t, conn.Called, []string{"list", "state", "lookup", "change-media"})
+	data, err := ioutil.ReadFile(filepath.Join(runtime.configDataDir(name), "user-data"))
+	expectNoError(t, err)
+	expected, err := makeUserData(&manifests[0].Containers[0])
+	expectNoError(t, err)
+	verifyStringEquals(t, string(data), string(expected))
+	if _, ok := conn.Domains[name]; !ok {
+		t.Errorf("Expected %s to keep running: %#v", name, conn.Domains)
+	}
+
+	expectNoError(t, kubelet.SyncManifests([]api.ContainerManifest{}))
+	for _, file := range []string{runtime.configDrivePath(name), runtime.configDataDir(name)} {
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed: %#v", file, err)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// migrationTimeout is how long a pod stays marked as migrating if the master never moves it
+// from the manifests of the source host to those of the destination.
+var migrationTimeout = 10 * time.Minute
+
+// podMigrations tracks the pods that are being migrated to or from this host. SyncManifests
+// leaves the members of those pods alone: the source still has the pod in its manifests while
+// the members move away, and the destination runs members of a pod that isn't in its
+// manifests yet.
+type podMigrations struct {
+	lock     sync.Mutex
+	incoming map[string]*podMigration
+	outgoing map[string]*podMigration
+}
+
+type podMigration struct {
+	deadline time.Time
+	// prepared are the members that the destination prepared to receive.
+	prepared []PodMember
+}
+
+func (m *podMigrations) startIncoming(podID string, prepared []PodMember) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.incoming == nil {
+		m.incoming = map[string]*podMigration{}
+	}
+	m.incoming[podID] = &podMigration{deadline: time.Now().Add(migrationTimeout), prepared: prepared}
+}
+
+// startOutgoing marks 'podID' as leaving this host. It returns false if the pod is already migrating.
+func (m *podMigrations) startOutgoing(podID string) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.outgoing == nil {
+		m.outgoing = map[string]*podMigration{}
+	}
+	if _, found := m.outgoing[podID]; found {
+		return false
+	}
+	m.outgoing[podID] = &podMigration{deadline: time.Now().Add(migrationTimeout)}
+	return true
+}
+
+func (m *podMigrations) cancelOutgoing(podID string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.outgoing, podID)
+}
+
+// endIncoming ends the migration of 'podID' to this host, and returns the members that were
+// prepared for it. It returns false if the pod isn't migrating here.
+func (m *podMigrations) endIncoming(podID string) ([]PodMember, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	migration, found := m.incoming[podID]
+	if !found {
+		return nil, false
+	}
+	delete(m.incoming, podID)
+	return migration.prepared, true
+}
+
+func (m *podMigrations) migrating(podID string) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	_, incoming := m.incoming[podID]
+	_, outgoing := m.outgoing[podID]
+	return incoming || outgoing
+}
+
+// update ends the migrations that 'config' reflects: incoming pods that are in it, and outgoing
+// pods that are gone from it. Migrations past their deadline end too, and the members prepared
+// for incoming pods that timed out are returned.
+func (m *podMigrations) update(config []api.ContainerManifest, now time.Time) []PodMember {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	present := map[string]bool{}
+	for _, manifest := range config {
+		present[manifest.Id] = true
+	}
+	abandoned := []PodMember{}
+	for podID, migration := range m.incoming {
+		if present[podID] {
+			delete(m.incoming, podID)
+		} else if now.After(migration.deadline) {
+			log.Printf("Migration of %s to this host timed out", podID)
+			abandoned = append(abandoned, migration.prepared...)
+			delete(m.incoming, podID)
+		}
+	}
+	for podID, migration := range m.outgoing {
+		if !present[podID] {
+			delete(m.outgoing, podID)
+		} else if now.After(migration.deadline) {
+			log.Printf("Migration of %s from this host timed out", podID)
+			delete(m.outgoing, podID)
+		}
+	}
+	return abandoned
+}
+
+// syncMigrations ends migrations as described in podMigrations.update, and cleans up after
+// members that never arrived.
+func (kl *Kubelet) syncMigrations(config []api.ContainerManifest) {
+	abandoned := kl.migrations.update(config, time.Now())
+	runtime, ok := kl.Runtime.(MigratingRuntime)
+	if !ok {
+		return
+	}
+	for _, member := range abandoned {
+		if err := runtime.AbortMigration(member); err != nil {
+			log.Printf("Error cleaning up after %s: %#v", member.Name, err)
+		}
+	}
+}
+
+// PrepareMigration readies this host to receive the members of the pod described by 'manifest'
+// from another host. It returns the names the members will have here, by container name.
+func (kl *Kubelet) PrepareMigration(manifest api.ContainerManifest) (map[string]string, error) {
+	runtime, ok := kl.Runtime.(MigratingRuntime)
+	if !ok {
+		return nil, fmt.Errorf("the runtime doesn't support migration")
+	}
+	names := map[string]string{}
+	prepared := []PodMember{}
+	for ix := range manifest.Containers {
+		container := &manifest.Containers[ix]
+		member, err := runtime.PrepareMigration(&manifest, container)
+		if err != nil {
+			for _, member := range prepared {
+				runtime.AbortMigration(member)
+			}
+			return nil, err
+		}
+		names[container.Name] = member.Name
+		prepared = append(prepared, member)
+	}
+	kl.migrations.startIncoming(manifest.Id, prepared)
+	return names, nil
+}
+
+// AbortMigration gives up on receiving pod 'podID' from another host: the members that arrived
+// are stopped, and the disks and addresses prepared for the others are removed.
+func (kl *Kubelet) AbortMigration(podID string) error {
+	runtime, ok := kl.Runtime.(MigratingRuntime)
+	if !ok {
+		return fmt.Errorf("the runtime doesn't support migration")
+	}
+	members, err := kl.Runtime.ListPodMembers()
+	if err != nil {
+		return err
+	}
+	arrived := map[string]bool{}
+	for _, member := range members {
+		if member.PodID == podID {
+			arrived[member.ID] = true
+		}
+	}
+	prepared, found := kl.migrations.endIncoming(podID)
+	if !found {
+		return fmt.Errorf("pod %s isn't migrating to this host", podID)
+	}
+	for _, member := range prepared {
+		if arrived[member.ID] {
+			err = kl.Runtime.StopPodMember(member)
+		} else {
+			err = runtime.AbortMigration(member)
+		}
+		if err != nil {
+			log.Printf("Error cleaning up after %s: %#v", member.Name, err)
+		}
+	}
+	return err
+}
+
+// MigratePod live migrates the members of a pod to the host in 'migration', and returns once
+// they run there. If it fails, the members that didn't move are left running here.
+func (kl *Kubelet) MigratePod(migration api.PodMigration) error {
+	runtime, ok := kl.Runtime.(MigratingRuntime)
+	if !ok {
+		return fmt.Errorf("the runtime doesn't support migration")
+	}
+	members, err := kl.Runtime.ListPodMembers()
+	if err != nil {
+		return err
+	}
+	migrating := []PodMember{}
+	for _, member := range members {
+		if member.PodID != migration.PodID {
+			continue
+		}
+		if _, found := migration.Members[member.ContainerName]; !found {
+			return fmt.Errorf("%s wasn't prepared on %s", member.ContainerName, migration.Target)
+		}
+		migrating = append(migrating, member)
+	}
+	if len(migrating) == 0 {
+		return fmt.Errorf("pod %s isn't running here", migration.PodID)
+	}
+	if !kl.migrations.startOutgoing(migration.PodID) {
+		return fmt.Errorf("pod %s is already migrating", migration.PodID)
+	}
+	for _, member := range migrating {
+		err := runtime.MigratePodMember(member, migration.Target, migration.Members[member.ContainerName])
+		if err != nil {
+			kl.migrations.cancelOutgoing(migration.PodID)
+			return err
+		}
+	}
+	return nil
+}
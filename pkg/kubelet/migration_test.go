@@ -0,0 +1,146 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+func TestMigratePod(t *testing.T) {
+	source, sourceConn, cleanupSource := makeTestLibvirtRuntime(t)
+	defer cleanupSource()
+	dest, destConn, cleanupDest := makeTestLibvirtRuntime(t)
+	defer cleanupDest()
+	sourceConn.Peers["qemu+ssh://dest/system"] = destConn
+	sourceKubelet := &Kubelet{Runtime: source}
+	destKubelet := &Kubelet{Runtime: dest}
+	manifests := []api.ContainerManifest{
+		{
+			Id:         "foo",
+			Containers: []api.Container{{Name: "bar", Image: "base"}},
+		},
+	}
+	expectNoError(t, sourceKubelet.SyncManifests(manifests))
+
+	names, err := destKubelet.PrepareMigration(manifests[0])
+	expectNoError(t, err)
+	name := names["bar"]
+	if _, err := os.Stat(dest.diskPath(name)); err != nil {
+		t.Errorf("Expected a root disk for %s: %#v", name, err)
+	}
+	expectNoError(t, sourceKubelet.MigratePod(api.PodMigration{PodID: "foo", Target: "dest", Members: names}))
+	if len(sourceConn.Domains) != 0 {
+		t.Errorf("Unexpected domains on the source: %#v", sourceConn.Domains)
+	}
+	domain := destConn.Domains[name]
+	if domain == nil || destConn.States[name] != "running" {
+		t.Fatalf("Expected %s to run on the destination: %#v", name, destConn.Domains)
+	}
+	verifyStringEquals(t, domain.Devices.Disks[0].Source.File, source.diskPath(name))
+	verifyStringEquals(t, domain.Devices.Serials[0].Source.Path, source.consolePath(name))
+
+	// Until the master moves the pod, neither kubelet touches its members.
+	expectNoError(t, sourceKubelet.SyncManifests(manifests))
+	expectNoError(t, destKubelet.SyncManifests([]api.ContainerManifest{}))
+	if len(sourceConn.Domains) != 0 || len(destConn.Domains) != 1 {
+		t.Errorf("Unexpected domains: %#v %#v", sourceConn.Domains, destConn.Domains)
+	}
+
+	expectNoError(t, sourceKubelet.SyncManifests([]api.ContainerManifest{}))
+	expectNoError(t, destKubelet.SyncManifests(manifests))
+	if sourceKubelet.migrations.migrating("foo") || destKubelet.migrations.migrating("foo") {
+		t.Error("Expected the migration to be over")
+	}
+	if len(destConn.Domains) != 1 || destConn.Domains[name] == nil {
+		t.Errorf("Unexpected domains: %#v", destConn.Domains)
+	}
+}
+
+func TestAbortMigration(t *testing.T) {
+	source, sourceConn, cleanupSource := makeTestLibvirtRuntime(t)
+	defer cleanupSource()
+	dest, destConn, cleanupDest := makeTestLibvirtRuntime(t)
+	defer cleanupDest()
+	sourceConn.Peers["qemu+ssh://dest/system"] = destConn
+	sourceKubelet := &Kubelet{Runtime: source}
+	destKubelet := &Kubelet{Runtime: dest}
+	manifest := api.ContainerManifest{
+		Id:         "foo",
+		Containers: []api.Container{{Name: "bar", Image: "base"}},
+	}
+	expectNoError(t, sourceKubelet.SyncManifests([]api.ContainerManifest{manifest}))
+
+	// Only bar runs on the source, so baz is prepared but never arrives.
+	prepared := manifest
+	prepared.Containers = append(prepared.Containers, api.Container{Name: "baz", Image: "base"})
+	names, err := destKubelet.PrepareMigration(prepared)
+	expectNoError(t, err)
+	expectNoError(t, sourceKubelet.MigratePod(api.PodMigration{PodID: "foo", Target: "dest", Members: names}))
+	if destConn.Domains[names["bar"]] == nil {
+		t.Fatalf("Expected %s to run on the destination: %#v", names["bar"], destConn.Domains)
+	}
+
+	expectNoError(t, destKubelet.AbortMigration("foo"))
+	if len(destConn.Domains) != 0 {
+		t.Errorf("Unexpected domains: %#v", destConn.Domains)
+	}
+	for _, name := range names {
+		if _, err := os.Stat(dest.diskPath(name)); !os.IsNotExist(err) {
+			t.Errorf("Expected the root disk of %s to be removed: %#v", name, err)
+		}
+	}
+	if destKubelet.migrations.migrating("foo") {
+		t.Error("Expected the migration to be over")
+	}
+	verifyError(t, destKubelet.AbortMigration("foo"))
+}
+
+func TestMigratePodNotRunning(t *testing.T) {
+	runtime, _, cleanup := makeTestLibvirtRuntime(t)
+	defer cleanup()
+	kubelet := &Kubelet{Runtime: runtime}
+	err := kubelet.MigratePod(api.PodMigration{PodID: "foo", Target: "dest", Members: map[string]string{}})
+	verifyError(t, err)
+	if kubelet.migrations.migrating("foo") {
+		t.Error("Unexpected migration")
+	}
+}
+
+func TestPrepareMigrationTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { migrationTimeout = timeout }(migrationTimeout)
+	migrationTimeout = -time.Second
+	runtime, _, cleanup := makeTestLibvirtRuntime(t)
+	defer cleanup()
+	kubelet := &Kubelet{Runtime: runtime}
+	manifest := api.ContainerManifest{
+		Id:         "foo",
+		Containers: []api.Container{{Name: "bar", Image: "base"}},
+	}
+	names, err := kubelet.PrepareMigration(manifest)
+	expectNoError(t, err)
+	expectNoError(t, kubelet.SyncManifests([]api.ContainerManifest{}))
+	if _, err := os.Stat(runtime.diskPath(names["bar"])); !os.IsNotExist(err) {
+		t.Errorf("Expected the prepared root disk to be removed: %#v", err)
+	}
+	if refs, _ := runtime.images.References("base"); refs != 0 {
+		t.Errorf("Unexpected references: %d", refs)
+	}
+}
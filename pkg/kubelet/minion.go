@@ -0,0 +1,147 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// MasterInterface is the part of the API client the kubelet uses to report to the master.
+// It is an interface to allow testing.
+type MasterInterface interface {
+	GetMinion(name string) (api.Minion, error)
+	CreateMinion(api.Minion) (api.Minion, error)
+	UpdateMinion(api.Minion) (api.Minion, error)
+	MinionHeartbeat(name string) (api.Minion, error)
+}
+
+// makeMinion describes this host to the master.
+func (kl *Kubelet) makeMinion() api.Minion {
+	minion := api.Minion{
+		JSONBase: api.JSONBase{ID: strings.TrimSpace(kl.Hostname)},
+		HostIP:   kl.HostIP,
+		Capacity: kl.Capacity,
+	}
+	if kl.PodNetwork != nil {
+		minion.PodCIDR = kl.PodNetwork.CIDR()
+	}
+	return minion
+}
+
+// configurePodNetwork sets up the pod network with the subnet the master assigned to this host,
+// if it isn't set up yet.
+func (kl *Kubelet) configurePodNetwork() {
+	if kl.PodNetwork == nil || len(kl.PodNetwork.CIDR()) > 0 {
+		return
+	}
+	minion, err := kl.Master.GetMinion(strings.TrimSpace(kl.Hostname))
+	if err != nil {
+		log.Printf("Error getting the pod subnet: %#v", err)
+		return
+	}
+	if len(minion.PodCIDR) == 0 {
+		log.Printf("The master assigned no pod subnet to %s", kl.Hostname)
+		return
+	}
+	if err := kl.PodNetwork.Configure(minion.PodCIDR); err != nil {
+		log.Printf("Error configuring the pod network: %#v", err)
+	}
+}
+
+// register creates the minion of this host. A host that is already registered, e.g. when the
+// kubelet restarts, has its minion updated with what the kubelet knows about it.
+func (kl *Kubelet) register() error {
+	minion := kl.makeMinion()
+	_, err := kl.Master.CreateMinion(minion)
+	if !api.IsAlreadyExists(err) {
+		return err
+	}
+	existing, err := kl.Master.GetMinion(minion.ID)
+	if err != nil {
+		return err
+	}
+	existing.HostIP = minion.HostIP
+	existing.Capacity = minion.Capacity
+	if len(minion.PodCIDR) > 0 {
+		existing.PodCIDR = minion.PodCIDR
+	}
+	_, err = kl.Master.UpdateMinion(existing)
+	return err
+}
+
+// RunHeartbeats registers this host with the master, and then sends it a heartbeat every HeartbeatFrequency.
+// Until the pod network has a subnet, each heartbeat also asks the master for one.
+// It returns if registration fails, otherwise it loops forever.  It is intended to be run as a goroutine.
+func (kl *Kubelet) RunHeartbeats() {
+	if err := kl.register(); err != nil {
+		log.Printf("Error registering with the master: %#v", err)
+		return
+	}
+	log.Printf("Registered %s with the master", kl.Hostname)
+	name := strings.TrimSpace(kl.Hostname)
+	for {
+		if _, err := kl.Master.MinionHeartbeat(name); err != nil {
+			log.Printf("Error sending heartbeat: %#v", err)
+		} else {
+			kl.configurePodNetwork()
+		}
+		time.Sleep(kl.HeartbeatFrequency)
+	}
+}
+
+// parseMemInfo returns the total memory in bytes from the contents of /proc/meminfo.
+func parseMemInfo(data []byte) (int, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kb, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return 0, err
+		}
+		return kb * 1024, nil
+	}
+	return 0, fmt.Errorf("no MemTotal in meminfo")
+}
+
+// MachineCapacity returns the memory (in bytes) and CPU (in millicores) of this machine.
+func MachineCapacity() (api.MinionResources, error) {
+	data, err := ioutil.ReadFile("/proc/meminfo")
+	if err != nil {
+		return api.MinionResources{}, err
+	}
+	memory, err := parseMemInfo(data)
+	if err != nil {
+		return api.MinionResources{}, err
+	}
+	return api.MinionResources{
+		Memory: memory,
+		CPU:    runtime.NumCPU() * 1000,
+	}, nil
+}
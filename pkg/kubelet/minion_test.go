@@ -0,0 +1,186 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/libvirt"
+)
+
+type FakeMaster struct {
+	lock       sync.Mutex
+	created    []api.Minion
+	updated    []api.Minion
+	heartbeats []string
+	podCIDR    string
+	err        error
+	// If set, returned by CreateMinion instead of err.
+	createErr error
+}
+
+func (f *FakeMaster) GetMinion(name string) (api.Minion, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return api.Minion{JSONBase: api.JSONBase{ID: name}, PodCIDR: f.podCIDR}, f.err
+}
+
+func (f *FakeMaster) CreateMinion(minion api.Minion) (api.Minion, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.created = append(f.created, minion)
+	if f.createErr != nil {
+		return minion, f.createErr
+	}
+	return minion, f.err
+}
+
+func (f *FakeMaster) UpdateMinion(minion api.Minion) (api.Minion, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.updated = append(f.updated, minion)
+	return minion, f.err
+}
+
+func (f *FakeMaster) MinionHeartbeat(name string) (api.Minion, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.heartbeats = append(f.heartbeats, name)
+	return api.Minion{JSONBase: api.JSONBase{ID: name}, Condition: api.MinionReady}, f.err
+}
+
+func (f *FakeMaster) counts() (int, int, int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.created), len(f.updated), len(f.heartbeats)
+}
+
+func TestMakeMinion(t *testing.T) {
+	kubelet := Kubelet{
+		Hostname: "machine\n",
+		HostIP:   "10.0.0.1",
+		Capacity: api.MinionResources{Memory: 1024, CPU: 2000},
+	}
+	expected := api.Minion{
+		JSONBase: api.JSONBase{ID: "machine"},
+		HostIP:   "10.0.0.1",
+		Capacity: api.MinionResources{Memory: 1024, CPU: 2000},
+	}
+	if minion := kubelet.makeMinion(); !reflect.DeepEqual(minion, expected) {
+		t.Errorf("Unexpected minion: %#v, expected %#v", minion, expected)
+	}
+}
+
+func TestRunHeartbeats(t *testing.T) {
+	master := &FakeMaster{}
+	kubelet := Kubelet{
+		Hostname:           "machine",
+		Master:             master,
+		HeartbeatFrequency: time.Millisecond,
+	}
+	go kubelet.RunHeartbeats()
+	for i := 0; i < 1000; i++ {
+		if _, _, heartbeats := master.counts(); heartbeats >= 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	created, updated, heartbeats := master.counts()
+	if created != 1 || updated != 0 || heartbeats < 2 {
+		t.Errorf("Unexpected calls: %d creates, %d updates, %d heartbeats", created, updated, heartbeats)
+	}
+	master.lock.Lock()
+	defer master.lock.Unlock()
+	if master.heartbeats[0] != "machine" {
+		t.Errorf("Unexpected heartbeats: %#v", master.heartbeats)
+	}
+}
+
+func TestRunHeartbeatsAlreadyRegistered(t *testing.T) {
+	master := &FakeMaster{createErr: api.NewAlreadyExists("minion", "machine"), podCIDR: "10.244.1.0/24"}
+	kubelet := Kubelet{
+		Hostname:           "machine",
+		HostIP:             "10.0.0.1",
+		Master:             master,
+		HeartbeatFrequency: time.Millisecond,
+	}
+	go kubelet.RunHeartbeats()
+	for i := 0; i < 1000; i++ {
+		if _, _, heartbeats := master.counts(); heartbeats >= 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, _, heartbeats := master.counts(); heartbeats < 1 {
+		t.Errorf("Expected heartbeats from a kubelet that was already registered")
+	}
+	master.lock.Lock()
+	defer master.lock.Unlock()
+	// The registered minion is updated, and keeps the pod subnet the master assigned it.
+	if len(master.updated) != 1 || master.updated[0].HostIP != "10.0.0.1" || master.updated[0].PodCIDR != "10.244.1.0/24" {
+		t.Errorf("Unexpected updates: %#v", master.updated)
+	}
+}
+
+func TestRunHeartbeatsConfiguresPodNetwork(t *testing.T) {
+	master := &FakeMaster{podCIDR: "10.244.1.0/24"}
+	kubelet := Kubelet{
+		Hostname:           "machine",
+		Master:             master,
+		PodNetwork:         MakePodNetwork(libvirt.MakeFakeConnection(), "pods", "", ""),
+		HeartbeatFrequency: time.Millisecond,
+	}
+	go kubelet.RunHeartbeats()
+	for i := 0; i < 1000; i++ {
+		if _, _, heartbeats := master.counts(); heartbeats >= 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	verifyStringEquals(t, kubelet.PodNetwork.CIDR(), "10.244.1.0/24")
+}
+
+func TestRunHeartbeatsRegistrationFailure(t *testing.T) {
+	master := &FakeMaster{err: fmt.Errorf("test error")}
+	kubelet := Kubelet{
+		Hostname:           "machine",
+		Master:             master,
+		HeartbeatFrequency: time.Millisecond,
+	}
+	// Returns rather than sending heartbeats.
+	kubelet.RunHeartbeats()
+	created, updated, heartbeats := master.counts()
+	if created != 1 || updated != 0 || heartbeats != 0 {
+		t.Errorf("Unexpected calls: %d creates, %d updates, %d heartbeats", created, updated, heartbeats)
+	}
+}
+
+func TestParseMemInfo(t *testing.T) {
+	memory, err := parseMemInfo([]byte("MemTotal:        2048 kB\nMemFree:          1024 kB\n"))
+	expectNoError(t, err)
+	if memory != 2048*1024 {
+		t.Errorf("Unexpected memory: %d", memory)
+	}
+	if _, err := parseMemInfo([]byte("MemFree: 1024 kB\n")); err == nil {
+		t.Errorf("Expected error for missing MemTotal")
+	}
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/ipam"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/libvirt"
+)
+
+// PodNetwork gives pod members their own IP addresses on a routed libvirt network, which
+// is bridged to this minion's pod subnet. Addresses are handed to guests by libvirt's DHCP
+// server, and the leases are kept in a file so they survive kubelet restarts.
+type PodNetwork struct {
+	conn      libvirt.Connection
+	name      string
+	bridge    string
+	leaseFile string
+
+	lock sync.Mutex
+	// leases is nil until the network is configured with a subnet.
+	leases *ipam.LeaseStore
+}
+
+// MakePodNetwork returns a pod network backed by the libvirt network 'name' on bridge 'bridge'.
+// It can't hand out addresses until Configure is called.
+func MakePodNetwork(conn libvirt.Connection, name, bridge, leaseFile string) *PodNetwork {
+	return &PodNetwork{
+		conn:      conn,
+		name:      name,
+		bridge:    bridge,
+		leaseFile: leaseFile,
+	}
+}
+
+// Name returns the name of the libvirt network.
+func (p *PodNetwork) Name() string {
+	return p.name
+}
+
+// CIDR returns the pod subnet, or "" if the network isn't configured yet.
+func (p *PodNetwork) CIDR() string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.leases == nil {
+		return ""
+	}
+	return p.leases.Subnet().String()
+}
+
+// Configure sets the pod subnet to 'cidr', and makes sure the libvirt network is defined and
+// running with this minion as the gateway. It can be called again with the same subnet, but
+// the subnet can't be changed once set.
+func (p *PodNetwork) Configure(cidr string) error {
+	_, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.leases != nil && p.leases.Subnet().String() != subnet.String() {
+		return fmt.Errorf("pod network is already configured with %s, not %s", p.leases.Subnet(), subnet)
+	}
+	if err := p.ensureNetwork(subnet); err != nil {
+		return err
+	}
+	if p.leases != nil {
+		return nil
+	}
+	leases, err := ipam.MakeLeaseStore(subnet, p.leaseFile)
+	if err != nil {
+		return err
+	}
+	p.leases = leases
+	log.Printf("Configured pod network %s with %s", p.name, subnet)
+	return nil
+}
+
+// ensureNetwork defines the libvirt network for 'subnet' if it doesn't exist, and starts it.
+func (p *PodNetwork) ensureNetwork(subnet *net.IPNet) error {
+	network, err := p.conn.LookupNetwork(p.name)
+	if err != nil {
+		return err
+	}
+	if network == nil {
+		network = &libvirt.Network{
+			Name:    p.name,
+			Forward: &libvirt.NetworkForward{Mode: "route"},
+			IPs: []libvirt.NetworkIP{
+				{
+					Address: ipam.GatewayIP(subnet).String(),
+					Netmask: net.IP(subnet.Mask).String(),
+					DHCP:    &libvirt.NetworkDHCP{},
+				},
+			},
+		}
+		if len(p.bridge) > 0 {
+			network.Bridge = &libvirt.NetworkBridge{Name: p.bridge}
+		}
+		if err := p.conn.DefineNetwork(network); err != nil {
+			return err
+		}
+	}
+	active, err := p.conn.NetworkActive(p.name)
+	if err != nil || active {
+		return err
+	}
+	return p.conn.StartNetwork(p.name)
+}
+
+// makeMAC returns the hardware address for the interface of the member with lease key 'key'.
+// It is derived from the key rather than the address, so that it stays the same when a
+// member is migrated to another minion, and gets an address in that minion's subnet.
+func makeMAC(key string) string {
+	hash := sha1.Sum([]byte(key))
+	return fmt.Sprintf("52:54:00:%02x:%02x:%02x", hash[0], hash[1], hash[2])
+}
+
+// Allocate returns the address and MAC address leased to 'key', leasing a new address and
+// reserving it in the network's DHCP server if 'key' has none.
+func (p *PodNetwork) Allocate(key string) (net.IP, string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.leases == nil {
+		return nil, "", fmt.Errorf("pod network %s is not configured", p.name)
+	}
+	mac := makeMAC(key)
+	if ip := p.leases.Lookup(key); ip != nil {
+		return ip, mac, nil
+	}
+	for _, owner := range p.leases.Owners() {
+		if makeMAC(owner) == mac {
+			return nil, "", fmt.Errorf("the MAC address of %s is already used by %s", key, owner)
+		}
+	}
+	ip, err := p.leases.Allocate(key)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := p.conn.AddDHCPHost(p.name, libvirt.DHCPHost{MAC: mac, IP: ip.String()}); err != nil {
+		p.leases.Release(key)
+		return nil, "", err
+	}
+	return ip, mac, nil
+}
+
+// Release frees the address leased to 'key', if any.
+func (p *PodNetwork) Release(key string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.leases == nil {
+		return nil
+	}
+	ip := p.leases.Lookup(key)
+	if ip == nil {
+		return nil
+	}
+	if err := p.conn.RemoveDHCPHost(p.name, libvirt.DHCPHost{MAC: makeMAC(key), IP: ip.String()}); err != nil {
+		return err
+	}
+	return p.leases.Release(key)
+}
+
+// Lookup returns the address leased to 'key', or nil if it has none.
+func (p *PodNetwork) Lookup(key string) net.IP {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.leases == nil {
+		return nil
+	}
+	return p.leases.Lookup(key)
+}
+
+// podNetworkKey is the lease key of the member running 'containerName' in pod 'podID'. Unlike
+// member names, it is the same for every instance of the member, so that a restarted member
+// keeps its address.
+func podNetworkKey(podID, containerName string) string {
+	return escapeDash(podID) + "--" + escapeDash(containerName)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/libvirt"
+)
+
+func TestPodNetworkConfigure(t *testing.T) {
+	conn := libvirt.MakeFakeConnection()
+	network := MakePodNetwork(conn, "pods", "virbr-pods", "")
+	if _, _, err := network.Allocate("foo--bar"); err == nil {
+		t.Error("Unexpected non-error allocating before configuration")
+	}
+	expectNoError(t, network.Configure("10.244.1.0/24"))
+	verifyStringArrayEquals(t, conn.Called, []string{"net-lookup", "net-define", "net-active", "net-start"})
+	defined := conn.Networks["pods"]
+	if defined.Bridge.Name != "virbr-pods" || defined.IPs[0].Address != "10.244.1.1" || defined.IPs[0].Netmask != "255.255.255.0" {
+		t.Errorf("Unexpected network: %#v", defined)
+	}
+	if !conn.ActiveNetworks["pods"] {
+		t.Error("Expected the network to be started")
+	}
+
+	conn.ClearCalls()
+	expectNoError(t, network.Configure("10.244.1.0/24"))
+	verifyStringArrayEquals(t, conn.Called, []string{"net-lookup", "net-active"})
+	if err := network.Configure("10.244.2.0/24"); err == nil {
+		t.Error("Unexpected non-error changing the subnet")
+	}
+	verifyStringEquals(t, network.CIDR(), "10.244.1.0/24")
+}
+
+func TestPodNetworkLeases(t *testing.T) {
+	dir, err := ioutil.TempDir("", "podnetwork")
+	expectNoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "leases.json")
+	conn := libvirt.MakeFakeConnection()
+	network := MakePodNetwork(conn, "pods", "", file)
+	expectNoError(t, network.Configure("10.244.1.0/24"))
+
+	ip, mac, err := network.Allocate("foo--bar")
+	expectNoError(t, err)
+	verifyStringEquals(t, ip.String(), "10.244.1.2")
+	verifyStringEquals(t, mac, "52:54:00:ad:23:cb")
+	again, _, err := network.Allocate("foo--bar")
+	expectNoError(t, err)
+	if !again.Equal(ip) {
+		t.Errorf("Expected the same address, got %s", again)
+	}
+	hosts := conn.Networks["pods"].IPs[0].DHCP.Hosts
+	if len(hosts) != 1 || hosts[0].MAC != mac || hosts[0].IP != "10.244.1.2" {
+		t.Errorf("Unexpected DHCP hosts: %#v", hosts)
+	}
+
+	// A restarted kubelet finds the lease.
+	restarted := MakePodNetwork(conn, "pods", "", file)
+	expectNoError(t, restarted.Configure("10.244.1.0/24"))
+	if found := restarted.Lookup("foo--bar"); !found.Equal(ip) {
+		t.Errorf("Expected %s, got %s", ip, found)
+	}
+
+	expectNoError(t, restarted.Release("foo--bar"))
+	if found := restarted.Lookup("foo--bar"); found != nil {
+		t.Errorf("Unexpected address after release: %s", found)
+	}
+	if hosts := conn.Networks["pods"].IPs[0].DHCP.Hosts; len(hosts) != 0 {
+		t.Errorf("Unexpected DHCP hosts: %#v", hosts)
+	}
+}
+
+func TestPodNetworkAllocateError(t *testing.T) {
+	conn := libvirt.MakeFakeConnection()
+	network := MakePodNetwork(conn, "pods", "", "")
+	expectNoError(t, network.Configure("10.244.1.0/24"))
+	conn.ActiveNetworks["pods"] = false
+	if _, _, err := network.Allocate("foo--bar"); err == nil {
+		t.Error("Unexpected non-error")
+	}
+	if ip := network.Lookup("foo--bar"); ip != nil {
+		t.Errorf("Unexpected lease after a failed allocation: %s", ip)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"io"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// PodMember is a single running member of a pod, for example a Docker container or a
+// libvirt domain. There is one member for each container in the pod's manifest.
+type PodMember struct {
+	// ID is the runtime's identifier for the member.
+	ID string
+	// Name is the runtime's name for the member.
+	Name string
+	// PodID is the id of the manifest the member belongs to.
+	PodID string
+	// ContainerName is the name of the container in the manifest that the member runs.
+	ContainerName string
+}
+
+// PodMemberStatus is the state of a pod member as reported by its runtime.
+type PodMemberStatus string
+
+const (
+	PodMemberRunning PodMemberStatus = "Running"
+	PodMemberStopped PodMemberStatus = "Stopped"
+	PodMemberUnknown PodMemberStatus = "Unknown"
+)
+
+// Runtime is the interface the kubelet uses to run pods on its host. Implementations
+// map the members of a pod onto a concrete backend such as Docker.
+type Runtime interface {
+	// ListPodMembers returns all pod members managed by this runtime on this host.
+	ListPodMembers() ([]PodMember, error)
+	// StartPodMember creates and starts the member for 'container' in the pod described by 'manifest'.
+	StartPodMember(manifest *api.ContainerManifest, container *api.Container) (PodMember, error)
+	// StopPodMember stops a running member.
+	StopPodMember(member PodMember) error
+	// PullImage makes 'image' available on the host, fetching it if needed.
+	PullImage(image string) error
+	// InspectPodMember returns the runtime specific description of the member with the given id.
+	InspectPodMember(id string) (interface{}, error)
+	// PodMemberStatus returns the state of the member with the given id.
+	PodMemberStatus(id string) (PodMemberStatus, error)
+}
+
+// ConsoleRuntime is implemented by runtimes whose pod members have a console, such as the
+// serial console of a VM.
+type ConsoleRuntime interface {
+	// PodMemberConsole returns the console output of the member with the given id. If 'follow'
+	// is set, reads wait for new output until the member stops or the reader is closed.
+	PodMemberConsole(id string, follow bool) (io.ReadCloser, error)
+}
+
+// UpdatingRuntime is implemented by runtimes that apply changes to the manifest of a running
+// member without restarting it, such as the configuration a VM reads when it boots.
+type UpdatingRuntime interface {
+	// UpdatePodMember applies 'container' from 'manifest' to its running member.
+	UpdatePodMember(member PodMember, manifest *api.ContainerManifest, container *api.Container) error
+}
+
+// MigratingRuntime is implemented by runtimes that can move running pod members to another
+// host, such as libvirt's live migration of VMs.
+type MigratingRuntime interface {
+	// PrepareMigration readies this host to receive the member for 'container' in the pod
+	// described by 'manifest', and returns the member it will arrive as.
+	PrepareMigration(manifest *api.ContainerManifest, container *api.Container) (PodMember, error)
+	// AbortMigration cleans up after a prepared member that never arrived.
+	AbortMigration(member PodMember) error
+	// MigratePodMember moves a running member to 'host', which prepared it as 'name'.
+	MigratePodMember(member PodMember, host, name string) error
+}
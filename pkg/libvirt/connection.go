@@ -0,0 +1,283 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// DomainState is the run state of a domain, as reported by libvirt.
+type DomainState string
+
+const (
+	DomainRunning  DomainState = "running"
+	DomainPaused   DomainState = "paused"
+	DomainShutoff  DomainState = "shut off"
+	DomainCrashed  DomainState = "crashed"
+	DomainNoState  DomainState = "no state"
+	DomainShutdown DomainState = "in shutdown"
+)
+
+// Connection is a connection to a single libvirt daemon.
+// It is an interface to allow testing without a hypervisor.
+type Connection interface {
+	// ListDomains returns the names of all domains defined on the host, running or not.
+	ListDomains() ([]string, error)
+	// LookupDomain returns the description of the named domain, or nil if it isn't defined.
+	LookupDomain(name string) (*Domain, error)
+	// DomainState returns the run state of the named domain.
+	DomainState(name string) (DomainState, error)
+	// DefineDomain defines a new persistent domain, without starting it.
+	DefineDomain(domain *Domain) error
+	// StartDomain boots a defined domain.
+	StartDomain(name string) error
+	// DestroyDomain forcibly powers off a running domain.
+	DestroyDomain(name string) error
+	// UndefineDomain removes the definition of a domain that isn't running.
+	UndefineDomain(name string) error
+	// ChangeMedia replaces the medium in the removable drive 'target' (e.g. "hdc") of a running
+	// domain with the file 'source'.
+	ChangeMedia(name, target, source string) error
+	// MigrateDomain live migrates a running domain, along with its disks, to the libvirt daemon
+	// at 'destURI', where it runs as 'domain'. The domain is undefined on this host afterwards.
+	MigrateDomain(name, destURI string, domain *Domain) error
+
+	// LookupNetwork returns the description of the named virtual network, or nil if it isn't defined.
+	LookupNetwork(name string) (*Network, error)
+	// NetworkActive returns whether the named network is started.
+	NetworkActive(name string) (bool, error)
+	// DefineNetwork defines a new persistent virtual network, without starting it.
+	DefineNetwork(network *Network) error
+	// StartNetwork starts a defined network, and marks it to be started when the host boots.
+	StartNetwork(name string) error
+	// AddDHCPHost adds a DHCP reservation to a running network.
+	AddDHCPHost(network string, host DHCPHost) error
+	// RemoveDHCPHost removes the DHCP reservation for the host's MAC address from a running network.
+	RemoveDHCPHost(network string, host DHCPHost) error
+}
+
+// VirshConnection implements Connection by running the virsh command line tool.
+type VirshConnection struct {
+	// URI is the libvirt connection URI, e.g. qemu:///system
+	URI string
+}
+
+// MakeVirshConnection creates a connection to the libvirt daemon at 'uri'.
+func MakeVirshConnection(uri string) *VirshConnection {
+	return &VirshConnection{
+		URI: uri,
+	}
+}
+
+func (v *VirshConnection) virsh(args ...string) ([]byte, error) {
+	if len(v.URI) > 0 {
+		args = append([]string{"-c", v.URI}, args...)
+	}
+	cmd := exec.Command("virsh", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return out, fmt.Errorf("virsh %s failed: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// parseNameList parses the output of 'virsh list --name', one name per line.
+func parseNameList(out []byte) []string {
+	result := []string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) > 0 {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+func (v *VirshConnection) ListDomains() ([]string, error) {
+	out, err := v.virsh("list", "--all", "--name")
+	if err != nil {
+		return nil, err
+	}
+	return parseNameList(out), nil
+}
+
+func (v *VirshConnection) LookupDomain(name string) (*Domain, error) {
+	names, err := v.ListDomains()
+	if err != nil {
+		return nil, err
+	}
+	if !containsName(names, name) {
+		return nil, nil
+	}
+	out, err := v.virsh("dumpxml", name)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalDomain(out)
+}
+
+func (v *VirshConnection) DomainState(name string) (DomainState, error) {
+	out, err := v.virsh("domstate", name)
+	if err != nil {
+		return DomainNoState, err
+	}
+	return DomainState(strings.TrimSpace(string(out))), nil
+}
+
+// writeXMLFile writes 'data' to a temporary file for virsh to read. The caller removes it.
+func writeXMLFile(data []byte) (string, error) {
+	file, err := ioutil.TempFile("", "libvirt")
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(data)
+	file.Close()
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// defineFromXML runs a virsh define command on a temporary file holding 'data'.
+func (v *VirshConnection) defineFromXML(command string, data []byte) error {
+	file, err := writeXMLFile(data)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file)
+	_, err = v.virsh(command, file)
+	return err
+}
+
+func (v *VirshConnection) DefineDomain(domain *Domain) error {
+	data, err := MarshalDomain(domain)
+	if err != nil {
+		return err
+	}
+	return v.defineFromXML("define", data)
+}
+
+func (v *VirshConnection) StartDomain(name string) error {
+	_, err := v.virsh("start", name)
+	return err
+}
+
+func (v *VirshConnection) DestroyDomain(name string) error {
+	_, err := v.virsh("destroy", name)
+	return err
+}
+
+func (v *VirshConnection) UndefineDomain(name string) error {
+	_, err := v.virsh("undefine", name)
+	return err
+}
+
+func (v *VirshConnection) ChangeMedia(name, target, source string) error {
+	_, err := v.virsh("change-media", name, target, source, "--update", "--live", "--config")
+	return err
+}
+
+// MigrateDomain copies the domain's disks incrementally, so the destination must already have
+// overlays at the disk paths of 'domain', backed by the same base images.
+func (v *VirshConnection) MigrateDomain(name, destURI string, domain *Domain) error {
+	data, err := MarshalDomain(domain)
+	if err != nil {
+		return err
+	}
+	file, err := writeXMLFile(data)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file)
+	_, err = v.virsh("migrate", "--live", "--persistent", "--undefinesource", "--copy-storage-inc",
+		"--dname", domain.Name, "--xml", file, name, destURI)
+	return err
+}
+
+func containsName(names []string, name string) bool {
+	for _, value := range names {
+		if value == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *VirshConnection) LookupNetwork(name string) (*Network, error) {
+	out, err := v.virsh("net-list", "--all", "--name")
+	if err != nil {
+		return nil, err
+	}
+	if !containsName(parseNameList(out), name) {
+		return nil, nil
+	}
+	out, err = v.virsh("net-dumpxml", name)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalNetwork(out)
+}
+
+func (v *VirshConnection) NetworkActive(name string) (bool, error) {
+	out, err := v.virsh("net-list", "--name")
+	if err != nil {
+		return false, err
+	}
+	return containsName(parseNameList(out), name), nil
+}
+
+func (v *VirshConnection) DefineNetwork(network *Network) error {
+	data, err := MarshalNetwork(network)
+	if err != nil {
+		return err
+	}
+	return v.defineFromXML("net-define", data)
+}
+
+func (v *VirshConnection) StartNetwork(name string) error {
+	if _, err := v.virsh("net-start", name); err != nil {
+		return err
+	}
+	_, err := v.virsh("net-autostart", name)
+	return err
+}
+
+func (v *VirshConnection) updateDHCPHost(command, network string, host DHCPHost) error {
+	data, err := xml.Marshal(host)
+	if err != nil {
+		return err
+	}
+	_, err = v.virsh("net-update", network, command, "ip-dhcp-host", string(data), "--live", "--config")
+	return err
+}
+
+func (v *VirshConnection) AddDHCPHost(network string, host DHCPHost) error {
+	return v.updateDHCPHost("add", network, host)
+}
+
+func (v *VirshConnection) RemoveDHCPHost(network string, host DHCPHost) error {
+	return v.updateDHCPHost("delete", network, host)
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNameList(t *testing.T) {
+	out := []byte("foo--bar--1234\n  baz \n\n")
+	names := parseNameList(out)
+	expected := []string{"foo--bar--1234", "baz"}
+	if !reflect.DeepEqual(expected, names) {
+		t.Errorf("Expected %#v, got %#v", expected, names)
+	}
+	if names := parseNameList([]byte("\n")); len(names) != 0 {
+		t.Errorf("Unexpected names: %#v", names)
+	}
+}
+
+func TestFakeConnectionLifecycle(t *testing.T) {
+	conn := MakeFakeConnection()
+	err := conn.DefineDomain(&Domain{Name: "foo"})
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if err := conn.DefineDomain(&Domain{Name: "foo"}); err == nil {
+		t.Error("Unexpected non-error defining a duplicate domain")
+	}
+	if state, _ := conn.DomainState("foo"); state != DomainShutoff {
+		t.Errorf("Unexpected state: %s", state)
+	}
+	if err := conn.StartDomain("foo"); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if state, _ := conn.DomainState("foo"); state != DomainRunning {
+		t.Errorf("Unexpected state: %s", state)
+	}
+	if err := conn.DestroyDomain("foo"); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if err := conn.UndefineDomain("foo"); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	names, err := conn.ListDomains()
+	if err != nil || len(names) != 0 {
+		t.Errorf("Unexpected domains: %#v %#v", names, err)
+	}
+}
+
+func TestFakeConnectionNetwork(t *testing.T) {
+	conn := MakeFakeConnection()
+	host := DHCPHost{MAC: "52:54:00:0a:01:02", IP: "10.1.1.2"}
+	if err := conn.AddDHCPHost("pods", host); err == nil {
+		t.Error("Unexpected non-error adding a host to a missing network")
+	}
+	network := &Network{
+		Name: "pods",
+		IPs:  []NetworkIP{{Address: "10.1.1.1", Netmask: "255.255.255.0"}},
+	}
+	if err := conn.DefineNetwork(network); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if active, _ := conn.NetworkActive("pods"); active {
+		t.Error("Unexpected active network before start")
+	}
+	if err := conn.StartNetwork("pods"); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if err := conn.AddDHCPHost("pods", host); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if err := conn.AddDHCPHost("pods", host); err == nil {
+		t.Error("Unexpected non-error adding a duplicate host")
+	}
+	out, _ := conn.LookupNetwork("pods")
+	if out == nil || len(out.IPs[0].DHCP.Hosts) != 1 {
+		t.Errorf("Unexpected network: %#v", out)
+	}
+	if err := conn.RemoveDHCPHost("pods", host); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if len(out.IPs[0].DHCP.Hosts) != 0 {
+		t.Errorf("Unexpected hosts: %#v", out.IPs[0].DHCP.Hosts)
+	}
+	if out, _ := conn.LookupNetwork("other"); out != nil {
+		t.Errorf("Unexpected network: %#v", out)
+	}
+}
+
+func TestFakeConnectionMigrate(t *testing.T) {
+	conn := MakeFakeConnection()
+	peer := MakeFakeConnection()
+	conn.Peers["qemu+ssh://dest/system"] = peer
+	conn.DefineDomain(&Domain{Name: "foo"})
+	if err := conn.MigrateDomain("foo", "qemu+ssh://dest/system", &Domain{Name: "bar"}); err == nil {
+		t.Error("Unexpected non-error migrating a domain that isn't running")
+	}
+	conn.StartDomain("foo")
+	if err := conn.MigrateDomain("foo", "qemu+ssh://other/system", &Domain{Name: "bar"}); err == nil {
+		t.Error("Unexpected non-error migrating to an unknown host")
+	}
+	if err := conn.MigrateDomain("foo", "qemu+ssh://dest/system", &Domain{Name: "bar"}); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if domain, _ := conn.LookupDomain("foo"); domain != nil {
+		t.Errorf("Unexpected domain on the source: %#v", domain)
+	}
+	if state, _ := peer.DomainState("bar"); state != DomainRunning {
+		t.Errorf("Unexpected state on the destination: %s", state)
+	}
+}
+
+func TestFakeConnectionChangeMedia(t *testing.T) {
+	conn := MakeFakeConnection()
+	conn.DefineDomain(&Domain{
+		Name: "foo",
+		Devices: Devices{
+			Disks: []Disk{{Device: "cdrom", Source: DiskSource{File: "/old.iso"}, Target: DiskTarget{Dev: "hdc"}}},
+		},
+	})
+	conn.StartDomain("foo")
+	if err := conn.ChangeMedia("foo", "hdc", "/new.iso"); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if file := conn.Domains["foo"].Devices.Disks[0].Source.File; file != "/new.iso" {
+		t.Errorf("Unexpected source: %s", file)
+	}
+	if err := conn.ChangeMedia("foo", "hdd", "/new.iso"); err == nil {
+		t.Error("Unexpected non-error changing a missing drive")
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"encoding/xml"
+)
+
+// QEMUNamespace is the XML namespace of libvirt's QEMU specific domain extensions.
+const QEMUNamespace = "http://libvirt.org/schemas/domain/qemu/1.0"
+
+// Domain is the XML description of a libvirt domain (a virtual machine).
+// Only the parts of the schema used by Kubernetes are represented.
+// See http://libvirt.org/formatdomain.html
+type Domain struct {
+	XMLName         xml.Name         `xml:"domain"`
+	Type            string           `xml:"type,attr"`
+	Name            string           `xml:"name"`
+	UUID            string           `xml:"uuid,omitempty"`
+	Memory          Memory           `xml:"memory"`
+	VCPU            int              `xml:"vcpu"`
+	OS              OS               `xml:"os"`
+	Devices         Devices          `xml:"devices"`
+	QEMUCommandLine *QEMUCommandLine `xml:"http://libvirt.org/schemas/domain/qemu/1.0 commandline,omitempty"`
+}
+
+// Memory is an amount of memory, in units of Unit (e.g. "KiB").
+type Memory struct {
+	Unit  string `xml:"unit,attr,omitempty"`
+	Value uint64 `xml:",chardata"`
+}
+
+// OS describes how the domain boots.
+type OS struct {
+	Type OSType `xml:"type"`
+	Boot []Boot `xml:"boot"`
+}
+
+// OSType is the type of operating system the domain runs, usually "hvm".
+type OSType struct {
+	Arch  string `xml:"arch,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+// Boot is a device to boot from, e.g. "hd".
+type Boot struct {
+	Dev string `xml:"dev,attr"`
+}
+
+// Devices holds the devices attached to a domain.
+type Devices struct {
+	Disks      []Disk      `xml:"disk"`
+	Interfaces []Interface `xml:"interface"`
+	Serials    []Serial    `xml:"serial"`
+}
+
+// Disk is a block device backed by a file on the host.
+type Disk struct {
+	Type     string     `xml:"type,attr"`
+	Device   string     `xml:"device,attr"`
+	Driver   DiskDriver `xml:"driver"`
+	Source   DiskSource `xml:"source"`
+	Target   DiskTarget `xml:"target"`
+	ReadOnly *struct{}  `xml:"readonly"`
+}
+
+// DiskDriver selects the hypervisor driver and image format of a disk, e.g. qemu and qcow2.
+type DiskDriver struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// DiskSource is the host file backing a disk.
+type DiskSource struct {
+	File string `xml:"file,attr"`
+}
+
+// DiskTarget is how a disk is exposed to the guest, e.g. vda on the virtio bus.
+type DiskTarget struct {
+	Dev string `xml:"dev,attr"`
+	Bus string `xml:"bus,attr,omitempty"`
+}
+
+// Interface is a network interface of the domain.
+type Interface struct {
+	Type   string          `xml:"type,attr"`
+	MAC    *InterfaceMAC   `xml:"mac"`
+	Source InterfaceSource `xml:"source"`
+	Model  InterfaceModel  `xml:"model"`
+}
+
+// InterfaceMAC is the hardware address of an interface, e.g. 52:54:00:00:00:01.
+type InterfaceMAC struct {
+	Address string `xml:"address,attr"`
+}
+
+// InterfaceSource names the network or bridge an interface is attached to.
+type InterfaceSource struct {
+	Network string `xml:"network,attr,omitempty"`
+	Bridge  string `xml:"bridge,attr,omitempty"`
+}
+
+// InterfaceModel is the device model presented to the guest, e.g. virtio.
+type InterfaceModel struct {
+	Type string `xml:"type,attr"`
+}
+
+// Serial is a serial port of the guest. With type "file", everything the guest writes to
+// the port is saved to Source.Path on the host.
+type Serial struct {
+	Type   string        `xml:"type,attr"`
+	Source *SerialSource `xml:"source"`
+	Target SerialTarget  `xml:"target"`
+}
+
+type SerialSource struct {
+	Path string `xml:"path,attr"`
+}
+
+type SerialTarget struct {
+	Port int `xml:"port,attr"`
+}
+
+// QEMUCommandLine holds extra arguments passed straight to the QEMU process.
+type QEMUCommandLine struct {
+	Args []QEMUArg `xml:"http://libvirt.org/schemas/domain/qemu/1.0 arg"`
+}
+
+// QEMUArg is a single QEMU command line argument.
+type QEMUArg struct {
+	Value string `xml:"value,attr"`
+}
+
+// MarshalDomain returns the XML description of 'domain'.
+func MarshalDomain(domain *Domain) ([]byte, error) {
+	return xml.MarshalIndent(domain, "", "  ")
+}
+
+// UnmarshalDomain parses an XML domain description.
+func UnmarshalDomain(data []byte) (*Domain, error) {
+	var domain Domain
+	err := xml.Unmarshal(data, &domain)
+	if err != nil {
+		return nil, err
+	}
+	return &domain, nil
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarshalUnmarshalDomain(t *testing.T) {
+	domain := &Domain{
+		Type:   "kvm",
+		Name:   "foo",
+		Memory: Memory{Unit: "KiB", Value: 524288},
+		VCPU:   2,
+		OS: OS{
+			Type: OSType{Value: "hvm"},
+			Boot: []Boot{{Dev: "hd"}},
+		},
+		Devices: Devices{
+			Disks: []Disk{
+				{
+					Type:     "file",
+					Device:   "disk",
+					Driver:   DiskDriver{Name: "qemu", Type: "raw"},
+					Source:   DiskSource{File: "/exports/data.img"},
+					Target:   DiskTarget{Dev: "vdb", Bus: "virtio"},
+					ReadOnly: &struct{}{},
+				},
+			},
+			Serials: []Serial{
+				{Type: "file", Source: &SerialSource{Path: "/disks/foo.log"}, Target: SerialTarget{Port: 0}},
+			},
+		},
+		QEMUCommandLine: &QEMUCommandLine{
+			Args: []QEMUArg{{Value: "-netdev"}, {Value: "user,id=net0"}},
+		},
+	}
+	data, err := MarshalDomain(domain)
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	if !strings.Contains(string(data), QEMUNamespace) {
+		t.Errorf("Expected QEMU namespace in %s", string(data))
+	}
+	out, err := UnmarshalDomain(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	out.XMLName = domain.XMLName
+	if !reflect.DeepEqual(domain, out) {
+		t.Errorf("Expected %#v, got %#v", domain, out)
+	}
+}
+
+func TestUnmarshalLibvirtDomain(t *testing.T) {
+	// As produced by 'virsh dumpxml', with a namespace prefix for the QEMU extensions.
+	data := `<domain type='kvm' xmlns:qemu='http://libvirt.org/schemas/domain/qemu/1.0'>
+  <name>foo</name>
+  <memory unit='KiB'>1048576</memory>
+  <vcpu placement='static'>1</vcpu>
+  <devices>
+    <disk type='file' device='disk'>
+      <driver name='qemu' type='qcow2'/>
+      <source file='/var/lib/kubelet/foo.qcow2'/>
+      <target dev='vda' bus='virtio'/>
+    </disk>
+  </devices>
+  <qemu:commandline>
+    <qemu:arg value='-netdev'/>
+  </qemu:commandline>
+</domain>`
+	domain, err := UnmarshalDomain([]byte(data))
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	if domain.Name != "foo" || domain.Memory.Value != 1048576 || domain.VCPU != 1 {
+		t.Errorf("Unexpected domain: %#v", domain)
+	}
+	if len(domain.Devices.Disks) != 1 || domain.Devices.Disks[0].Source.File != "/var/lib/kubelet/foo.qcow2" ||
+		domain.Devices.Disks[0].ReadOnly != nil {
+		t.Errorf("Unexpected disks: %#v", domain.Devices.Disks)
+	}
+	if domain.QEMUCommandLine == nil || len(domain.QEMUCommandLine.Args) != 1 || domain.QEMUCommandLine.Args[0].Value != "-netdev" {
+		t.Errorf("Unexpected QEMU command line: %#v", domain.QEMUCommandLine)
+	}
+}
+
+func TestUnmarshalDomainError(t *testing.T) {
+	_, err := UnmarshalDomain([]byte("<domain"))
+	if err == nil {
+		t.Error("Unexpected non-error")
+	}
+}
+
+func TestMarshalUnmarshalNetwork(t *testing.T) {
+	network := &Network{
+		Name:    "pods",
+		Forward: &NetworkForward{Mode: "route"},
+		Bridge:  &NetworkBridge{Name: "virbr-pods"},
+		IPs: []NetworkIP{
+			{
+				Address: "10.244.1.1",
+				Netmask: "255.255.255.0",
+				DHCP: &NetworkDHCP{
+					Hosts: []DHCPHost{{MAC: "52:54:00:f4:01:02", IP: "10.244.1.2"}},
+				},
+			},
+		},
+	}
+	data, err := MarshalNetwork(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	out, err := UnmarshalNetwork(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	out.XMLName = network.XMLName
+	for i := range out.IPs[0].DHCP.Hosts {
+		out.IPs[0].DHCP.Hosts[i].XMLName = network.IPs[0].DHCP.Hosts[i].XMLName
+	}
+	if !reflect.DeepEqual(network, out) {
+		t.Errorf("Expected %#v, got %#v", network, out)
+	}
+}
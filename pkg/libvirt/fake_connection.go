@@ -0,0 +1,291 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// FakeConnection is an in-memory Connection, for testing code that uses libvirt
+// on machines without a hypervisor.
+type FakeConnection struct {
+	lock    sync.Mutex
+	Domains map[string]*Domain
+	States  map[string]DomainState
+	// Networks are the defined networks, and ActiveNetworks the ones that are started.
+	Networks       map[string]*Network
+	ActiveNetworks map[string]bool
+	// Peers are the connections that domains can be migrated to, by URI.
+	Peers map[string]*FakeConnection
+	// Err, if set, is returned from every call.
+	Err    error
+	Called []string
+}
+
+func MakeFakeConnection() *FakeConnection {
+	return &FakeConnection{
+		Domains:        map[string]*Domain{},
+		States:         map[string]DomainState{},
+		Networks:       map[string]*Network{},
+		ActiveNetworks: map[string]bool{},
+		Peers:          map[string]*FakeConnection{},
+	}
+}
+
+func (f *FakeConnection) appendCall(call string) {
+	f.Called = append(f.Called, call)
+}
+
+// ClearCalls resets the list of recorded calls.
+func (f *FakeConnection) ClearCalls() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.Called = []string{}
+}
+
+func (f *FakeConnection) ListDomains() ([]string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.appendCall("list")
+	names := []string{}
+	for name := range f.Domains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, f.Err
+}
+
+func (f *FakeConnection) LookupDomain(name string) (*Domain, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.appendCall("lookup")
+	return f.Domains[name], f.Err
+}
+
+func (f *FakeConnection) DomainState(name string) (DomainState, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.appendCall("state")
+	if _, ok := f.Domains[name]; !ok {
+		return DomainNoState, fmt.Errorf("domain not found: %s", name)
+	}
+	return f.States[name], f.Err
+}
+
+func (f *FakeConnection) DefineDomain(domain *Domain) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.appendCall("define")
+	if f.Err != nil {
+		return f.Err
+	}
+	if _, ok := f.Domains[domain.Name]; ok {
+		return fmt.Errorf("domain already exists: %s", domain.Name)
+	}
+	f.Domains[domain.Name] = domain
+	f.States[domain.Name] = DomainShutoff
+	return nil
+}
+
+func (f *FakeConnection) StartDomain(name string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.appendCall("start")
+	if f.Err != nil {
+		return f.Err
+	}
+	if _, ok := f.Domains[name]; !ok {
+		return fmt.Errorf("domain not found: %s", name)
+	}
+	f.States[name] = DomainRunning
+	return nil
+}
+
+func (f *FakeConnection) DestroyDomain(name string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.appendCall("destroy")
+	if f.Err != nil {
+		return f.Err
+	}
+	if f.States[name] != DomainRunning {
+		return fmt.Errorf("domain is not running: %s", name)
+	}
+	f.States[name] = DomainShutoff
+	return nil
+}
+
+func (f *FakeConnection) UndefineDomain(name string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.appendCall("undefine")
+	if f.Err != nil {
+		return f.Err
+	}
+	if _, ok := f.Domains[name]; !ok {
+		return fmt.Errorf("domain not found: %s", name)
+	}
+	delete(f.Domains, name)
+	delete(f.States, name)
+	return nil
+}
+
+func (f *FakeConnection) ChangeMedia(name, target, source string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.appendCall("change-media")
+	if f.Err != nil {
+		return f.Err
+	}
+	domain, ok := f.Domains[name]
+	if !ok || f.States[name] != DomainRunning {
+		return fmt.Errorf("domain is not running: %s", name)
+	}
+	for ix := range domain.Devices.Disks {
+		if domain.Devices.Disks[ix].Target.Dev == target {
+			domain.Devices.Disks[ix].Source.File = source
+			return nil
+		}
+	}
+	return fmt.Errorf("domain %s has no drive %s", name, target)
+}
+
+func (f *FakeConnection) MigrateDomain(name, destURI string, domain *Domain) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.appendCall("migrate")
+	if f.Err != nil {
+		return f.Err
+	}
+	if f.States[name] != DomainRunning {
+		return fmt.Errorf("domain is not running: %s", name)
+	}
+	peer, ok := f.Peers[destURI]
+	if !ok {
+		return fmt.Errorf("unable to connect to %s", destURI)
+	}
+	peer.lock.Lock()
+	defer peer.lock.Unlock()
+	if _, ok := peer.Domains[domain.Name]; ok {
+		return fmt.Errorf("domain already exists on %s: %s", destURI, domain.Name)
+	}
+	peer.Domains[domain.Name] = domain
+	peer.States[domain.Name] = DomainRunning
+	delete(f.Domains, name)
+	delete(f.States, name)
+	return nil
+}
+
+func (f *FakeConnection) LookupNetwork(name string) (*Network, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.appendCall("net-lookup")
+	return f.Networks[name], f.Err
+}
+
+func (f *FakeConnection) NetworkActive(name string) (bool, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.appendCall("net-active")
+	return f.ActiveNetworks[name], f.Err
+}
+
+func (f *FakeConnection) DefineNetwork(network *Network) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.appendCall("net-define")
+	if f.Err != nil {
+		return f.Err
+	}
+	if _, ok := f.Networks[network.Name]; ok {
+		return fmt.Errorf("network already exists: %s", network.Name)
+	}
+	f.Networks[network.Name] = network
+	return nil
+}
+
+func (f *FakeConnection) StartNetwork(name string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.appendCall("net-start")
+	if f.Err != nil {
+		return f.Err
+	}
+	if _, ok := f.Networks[name]; !ok {
+		return fmt.Errorf("network not found: %s", name)
+	}
+	f.ActiveNetworks[name] = true
+	return nil
+}
+
+// runningNetworkIP returns the IP element of a started network that DHCP hosts go in.
+func (f *FakeConnection) runningNetworkIP(name string) (*NetworkIP, error) {
+	network, ok := f.Networks[name]
+	if !ok || !f.ActiveNetworks[name] {
+		return nil, fmt.Errorf("network is not running: %s", name)
+	}
+	if len(network.IPs) == 0 {
+		return nil, fmt.Errorf("network has no ip: %s", name)
+	}
+	ip := &network.IPs[0]
+	if ip.DHCP == nil {
+		ip.DHCP = &NetworkDHCP{}
+	}
+	return ip, nil
+}
+
+func (f *FakeConnection) AddDHCPHost(network string, host DHCPHost) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.appendCall("dhcp-add")
+	if f.Err != nil {
+		return f.Err
+	}
+	ip, err := f.runningNetworkIP(network)
+	if err != nil {
+		return err
+	}
+	for _, existing := range ip.DHCP.Hosts {
+		if existing.MAC == host.MAC || existing.IP == host.IP {
+			return fmt.Errorf("dhcp host already exists: %#v", existing)
+		}
+	}
+	ip.DHCP.Hosts = append(ip.DHCP.Hosts, host)
+	return nil
+}
+
+func (f *FakeConnection) RemoveDHCPHost(network string, host DHCPHost) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.appendCall("dhcp-remove")
+	if f.Err != nil {
+		return f.Err
+	}
+	ip, err := f.runningNetworkIP(network)
+	if err != nil {
+		return err
+	}
+	for i, existing := range ip.DHCP.Hosts {
+		if existing.MAC == host.MAC {
+			ip.DHCP.Hosts = append(ip.DHCP.Hosts[:i], ip.DHCP.Hosts[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("dhcp host not found: %s", host.MAC)
+}
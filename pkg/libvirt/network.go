@@ -0,0 +1,77 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"encoding/xml"
+)
+
+// Network is the XML description of a libvirt virtual network.
+// Only the parts of the schema used by Kubernetes are represented.
+// See http://libvirt.org/formatnetwork.html
+type Network struct {
+	XMLName xml.Name        `xml:"network"`
+	Name    string          `xml:"name"`
+	Forward *NetworkForward `xml:"forward"`
+	Bridge  *NetworkBridge  `xml:"bridge"`
+	IPs     []NetworkIP     `xml:"ip"`
+}
+
+// NetworkForward is how traffic leaves the network, e.g. "route" or "nat".
+type NetworkForward struct {
+	Mode string `xml:"mode,attr"`
+}
+
+// NetworkBridge is the host bridge device the network is built on.
+type NetworkBridge struct {
+	Name string `xml:"name,attr"`
+}
+
+// NetworkIP is the address of the host on the network, and the DHCP service for the subnet.
+type NetworkIP struct {
+	Address string       `xml:"address,attr"`
+	Netmask string       `xml:"netmask,attr,omitempty"`
+	DHCP    *NetworkDHCP `xml:"dhcp"`
+}
+
+// NetworkDHCP holds the static DHCP reservations of a network.
+type NetworkDHCP struct {
+	Hosts []DHCPHost `xml:"host"`
+}
+
+// DHCPHost reserves an IP address for the interface with the given MAC address.
+type DHCPHost struct {
+	XMLName xml.Name `xml:"host"`
+	MAC     string   `xml:"mac,attr"`
+	Name    string   `xml:"name,attr,omitempty"`
+	IP      string   `xml:"ip,attr"`
+}
+
+// MarshalNetwork returns the XML description of 'network'.
+func MarshalNetwork(network *Network) ([]byte, error) {
+	return xml.MarshalIndent(network, "", "  ")
+}
+
+// UnmarshalNetwork parses an XML network description.
+func UnmarshalNetwork(data []byte) (*Network, error) {
+	var network Network
+	err := xml.Unmarshal(data, &network)
+	if err != nil {
+		return nil, err
+	}
+	return &network, nil
+}
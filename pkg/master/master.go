@@ -17,79 +17,166 @@ limitations under the License.
 package master
 
 import (
+	"crypto/tls"
+	"fmt"
+	"log"
 	"math/rand"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/admission"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/audit"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/cloudprovider"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/ipam"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/registry"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/coreos/go-etcd/etcd"
 )
 
+// Minions that haven't sent a heartbeat for this long are not scheduled onto.
+const minionHeartbeatTimeout = 60 * time.Second
+
 // Master contains state for a Kubernetes cluster master/api server.
 type Master struct {
 	podRegistry        registry.PodRegistry
 	controllerRegistry registry.ControllerRegistry
 	serviceRegistry    registry.ServiceRegistry
+	minionRegistry     registry.MinionRegistry
+	containerInfo      client.ContainerInfo
 
-	minions []string
-	random  *rand.Rand
-	storage map[string]apiserver.RESTStorage
+	random   *rand.Rand
+	storage  map[string]apiserver.RESTStorage
+	requests *auth.Requests
 }
 
-// Returns a memory (not etcd) backed apiserver.
-func NewMemoryServer(minions []string, cloud cloudprovider.Interface) *Master {
+// Returns a memory (not etcd) backed apiserver. If 'podSubnets' is set, minions are assigned pod subnets from it.
+// 'kubeletPort' is the port the kubelets of minions serve on.
+func NewMemoryServer(minions []string, cloud cloudprovider.Interface, podSubnets *ipam.SubnetAllocator, kubeletPort uint) *Master {
 	m := &Master{
 		podRegistry:        registry.MakeMemoryRegistry(),
 		controllerRegistry: registry.MakeMemoryRegistry(),
 		serviceRegistry:    registry.MakeMemoryRegistry(),
+		minionRegistry:     registry.MakeMemoryRegistry(),
 	}
-	m.init(minions, cloud)
+	m.init(minions, cloud, podSubnets, kubeletPort)
 	return m
 }
 
-// Returns a new apiserver.
-func New(etcdServers, minions []string, cloud cloudprovider.Interface) *Master {
+// Returns a new apiserver. If 'podSubnets' is set, minions are assigned pod subnets from it.
+// 'kubeletPort' is the port the kubelets of minions serve on.
+func New(etcdServers, minions []string, cloud cloudprovider.Interface, podSubnets *ipam.SubnetAllocator, kubeletPort uint) *Master {
 	etcdClient := etcd.NewClient(etcdServers)
+	etcdRegistry := registry.MakeEtcdRegistry(etcdClient, nil)
 	m := &Master{
-		podRegistry:        registry.MakeEtcdRegistry(etcdClient, minions),
-		controllerRegistry: registry.MakeEtcdRegistry(etcdClient, minions),
-		serviceRegistry:    registry.MakeEtcdRegistry(etcdClient, minions),
+		podRegistry:        etcdRegistry,
+		controllerRegistry: etcdRegistry,
+		serviceRegistry:    etcdRegistry,
+		minionRegistry:     etcdRegistry,
+	}
+	m.init(minions, cloud, podSubnets, kubeletPort)
+	if err := etcdRegistry.MigrateLegacyKeys(); err != nil {
+		log.Printf("Failed to move objects stored before namespaces into the default namespace: %v", err)
 	}
-	m.init(minions, cloud)
 	return m
 }
 
-func (m *Master) init(minions []string, cloud cloudprovider.Interface) {
-	containerInfo := &client.HTTPContainerInfo{
+// 'minions' that are not registered yet are added to the minion registry.
+func (m *Master) init(minions []string, cloud cloudprovider.Interface, podSubnets *ipam.SubnetAllocator, kubeletPort uint) {
+	m.containerInfo = &client.HTTPContainerInfo{
+		Client: http.DefaultClient,
+		Port:   kubeletPort,
+	}
+	podConsole := &client.HTTPPodConsole{
 		Client: http.DefaultClient,
-		Port:   10250,
+		Port:   kubeletPort,
 	}
+	podMigration := &client.HTTPPodMigration{
+		Client: http.DefaultClient,
+		Port:   kubeletPort,
+	}
+	migrator := registry.MakePodMigrator(m.podRegistry, m.minionRegistry, podMigration)
 
-	m.minions = minions
+	registry.AddMinions(m.minionRegistry, minions)
 	m.random = rand.New(rand.NewSource(int64(time.Now().Nanosecond())))
 	m.storage = map[string]apiserver.RESTStorage{
-		"pods": registry.MakePodRegistryStorage(m.podRegistry, containerInfo, registry.MakeFirstFitScheduler(m.minions, m.podRegistry, m.random)),
+		"pods": registry.MakePodRegistryStorage(m.podRegistry, m.containerInfo, podConsole, migrator, registry.MakeFirstFitScheduler(m.minionRegistry, m.podRegistry, m.random)),
 		"replicationControllers": registry.MakeControllerRegistryStorage(m.controllerRegistry),
-		"services":               registry.MakeServiceRegistryStorage(m.serviceRegistry, cloud, m.minions),
+		"services":               registry.MakeServiceRegistryStorage(m.serviceRegistry, cloud, m.minionRegistry),
+		"minions":                registry.MakeMinionRegistryStorage(m.minionRegistry, podSubnets, kubeletPort),
 	}
 
 }
 
-// Runs master. Never returns.
-func (m *Master) Run(myAddress, apiPrefix string) error {
-	endpoints := registry.MakeEndpointController(m.serviceRegistry, m.podRegistry)
+// checkAPIPrefix returns an error if 'apiPrefix' ends in an API version. The version is added to
+// the prefix, and prefixes such as /api/v1beta1 used to include it.
+func checkAPIPrefix(apiPrefix string) error {
+	for _, version := range api.Versions {
+		if strings.HasSuffix(strings.TrimRight(apiPrefix, "/"), "/"+version) {
+			return fmt.Errorf("the API prefix %s ends in version %s, which is added to it: use %s", apiPrefix, version, strings.TrimSuffix(strings.TrimRight(apiPrefix, "/"), "/"+version))
+		}
+	}
+	return nil
+}
+
+// Runs master. Never returns. If 'authenticator' is set, requests it does not authenticate are
+// rejected. If 'authorizer' is set, requests it does not allow are forbidden. If 'admit' is set,
+// objects are created and updated as it admits them. If 'auditSink' is set, requests that change
+// objects are recorded in it. If 'certFile' is set, the master serves HTTPS, and asks clients
+// for certificates.
+func (m *Master) Run(myAddress, apiPrefix string, authenticator auth.Authenticator, authorizer auth.Authorizer, admit admission.Interface, auditSink audit.Sink, certFile, keyFile string) error {
+	if err := checkAPIPrefix(apiPrefix); err != nil {
+		return err
+	}
+	endpoints := registry.MakeEndpointController(m.serviceRegistry, m.podRegistry, m.containerInfo)
 	go util.Forever(func() { endpoints.SyncServiceEndpoints() }, time.Second*10)
+	minions := registry.MakeMinionController(m.minionRegistry, minionHeartbeatTimeout)
+	go util.Forever(func() { minions.SyncMinionConditions() }, time.Second*10)
+
+	// Every version of the API is served under its own prefix, from the same storage and operations.
+	m.requests = auth.MakeRequests()
+	handler := http.NewServeMux()
+	ops := apiserver.MakeOperations()
+	serve := func(pattern, prefix, version string) {
+		server := apiserver.NewVersioned(m.storage, ops, prefix, version)
+		if authorizer != nil {
+			server.SetAuthorizer(authorizer, m.requests)
+		}
+		if admit != nil {
+			server.SetAdmissionControl(admit)
+		}
+		if auditSink != nil {
+			server.SetAuditSink(auditSink)
+		}
+		handler.Handle(pattern, server)
+	}
+	for _, version := range api.Versions {
+		prefix := apiPrefix + "/" + version
+		serve(prefix+"/", prefix, version)
+	}
+	serve("/", apiPrefix+"/"+api.StorageVersion, api.StorageVersion)
+
+	var root http.Handler = handler
+	if authenticator != nil {
+		root = &auth.Handler{Requests: m.requests, Authenticator: authenticator, Handler: handler}
+	}
 
+	// There is no write timeout, because followed pod consoles stream for as long as the pod runs.
 	s := &http.Server{
 		Addr:           myAddress,
-		Handler:        apiserver.New(m.storage, apiPrefix),
+		Handler:        root,
 		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
 		MaxHeaderBytes: 1 << 20,
 	}
+	if len(certFile) > 0 {
+		// Client certificates are checked by the authenticator, so clients without one can
+		// still use a password or token.
+		s.TLSConfig = &tls.Config{ClientAuth: tls.RequestClientCert}
+		return s.ListenAndServeTLS(certFile, keyFile)
+	}
 	return s.ListenAndServe()
 }
@@ -0,0 +1,34 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"testing"
+)
+
+func TestCheckAPIPrefix(t *testing.T) {
+	for _, prefix := range []string{"/api", "/api/", "/k8s/api", "/api/v1"} {
+		if err := checkAPIPrefix(prefix); err != nil {
+			t.Errorf("Unexpected error for %s: %v", prefix, err)
+		}
+	}
+	for _, prefix := range []string{"/api/v1beta1", "/api/v1beta1/", "/api/v1beta2"} {
+		if err := checkAPIPrefix(prefix); err == nil {
+			t.Errorf("Expected an error for %s", prefix)
+		}
+	}
+}
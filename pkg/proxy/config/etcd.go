@@ -21,11 +21,15 @@ limitations under the License.
 // http://<etcd server>/v2/keys/registry/services
 //
 // The port that proxy needs to listen in for each service is a value in:
-// registry/services/<service>
+// registry/services/specs/<namespace>/<service>
 //
 // The endpoints for each of the services found is a json string
 // representing that service at:
-// /registry/services/<service>/endpoint
+// /registry/services/endpoints/<namespace>/<service>
+//
+// Services of different namespaces may have the same ID, so the proxy knows services and
+// endpoints as "<namespace>/<service>". Services stored before namespaces were added, at
+// registry/services/specs/<service>, are in the default namespace.
 // and the format is:
 // '[ { "machine": <host>, "name": <name", "port": <port> },
 //    { "machine": <host2>, "name": <name2", "port": <port2> }
@@ -110,40 +114,71 @@ func (impl ConfigSourceEtcd) Run() {
 // Finds the list of services and their endpoints from etcd.
 // This operation is akin to a set a known good at regular intervals.
 func (impl ConfigSourceEtcd) GetServices() ([]api.Service, []api.Endpoints, error) {
-	response, err := impl.client.Get(RegistryRoot+"/specs", true, false)
+	response, err := impl.client.Get(RegistryRoot+"/specs", true, true)
 	if err != nil {
 		log.Printf("Failed to get the key %s: %v", RegistryRoot, err)
 		return make([]api.Service, 0), make([]api.Endpoints, 0), err
 	}
 	if response.Node.Dir == true {
-		retServices := make([]api.Service, len(response.Node.Nodes))
-		retEndpoints := make([]api.Endpoints, len(response.Node.Nodes))
-		// Ok, so we have directories, this list should be the list
-		// of services. Find the local port to listen on and remote endpoints
-		// and create a Service entry for it.
-		for i, node := range response.Node.Nodes {
-			var svc api.Service
-			err = json.Unmarshal([]byte(node.Value), &svc)
-			if err != nil {
-				log.Printf("Failed to load Service: %s (%#v)", node.Value, err)
-				continue
+		retServices := []api.Service{}
+		retEndpoints := []api.Endpoints{}
+		// Ok, so we have directories, one per namespace, and below them the services.
+		// Find the local port to listen on and remote endpoints and create a Service
+		// entry for it.
+		for _, namespace := range response.Node.Nodes {
+			nodes := namespace.Nodes
+			if !namespace.Dir {
+				nodes = []*etcd.Node{namespace}
 			}
-			retServices[i] = svc
-			endpoints, err := impl.GetEndpoints(svc.ID)
-			if err != nil {
-				log.Printf("Couldn't get endpoints for %s : %v skipping", svc.ID, err)
+			for _, node := range nodes {
+				name, ok := serviceName(node.Key)
+				if !ok {
+					continue
+				}
+				var svc api.Service
+				err = api.DecodeInto([]byte(node.Value), &svc)
+				if err != nil {
+					log.Printf("Failed to load Service: %s (%#v)", node.Value, err)
+					continue
+				}
+				svc.ID = name
+				retServices = append(retServices, svc)
+				endpoints, err := impl.GetEndpoints(name)
+				if err != nil {
+					log.Printf("Couldn't get endpoints for %s : %v skipping", name, err)
+				}
+				endpoints.Name = name
+				log.Printf("Got service: %s on localport %d mapping to: %s", name, svc.Port, endpoints)
+				retEndpoints = append(retEndpoints, endpoints)
 			}
-			log.Printf("Got service: %s on localport %d mapping to: %s", svc.ID, svc.Port, endpoints)
-			retEndpoints[i] = endpoints
 		}
 		return retServices, retEndpoints, err
 	}
 	return nil, nil, fmt.Errorf("did not get the root of the registry %s", RegistryRoot)
 }
 
+// serviceName returns the name the proxy knows the service or endpoints stored at etcd 'key' by,
+// "<namespace>/<service>", or false if 'key' is not of a service or endpoints. Keys without a
+// namespace are of the default namespace.
+func serviceName(key string) (string, bool) {
+	parts := strings.Split(strings.Trim(key, "/"), "/")
+	switch len(parts) {
+	case 4:
+		return api.NamespaceDefault + "/" + parts[3], true
+	case 5:
+		return parts[3] + "/" + parts[4], true
+	}
+	return "", false
+}
+
 func (impl ConfigSourceEtcd) GetEndpoints(service string) (api.Endpoints, error) {
 	key := fmt.Sprintf(RegistryRoot + "/endpoints/" + service)
 	response, err := impl.client.Get(key, true, false)
+	if err != nil && strings.HasPrefix(service, api.NamespaceDefault+"/") {
+		// The endpoints may not have been moved into the default namespace yet.
+		key = RegistryRoot + "/endpoints/" + strings.TrimPrefix(service, api.NamespaceDefault+"/")
+		response, err = impl.client.Get(key, true, false)
+	}
 	if err != nil {
 		log.Printf("Failed to get the key: %s %v", key, err)
 		return api.Endpoints{}, err
@@ -159,7 +194,7 @@ func EtcdResponseToService(response *etcd.Response) (*api.Service, error) {
 		return nil, fmt.Errorf("invalid response from etcd: %#v", response)
 	}
 	var svc api.Service
-	err := json.Unmarshal([]byte(response.Node.Value), &svc)
+	err := api.DecodeInto([]byte(response.Node.Value), &svc)
 	if err != nil {
 		return nil, err
 	}
@@ -184,17 +219,27 @@ func (impl ConfigSourceEtcd) WatchForChanges() {
 
 func (impl ConfigSourceEtcd) ProcessChange(response *etcd.Response) {
 	log.Printf("Processing a change in service configuration... %s", *response)
+	// Namespace directories are neither services nor endpoints.
+	if response.Node.Dir {
+		return
+	}
 
 	// If it's a new service being added (signified by a localport being added)
 	// then process it as such
 	if strings.Contains(response.Node.Key, "/endpoints/") {
 		impl.ProcessEndpointResponse(response)
-	} else if response.Action == "set" {
+	} else if response.Action == "set" || response.Action == "create" || response.Action == "compareAndSwap" {
+		name, ok := serviceName(response.Node.Key)
+		if !ok {
+			log.Printf("Unknown service key: %s", response.Node.Key)
+			return
+		}
 		service, err := EtcdResponseToService(response)
 		if err != nil {
 			log.Printf("Failed to parse %s Port: %s", response, err)
 			return
 		}
+		service.ID = name
 
 		log.Printf("New service added/updated: %#v", service)
 		serviceUpdate := ServiceUpdate{Op: ADD, Services: []api.Service{*service}}
@@ -202,14 +247,13 @@ func (impl ConfigSourceEtcd) ProcessChange(response *etcd.Response) {
 		return
 	}
 	if response.Action == "delete" {
-		parts := strings.Split(response.Node.Key[1:], "/")
-		if len(parts) == 4 {
-			log.Printf("Deleting service: %s", parts[3])
-			serviceUpdate := ServiceUpdate{Op: REMOVE, Services: []api.Service{{JSONBase: api.JSONBase{ID: parts[3]}}}}
+		if name, ok := serviceName(response.Node.Key); ok {
+			log.Printf("Deleting service: %s", name)
+			serviceUpdate := ServiceUpdate{Op: REMOVE, Services: []api.Service{{JSONBase: api.JSONBase{ID: name}}}}
 			impl.serviceChannel <- serviceUpdate
 			return
 		} else {
-			log.Printf("Unknown service delete: %#v", parts)
+			log.Printf("Unknown service delete: %s", response.Node.Key)
 		}
 	}
 }
@@ -222,6 +266,9 @@ func (impl ConfigSourceEtcd) ProcessEndpointResponse(response *etcd.Response) {
 		log.Printf("Failed to parse service out of etcd key: %v : %+v", response.Node.Value, err)
 		return
 	}
+	if name, ok := serviceName(response.Node.Key); ok {
+		endpoints.Name = name
+	}
 	endpointsUpdate := EndpointsUpdate{Op: ADD, Endpoints: []api.Endpoints{endpoints}}
 	impl.endpointsChannel <- endpointsUpdate
 }
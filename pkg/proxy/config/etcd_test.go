@@ -54,3 +54,23 @@ func TestParseJsonEndpoints(t *testing.T) {
 	ValidateJsonParsing(t, string(data), endpoints, false)
 	//	ValidateJsonParsing(t, "[{\"port\":8000,\"name\":\"mysql\",\"machine\":\"foo\"},{\"port\":9000,\"name\":\"mysql\",\"machine\":\"bar\"}]", []string{"foo:8000", "bar:9000"}, false)
 }
+
+func TestServiceName(t *testing.T) {
+	table := []struct {
+		key  string
+		name string
+		ok   bool
+	}{
+		{"/registry/services/specs/default/foo", "default/foo", true},
+		{"/registry/services/endpoints/team/foo", "team/foo", true},
+		{"/registry/services/specs/foo", "default/foo", true},
+		{"/registry/services/endpoints/foo", "default/foo", true},
+		{"/registry/services/specs", "", false},
+	}
+	for _, item := range table {
+		name, ok := serviceName(item.key)
+		if name != item.name || ok != item.ok {
+			t.Errorf("Unexpected name for %s: %s %v", item.key, name, ok)
+		}
+	}
+}
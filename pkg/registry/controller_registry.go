@@ -17,11 +17,12 @@ limitations under the License.
 package registry
 
 import (
-	"encoding/json"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/validation"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
 // Implementation of RESTStorage for the api server.
@@ -35,9 +36,9 @@ func MakeControllerRegistryStorage(registry ControllerRegistry) apiserver.RESTSt
 	}
 }
 
-func (storage *ControllerRegistryStorage) List(query labels.Query) (interface{}, error) {
+func (storage *ControllerRegistryStorage) List(namespace string, query labels.Query) (interface{}, error) {
 	result := api.ReplicationControllerList{JSONBase: api.JSONBase{Kind: "cluster#replicationControllerList"}}
-	controllers, err := storage.registry.ListControllers()
+	controllers, err := storage.registry.ListControllers(namespace)
 	if err == nil {
 		for _, controller := range controllers {
 			if query.Matches(labels.Set(controller.Labels)) {
@@ -48,30 +49,57 @@ func (storage *ControllerRegistryStorage) List(query labels.Query) (interface{},
 	return result, err
 }
 
-func (storage *ControllerRegistryStorage) Get(id string) (interface{}, error) {
-	controller, err := storage.registry.GetController(id)
+func (storage *ControllerRegistryStorage) Get(namespace, id string) (interface{}, error) {
+	controller, err := storage.registry.GetController(namespace, id)
 	if err != nil {
 		return nil, err
 	}
+	if controller == nil {
+		return nil, api.NewNotFound("replicationController", id)
+	}
 	controller.Kind = "cluster#replicationController"
 	return controller, err
 }
 
-func (storage *ControllerRegistryStorage) Delete(id string) error {
-	return storage.registry.DeleteController(id)
+// Watch implements apiserver.ResourceWatcher.
+func (storage *ControllerRegistryStorage) Watch(namespace string, query labels.Query, resourceVersion uint64) (watch.Interface, error) {
+	w, err := storage.registry.WatchControllers(resourceVersion)
+	if err != nil {
+		return nil, err
+	}
+	return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+		controller := event.Object.(api.ReplicationController)
+		controller.Kind = "cluster#replicationController"
+		event.Object = controller
+		return event, inNamespace(namespace, controller.Namespace) && query.Matches(labels.Set(controller.Labels))
+	}), nil
+}
+
+func (storage *ControllerRegistryStorage) Delete(namespace, id string) error {
+	return storage.registry.DeleteController(namespace, id)
 }
 
-func (storage *ControllerRegistryStorage) Extract(body string) (interface{}, error) {
+func (storage *ControllerRegistryStorage) Extract(body string, version string) (interface{}, error) {
 	result := api.ReplicationController{}
-	err := json.Unmarshal([]byte(body), &result)
+	if err := api.DecodeVersionInto([]byte(body), version, &result); err != nil {
+		return nil, api.NewInvalid("replicationController", "", err)
+	}
 	result.Kind = "cluster#replicationController"
-	return result, err
+	return result, nil
 }
 
 func (storage *ControllerRegistryStorage) Create(controller interface{}) error {
-	return storage.registry.CreateController(controller.(api.ReplicationController))
+	controllerObj := controller.(api.ReplicationController)
+	if errs := validation.ValidateReplicationController(&controllerObj); len(errs) > 0 {
+		return api.NewInvalidFields("replicationController", controllerObj.ID, errs.Causes())
+	}
+	return storage.registry.CreateController(controllerObj)
 }
 
 func (storage *ControllerRegistryStorage) Update(controller interface{}) error {
-	return storage.registry.UpdateController(controller.(api.ReplicationController))
+	controllerObj := controller.(api.ReplicationController)
+	if errs := validation.ValidateReplicationController(&controllerObj); len(errs) > 0 {
+		return api.NewInvalidFields("replicationController", controllerObj.ID, errs.Causes())
+	}
+	return storage.registry.UpdateController(controllerObj)
 }
@@ -24,18 +24,20 @@ import (
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
 type MockControllerRegistry struct {
 	err         error
 	controllers []api.ReplicationController
+	watcher     watch.Interface
 }
 
-func (registry *MockControllerRegistry) ListControllers() ([]api.ReplicationController, error) {
+func (registry *MockControllerRegistry) ListControllers(namespace string) ([]api.ReplicationController, error) {
 	return registry.controllers, registry.err
 }
 
-func (registry *MockControllerRegistry) GetController(ID string) (*api.ReplicationController, error) {
+func (registry *MockControllerRegistry) GetController(namespace, ID string) (*api.ReplicationController, error) {
 	return &api.ReplicationController{}, registry.err
 }
 
@@ -46,9 +48,12 @@ func (registry *MockControllerRegistry) CreateController(controller api.Replicat
 func (registry *MockControllerRegistry) UpdateController(controller api.ReplicationController) error {
 	return registry.err
 }
-func (registry *MockControllerRegistry) DeleteController(ID string) error {
+func (registry *MockControllerRegistry) DeleteController(namespace, ID string) error {
 	return registry.err
 }
+func (registry *MockControllerRegistry) WatchControllers(resourceVersion uint64) (watch.Interface, error) {
+	return registry.watcher, registry.err
+}
 
 func TestListControllersError(t *testing.T) {
 	mockRegistry := MockControllerRegistry{
@@ -57,7 +62,7 @@ func TestListControllersError(t *testing.T) {
 	storage := ControllerRegistryStorage{
 		registry: &mockRegistry,
 	}
-	controllersObj, err := storage.List(nil)
+	controllersObj, err := storage.List(api.NamespaceAll, nil)
 	controllers := controllersObj.(api.ReplicationControllerList)
 	if err != mockRegistry.err {
 		t.Errorf("Expected %#v, Got %#v", mockRegistry.err, err)
@@ -72,7 +77,7 @@ func TestListEmptyControllerList(t *testing.T) {
 	storage := ControllerRegistryStorage{
 		registry: &mockRegistry,
 	}
-	controllers, err := storage.List(labels.Everything())
+	controllers, err := storage.List(api.NamespaceAll, labels.Everything())
 	expectNoError(t, err)
 	if len(controllers.(api.ReplicationControllerList).Items) != 0 {
 		t.Errorf("Unexpected non-zero ctrl list: %#v", controllers)
@@ -97,7 +102,7 @@ func TestListControllerList(t *testing.T) {
 	storage := ControllerRegistryStorage{
 		registry: &mockRegistry,
 	}
-	controllersObj, err := storage.List(labels.Everything())
+	controllersObj, err := storage.List(api.NamespaceAll, labels.Everything())
 	controllers := controllersObj.(api.ReplicationControllerList)
 	expectNoError(t, err)
 	if len(controllers.Items) != 2 {
@@ -123,7 +128,7 @@ func TestExtractControllerJson(t *testing.T) {
 	}
 	body, err := json.Marshal(controller)
 	expectNoError(t, err)
-	controllerOut, err := storage.Extract(string(body))
+	controllerOut, err := storage.Extract(string(body), api.StorageVersion)
 	expectNoError(t, err)
 	// Extract adds a Kind
 	controller.Kind = "cluster#replicationController"
@@ -132,6 +137,29 @@ func TestExtractControllerJson(t *testing.T) {
 	}
 }
 
+func TestCreateControllerInvalid(t *testing.T) {
+	storage := ControllerRegistryStorage{
+		registry: &MockControllerRegistry{},
+	}
+	err := storage.Create(api.ReplicationController{
+		JSONBase: api.JSONBase{ID: "foo"},
+		DesiredState: api.ReplicationControllerState{
+			Replicas:      -1,
+			ReplicasInSet: map[string]string{"name": "foo"},
+			PodTemplate: api.PodTemplate{
+				Labels: map[string]string{"name": "foo"},
+			},
+		},
+	})
+	if !api.IsInvalid(err) {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	causes := api.ErrorToStatus(err).Details.Causes
+	if len(causes) != 1 || causes[0].Field != "desiredState.replicas" {
+		t.Errorf("Unexpected causes: %#v", causes)
+	}
+}
+
 func TestControllerParsing(t *testing.T) {
 	expectedController := api.ReplicationController{
 		JSONBase: api.JSONBase{
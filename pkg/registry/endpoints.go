@@ -20,29 +20,48 @@ import (
 	"log"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 )
 
-func MakeEndpointController(serviceRegistry ServiceRegistry, podRegistry PodRegistry) *EndpointController {
+func MakeEndpointController(serviceRegistry ServiceRegistry, podRegistry PodRegistry, containerInfo client.ContainerInfo) *EndpointController {
 	return &EndpointController{
 		serviceRegistry: serviceRegistry,
 		podRegistry:     podRegistry,
+		containerInfo:   containerInfo,
 	}
 }
 
 type EndpointController struct {
 	serviceRegistry ServiceRegistry
 	podRegistry     PodRegistry
+	containerInfo   client.ContainerInfo
 }
 
+// makeEndpoint returns the address a service reaches 'pod' at. Pods with their own IP are
+// reached directly on their container port, other pods through the host port on their minion.
+func (e *EndpointController) makeEndpoint(pod api.Pod) string {
+	// TODO: Use port names in the service object, don't just use port #0
+	port := pod.DesiredState.Manifest.Containers[0].Ports[0]
+	info, err := e.containerInfo.GetContainerInfo(pod.CurrentState.Host, makeManifestID(pod.Namespace, pod.ID))
+	if err != nil {
+		log.Printf("Error getting info for pod %s: %#v", pod.ID, err)
+	} else if podIP := makePodIP(info); len(podIP) > 0 {
+		return fmt.Sprintf("%s:%d", podIP, port.ContainerPort)
+	}
+	return fmt.Sprintf("%s:%d", pod.CurrentState.Host, port.HostPort)
+}
+
+// SyncServiceEndpoints updates the endpoints of every service to the pods in its namespace that
+// match its labels.
 func (e *EndpointController) SyncServiceEndpoints() error {
-	services, err := e.serviceRegistry.ListServices()
+	services, err := e.serviceRegistry.ListServices(api.NamespaceAll)
 	if err != nil {
 		return err
 	}
 	var resultErr error
 	for _, service := range services.Items {
-		pods, err := e.podRegistry.ListPods(labels.QueryFromSet(labels.Set(service.Labels)))
+		pods, err := e.podRegistry.ListPods(defaultNamespace(service.Namespace), labels.QueryFromSet(labels.Set(service.Labels)))
 		if err != nil {
 			log.Printf("Error syncing service: %#v, skipping.", service)
 			resultErr = err
@@ -50,11 +69,11 @@ func (e *EndpointController) SyncServiceEndpoints() error {
 		}
 		endpoints := make([]string, len(pods))
 		for ix, pod := range pods {
-			// TODO: Use port names in the service object, don't just use port #0
-			endpoints[ix] = fmt.Sprintf("%s:%d", pod.CurrentState.Host, pod.DesiredState.Manifest.Containers[0].Ports[0].HostPort)
+			endpoints[ix] = e.makeEndpoint(pod)
 		}
 		err = e.serviceRegistry.UpdateEndpoints(api.Endpoints{
 			Name:      service.ID,
+			Namespace: service.Namespace,
 			Endpoints: endpoints,
 		})
 		if err != nil {
@@ -20,13 +20,14 @@ import (
 	"testing"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 )
 
 func TestSyncEndpointsEmpty(t *testing.T) {
 	serviceRegistry := MockServiceRegistry{}
 	podRegistry := MockPodRegistry{}
 
-	endpoints := MakeEndpointController(&serviceRegistry, &podRegistry)
+	endpoints := MakeEndpointController(&serviceRegistry, &podRegistry, &client.FakeContainerInfo{})
 	err := endpoints.SyncServiceEndpoints()
 	expectNoError(t, err)
 }
@@ -37,7 +38,7 @@ func TestSyncEndpointsError(t *testing.T) {
 	}
 	podRegistry := MockPodRegistry{}
 
-	endpoints := MakeEndpointController(&serviceRegistry, &podRegistry)
+	endpoints := MakeEndpointController(&serviceRegistry, &podRegistry, &client.FakeContainerInfo{})
 	err := endpoints.SyncServiceEndpoints()
 	if err != serviceRegistry.err {
 		t.Errorf("Errors don't match: %#v %#v", err, serviceRegistry.err)
@@ -79,7 +80,7 @@ func TestSyncEndpointsItems(t *testing.T) {
 		},
 	}
 
-	endpoints := MakeEndpointController(&serviceRegistry, &podRegistry)
+	endpoints := MakeEndpointController(&serviceRegistry, &podRegistry, &client.FakeContainerInfo{})
 	err := endpoints.SyncServiceEndpoints()
 	expectNoError(t, err)
 	if len(serviceRegistry.endpoints.Endpoints) != 1 {
@@ -87,6 +88,63 @@ func TestSyncEndpointsItems(t *testing.T) {
 	}
 }
 
+func TestSyncEndpointsPodIP(t *testing.T) {
+	serviceRegistry := MockServiceRegistry{
+		list: api.ServiceList{
+			Items: []api.Service{
+				{
+					Labels: map[string]string{
+						"foo": "bar",
+					},
+				},
+			},
+		},
+	}
+	podRegistry := MockPodRegistry{
+		pods: []api.Pod{
+			{
+				DesiredState: api.PodState{
+					Manifest: api.ContainerManifest{
+						Containers: []api.Container{
+							{
+								Ports: []api.Port{
+									{
+										ContainerPort: 80,
+										HostPort:      8080,
+									},
+								},
+							},
+						},
+					},
+				},
+				CurrentState: api.PodState{
+					Host: "machine",
+				},
+				Labels: map[string]string{
+					"foo": "bar",
+				},
+			},
+		},
+	}
+	containerInfo := &client.FakeContainerInfo{
+		Data: map[string]interface{}{"PodIP": "10.244.1.2"},
+	}
+
+	endpoints := MakeEndpointController(&serviceRegistry, &podRegistry, containerInfo)
+	err := endpoints.SyncServiceEndpoints()
+	expectNoError(t, err)
+	if len(serviceRegistry.endpoints.Endpoints) != 1 || serviceRegistry.endpoints.Endpoints[0] != "10.244.1.2:80" {
+		t.Errorf("Unexpected endpoints update: %#v", serviceRegistry.endpoints)
+	}
+
+	containerInfo.Data = map[string]interface{}{}
+	err = endpoints.SyncServiceEndpoints()
+	expectNoError(t, err)
+	if len(serviceRegistry.endpoints.Endpoints) != 1 || serviceRegistry.endpoints.Endpoints[0] != "machine:8080" {
+		t.Errorf("Unexpected endpoints update: %#v", serviceRegistry.endpoints)
+	}
+}
+
 func TestSyncEndpointsPodError(t *testing.T) {
 	serviceRegistry := MockServiceRegistry{
 		list: api.ServiceList{
@@ -103,7 +161,7 @@ func TestSyncEndpointsPodError(t *testing.T) {
 		err: fmt.Errorf("test error."),
 	}
 
-	endpoints := MakeEndpointController(&serviceRegistry, &podRegistry)
+	endpoints := MakeEndpointController(&serviceRegistry, &podRegistry, &client.FakeContainerInfo{})
 	err := endpoints.SyncServiceEndpoints()
 	if err == nil {
 		t.Error("Unexpected non-error")
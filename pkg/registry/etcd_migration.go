@@ -0,0 +1,120 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"path"
+	"reflect"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// MigrateLegacyKeys moves the pods, controllers, services and endpoints stored before
+// namespaces were added into the default namespace, and renames the manifests of the moved
+// pods to match. Keys that are already in a namespace are left alone, so it is safe to run
+// more than once.
+func (registry *EtcdRegistry) MigrateLegacyKeys() error {
+	dirs := map[string]func() interface{}{
+		"/registry/controllers":        func() interface{} { return &api.ReplicationController{} },
+		"/registry/services/specs":     func() interface{} { return &api.Service{} },
+		"/registry/services/endpoints": func() interface{} { return &api.Endpoints{} },
+	}
+	for dir, newObj := range dirs {
+		if _, err := registry.migrateDir(dir, newObj); err != nil {
+			return err
+		}
+	}
+	machines, err := listMinionNames(registry.minionRegistry)
+	if err != nil {
+		return err
+	}
+	for _, machine := range machines {
+		podIDs, err := registry.migrateDir("/registry/hosts/"+machine+"/pods", func() interface{} { return &api.Pod{} })
+		if err != nil {
+			return err
+		}
+		if len(podIDs) == 0 {
+			continue
+		}
+		err = registry.updateManifests(machine, func(manifests []api.ContainerManifest) ([]api.ContainerManifest, error) {
+			for ix := range manifests {
+				if podIDs[manifests[ix].Id] {
+					manifests[ix].Id = makeManifestID(api.NamespaceDefault, manifests[ix].Id)
+				}
+			}
+			return manifests, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateDir moves the objects stored directly in 'dir' to the default namespace directory
+// below it. 'newObj' returns a pointer to an object of the type stored there. It returns the
+// IDs of the objects it moved.
+func (registry *EtcdRegistry) migrateDir(dir string, newObj func() interface{}) (map[string]bool, error) {
+	moved := map[string]bool{}
+	response, err := registry.etcdClient.Get(dir, false, false)
+	if isEtcdNotFound(err) {
+		return moved, nil
+	}
+	if err != nil {
+		return moved, err
+	}
+	if response.Node == nil {
+		return moved, nil
+	}
+	for _, node := range response.Node.Nodes {
+		if node.Dir {
+			continue
+		}
+		id := path.Base(node.Key)
+		obj := newObj()
+		if err := decodeNodeValue(node, obj); err != nil {
+			return moved, err
+		}
+		setNamespace(obj, api.NamespaceDefault)
+		setResourceVersion(obj, 0)
+		data, err := api.Encode(obj)
+		if err != nil {
+			return moved, err
+		}
+		// The object may already have been copied by an earlier run that failed to delete it.
+		if _, err := registry.etcdClient.Create(dir+"/"+api.NamespaceDefault+"/"+id, string(data), 0); err != nil && !isEtcdNodeExist(err) {
+			return moved, err
+		}
+		if _, err := registry.etcdClient.Delete(node.Key, false); err != nil {
+			return moved, err
+		}
+		moved[id] = true
+	}
+	return moved, nil
+}
+
+// setNamespace sets the Namespace field of the struct objPtr points to, if it has one.
+func setNamespace(objPtr interface{}, namespace string) {
+	v := reflect.ValueOf(objPtr).Elem()
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	field := v.FieldByName("Namespace")
+	if field.IsValid() && field.CanSet() && field.Kind() == reflect.String {
+		field.SetString(namespace)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/coreos/go-etcd/etcd"
+)
+
+func TestEtcdMigrateLegacyKeys(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	notFound := EtcdResponseWithError{R: &etcd.Response{}, E: &etcd.EtcdError{ErrorCode: 100}}
+	fakeClient.Data["/registry/controllers"] = EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: &etcd.Node{
+				Nodes: []*etcd.Node{
+					{Key: "/registry/controllers/foo", Value: util.MakeJSONString(api.ReplicationController{JSONBase: api.JSONBase{ID: "foo"}})},
+					{Key: "/registry/controllers/team", Dir: true},
+				},
+			},
+		},
+	}
+	fakeClient.Data["/registry/services/specs"] = notFound
+	fakeClient.Data["/registry/services/endpoints"] = notFound
+	fakeClient.Data["/registry/hosts/machine/pods"] = EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: &etcd.Node{
+				Nodes: []*etcd.Node{
+					{Key: "/registry/hosts/machine/pods/bar", Value: util.MakeJSONString(api.Pod{JSONBase: api.JSONBase{ID: "bar"}})},
+				},
+			},
+		},
+	}
+	fakeClient.Set("/registry/hosts/machine/kubelet", util.MakeJSONString([]api.ContainerManifest{{Id: "bar"}, {Id: "baz.team"}}), 0)
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+
+	expectNoError(t, registry.MigrateLegacyKeys())
+	controller, err := registry.GetController(api.NamespaceDefault, "foo")
+	expectNoError(t, err)
+	if controller.ID != "foo" || controller.Namespace != api.NamespaceDefault {
+		t.Errorf("Unexpected controller: %#v", controller)
+	}
+	pod, err := registry.GetPod(api.NamespaceDefault, "bar")
+	expectNoError(t, err)
+	if pod.ID != "bar" || pod.Namespace != api.NamespaceDefault {
+		t.Errorf("Unexpected pod: %#v", pod)
+	}
+	manifests, err := registry.loadManifests("machine")
+	expectNoError(t, err)
+	if len(manifests) != 2 || manifests[0].Id != "bar.default" || manifests[1].Id != "baz.team" {
+		t.Errorf("Unexpected manifests: %#v", manifests)
+	}
+	expected := []string{"/registry/controllers/foo", "/registry/hosts/machine/pods/bar"}
+	if len(fakeClient.deletedKeys) != len(expected) {
+		t.Fatalf("Unexpected deletes: %#v", fakeClient.deletedKeys)
+	}
+	for _, key := range expected {
+		found := false
+		for _, deleted := range fakeClient.deletedKeys {
+			found = found || deleted == key
+		}
+		if !found {
+			t.Errorf("Expected %s to be deleted: %#v", key, fakeClient.deletedKeys)
+		}
+	}
+}
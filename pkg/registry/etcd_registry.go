@@ -25,6 +25,7 @@ import (
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
 // TODO: Need to add a reconciler loop that makes sure that things in pods are reflected into
@@ -36,6 +37,8 @@ type EtcdClient interface {
 	Get(key string, sort, recursive bool) (*etcd.Response, error)
 	Set(key, value string, ttl uint64) (*etcd.Response, error)
 	Create(key, value string, ttl uint64) (*etcd.Response, error)
+	Update(key, value string, ttl uint64) (*etcd.Response, error)
+	CompareAndSwap(key, value string, ttl uint64, prevValue string, prevIndex uint64) (*etcd.Response, error)
 	Delete(key string, recursive bool) (*etcd.Response, error)
 	// I'd like to use directional channels here (e.g. <-chan) but this interface mimics
 	// the etcd client interface which doesn't, and it doesn't seem worth it to wrap the api.
@@ -45,18 +48,21 @@ type EtcdClient interface {
 // EtcdRegistry is an implementation of both ControllerRegistry and PodRegistry which is backed with etcd.
 type EtcdRegistry struct {
 	etcdClient      EtcdClient
-	machines        []string
+	minionRegistry  MinionRegistry
 	manifestFactory ManifestFactory
 }
 
 // MakeEtcdRegistry creates an etcd registry.
 // 'client' is the connection to etcd
-// 'machines' is the list of machines
-// 'scheduler' is the scheduling algorithm to use.
-func MakeEtcdRegistry(client EtcdClient, machines []string) *EtcdRegistry {
+// 'minionRegistry' is the set of machines that pods run on.  If it is nil, the minions stored
+// in etcd by this registry are used.
+func MakeEtcdRegistry(client EtcdClient, minionRegistry MinionRegistry) *EtcdRegistry {
 	registry := &EtcdRegistry{
-		etcdClient: client,
-		machines:   machines,
+		etcdClient:     client,
+		minionRegistry: minionRegistry,
+	}
+	if minionRegistry == nil {
+		registry.minionRegistry = registry
 	}
 	registry.manifestFactory = &BasicManifestFactory{
 		serviceRegistry: registry,
@@ -64,15 +70,28 @@ func MakeEtcdRegistry(client EtcdClient, machines []string) *EtcdRegistry {
 	return registry
 }
 
-func makePodKey(machine, podID string) string {
-	return "/registry/hosts/" + machine + "/pods/" + podID
+func makePodKey(machine, namespace, podID string) string {
+	return "/registry/hosts/" + machine + "/pods/" + defaultNamespace(namespace) + "/" + podID
 }
 
-func (registry *EtcdRegistry) ListPods(query labels.Query) ([]api.Pod, error) {
+// makeListKey returns the key of the objects in 'namespace' below 'dir', or of the objects of
+// every namespace if it is api.NamespaceAll.
+func makeListKey(dir, namespace string) string {
+	if namespace == api.NamespaceAll {
+		return dir
+	}
+	return dir + "/" + namespace
+}
+
+func (registry *EtcdRegistry) ListPods(namespace string, query labels.Query) ([]api.Pod, error) {
 	pods := []api.Pod{}
-	for _, machine := range registry.machines {
+	machines, err := listMinionNames(registry.minionRegistry)
+	if err != nil {
+		return pods, err
+	}
+	for _, machine := range machines {
 		var machinePods []api.Pod
-		err := registry.extractList("/registry/hosts/"+machine+"/pods", &machinePods)
+		err := registry.extractList(makeListKey("/registry/hosts/"+machine+"/pods", namespace), &machinePods)
 		if err != nil {
 			return pods, err
 		}
@@ -95,7 +114,21 @@ func (registry *EtcdRegistry) listEtcdNode(key string) ([]*etcd.Node, error) {
 			return nodes, err
 		}
 	}
-	return result.Node.Nodes, nil
+	return flattenNodes(result.Node.Nodes), nil
+}
+
+// flattenNodes returns the nodes with values in 'nodes' and the directories below them, such
+// as the objects in each namespace of a list of every namespace.
+func flattenNodes(nodes []*etcd.Node) []*etcd.Node {
+	result := []*etcd.Node{}
+	for _, node := range nodes {
+		if node.Dir {
+			result = append(result, flattenNodes(node.Nodes)...)
+		} else {
+			result = append(result, node)
+		}
+	}
+	return result
 }
 
 // Extract a go object per etcd node into a slice.
@@ -112,46 +145,64 @@ func (r *EtcdRegistry) extractList(key string, slicePtr interface{}) error {
 	v := pv.Elem()
 	for _, node := range nodes {
 		obj := reflect.New(v.Type().Elem())
-		err = json.Unmarshal([]byte(node.Value), obj.Interface())
+		err = api.DecodeInto([]byte(node.Value), obj.Interface())
 		if err != nil {
 			return err
 		}
+		setResourceVersion(obj.Interface(), node.ModifiedIndex)
 		v.Set(reflect.Append(v, obj.Elem()))
 	}
 	return nil
 }
 
-// Unmarshals json found at key into objPtr. On a not found error, will either return
-// a zero object of the requested type, or an error, depending on ignoreNotFound. Treats
-// empty responses and nil response nodes exactly like a not found error.
-func (r *EtcdRegistry) extractObj(key string, objPtr interface{}, ignoreNotFound bool) error {
+// setResourceVersion sets the ResourceVersion of the object 'objPtr' points to, if it has one.
+func setResourceVersion(objPtr interface{}, version uint64) {
+	v := reflect.ValueOf(objPtr).Elem()
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	field := v.FieldByName("ResourceVersion")
+	if field.IsValid() && field.CanSet() && field.Kind() == reflect.Uint64 {
+		field.SetUint(version)
+	}
+}
+
+// Decodes the object found at key into objPtr, and sets its ResourceVersion to the etcd index
+// the key was last modified at, which is returned. On a not found error, will either return
+// a zero object of the requested type and index, or an error, depending on ignoreNotFound.
+// Treats empty responses and nil response nodes exactly like a not found error.
+func (r *EtcdRegistry) extractObj(key string, objPtr interface{}, ignoreNotFound bool) (uint64, error) {
 	response, err := r.etcdClient.Get(key, false, false)
 	returnZero := false
 	if err != nil {
 		if ignoreNotFound && isEtcdNotFound(err) {
 			returnZero = true
 		} else {
-			return err
+			return 0, err
 		}
 	}
 	if !returnZero && (response.Node == nil || len(response.Node.Value) == 0) {
 		if ignoreNotFound {
 			returnZero = true
 		} else {
-			return fmt.Errorf("key '%v' found no nodes field: %#v", key, response)
+			return 0, fmt.Errorf("key '%v' found no nodes field: %#v", key, response)
 		}
 	}
 	if returnZero {
 		pv := reflect.ValueOf(objPtr)
 		pv.Elem().Set(reflect.Zero(pv.Type().Elem()))
-		return nil
+		return 0, nil
 	}
-	return json.Unmarshal([]byte(response.Node.Value), objPtr)
+	if err := api.DecodeInto([]byte(response.Node.Value), objPtr); err != nil {
+		return 0, err
+	}
+	setResourceVersion(objPtr, response.Node.ModifiedIndex)
+	return response.Node.ModifiedIndex, nil
 }
 
-// json marshals obj, and stores under key.
+// setObj encodes obj in the api.StorageVersion, and stores it under key.
 func (r *EtcdRegistry) setObj(key string, obj interface{}) error {
-	data, err := json.Marshal(obj)
+	data, err := api.Encode(obj)
 	if err != nil {
 		return err
 	}
@@ -159,8 +210,45 @@ func (r *EtcdRegistry) setObj(key string, obj interface{}) error {
 	return err
 }
 
-func (registry *EtcdRegistry) GetPod(podID string) (*api.Pod, error) {
-	pod, _, err := registry.findPod(podID)
+// createObj encodes obj like setObj, and stores it under key if there is nothing there yet.
+// It returns an AlreadyExists error naming 'kind' and 'id' if there is.
+func (r *EtcdRegistry) createObj(key string, obj interface{}, kind, id string) error {
+	data, err := api.Encode(obj)
+	if err != nil {
+		return err
+	}
+	_, err = r.etcdClient.Create(key, string(data), 0)
+	if isEtcdNodeExist(err) {
+		return api.NewAlreadyExists(kind, id)
+	}
+	return err
+}
+
+// updateObj encodes obj like setObj, and stores it under key if key was last modified at etcd index
+// 'resourceVersion', so that concurrent updates can't overwrite each other. If 'resourceVersion'
+// is 0, obj replaces whatever is there, but the key is never created. It returns a NotFound or
+// Conflict error naming 'kind' and 'id' if the key is missing or has changed.
+func (r *EtcdRegistry) updateObj(key string, obj interface{}, resourceVersion uint64, kind, id string) error {
+	data, err := api.Encode(obj)
+	if err != nil {
+		return err
+	}
+	if resourceVersion == 0 {
+		_, err = r.etcdClient.Update(key, string(data), 0)
+	} else {
+		_, err = r.etcdClient.CompareAndSwap(key, string(data), 0, "", resourceVersion)
+	}
+	if isEtcdNotFound(err) {
+		return api.NewNotFound(kind, id)
+	}
+	if isEtcdTestFailed(err) {
+		return api.NewConflict(kind, id, fmt.Errorf("resourceVersion %d is out of date", resourceVersion))
+	}
+	return err
+}
+
+func (registry *EtcdRegistry) GetPod(namespace, podID string) (*api.Pod, error) {
+	pod, _, err := registry.findPod(namespace, podID)
 	return &pod, err
 }
 
@@ -169,101 +257,237 @@ func makeContainerKey(machine string) string {
 }
 
 func (registry *EtcdRegistry) loadManifests(machine string) (manifests []api.ContainerManifest, err error) {
-	err = registry.extractObj(makeContainerKey(machine), &manifests, true)
+	_, err = registry.extractObj(makeContainerKey(machine), &manifests, true)
 	return
 }
 
-func (registry *EtcdRegistry) updateManifests(machine string, manifests []api.ContainerManifest) error {
-	return registry.setObj(makeContainerKey(machine), manifests)
+// updateManifests replaces the manifests of 'machine' with what 'update' makes of them. If the
+// manifests are changed by someone else in the meantime, it starts over with the new manifests.
+func (registry *EtcdRegistry) updateManifests(machine string, update func([]api.ContainerManifest) ([]api.ContainerManifest, error)) error {
+	key := makeContainerKey(machine)
+	for {
+		var manifests []api.ContainerManifest
+		index, err := registry.extractObj(key, &manifests, true)
+		if err != nil {
+			return err
+		}
+		manifests, err = update(manifests)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(manifests)
+		if err != nil {
+			return err
+		}
+		if index == 0 {
+			_, err = registry.etcdClient.Create(key, string(data), 0)
+			if isEtcdNodeExist(err) {
+				continue
+			}
+		} else {
+			_, err = registry.etcdClient.CompareAndSwap(key, string(data), 0, "", index)
+			if isEtcdTestFailed(err) {
+				continue
+			}
+		}
+		return err
+	}
+}
+
+// addManifest returns an update for updateManifests that adds 'manifest', replacing any manifest
+// with the same id.
+func addManifest(manifest api.ContainerManifest) func([]api.ContainerManifest) ([]api.ContainerManifest, error) {
+	return func(manifests []api.ContainerManifest) ([]api.ContainerManifest, error) {
+		newManifests := []api.ContainerManifest{}
+		for _, existing := range manifests {
+			if existing.Id != manifest.Id {
+				newManifests = append(newManifests, existing)
+			}
+		}
+		return append(newManifests, manifest), nil
+	}
+}
+
+// removeManifest returns an update for updateManifests that removes the manifest of pod 'podID'
+// in 'namespace'.
+func removeManifest(namespace, podID string) func([]api.ContainerManifest) ([]api.ContainerManifest, error) {
+	manifestID := makeManifestID(namespace, podID)
+	return func(manifests []api.ContainerManifest) ([]api.ContainerManifest, error) {
+		newManifests := make([]api.ContainerManifest, 0)
+		found := false
+		for _, manifest := range manifests {
+			if manifest.Id != manifestID {
+				newManifests = append(newManifests, manifest)
+			} else {
+				found = true
+			}
+		}
+		if !found {
+			// This really shouldn't happen, it indicates something is broken, and likely
+			// there is a lost pod somewhere.
+			// However it is "deleted" so log it and move on
+			log.Printf("Couldn't find: %s in %#v", manifestID, manifests)
+		}
+		return newManifests, nil
+	}
 }
 
 func (registry *EtcdRegistry) CreatePod(machineIn string, pod api.Pod) error {
-	podOut, machine, err := registry.findPod(pod.ID)
+	pod.Namespace = defaultNamespace(pod.Namespace)
+	_, _, err := registry.findPod(pod.Namespace, pod.ID)
 	if err == nil {
-		return fmt.Errorf("a pod named %s already exists on %s (%#v)", pod.ID, machine, podOut)
+		return api.NewAlreadyExists("pod", pod.ID)
 	}
 	return registry.runPod(pod, machineIn)
 }
 
 func (registry *EtcdRegistry) runPod(pod api.Pod, machine string) error {
-	manifests, err := registry.loadManifests(machine)
-	if err != nil {
+	// Fail before writing anything if the manifests of 'machine' can't be read.
+	if _, err := registry.loadManifests(machine); err != nil {
 		return err
 	}
 
-	key := makePodKey(machine, pod.ID)
-	data, err := json.Marshal(pod)
-	if err != nil {
+	pod.ResourceVersion = 0
+	if err := registry.createObj(makePodKey(machine, pod.Namespace, pod.ID), pod, "pod", pod.ID); err != nil {
 		return err
 	}
-	_, err = registry.etcdClient.Create(key, string(data), 0)
 
 	manifest, err := registry.manifestFactory.MakeManifest(machine, pod)
 	if err != nil {
 		return err
 	}
-	manifests = append(manifests, manifest)
-	return registry.updateManifests(machine, manifests)
+	return registry.updateManifests(machine, addManifest(manifest))
 }
 
 func (registry *EtcdRegistry) UpdatePod(pod api.Pod) error {
 	return fmt.Errorf("unimplemented!")
 }
 
-func (registry *EtcdRegistry) DeletePod(podID string) error {
-	_, machine, err := registry.findPod(podID)
+func (registry *EtcdRegistry) DeletePod(namespace, podID string) error {
+	_, machine, err := registry.findPod(namespace, podID)
 	if err != nil {
 		return err
 	}
-	return registry.deletePodFromMachine(machine, podID)
+	return registry.deletePodFromMachine(machine, namespace, podID)
+}
+
+func (registry *EtcdRegistry) deletePodFromMachine(machine, namespace, podID string) error {
+	if err := registry.updateManifests(machine, removeManifest(namespace, podID)); err != nil {
+		return err
+	}
+	key := makePodKey(machine, namespace, podID)
+	_, err := registry.etcdClient.Delete(key, true)
+	return err
 }
 
-func (registry *EtcdRegistry) deletePodFromMachine(machine, podID string) error {
-	manifests, err := registry.loadManifests(machine)
+// MovePod moves a pod and its manifest from the machine it is on to 'machine'. etcd can't
+// change several keys at once, so the move isn't atomic: the pod is added to 'machine' before
+// it is removed from its old machine, so that it is never missing from both. If removing it
+// fails, the pod is listed on both machines until MovePod is called again, which finishes the
+// move, and an error says so. Kubelets leave a migrating pod alone while it is on both machines.
+func (registry *EtcdRegistry) MovePod(namespace, podID, machine string) error {
+	machines, err := listMinionNames(registry.minionRegistry)
 	if err != nil {
 		return err
 	}
-	newManifests := make([]api.ContainerManifest, 0)
-	found := false
-	for _, manifest := range manifests {
-		if manifest.Id != podID {
-			newManifests = append(newManifests, manifest)
-		} else {
-			found = true
+	var pod *api.Pod
+	moved := false
+	sources := []string{}
+	for _, host := range machines {
+		found, err := registry.getPodForMachine(host, namespace, podID)
+		if err != nil {
+			continue
+		}
+		if host == machine {
+			moved = true
+			continue
+		}
+		if pod == nil {
+			pod = &found
 		}
+		sources = append(sources, host)
 	}
-	if !found {
-		// This really shouldn't happen, it indicates something is broken, and likely
-		// there is a lost pod somewhere.
-		// However it is "deleted" so log it and move on
-		log.Printf("Couldn't find: %s in %#v", podID, manifests)
+	if pod == nil && !moved {
+		return api.NewNotFound("pod", podID)
 	}
-	if err = registry.updateManifests(machine, newManifests); err != nil {
+	if !moved {
+		if err := registry.addPodToMachine(*pod, sources[0], machine); err != nil {
+			return err
+		}
+	}
+	for _, source := range sources {
+		if err := registry.deletePodFromMachine(source, namespace, podID); err != nil {
+			return fmt.Errorf("pod %s is on both %s and %s until it is moved again: %v", podID, source, machine, err)
+		}
+	}
+	return nil
+}
+
+// addPodToMachine stores 'pod' and the manifest it has on 'source' on 'machine' too.
+func (registry *EtcdRegistry) addPodToMachine(pod api.Pod, source, machine string) error {
+	sourceManifests, err := registry.loadManifests(source)
+	if err != nil {
 		return err
 	}
-	key := makePodKey(machine, podID)
-	_, err = registry.etcdClient.Delete(key, true)
-	return err
+	var manifest *api.ContainerManifest
+	manifestID := makeManifestID(pod.Namespace, pod.ID)
+	for ix := range sourceManifests {
+		if sourceManifests[ix].Id == manifestID {
+			manifest = &sourceManifests[ix]
+		}
+	}
+	if manifest == nil {
+		return fmt.Errorf("pod %s has no manifest on %s", pod.ID, source)
+	}
+	if err := registry.updateManifests(machine, addManifest(*manifest)); err != nil {
+		return err
+	}
+	pod.CurrentState.Host = machine
+	pod.ResourceVersion = 0
+	if err := registry.setObj(makePodKey(machine, pod.Namespace, pod.ID), pod); err != nil {
+		if removeErr := registry.updateManifests(machine, removeManifest(pod.Namespace, pod.ID)); removeErr != nil {
+			return fmt.Errorf("pod %s has a manifest on %s until it is moved again: %v, %v", pod.ID, machine, err, removeErr)
+		}
+		return err
+	}
+	return nil
 }
 
-func (registry *EtcdRegistry) getPodForMachine(machine, podID string) (pod api.Pod, err error) {
-	key := makePodKey(machine, podID)
-	err = registry.extractObj(key, &pod, false)
+func (registry *EtcdRegistry) getPodForMachine(machine, namespace, podID string) (pod api.Pod, err error) {
+	key := makePodKey(machine, namespace, podID)
+	_, err = registry.extractObj(key, &pod, false)
 	if err != nil {
 		return
 	}
+	pod.Namespace = defaultNamespace(namespace)
 	pod.CurrentState.Host = machine
 	return
 }
 
-func (registry *EtcdRegistry) findPod(podID string) (api.Pod, string, error) {
-	for _, machine := range registry.machines {
-		pod, err := registry.getPodForMachine(machine, podID)
-		if err == nil {
-			return pod, machine, nil
+// findPod returns pod 'podID' in 'namespace' and the machine it is on. If an interrupted move
+// left the pod on several machines, it returns the machine it was last stored on.
+func (registry *EtcdRegistry) findPod(namespace, podID string) (api.Pod, string, error) {
+	machines, err := listMinionNames(registry.minionRegistry)
+	if err != nil {
+		return api.Pod{}, "", err
+	}
+	result, host := api.Pod{}, ""
+	for _, machine := range machines {
+		pod, err := registry.getPodForMachine(machine, namespace, podID)
+		if err == nil && (host == "" || pod.ResourceVersion > result.ResourceVersion) {
+			result, host = pod, machine
 		}
 	}
-	return api.Pod{}, "", fmt.Errorf("pod not found %s", podID)
+	if host == "" {
+		return api.Pod{}, "", api.NewNotFound("pod", podID)
+	}
+	return result, host, nil
+}
+
+// WatchPods reports changes to the pods on all machines, including pods on machines that are
+// not minions.
+func (registry *EtcdRegistry) WatchPods(resourceVersion uint64) (watch.Interface, error) {
+	return makeEtcdWatcher(registry.etcdClient, "/registry/hosts", resourceVersion, decodePodNode), nil
 }
 
 func isEtcdNotFound(err error) bool {
@@ -283,20 +507,43 @@ func isEtcdNotFound(err error) bool {
 	return false
 }
 
-func (registry *EtcdRegistry) ListControllers() ([]api.ReplicationController, error) {
+// isEtcdErrorCode returns true if 'err' is an etcd error with 'code'.
+func isEtcdErrorCode(err error, code int) bool {
+	etcdError, ok := err.(*etcd.EtcdError)
+	return ok && etcdError != nil && etcdError.ErrorCode == code
+}
+
+// isEtcdTestFailed returns true if 'err' says that a compare and swap found another value.
+func isEtcdTestFailed(err error) bool {
+	return isEtcdErrorCode(err, 101)
+}
+
+// isEtcdNodeExist returns true if 'err' says that the key to create exists.
+func isEtcdNodeExist(err error) bool {
+	return isEtcdErrorCode(err, 105)
+}
+
+func (registry *EtcdRegistry) ListControllers(namespace string) ([]api.ReplicationController, error) {
 	var controllers []api.ReplicationController
-	err := registry.extractList("/registry/controllers", &controllers)
+	err := registry.extractList(makeListKey("/registry/controllers", namespace), &controllers)
 	return controllers, err
 }
 
-func makeControllerKey(id string) string {
-	return "/registry/controllers/" + id
+func (registry *EtcdRegistry) WatchControllers(resourceVersion uint64) (watch.Interface, error) {
+	return makeEtcdWatcher(registry.etcdClient, "/registry/controllers", resourceVersion, decodeControllerNode), nil
 }
 
-func (registry *EtcdRegistry) GetController(controllerID string) (*api.ReplicationController, error) {
+func makeControllerKey(namespace, id string) string {
+	return "/registry/controllers/" + defaultNamespace(namespace) + "/" + id
+}
+
+func (registry *EtcdRegistry) GetController(namespace, controllerID string) (*api.ReplicationController, error) {
 	var controller api.ReplicationController
-	key := makeControllerKey(controllerID)
-	err := registry.extractObj(key, &controller, false)
+	key := makeControllerKey(namespace, controllerID)
+	_, err := registry.extractObj(key, &controller, false)
+	if isEtcdNotFound(err) {
+		return nil, api.NewNotFound("replicationController", controllerID)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -304,59 +551,143 @@ func (registry *EtcdRegistry) GetController(controllerID string) (*api.Replicati
 }
 
 func (registry *EtcdRegistry) CreateController(controller api.ReplicationController) error {
-	// TODO : check for existence here and error.
-	return registry.UpdateController(controller)
+	controller.ResourceVersion = 0
+	controller.Namespace = defaultNamespace(controller.Namespace)
+	return registry.createObj(makeControllerKey(controller.Namespace, controller.ID), controller, "replicationController", controller.ID)
 }
 
+// UpdateController stores 'controller', if it has not changed since its ResourceVersion.
 func (registry *EtcdRegistry) UpdateController(controller api.ReplicationController) error {
-	return registry.setObj(makeControllerKey(controller.ID), controller)
+	version := controller.ResourceVersion
+	controller.ResourceVersion = 0
+	controller.Namespace = defaultNamespace(controller.Namespace)
+	return registry.updateObj(makeControllerKey(controller.Namespace, controller.ID), controller, version, "replicationController", controller.ID)
 }
 
-func (registry *EtcdRegistry) DeleteController(controllerID string) error {
-	key := makeControllerKey(controllerID)
+func (registry *EtcdRegistry) DeleteController(namespace, controllerID string) error {
+	key := makeControllerKey(namespace, controllerID)
 	_, err := registry.etcdClient.Delete(key, false)
+	if isEtcdNotFound(err) {
+		return api.NewNotFound("replicationController", controllerID)
+	}
 	return err
 }
 
-func makeServiceKey(name string) string {
-	return "/registry/services/specs/" + name
+func makeServiceKey(namespace, name string) string {
+	return "/registry/services/specs/" + defaultNamespace(namespace) + "/" + name
 }
 
-func (registry *EtcdRegistry) ListServices() (api.ServiceList, error) {
+func makeEndpointsKey(namespace, name string) string {
+	return "/registry/services/endpoints/" + defaultNamespace(namespace) + "/" + name
+}
+
+func (registry *EtcdRegistry) ListServices(namespace string) (api.ServiceList, error) {
 	var list api.ServiceList
-	err := registry.extractList("/registry/services/specs", &list.Items)
+	err := registry.extractList(makeListKey("/registry/services/specs", namespace), &list.Items)
 	return list, err
 }
 
 func (registry *EtcdRegistry) CreateService(svc api.Service) error {
-	return registry.setObj(makeServiceKey(svc.ID), svc)
+	svc.ResourceVersion = 0
+	svc.Namespace = defaultNamespace(svc.Namespace)
+	return registry.createObj(makeServiceKey(svc.Namespace, svc.ID), svc, "service", svc.ID)
 }
 
-func (registry *EtcdRegistry) GetService(name string) (*api.Service, error) {
-	key := makeServiceKey(name)
+func (registry *EtcdRegistry) GetService(namespace, name string) (*api.Service, error) {
+	key := makeServiceKey(namespace, name)
 	var svc api.Service
-	err := registry.extractObj(key, &svc, false)
+	_, err := registry.extractObj(key, &svc, false)
+	if isEtcdNotFound(err) {
+		return nil, api.NewNotFound("service", name)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &svc, nil
 }
 
-func (registry *EtcdRegistry) DeleteService(name string) error {
-	key := makeServiceKey(name)
+func (registry *EtcdRegistry) DeleteService(namespace, name string) error {
+	key := makeServiceKey(namespace, name)
 	_, err := registry.etcdClient.Delete(key, true)
+	if isEtcdNotFound(err) {
+		return api.NewNotFound("service", name)
+	}
 	if err != nil {
 		return err
 	}
-	key = "/registry/services/endpoints/" + name
+	key = makeEndpointsKey(namespace, name)
 	_, err = registry.etcdClient.Delete(key, true)
 	return err
 }
 
+// UpdateService stores 'svc', if it has not changed since its ResourceVersion.
 func (registry *EtcdRegistry) UpdateService(svc api.Service) error {
-	return registry.CreateService(svc)
+	version := svc.ResourceVersion
+	svc.ResourceVersion = 0
+	svc.Namespace = defaultNamespace(svc.Namespace)
+	return registry.updateObj(makeServiceKey(svc.Namespace, svc.ID), svc, version, "service", svc.ID)
+}
+
+func (registry *EtcdRegistry) WatchServices(resourceVersion uint64) (watch.Interface, error) {
+	return makeEtcdWatcher(registry.etcdClient, "/registry/services/specs", resourceVersion, decodeServiceNode), nil
+}
+
+// GetEndpoints returns the endpoints of service 'name' in 'namespace'.
+func (registry *EtcdRegistry) GetEndpoints(namespace, name string) (*api.Endpoints, error) {
+	var endpoints api.Endpoints
+	_, err := registry.extractObj(makeEndpointsKey(namespace, name), &endpoints, false)
+	if isEtcdNotFound(err) {
+		return nil, api.NewNotFound("endpoints", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &endpoints, nil
 }
 
 func (registry *EtcdRegistry) UpdateEndpoints(e api.Endpoints) error {
-	return registry.setObj("/registry/services/endpoints/"+e.Name, e)
+	e.Namespace = defaultNamespace(e.Namespace)
+	return registry.setObj(makeEndpointsKey(e.Namespace, e.Name), e)
+}
+
+func makeMinionKey(id string) string {
+	return "/registry/minions/" + id
+}
+
+func (registry *EtcdRegistry) ListMinions() ([]api.Minion, error) {
+	minions := []api.Minion{}
+	err := registry.extractList("/registry/minions", &minions)
+	return minions, err
+}
+
+func (registry *EtcdRegistry) GetMinion(minionID string) (*api.Minion, error) {
+	var minion api.Minion
+	_, err := registry.extractObj(makeMinionKey(minionID), &minion, false)
+	if isEtcdNotFound(err) {
+		return nil, api.NewNotFound("minion", minionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &minion, nil
+}
+
+func (registry *EtcdRegistry) CreateMinion(minion api.Minion) error {
+	minion.ResourceVersion = 0
+	return registry.createObj(makeMinionKey(minion.ID), minion, "minion", minion.ID)
+}
+
+// UpdateMinion stores 'minion', if it has not changed since its ResourceVersion.
+func (registry *EtcdRegistry) UpdateMinion(minion api.Minion) error {
+	version := minion.ResourceVersion
+	minion.ResourceVersion = 0
+	return registry.updateObj(makeMinionKey(minion.ID), minion, version, "minion", minion.ID)
+}
+
+func (registry *EtcdRegistry) DeleteMinion(minionID string) error {
+	_, err := registry.etcdClient.Delete(makeMinionKey(minionID), false)
+	if isEtcdNotFound(err) {
+		return api.NewNotFound("minion", minionID)
+	}
+	return err
 }
@@ -16,11 +16,16 @@ limitations under the License.
 package registry
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/coreos/go-etcd/etcd"
@@ -28,18 +33,18 @@ import (
 
 func TestEtcdGetPod(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
-	fakeClient.Set("/registry/hosts/machine/pods/foo", util.MakeJSONString(api.Pod{JSONBase: api.JSONBase{ID: "foo"}}), 0)
+	fakeClient.Set("/registry/hosts/machine/pods/default/foo", util.MakeJSONString(api.Pod{JSONBase: api.JSONBase{ID: "foo"}}), 0)
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
-	pod, err := registry.GetPod("foo")
+	pod, err := registry.GetPod(api.NamespaceDefault, "foo")
 	expectNoError(t, err)
-	if pod.ID != "foo" {
+	if pod.ID != "foo" || pod.ResourceVersion != fakeClient.ChangeIndex {
 		t.Errorf("Unexpected pod: %#v", pod)
 	}
 }
 
 func TestEtcdGetPodNotFound(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
-	fakeClient.Data["/registry/hosts/machine/pods/foo"] = EtcdResponseWithError{
+	fakeClient.Data["/registry/hosts/machine/pods/default/foo"] = EtcdResponseWithError{
 		R: &etcd.Response{
 			Node: nil,
 		},
@@ -48,15 +53,15 @@ func TestEtcdGetPodNotFound(t *testing.T) {
 		},
 	}
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
-	_, err := registry.GetPod("foo")
-	if err == nil {
-		t.Errorf("Unexpected non-error.")
+	_, err := registry.GetPod(api.NamespaceDefault, "foo")
+	if !api.IsNotFound(err) {
+		t.Errorf("Unexpected error: %#v", err)
 	}
 }
 
 func TestEtcdCreatePod(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
-	fakeClient.Data["/registry/hosts/machine/pods/foo"] = EtcdResponseWithError{
+	fakeClient.Data["/registry/hosts/machine/pods/default/foo"] = EtcdResponseWithError{
 		R: &etcd.Response{
 			Node: nil,
 		},
@@ -79,7 +84,7 @@ func TestEtcdCreatePod(t *testing.T) {
 		},
 	})
 	expectNoError(t, err)
-	resp, err := fakeClient.Get("/registry/hosts/machine/pods/foo", false, false)
+	resp, err := fakeClient.Get("/registry/hosts/machine/pods/default/foo", false, false)
 	expectNoError(t, err)
 	var pod api.Pod
 	err = json.Unmarshal([]byte(resp.Node.Value), &pod)
@@ -91,14 +96,14 @@ func TestEtcdCreatePod(t *testing.T) {
 	resp, err = fakeClient.Get("/registry/hosts/machine/kubelet", false, false)
 	expectNoError(t, err)
 	err = json.Unmarshal([]byte(resp.Node.Value), &manifests)
-	if len(manifests) != 1 || manifests[0].Id != "foo" {
+	if len(manifests) != 1 || manifests[0].Id != "foo.default" {
 		t.Errorf("Unexpected manifest list: %#v", manifests)
 	}
 }
 
 func TestEtcdCreatePodAlreadyExisting(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
-	fakeClient.Data["/registry/hosts/machine/pods/foo"] = EtcdResponseWithError{
+	fakeClient.Data["/registry/hosts/machine/pods/default/foo"] = EtcdResponseWithError{
 		R: &etcd.Response{
 			Node: &etcd.Node{
 				Value: util.MakeJSONString(api.Pod{JSONBase: api.JSONBase{ID: "foo"}}),
@@ -112,14 +117,14 @@ func TestEtcdCreatePodAlreadyExisting(t *testing.T) {
 			ID: "foo",
 		},
 	})
-	if err == nil {
-		t.Error("Unexpected non-error")
+	if !api.IsAlreadyExists(err) {
+		t.Errorf("Unexpected error: %#v", err)
 	}
 }
 
 func TestEtcdCreatePodWithContainersError(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
-	fakeClient.Data["/registry/hosts/machine/pods/foo"] = EtcdResponseWithError{
+	fakeClient.Data["/registry/hosts/machine/pods/default/foo"] = EtcdResponseWithError{
 		R: &etcd.Response{
 			Node: nil,
 		},
@@ -140,7 +145,7 @@ func TestEtcdCreatePodWithContainersError(t *testing.T) {
 	if err == nil {
 		t.Error("Unexpected non-error")
 	}
-	_, err = fakeClient.Get("/registry/hosts/machine/pods/foo", false, false)
+	_, err = fakeClient.Get("/registry/hosts/machine/pods/default/foo", false, false)
 	if err == nil {
 		t.Error("Unexpected non-error")
 	}
@@ -151,7 +156,7 @@ func TestEtcdCreatePodWithContainersError(t *testing.T) {
 
 func TestEtcdCreatePodWithContainersNotFound(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
-	fakeClient.Data["/registry/hosts/machine/pods/foo"] = EtcdResponseWithError{
+	fakeClient.Data["/registry/hosts/machine/pods/default/foo"] = EtcdResponseWithError{
 		R: &etcd.Response{
 			Node: nil,
 		},
@@ -170,7 +175,7 @@ func TestEtcdCreatePodWithContainersNotFound(t *testing.T) {
 		},
 		DesiredState: api.PodState{
 			Manifest: api.ContainerManifest{
-				Id: "foo",
+				Id: "foo.default",
 				Containers: []api.Container{
 					{
 						Name: "foo",
@@ -180,7 +185,7 @@ func TestEtcdCreatePodWithContainersNotFound(t *testing.T) {
 		},
 	})
 	expectNoError(t, err)
-	resp, err := fakeClient.Get("/registry/hosts/machine/pods/foo", false, false)
+	resp, err := fakeClient.Get("/registry/hosts/machine/pods/default/foo", false, false)
 	expectNoError(t, err)
 	var pod api.Pod
 	err = json.Unmarshal([]byte(resp.Node.Value), &pod)
@@ -192,14 +197,14 @@ func TestEtcdCreatePodWithContainersNotFound(t *testing.T) {
 	resp, err = fakeClient.Get("/registry/hosts/machine/kubelet", false, false)
 	expectNoError(t, err)
 	err = json.Unmarshal([]byte(resp.Node.Value), &manifests)
-	if len(manifests) != 1 || manifests[0].Id != "foo" {
+	if len(manifests) != 1 || manifests[0].Id != "foo.default" {
 		t.Errorf("Unexpected manifest list: %#v", manifests)
 	}
 }
 
 func TestEtcdCreatePodWithExistingContainers(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
-	fakeClient.Data["/registry/hosts/machine/pods/foo"] = EtcdResponseWithError{
+	fakeClient.Data["/registry/hosts/machine/pods/default/foo"] = EtcdResponseWithError{
 		R: &etcd.Response{
 			Node: nil,
 		},
@@ -207,7 +212,7 @@ func TestEtcdCreatePodWithExistingContainers(t *testing.T) {
 	}
 	fakeClient.Set("/registry/hosts/machine/kubelet", util.MakeJSONString([]api.ContainerManifest{
 		{
-			Id: "bar",
+			Id: "bar.default",
 		},
 	}), 0)
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
@@ -217,7 +222,7 @@ func TestEtcdCreatePodWithExistingContainers(t *testing.T) {
 		},
 		DesiredState: api.PodState{
 			Manifest: api.ContainerManifest{
-				Id: "foo",
+				Id: "foo.default",
 				Containers: []api.Container{
 					{
 						Name: "foo",
@@ -227,7 +232,7 @@ func TestEtcdCreatePodWithExistingContainers(t *testing.T) {
 		},
 	})
 	expectNoError(t, err)
-	resp, err := fakeClient.Get("/registry/hosts/machine/pods/foo", false, false)
+	resp, err := fakeClient.Get("/registry/hosts/machine/pods/default/foo", false, false)
 	expectNoError(t, err)
 	var pod api.Pod
 	err = json.Unmarshal([]byte(resp.Node.Value), &pod)
@@ -239,22 +244,22 @@ func TestEtcdCreatePodWithExistingContainers(t *testing.T) {
 	resp, err = fakeClient.Get("/registry/hosts/machine/kubelet", false, false)
 	expectNoError(t, err)
 	err = json.Unmarshal([]byte(resp.Node.Value), &manifests)
-	if len(manifests) != 2 || manifests[1].Id != "foo" {
+	if len(manifests) != 2 || manifests[1].Id != "foo.default" {
 		t.Errorf("Unexpected manifest list: %#v", manifests)
 	}
 }
 
 func TestEtcdDeletePod(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
-	key := "/registry/hosts/machine/pods/foo"
+	key := "/registry/hosts/machine/pods/default/foo"
 	fakeClient.Set(key, util.MakeJSONString(api.Pod{JSONBase: api.JSONBase{ID: "foo"}}), 0)
 	fakeClient.Set("/registry/hosts/machine/kubelet", util.MakeJSONString([]api.ContainerManifest{
 		{
-			Id: "foo",
+			Id: "foo.default",
 		},
 	}), 0)
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
-	err := registry.DeletePod("foo")
+	err := registry.DeletePod(api.NamespaceDefault, "foo")
 	expectNoError(t, err)
 	if len(fakeClient.deletedKeys) != 1 {
 		t.Errorf("Expected 1 delete, found %#v", fakeClient.deletedKeys)
@@ -270,14 +275,14 @@ func TestEtcdDeletePod(t *testing.T) {
 
 func TestEtcdDeletePodMultipleContainers(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
-	key := "/registry/hosts/machine/pods/foo"
+	key := "/registry/hosts/machine/pods/default/foo"
 	fakeClient.Set(key, util.MakeJSONString(api.Pod{JSONBase: api.JSONBase{ID: "foo"}}), 0)
 	fakeClient.Set("/registry/hosts/machine/kubelet", util.MakeJSONString([]api.ContainerManifest{
-		{Id: "foo"},
-		{Id: "bar"},
+		{Id: "foo.default"},
+		{Id: "bar.default"},
 	}), 0)
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
-	err := registry.DeletePod("foo")
+	err := registry.DeletePod(api.NamespaceDefault, "foo")
 	expectNoError(t, err)
 	if len(fakeClient.deletedKeys) != 1 {
 		t.Errorf("Expected 1 delete, found %#v", fakeClient.deletedKeys)
@@ -291,11 +296,100 @@ func TestEtcdDeletePodMultipleContainers(t *testing.T) {
 	if len(manifests) != 1 {
 		t.Errorf("Unexpected manifest set: %#v, expected empty", manifests)
 	}
-	if manifests[0].Id != "bar" {
+	if manifests[0].Id != "bar.default" {
 		t.Errorf("Deleted wrong manifest: %#v", manifests)
 	}
 }
 
+// failingDeleteEtcdClient fails deletes with 'err' while it is set.
+type failingDeleteEtcdClient struct {
+	*FakeEtcdClient
+	err error
+}
+
+func (f *failingDeleteEtcdClient) Delete(key string, recursive bool) (*etcd.Response, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.FakeEtcdClient.Delete(key, recursive)
+}
+
+func TestEtcdMovePodRetry(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	fakeClient.Set("/registry/hosts/machine/pods/default/foo", util.MakeJSONString(api.Pod{JSONBase: api.JSONBase{ID: "foo"}}), 0)
+	fakeClient.Set("/registry/hosts/machine/kubelet", util.MakeJSONString([]api.ContainerManifest{{Id: "foo.default"}}), 0)
+	fakeClient.Data["/registry/hosts/other/pods/default/foo"] = EtcdResponseWithError{
+		R: &etcd.Response{},
+		E: &etcd.EtcdError{ErrorCode: 100},
+	}
+	fakeClient.Set("/registry/hosts/other/kubelet", util.MakeJSONString([]api.ContainerManifest{}), 0)
+	client := &failingDeleteEtcdClient{FakeEtcdClient: fakeClient, err: fmt.Errorf("test error")}
+	registry := MakeTestEtcdRegistry(client, []string{"machine", "other"})
+	if err := registry.MovePod(api.NamespaceDefault, "foo", "other"); err == nil {
+		t.Error("Unexpected non-error")
+	}
+	pod, err := registry.GetPod(api.NamespaceDefault, "foo")
+	expectNoError(t, err)
+	if pod.CurrentState.Host != "other" {
+		t.Errorf("Expected the pod to be found on its new machine: %#v", pod)
+	}
+
+	client.err = nil
+	expectNoError(t, registry.MovePod(api.NamespaceDefault, "foo", "other"))
+	if !reflect.DeepEqual(fakeClient.deletedKeys, []string{"/registry/hosts/machine/pods/default/foo"}) {
+		t.Errorf("Unexpected deletes: %#v", fakeClient.deletedKeys)
+	}
+	var manifests []api.ContainerManifest
+	response, _ := fakeClient.Get("/registry/hosts/other/kubelet", false, false)
+	json.Unmarshal([]byte(response.Node.Value), &manifests)
+	if len(manifests) != 1 || manifests[0].Id != "foo.default" {
+		t.Errorf("Unexpected manifests on the new machine: %#v", manifests)
+	}
+}
+
+func TestEtcdMovePod(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	fakeClient.Set("/registry/hosts/machine/pods/default/foo", util.MakeJSONString(api.Pod{JSONBase: api.JSONBase{ID: "foo"}}), 0)
+	fakeClient.Set("/registry/hosts/machine/kubelet", util.MakeJSONString([]api.ContainerManifest{
+		{Id: "foo.default"},
+		{Id: "bar.default"},
+	}), 0)
+	fakeClient.Data["/registry/hosts/other/pods/default/foo"] = EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: nil,
+		},
+		E: &etcd.EtcdError{
+			ErrorCode: 100,
+		},
+	}
+	fakeClient.Set("/registry/hosts/other/kubelet", util.MakeJSONString([]api.ContainerManifest{
+		{Id: "baz.default"},
+	}), 0)
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine", "other"})
+	err := registry.MovePod(api.NamespaceDefault, "foo", "other")
+	expectNoError(t, err)
+	if !reflect.DeepEqual(fakeClient.deletedKeys, []string{"/registry/hosts/machine/pods/default/foo"}) {
+		t.Errorf("Unexpected deletes: %#v", fakeClient.deletedKeys)
+	}
+	var pod api.Pod
+	response, _ := fakeClient.Get("/registry/hosts/other/pods/default/foo", false, false)
+	json.Unmarshal([]byte(response.Node.Value), &pod)
+	if pod.ID != "foo" || pod.CurrentState.Host != "other" {
+		t.Errorf("Unexpected pod: %#v", pod)
+	}
+	var manifests []api.ContainerManifest
+	response, _ = fakeClient.Get("/registry/hosts/machine/kubelet", false, false)
+	json.Unmarshal([]byte(response.Node.Value), &manifests)
+	if len(manifests) != 1 || manifests[0].Id != "bar.default" {
+		t.Errorf("Unexpected manifests on the old machine: %#v", manifests)
+	}
+	response, _ = fakeClient.Get("/registry/hosts/other/kubelet", false, false)
+	json.Unmarshal([]byte(response.Node.Value), &manifests)
+	if len(manifests) != 2 || manifests[0].Id != "baz.default" || manifests[1].Id != "foo.default" {
+		t.Errorf("Unexpected manifests on the new machine: %#v", manifests)
+	}
+}
+
 func TestEtcdEmptyListPods(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
 	key := "/registry/hosts/machine/pods"
@@ -308,7 +402,7 @@ func TestEtcdEmptyListPods(t *testing.T) {
 		E: nil,
 	}
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
-	pods, err := registry.ListPods(labels.Everything())
+	pods, err := registry.ListPods(api.NamespaceAll, labels.Everything())
 	expectNoError(t, err)
 	if len(pods) != 0 {
 		t.Errorf("Unexpected pod list: %#v", pods)
@@ -323,7 +417,7 @@ func TestEtcdListPodsNotFound(t *testing.T) {
 		E: &etcd.EtcdError{ErrorCode: 100},
 	}
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
-	pods, err := registry.ListPods(labels.Everything())
+	pods, err := registry.ListPods(api.NamespaceAll, labels.Everything())
 	expectNoError(t, err)
 	if len(pods) != 0 {
 		t.Errorf("Unexpected pod list: %#v", pods)
@@ -338,10 +432,12 @@ func TestEtcdListPods(t *testing.T) {
 			Node: &etcd.Node{
 				Nodes: []*etcd.Node{
 					{
-						Value: util.MakeJSONString(api.Pod{JSONBase: api.JSONBase{ID: "foo"}}),
+						Value:         util.MakeJSONString(api.Pod{JSONBase: api.JSONBase{ID: "foo"}}),
+						ModifiedIndex: 1,
 					},
 					{
-						Value: util.MakeJSONString(api.Pod{JSONBase: api.JSONBase{ID: "bar"}}),
+						Value:         util.MakeJSONString(api.Pod{JSONBase: api.JSONBase{ID: "bar"}}),
+						ModifiedIndex: 2,
 					},
 				},
 			},
@@ -349,9 +445,50 @@ func TestEtcdListPods(t *testing.T) {
 		E: nil,
 	}
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
-	pods, err := registry.ListPods(labels.Everything())
+	pods, err := registry.ListPods(api.NamespaceAll, labels.Everything())
 	expectNoError(t, err)
-	if len(pods) != 2 || pods[0].ID != "foo" || pods[1].ID != "bar" {
+	if len(pods) != 2 || pods[0].ID != "foo" || pods[1].ID != "bar" || pods[0].ResourceVersion != 1 || pods[1].ResourceVersion != 2 {
+		t.Errorf("Unexpected pod list: %#v", pods)
+	}
+}
+
+func TestEtcdListPodsInNamespaces(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	teamPods := &etcd.Node{
+		Key: "/registry/hosts/machine/pods/team",
+		Dir: true,
+		Nodes: []*etcd.Node{
+			{Value: util.MakeJSONString(api.Pod{JSONBase: api.JSONBase{ID: "foo", Namespace: "team"}})},
+		},
+	}
+	fakeClient.Data["/registry/hosts/machine/pods"] = EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: &etcd.Node{
+				Nodes: []*etcd.Node{
+					{
+						Key: "/registry/hosts/machine/pods/default",
+						Dir: true,
+						Nodes: []*etcd.Node{
+							{Value: util.MakeJSONString(api.Pod{JSONBase: api.JSONBase{ID: "foo", Namespace: "default"}})},
+						},
+					},
+					teamPods,
+				},
+			},
+		},
+	}
+	fakeClient.Data["/registry/hosts/machine/pods/team"] = EtcdResponseWithError{
+		R: &etcd.Response{Node: teamPods},
+	}
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+	pods, err := registry.ListPods(api.NamespaceAll, labels.Everything())
+	expectNoError(t, err)
+	if len(pods) != 2 || pods[0].Namespace != "default" || pods[1].Namespace != "team" {
+		t.Errorf("Unexpected pod list: %#v", pods)
+	}
+	pods, err = registry.ListPods("team", labels.Everything())
+	expectNoError(t, err)
+	if len(pods) != 1 || pods[0].ID != "foo" || pods[0].Namespace != "team" {
 		t.Errorf("Unexpected pod list: %#v", pods)
 	}
 }
@@ -364,7 +501,7 @@ func TestEtcdListControllersNotFound(t *testing.T) {
 		E: &etcd.EtcdError{ErrorCode: 100},
 	}
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
-	controllers, err := registry.ListControllers()
+	controllers, err := registry.ListControllers(api.NamespaceAll)
 	expectNoError(t, err)
 	if len(controllers) != 0 {
 		t.Errorf("Unexpected controller list: %#v", controllers)
@@ -379,7 +516,7 @@ func TestEtcdListServicesNotFound(t *testing.T) {
 		E: &etcd.EtcdError{ErrorCode: 100},
 	}
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
-	services, err := registry.ListServices()
+	services, err := registry.ListServices(api.NamespaceAll)
 	expectNoError(t, err)
 	if len(services.Items) != 0 {
 		t.Errorf("Unexpected controller list: %#v", services)
@@ -405,7 +542,7 @@ func TestEtcdListControllers(t *testing.T) {
 		E: nil,
 	}
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
-	controllers, err := registry.ListControllers()
+	controllers, err := registry.ListControllers(api.NamespaceAll)
 	expectNoError(t, err)
 	if len(controllers) != 2 || controllers[0].ID != "foo" || controllers[1].ID != "bar" {
 		t.Errorf("Unexpected controller list: %#v", controllers)
@@ -414,9 +551,9 @@ func TestEtcdListControllers(t *testing.T) {
 
 func TestEtcdGetController(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
-	fakeClient.Set("/registry/controllers/foo", util.MakeJSONString(api.ReplicationController{JSONBase: api.JSONBase{ID: "foo"}}), 0)
+	fakeClient.Set("/registry/controllers/default/foo", util.MakeJSONString(api.ReplicationController{JSONBase: api.JSONBase{ID: "foo"}}), 0)
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
-	ctrl, err := registry.GetController("foo")
+	ctrl, err := registry.GetController(api.NamespaceDefault, "foo")
 	expectNoError(t, err)
 	if ctrl.ID != "foo" {
 		t.Errorf("Unexpected controller: %#v", ctrl)
@@ -425,7 +562,7 @@ func TestEtcdGetController(t *testing.T) {
 
 func TestEtcdGetControllerNotFound(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
-	fakeClient.Data["/registry/controllers/foo"] = EtcdResponseWithError{
+	fakeClient.Data["/registry/controllers/default/foo"] = EtcdResponseWithError{
 		R: &etcd.Response{
 			Node: nil,
 		},
@@ -434,24 +571,24 @@ func TestEtcdGetControllerNotFound(t *testing.T) {
 		},
 	}
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
-	ctrl, err := registry.GetController("foo")
+	ctrl, err := registry.GetController(api.NamespaceDefault, "foo")
 	if ctrl != nil {
 		t.Errorf("Unexpected non-nil controller: %#v", ctrl)
 	}
-	if err == nil {
-		t.Error("Unexpected non-error.")
+	if !api.IsNotFound(err) {
+		t.Errorf("Unexpected error: %#v", err)
 	}
 }
 
 func TestEtcdDeleteController(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
-	err := registry.DeleteController("foo")
+	err := registry.DeleteController(api.NamespaceDefault, "foo")
 	expectNoError(t, err)
 	if len(fakeClient.deletedKeys) != 1 {
 		t.Errorf("Expected 1 delete, found %#v", fakeClient.deletedKeys)
 	}
-	key := "/registry/controllers/foo"
+	key := "/registry/controllers/default/foo"
 	if fakeClient.deletedKeys[0] != key {
 		t.Errorf("Unexpected key: %s, expected %s", fakeClient.deletedKeys[0], key)
 	}
@@ -466,7 +603,7 @@ func TestEtcdCreateController(t *testing.T) {
 		},
 	})
 	expectNoError(t, err)
-	resp, err := fakeClient.Get("/registry/controllers/foo", false, false)
+	resp, err := fakeClient.Get("/registry/controllers/default/foo", false, false)
 	expectNoError(t, err)
 	var ctrl api.ReplicationController
 	err = json.Unmarshal([]byte(resp.Node.Value), &ctrl)
@@ -476,9 +613,74 @@ func TestEtcdCreateController(t *testing.T) {
 	}
 }
 
+func TestEtcdCreateDuplicate(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	for _, key := range []string{"/registry/hosts/machine/pods/default/foo", "/registry/hosts/machine/kubelet", "/registry/minions"} {
+		fakeClient.Data[key] = EtcdResponseWithError{
+			R: &etcd.Response{},
+			E: &etcd.EtcdError{ErrorCode: 100},
+		}
+	}
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+	server := httptest.NewServer(apiserver.New(map[string]apiserver.RESTStorage{
+		"pods":                   MakePodRegistryStorage(registry, nil, nil, nil, MakeRoundRobinScheduler(registry.minionRegistry)),
+		"replicationControllers": MakeControllerRegistryStorage(registry),
+		"services":               MakeServiceRegistryStorage(registry, nil, registry.minionRegistry),
+		"minions":                MakeMinionRegistryStorage(registry, nil, 10250),
+	}, "/api/v1beta1"))
+	defer server.Close()
+
+	table := map[string]interface{}{
+		"pods": api.Pod{JSONBase: api.JSONBase{ID: "foo"}},
+		"replicationControllers": api.ReplicationController{
+			JSONBase: api.JSONBase{ID: "foo"},
+			DesiredState: api.ReplicationControllerState{
+				ReplicasInSet: map[string]string{"name": "foo"},
+				PodTemplate:   api.PodTemplate{Labels: map[string]string{"name": "foo"}},
+			},
+		},
+		"services": api.Service{JSONBase: api.JSONBase{ID: "foo"}, Port: 8080},
+		"minions":  api.Minion{JSONBase: api.JSONBase{ID: "foo"}},
+	}
+	for resource, obj := range table {
+		data, err := api.Encode(obj)
+		expectNoError(t, err)
+		for _, code := range []int{http.StatusOK, http.StatusConflict} {
+			response, err := http.Post(server.URL+"/api/v1beta1/"+resource, "application/json", bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var status api.Status
+			json.NewDecoder(response.Body).Decode(&status)
+			response.Body.Close()
+			if response.StatusCode != code {
+				t.Errorf("Unexpected status creating %s: %d, expected %d", resource, response.StatusCode, code)
+			}
+			if code == http.StatusConflict && status.Reason != api.StatusReasonAlreadyExists {
+				t.Errorf("Unexpected status creating %s: %#v", resource, status)
+			}
+		}
+	}
+}
+
+func TestEtcdCreateMinionAlreadyExisting(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	registry := MakeEtcdRegistry(fakeClient, nil)
+	fakeClient.Set("/registry/minions/m1", util.MakeJSONString(api.Minion{JSONBase: api.JSONBase{ID: "m1"}, HostIP: "10.0.0.1"}), 0)
+	err := registry.CreateMinion(api.Minion{JSONBase: api.JSONBase{ID: "m1"}})
+	if !api.IsAlreadyExists(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	minion, err := registry.GetMinion("m1")
+	expectNoError(t, err)
+	if minion.HostIP != "10.0.0.1" {
+		t.Errorf("Expected the minion to be unchanged: %#v", minion)
+	}
+}
+
 func TestEtcdUpdateController(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
-	fakeClient.Set("/registry/controllers/foo", util.MakeJSONString(api.ReplicationController{JSONBase: api.JSONBase{ID: "foo"}}), 0)
+	fakeClient.Set("/registry/controllers/default/foo", util.MakeJSONString(api.ReplicationController{JSONBase: api.JSONBase{ID: "foo"}}), 0)
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
 	err := registry.UpdateController(api.ReplicationController{
 		JSONBase: api.JSONBase{ID: "foo"},
@@ -487,12 +689,60 @@ func TestEtcdUpdateController(t *testing.T) {
 		},
 	})
 	expectNoError(t, err)
-	ctrl, err := registry.GetController("foo")
+	ctrl, err := registry.GetController(api.NamespaceDefault, "foo")
 	if ctrl.DesiredState.Replicas != 2 {
 		t.Errorf("Unexpected controller: %#v", ctrl)
 	}
 }
 
+func TestEtcdUpdateControllerResourceVersion(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	fakeClient.Set("/registry/controllers/default/foo", util.MakeJSONString(api.ReplicationController{JSONBase: api.JSONBase{ID: "foo"}}), 0)
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+	ctrl, err := registry.GetController(api.NamespaceDefault, "foo")
+	expectNoError(t, err)
+	ctrl.DesiredState.Replicas = 2
+	expectNoError(t, registry.UpdateController(*ctrl))
+
+	// ctrl is now out of date.
+	ctrl.DesiredState.Replicas = 3
+	err = registry.UpdateController(*ctrl)
+	if !api.IsConflict(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	stored, err := registry.GetController(api.NamespaceDefault, "foo")
+	expectNoError(t, err)
+	if stored.DesiredState.Replicas != 2 || stored.ResourceVersion != fakeClient.ChangeIndex {
+		t.Errorf("Unexpected controller: %#v", stored)
+	}
+	var data api.ReplicationController
+	expectNoError(t, json.Unmarshal([]byte(fakeClient.Data["/registry/controllers/default/foo"].R.Node.Value), &data))
+	if data.ResourceVersion != 0 {
+		t.Errorf("Unexpected stored resourceVersion: %#v", data)
+	}
+}
+
+func TestEtcdUpdateControllerNotFound(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+	err := registry.UpdateController(api.ReplicationController{JSONBase: api.JSONBase{ID: "foo", ResourceVersion: 1}})
+	if !api.IsNotFound(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
+
+func TestEtcdUpdateControllerWithoutVersionNotFound(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+	err := registry.UpdateController(api.ReplicationController{JSONBase: api.JSONBase{ID: "foo"}})
+	if !api.IsNotFound(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if _, ok := fakeClient.Data["/registry/controllers/default/foo"]; ok {
+		t.Errorf("Expected the controller not to be created: %#v", fakeClient.Data)
+	}
+}
+
 func TestEtcdListServices(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
 	key := "/registry/services/specs"
@@ -512,7 +762,7 @@ func TestEtcdListServices(t *testing.T) {
 		E: nil,
 	}
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
-	services, err := registry.ListServices()
+	services, err := registry.ListServices(api.NamespaceAll)
 	expectNoError(t, err)
 	if len(services.Items) != 2 || services.Items[0].ID != "foo" || services.Items[1].ID != "bar" {
 		t.Errorf("Unexpected pod list: %#v", services)
@@ -521,7 +771,7 @@ func TestEtcdListServices(t *testing.T) {
 
 func TestEtcdCreateService(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
-	fakeClient.Data["/registry/services/specs/foo"] = EtcdResponseWithError{
+	fakeClient.Data["/registry/services/specs/default/foo"] = EtcdResponseWithError{
 		R: &etcd.Response{
 			Node: nil,
 		},
@@ -532,21 +782,21 @@ func TestEtcdCreateService(t *testing.T) {
 		JSONBase: api.JSONBase{ID: "foo"},
 	})
 	expectNoError(t, err)
-	resp, err := fakeClient.Get("/registry/services/specs/foo", false, false)
+	resp, err := fakeClient.Get("/registry/services/specs/default/foo", false, false)
 	expectNoError(t, err)
 	var service api.Service
 	err = json.Unmarshal([]byte(resp.Node.Value), &service)
 	expectNoError(t, err)
-	if service.ID != "foo" {
+	if service.ID != "foo" || service.APIVersion != api.StorageVersion {
 		t.Errorf("Unexpected service: %#v %s", service, resp.Node.Value)
 	}
 }
 
 func TestEtcdGetService(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
-	fakeClient.Set("/registry/services/specs/foo", util.MakeJSONString(api.Service{JSONBase: api.JSONBase{ID: "foo"}}), 0)
+	fakeClient.Set("/registry/services/specs/default/foo", util.MakeJSONString(api.Service{JSONBase: api.JSONBase{ID: "foo"}}), 0)
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
-	service, err := registry.GetService("foo")
+	service, err := registry.GetService(api.NamespaceDefault, "foo")
 	expectNoError(t, err)
 	if service.ID != "foo" {
 		t.Errorf("Unexpected pod: %#v", service)
@@ -555,7 +805,7 @@ func TestEtcdGetService(t *testing.T) {
 
 func TestEtcdGetServiceNotFound(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
-	fakeClient.Data["/registry/services/specs/foo"] = EtcdResponseWithError{
+	fakeClient.Data["/registry/services/specs/default/foo"] = EtcdResponseWithError{
 		R: &etcd.Response{
 			Node: nil,
 		},
@@ -564,25 +814,25 @@ func TestEtcdGetServiceNotFound(t *testing.T) {
 		},
 	}
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
-	_, err := registry.GetService("foo")
-	if err == nil {
-		t.Errorf("Unexpected non-error.")
+	_, err := registry.GetService(api.NamespaceDefault, "foo")
+	if !api.IsNotFound(err) {
+		t.Errorf("Unexpected error: %#v", err)
 	}
 }
 
 func TestEtcdDeleteService(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
-	err := registry.DeleteService("foo")
+	err := registry.DeleteService(api.NamespaceDefault, "foo")
 	expectNoError(t, err)
 	if len(fakeClient.deletedKeys) != 2 {
 		t.Errorf("Expected 2 delete, found %#v", fakeClient.deletedKeys)
 	}
-	key := "/registry/services/specs/foo"
+	key := "/registry/services/specs/default/foo"
 	if fakeClient.deletedKeys[0] != key {
 		t.Errorf("Unexpected key: %s, expected %s", fakeClient.deletedKeys[0], key)
 	}
-	key = "/registry/services/endpoints/foo"
+	key = "/registry/services/endpoints/default/foo"
 	if fakeClient.deletedKeys[1] != key {
 		t.Errorf("Unexpected key: %s, expected %s", fakeClient.deletedKeys[1], key)
 	}
@@ -590,7 +840,7 @@ func TestEtcdDeleteService(t *testing.T) {
 
 func TestEtcdUpdateService(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
-	fakeClient.Set("/registry/services/specs/foo", util.MakeJSONString(api.Service{JSONBase: api.JSONBase{ID: "foo"}}), 0)
+	fakeClient.Set("/registry/services/specs/default/foo", util.MakeJSONString(api.Service{JSONBase: api.JSONBase{ID: "foo"}}), 0)
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
 	err := registry.UpdateService(api.Service{
 		JSONBase: api.JSONBase{ID: "foo"},
@@ -599,22 +849,90 @@ func TestEtcdUpdateService(t *testing.T) {
 		},
 	})
 	expectNoError(t, err)
-	svc, err := registry.GetService("foo")
+	svc, err := registry.GetService(api.NamespaceDefault, "foo")
 	if svc.Labels["baz"] != "bar" {
 		t.Errorf("Unexpected service: %#v", svc)
 	}
 }
 
+func TestEtcdUpdateServiceConflict(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	fakeClient.Set("/registry/services/specs/default/foo", util.MakeJSONString(api.Service{JSONBase: api.JSONBase{ID: "foo"}}), 0)
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+	err := registry.UpdateService(api.Service{JSONBase: api.JSONBase{ID: "foo", ResourceVersion: fakeClient.ChangeIndex + 1}})
+	if !api.IsConflict(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
+
+// racingEtcdClient runs 'race' just before the 'n'th get of 'key'.
+type racingEtcdClient struct {
+	*FakeEtcdClient
+	key  string
+	n    int
+	race func()
+}
+
+func (c *racingEtcdClient) Get(key string, sort, recursive bool) (*etcd.Response, error) {
+	if key == c.key {
+		c.n--
+		if c.n == 0 {
+			c.race()
+		}
+	}
+	return c.FakeEtcdClient.Get(key, sort, recursive)
+}
+
+func TestEtcdCreatePodRetriesManifestConflicts(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	fakeClient.Data["/registry/hosts/machine/pods/default/foo"] = EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: nil,
+		},
+		E: &etcd.EtcdError{ErrorCode: 100},
+	}
+	fakeClient.Set("/registry/hosts/machine/kubelet", util.MakeJSONString([]api.ContainerManifest{{Id: "bar.default"}}), 0)
+	client := &racingEtcdClient{
+		FakeEtcdClient: fakeClient,
+		key:            "/registry/hosts/machine/kubelet",
+		n:              2,
+		race: func() {
+			// Another pod is added after the manifests were read for the first time.
+			fakeClient.Set("/registry/hosts/machine/kubelet", util.MakeJSONString([]api.ContainerManifest{{Id: "bar.default"}, {Id: "baz.default"}}), 0)
+		},
+	}
+	registry := MakeTestEtcdRegistry(client, []string{"machine"})
+	err := registry.CreatePod("machine", api.Pod{
+		JSONBase: api.JSONBase{
+			ID: "foo",
+		},
+		DesiredState: api.PodState{
+			Manifest: api.ContainerManifest{
+				Id: "foo.default",
+			},
+		},
+	})
+	expectNoError(t, err)
+	var manifests []api.ContainerManifest
+	resp, err := fakeClient.Get("/registry/hosts/machine/kubelet", false, false)
+	expectNoError(t, err)
+	expectNoError(t, json.Unmarshal([]byte(resp.Node.Value), &manifests))
+	if len(manifests) != 3 || manifests[0].Id != "bar.default" || manifests[1].Id != "baz.default" || manifests[2].Id != "foo.default" {
+		t.Errorf("Unexpected manifest list: %#v", manifests)
+	}
+}
+
 func TestEtcdUpdateEndpoints(t *testing.T) {
 	fakeClient := MakeFakeEtcdClient(t)
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
 	endpoints := api.Endpoints{
 		Name:      "foo",
+		Namespace: "team",
 		Endpoints: []string{"baz", "bar"},
 	}
 	err := registry.UpdateEndpoints(endpoints)
 	expectNoError(t, err)
-	response, err := fakeClient.Get("/registry/services/endpoints/foo", false, false)
+	response, err := fakeClient.Get("/registry/services/endpoints/team/foo", false, false)
 	expectNoError(t, err)
 	var endpointsOut api.Endpoints
 	err = json.Unmarshal([]byte(response.Node.Value), &endpointsOut)
@@ -622,3 +940,91 @@ func TestEtcdUpdateEndpoints(t *testing.T) {
 		t.Errorf("Unexpected endpoints: %#v, expected %#v", endpointsOut, endpoints)
 	}
 }
+
+func TestEtcdListMinions(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	fakeClient.Data["/registry/minions"] = EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: &etcd.Node{
+				Nodes: []*etcd.Node{
+					{
+						Value: util.MakeJSONString(api.Minion{JSONBase: api.JSONBase{ID: "m1"}}),
+					},
+					{
+						Value: util.MakeJSONString(api.Minion{JSONBase: api.JSONBase{ID: "m2"}}),
+					},
+				},
+			},
+		},
+		E: nil,
+	}
+	registry := MakeEtcdRegistry(fakeClient, nil)
+	minions, err := registry.ListMinions()
+	expectNoError(t, err)
+	if len(minions) != 2 || minions[0].ID != "m1" || minions[1].ID != "m2" {
+		t.Errorf("Unexpected minion list: %#v", minions)
+	}
+}
+
+func TestEtcdCreateGetMinion(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	registry := MakeEtcdRegistry(fakeClient, nil)
+	err := registry.CreateMinion(api.Minion{JSONBase: api.JSONBase{ID: "m1"}, HostIP: "10.0.0.1"})
+	expectNoError(t, err)
+	minion, err := registry.GetMinion("m1")
+	expectNoError(t, err)
+	if minion.ID != "m1" || minion.HostIP != "10.0.0.1" {
+		t.Errorf("Unexpected minion: %#v", minion)
+	}
+}
+
+func TestEtcdDeleteMinion(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	registry := MakeEtcdRegistry(fakeClient, nil)
+	err := registry.DeleteMinion("m1")
+	expectNoError(t, err)
+	if len(fakeClient.deletedKeys) != 1 || fakeClient.deletedKeys[0] != "/registry/minions/m1" {
+		t.Errorf("Unexpected deleted keys: %#v", fakeClient.deletedKeys)
+	}
+}
+
+func TestEtcdListPodsFollowsMinions(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	fakeClient.Data["/registry/hosts/machine2/pods"] = EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: &etcd.Node{
+				Nodes: []*etcd.Node{
+					{
+						Value: util.MakeJSONString(api.Pod{JSONBase: api.JSONBase{ID: "foo"}}),
+					},
+				},
+			},
+		},
+		E: nil,
+	}
+	minions := MakeMemoryMinionRegistry([]string{})
+	registry := MakeEtcdRegistry(fakeClient, minions)
+	pods, err := registry.ListPods(api.NamespaceAll, labels.Everything())
+	expectNoError(t, err)
+	if len(pods) != 0 {
+		t.Errorf("Unexpected pod list: %#v", pods)
+	}
+	minions.CreateMinion(api.Minion{JSONBase: api.JSONBase{ID: "machine2"}})
+	pods, err = registry.ListPods(api.NamespaceAll, labels.Everything())
+	expectNoError(t, err)
+	if len(pods) != 1 || pods[0].ID != "foo" {
+		t.Errorf("Unexpected pod list: %#v", pods)
+	}
+}
+
+func TestEtcdGetEndpoints(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	endpoints := api.Endpoints{Name: "foo", Namespace: "team", Endpoints: []string{"baz", "bar"}}
+	fakeClient.Set("/registry/services/endpoints/team/foo", util.MakeJSONString(endpoints), 0)
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+	endpointsOut, err := registry.GetEndpoints("team", "foo")
+	expectNoError(t, err)
+	if !reflect.DeepEqual(endpointsOut, &endpoints) {
+		t.Errorf("Unexpected endpoints: %#v, expected %#v", endpointsOut, endpoints)
+	}
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"log"
+	"regexp"
+	"sync"
+
+	"github.com/coreos/go-etcd/etcd"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+)
+
+// etcdDecoder turns an etcd node into the object it stores. It returns false for nodes that
+// should not be reported, such as other keys under the watched directory. The node of a
+// deleted object may have no value.
+type etcdDecoder func(node *etcd.Node) (interface{}, bool, error)
+
+// etcdWatcher reports the changes etcd makes under a key as watch events.
+type etcdWatcher struct {
+	decode   etcdDecoder
+	incoming chan *etcd.Response
+	etcdStop chan bool
+	result   chan watch.Event
+	stopOnce sync.Once
+}
+
+// makeEtcdWatcher watches 'key' and everything below it, starting with the changes after
+// etcd index 'resourceVersion', or with the next change if it is 0.
+func makeEtcdWatcher(client EtcdClient, key string, resourceVersion uint64, decode etcdDecoder) *etcdWatcher {
+	w := &etcdWatcher{
+		decode:   decode,
+		incoming: make(chan *etcd.Response),
+		etcdStop: make(chan bool),
+		result:   make(chan watch.Event),
+	}
+	waitIndex := uint64(0)
+	if resourceVersion > 0 {
+		waitIndex = resourceVersion + 1
+	}
+	go func() {
+		defer util.HandleCrash()
+		// Watch closes 'incoming' when it returns.
+		_, err := client.Watch(key, waitIndex, true, w.incoming, w.etcdStop)
+		if err != nil && err != etcd.ErrWatchStoppedByUser {
+			log.Printf("Error watching %s: %#v", key, err)
+		}
+	}()
+	go w.translate()
+	return w
+}
+
+func (w *etcdWatcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+func (w *etcdWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.etcdStop)
+	})
+}
+
+// translate sends an event for each etcd response until the etcd watch ends. Responses that
+// arrive after Stop are dropped, so that the etcd watch is never blocked.
+func (w *etcdWatcher) translate() {
+	defer close(w.result)
+	for response := range w.incoming {
+		event, ok := w.makeEvent(response)
+		if !ok {
+			continue
+		}
+		select {
+		case w.result <- event:
+		case <-w.etcdStop:
+		}
+	}
+}
+
+func (w *etcdWatcher) makeEvent(response *etcd.Response) (watch.Event, bool) {
+	if response == nil {
+		return watch.Event{}, false
+	}
+	var eventType watch.EventType
+	node := response.Node
+	switch response.Action {
+	case "create":
+		eventType = watch.Added
+	case "set", "update", "compareAndSwap":
+		eventType = watch.Modified
+		if response.PrevNode == nil {
+			eventType = watch.Added
+		}
+	case "delete", "expire", "compareAndDelete":
+		eventType = watch.Deleted
+		if response.PrevNode != nil {
+			node = response.PrevNode
+		}
+	default:
+		log.Printf("Unknown etcd action %s: %#v", response.Action, response)
+		return watch.Event{}, false
+	}
+	if node == nil || node.Dir {
+		return watch.Event{}, false
+	}
+	obj, ok, err := w.decode(node)
+	if err != nil {
+		log.Printf("Error decoding %s: %#v", node.Key, err)
+		return watch.Event{}, false
+	}
+	if !ok {
+		return watch.Event{}, false
+	}
+	return watch.Event{Type: eventType, Object: obj}, true
+}
+
+// decodeNodeValue unmarshals the value of 'node' into 'objPtr', if it has one, and sets its
+// ResourceVersion.
+func decodeNodeValue(node *etcd.Node, objPtr interface{}) error {
+	if len(node.Value) == 0 {
+		return nil
+	}
+	if err := api.DecodeInto([]byte(node.Value), objPtr); err != nil {
+		return err
+	}
+	setResourceVersion(objPtr, node.ModifiedIndex)
+	return nil
+}
+
+var podKeyRegexp = regexp.MustCompile("^/registry/hosts/([^/]+)/pods/([^/]+)/([^/]+)$")
+
+func decodePodNode(node *etcd.Node) (interface{}, bool, error) {
+	match := podKeyRegexp.FindStringSubmatch(node.Key)
+	if match == nil {
+		return nil, false, nil
+	}
+	var pod api.Pod
+	if err := decodeNodeValue(node, &pod); err != nil {
+		return nil, false, err
+	}
+	pod.Namespace = match[2]
+	pod.ID = match[3]
+	pod.CurrentState.Host = match[1]
+	return pod, true, nil
+}
+
+// makeChildDecoder returns a decoder for objects stored in the namespace directories under
+// 'dir', which decodes them with 'decode' and gives them the namespace and ID of their key.
+func makeChildDecoder(dir string, decode func(namespace, id string, node *etcd.Node) (interface{}, error)) etcdDecoder {
+	keyRegexp := regexp.MustCompile("^" + regexp.QuoteMeta(dir) + "/([^/]+)/([^/]+)$")
+	return func(node *etcd.Node) (interface{}, bool, error) {
+		match := keyRegexp.FindStringSubmatch(node.Key)
+		if match == nil {
+			return nil, false, nil
+		}
+		obj, err := decode(match[1], match[2], node)
+		return obj, err == nil, err
+	}
+}
+
+var decodeControllerNode = makeChildDecoder("/registry/controllers", func(namespace, id string, node *etcd.Node) (interface{}, error) {
+	var controller api.ReplicationController
+	err := decodeNodeValue(node, &controller)
+	controller.Namespace = namespace
+	controller.ID = id
+	return controller, err
+})
+
+var decodeServiceNode = makeChildDecoder("/registry/services/specs", func(namespace, id string, node *etcd.Node) (interface{}, error) {
+	var svc api.Service
+	err := decodeNodeValue(node, &svc)
+	svc.Namespace = namespace
+	svc.ID = id
+	return svc, err
+})
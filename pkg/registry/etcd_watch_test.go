@@ -0,0 +1,128 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/go-etcd/etcd"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+)
+
+func TestEtcdWatchPods(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	fakeClient.WatchResponse = make(chan *etcd.Response)
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+	watching, err := registry.WatchPods(5)
+	expectNoError(t, err)
+
+	pod := api.Pod{JSONBase: api.JSONBase{ID: "foo"}, Labels: map[string]string{"name": "foo"}}
+	go func() {
+		fakeClient.WatchResponse <- &etcd.Response{
+			Action: "create",
+			Node:   &etcd.Node{Key: "/registry/hosts/machine/pods/default/foo", Value: util.MakeJSONString(pod)},
+		}
+		fakeClient.WatchResponse <- &etcd.Response{
+			Action: "set",
+			Node:   &etcd.Node{Key: "/registry/hosts/machine/kubelet", Value: "[]"},
+		}
+		fakeClient.WatchResponse <- &etcd.Response{
+			Action:   "delete",
+			Node:     &etcd.Node{Key: "/registry/hosts/machine/pods/default/foo"},
+			PrevNode: &etcd.Node{Key: "/registry/hosts/machine/pods/default/foo", Value: util.MakeJSONString(pod)},
+		}
+	}()
+
+	expectedPod := pod
+	expectedPod.Namespace = api.NamespaceDefault
+	expectedPod.CurrentState.Host = "machine"
+	for _, eventType := range []watch.EventType{watch.Added, watch.Deleted} {
+		event := <-watching.ResultChan()
+		if event.Type != eventType || !reflect.DeepEqual(event.Object, expectedPod) {
+			t.Errorf("Unexpected event: %#v", event)
+		}
+	}
+	if fakeClient.WatchKey != "/registry/hosts" || fakeClient.WatchIndex != 6 {
+		t.Errorf("Unexpected watch of %s from %d", fakeClient.WatchKey, fakeClient.WatchIndex)
+	}
+
+	watching.Stop()
+	if _, ok := <-watching.ResultChan(); ok {
+		t.Errorf("Expected the watch to be closed")
+	}
+}
+
+func TestEtcdWatchControllers(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	fakeClient.WatchResponse = make(chan *etcd.Response)
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+	watching, err := registry.WatchControllers(0)
+	expectNoError(t, err)
+
+	controller := api.ReplicationController{JSONBase: api.JSONBase{ID: "foo"}}
+	go func() {
+		fakeClient.WatchResponse <- &etcd.Response{
+			Action:   "set",
+			Node:     &etcd.Node{Key: "/registry/controllers/default/foo", Value: util.MakeJSONString(controller)},
+			PrevNode: &etcd.Node{Key: "/registry/controllers/default/foo", Value: util.MakeJSONString(controller)},
+		}
+		close(fakeClient.WatchResponse)
+	}()
+
+	expected := controller
+	expected.Namespace = api.NamespaceDefault
+	event := <-watching.ResultChan()
+	if event.Type != watch.Modified || !reflect.DeepEqual(event.Object, expected) {
+		t.Errorf("Unexpected event: %#v", event)
+	}
+	if fakeClient.WatchIndex != 0 {
+		t.Errorf("Unexpected watch index: %d", fakeClient.WatchIndex)
+	}
+	if _, ok := <-watching.ResultChan(); ok {
+		t.Errorf("Expected the watch to end with the etcd watch")
+	}
+}
+
+func TestEtcdWatcherMakeEvent(t *testing.T) {
+	w := &etcdWatcher{decode: decodeServiceNode}
+	svc := api.Service{JSONBase: api.JSONBase{ID: "foo", Namespace: "team"}, Port: 80}
+	node := &etcd.Node{Key: "/registry/services/specs/team/foo", Value: util.MakeJSONString(svc)}
+	table := []struct {
+		response *etcd.Response
+		event    watch.Event
+		ok       bool
+	}{
+		{&etcd.Response{Action: "create", Node: node}, watch.Event{Type: watch.Added, Object: svc}, true},
+		{&etcd.Response{Action: "set", Node: node}, watch.Event{Type: watch.Added, Object: svc}, true},
+		{&etcd.Response{Action: "compareAndSwap", Node: node, PrevNode: node}, watch.Event{Type: watch.Modified, Object: svc}, true},
+		{&etcd.Response{Action: "delete", Node: &etcd.Node{Key: node.Key}, PrevNode: node}, watch.Event{Type: watch.Deleted, Object: svc}, true},
+		{&etcd.Response{Action: "expire", Node: &etcd.Node{Key: node.Key}}, watch.Event{Type: watch.Deleted, Object: api.Service{JSONBase: api.JSONBase{ID: "foo", Namespace: "team"}}}, true},
+		{&etcd.Response{Action: "get", Node: node}, watch.Event{}, false},
+		{&etcd.Response{Action: "set", Node: &etcd.Node{Key: "/registry/services/endpoints/team/foo", Value: "{}"}}, watch.Event{}, false},
+		{&etcd.Response{Action: "set", Node: &etcd.Node{Key: node.Key, Value: "{"}}, watch.Event{}, false},
+	}
+	for _, item := range table {
+		event, ok := w.makeEvent(item.response)
+		if ok != item.ok || !reflect.DeepEqual(event, item.event) {
+			t.Errorf("Unexpected event for %#v: %#v, %v", item.response, event, ok)
+		}
+	}
+}
@@ -33,6 +33,13 @@ type FakeEtcdClient struct {
 	Err         error
 	t           *testing.T
 	Ix          int
+	// ChangeIndex is the etcd index of the last write, which Set assigns as ModifiedIndex.
+	ChangeIndex uint64
+	// If set, watches report the responses sent on WatchResponse.
+	WatchResponse chan *etcd.Response
+	// The index and key of the last watch.
+	WatchIndex uint64
+	WatchKey   string
 }
 
 func MakeFakeEtcdClient(t *testing.T) *FakeEtcdClient {
@@ -57,30 +64,94 @@ func (f *FakeEtcdClient) Get(key string, sort, recursive bool) (*etcd.Response,
 }
 
 func (f *FakeEtcdClient) Set(key, value string, ttl uint64) (*etcd.Response, error) {
+	f.ChangeIndex++
 	result := EtcdResponseWithError{
 		R: &etcd.Response{
 			Node: &etcd.Node{
-				Value: value,
+				Value:         value,
+				ModifiedIndex: f.ChangeIndex,
 			},
 		},
 	}
 	f.Data[key] = result
 	return result.R, f.Err
 }
+
+// Create sets 'key' if it has no value yet.
 func (f *FakeEtcdClient) Create(key, value string, ttl uint64) (*etcd.Response, error) {
+	if result, ok := f.Data[key]; ok && result.E == nil && result.R != nil && result.R.Node != nil {
+		return nil, &etcd.EtcdError{ErrorCode: 105}
+	}
+	return f.Set(key, value, ttl)
+}
+
+// Update sets 'key' if it already has a value.
+func (f *FakeEtcdClient) Update(key, value string, ttl uint64) (*etcd.Response, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	result, ok := f.Data[key]
+	if result.E != nil {
+		return nil, result.E
+	}
+	if !ok || result.R == nil || result.R.Node == nil {
+		return nil, &etcd.EtcdError{ErrorCode: 100}
+	}
+	return f.Set(key, value, ttl)
+}
+
+// CompareAndSwap sets 'key' if it was last modified at 'prevIndex'. Only comparing indexes is supported.
+func (f *FakeEtcdClient) CompareAndSwap(key, value string, ttl uint64, prevValue string, prevIndex uint64) (*etcd.Response, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if prevValue != "" {
+		return nil, fmt.Errorf("unimplemented")
+	}
+	result, ok := f.Data[key]
+	if result.E != nil {
+		return nil, result.E
+	}
+	if !ok || result.R == nil || result.R.Node == nil {
+		return nil, &etcd.EtcdError{ErrorCode: 100}
+	}
+	if result.R.Node.ModifiedIndex != prevIndex {
+		return nil, &etcd.EtcdError{ErrorCode: 101}
+	}
 	return f.Set(key, value, ttl)
 }
+
 func (f *FakeEtcdClient) Delete(key string, recursive bool) (*etcd.Response, error) {
 	f.deletedKeys = append(f.deletedKeys, key)
 	return &etcd.Response{}, f.Err
 }
 
+// Watch behaves like etcd.Client.Watch with a receiver: it closes 'receiver' when it returns.
 func (f *FakeEtcdClient) Watch(prefix string, waitIndex uint64, recursive bool, receiver chan *etcd.Response, stop chan bool) (*etcd.Response, error) {
-	return nil, fmt.Errorf("unimplemented")
+	if receiver == nil {
+		return nil, fmt.Errorf("unimplemented")
+	}
+	defer close(receiver)
+	if f.WatchResponse == nil {
+		return nil, fmt.Errorf("unimplemented")
+	}
+	f.WatchKey = prefix
+	f.WatchIndex = waitIndex
+	for {
+		select {
+		case <-stop:
+			return nil, etcd.ErrWatchStoppedByUser
+		case response, ok := <-f.WatchResponse:
+			if !ok {
+				return nil, fmt.Errorf("watch closed")
+			}
+			receiver <- response
+		}
+	}
 }
 
 func MakeTestEtcdRegistry(client EtcdClient, machines []string) *EtcdRegistry {
-	registry := MakeEtcdRegistry(client, machines)
+	registry := MakeEtcdRegistry(client, MakeMemoryMinionRegistry(machines))
 	registry.manifestFactory = &BasicManifestFactory{
 		serviceRegistry: &MockServiceRegistry{},
 	}
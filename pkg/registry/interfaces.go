@@ -18,37 +18,73 @@ package registry
 import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
+// Pods, controllers and services are stored in the namespace of their Namespace field. Lists
+// of api.NamespaceAll span every namespace; otherwise an empty namespace is the default one.
+// Watches report the objects of every namespace.
+
 // PodRegistry is an interface implemented by things that know how to store Pod objects.
 type PodRegistry interface {
-	// ListPods obtains a list of pods that match query.
-	ListPods(query labels.Query) ([]api.Pod, error)
+	// ListPods obtains a list of pods in 'namespace' that match query.
+	ListPods(namespace string, query labels.Query) ([]api.Pod, error)
 	// Get a specific pod
-	GetPod(podID string) (*api.Pod, error)
+	GetPod(namespace, podID string) (*api.Pod, error)
 	// Create a pod based on a specification, schedule it onto a specific machine.
 	CreatePod(machine string, pod api.Pod) error
 	// Update an existing pod
 	UpdatePod(pod api.Pod) error
 	// Delete an existing pod
-	DeletePod(podID string) error
+	DeletePod(namespace, podID string) error
+	// Move an existing pod, and its manifest, to another machine
+	MovePod(namespace, podID, machine string) error
+	// Watch for changes to pods, after 'resourceVersion' if the registry keeps a history.
+	// Events carry api.Pod values.
+	WatchPods(resourceVersion uint64) (watch.Interface, error)
 }
 
 // ControllerRegistry is an interface for things that know how to store Controllers.
 type ControllerRegistry interface {
-	ListControllers() ([]api.ReplicationController, error)
-	GetController(controllerId string) (*api.ReplicationController, error)
+	ListControllers(namespace string) ([]api.ReplicationController, error)
+	GetController(namespace, controllerId string) (*api.ReplicationController, error)
 	CreateController(controller api.ReplicationController) error
 	UpdateController(controller api.ReplicationController) error
-	DeleteController(controllerId string) error
+	DeleteController(namespace, controllerId string) error
+	WatchControllers(resourceVersion uint64) (watch.Interface, error)
 }
 
 // ServiceRegistry is an interface for things that know how to store services.
 type ServiceRegistry interface {
-	ListServices() (api.ServiceList, error)
+	ListServices(namespace string) (api.ServiceList, error)
 	CreateService(svc api.Service) error
-	GetService(name string) (*api.Service, error)
-	DeleteService(name string) error
+	GetService(namespace, name string) (*api.Service, error)
+	DeleteService(namespace, name string) error
 	UpdateService(svc api.Service) error
 	UpdateEndpoints(e api.Endpoints) error
+	GetEndpoints(namespace, name string) (*api.Endpoints, error)
+	WatchServices(resourceVersion uint64) (watch.Interface, error)
+}
+
+// defaultNamespace returns 'namespace', or the default namespace if it is empty.
+func defaultNamespace(namespace string) string {
+	if len(namespace) == 0 {
+		return api.NamespaceDefault
+	}
+	return namespace
+}
+
+// inNamespace returns whether an object in 'objNamespace' is in 'namespace', which may be
+// api.NamespaceAll.
+func inNamespace(namespace, objNamespace string) bool {
+	return namespace == api.NamespaceAll || defaultNamespace(namespace) == defaultNamespace(objNamespace)
+}
+
+// MinionRegistry is an interface for things that know how to store minions.
+type MinionRegistry interface {
+	ListMinions() ([]api.Minion, error)
+	GetMinion(minionID string) (*api.Minion, error)
+	CreateMinion(minion api.Minion) error
+	UpdateMinion(minion api.Minion) error
+	DeleteMinion(minionID string) error
 }
@@ -29,13 +29,20 @@ type BasicManifestFactory struct {
 }
 
 func (b *BasicManifestFactory) MakeManifest(machine string, pod api.Pod) (api.ContainerManifest, error) {
-	envVars, err := GetServiceEnvironmentVariables(b.serviceRegistry, machine)
+	envVars, err := GetServiceEnvironmentVariables(b.serviceRegistry, pod.Namespace, machine)
 	if err != nil {
 		return api.ContainerManifest{}, err
 	}
 	for ix, container := range pod.DesiredState.Manifest.Containers {
-		pod.DesiredState.Manifest.Id = pod.ID
+		pod.DesiredState.Manifest.Id = makeManifestID(pod.Namespace, pod.ID)
 		pod.DesiredState.Manifest.Containers[ix].Env = append(container.Env, envVars...)
 	}
 	return pod.DesiredState.Manifest, nil
 }
+
+// makeManifestID returns the ID of the manifest of pod 'podID' in 'namespace', which kubelets
+// know the pod by. Pods of different namespaces may share a host and an ID; namespaces have no
+// dots, so the manifest ID names both.
+func makeManifestID(namespace, podID string) string {
+	return podID + "." + defaultNamespace(namespace)
+}
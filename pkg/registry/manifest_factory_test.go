@@ -46,7 +46,7 @@ func TestMakeManifestNoServices(t *testing.T) {
 		container.Env[0].Value != "machine" {
 		t.Errorf("Expected one env vars, got: %#v", manifest)
 	}
-	if manifest.Id != "foobar" {
+	if manifest.Id != "foobar.default" {
 		t.Errorf("Failed to assign id to manifest: %#v")
 	}
 }
@@ -88,6 +88,50 @@ func TestMakeManifestServices(t *testing.T) {
 	}
 }
 
+func TestMakeManifestServicesInNamespace(t *testing.T) {
+	registry := MockServiceRegistry{
+		list: api.ServiceList{
+			Items: []api.Service{
+				{
+					JSONBase: api.JSONBase{ID: "test", Namespace: api.NamespaceDefault},
+					Port:     8080,
+				},
+				{
+					JSONBase: api.JSONBase{ID: "other", Namespace: "team"},
+					Port:     9090,
+				},
+			},
+		},
+	}
+	factory := &BasicManifestFactory{
+		serviceRegistry: &registry,
+	}
+
+	manifest, err := factory.MakeManifest("machine", api.Pod{
+		JSONBase: api.JSONBase{ID: "foo", Namespace: "team"},
+		DesiredState: api.PodState{
+			Manifest: api.ContainerManifest{
+				Containers: []api.Container{
+					{
+						Name: "foo",
+					},
+				},
+			},
+		},
+	})
+	expectNoError(t, err)
+	container := manifest.Containers[0]
+	if len(container.Env) != 2 ||
+		container.Env[0].Name != "OTHER_SERVICE_PORT" ||
+		container.Env[0].Value != "9090" ||
+		container.Env[1].Name != "SERVICE_HOST" {
+		t.Errorf("Expected 2 env vars, got: %#v", manifest)
+	}
+	if manifest.Id != "foo.team" {
+		t.Errorf("Unexpected manifest id: %s", manifest.Id)
+	}
+}
+
 func TestMakeManifestServicesExistingEnvVar(t *testing.T) {
 	registry := MockServiceRegistry{
 		list: api.ServiceList{
@@ -18,14 +18,24 @@ package registry
 import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
+// memoryWatchQueueLength is how many changes a MemoryRegistry queues for slow watchers.
+const memoryWatchQueueLength = 100
+
 // An implementation of PodRegistry and ControllerRegistry that is backed by memory
-// Mainly used for testing.
+// Mainly used for testing. Pods, controllers and services are keyed by makeMemoryKey.
 type MemoryRegistry struct {
 	podData        map[string]api.Pod
 	controllerData map[string]api.ReplicationController
 	serviceData    map[string]api.Service
+	endpointsData  map[string]api.Endpoints
+	minionData     map[string]api.Minion
+	// Changes are broadcast to watchers as they happen; there is no history to watch from.
+	podMux        *watch.Mux
+	controllerMux *watch.Mux
+	serviceMux    *watch.Mux
 }
 
 func MakeMemoryRegistry() *MemoryRegistry {
@@ -33,21 +43,40 @@ func MakeMemoryRegistry() *MemoryRegistry {
 		podData:        map[string]api.Pod{},
 		controllerData: map[string]api.ReplicationController{},
 		serviceData:    map[string]api.Service{},
+		endpointsData:  map[string]api.Endpoints{},
+		minionData:     map[string]api.Minion{},
+		podMux:         watch.MakeMux(memoryWatchQueueLength),
+		controllerMux:  watch.MakeMux(memoryWatchQueueLength),
+		serviceMux:     watch.MakeMux(memoryWatchQueueLength),
+	}
+}
+
+// MakeMemoryMinionRegistry returns a memory backed MinionRegistry containing 'minions'.
+func MakeMemoryMinionRegistry(minions []string) *MemoryRegistry {
+	registry := MakeMemoryRegistry()
+	for _, minion := range minions {
+		registry.minionData[minion] = api.Minion{JSONBase: api.JSONBase{ID: minion}}
 	}
+	return registry
 }
 
-func (registry *MemoryRegistry) ListPods(query labels.Query) ([]api.Pod, error) {
+// makeMemoryKey returns the key of object 'id' in 'namespace'.
+func makeMemoryKey(namespace, id string) string {
+	return defaultNamespace(namespace) + "/" + id
+}
+
+func (registry *MemoryRegistry) ListPods(namespace string, query labels.Query) ([]api.Pod, error) {
 	result := []api.Pod{}
 	for _, value := range registry.podData {
-		if query.Matches(labels.Set(value.Labels)) {
+		if inNamespace(namespace, value.Namespace) && query.Matches(labels.Set(value.Labels)) {
 			result = append(result, value)
 		}
 	}
 	return result, nil
 }
 
-func (registry *MemoryRegistry) GetPod(podID string) (*api.Pod, error) {
-	pod, found := registry.podData[podID]
+func (registry *MemoryRegistry) GetPod(namespace, podID string) (*api.Pod, error) {
+	pod, found := registry.podData[makeMemoryKey(namespace, podID)]
 	if found {
 		return &pod, nil
 	} else {
@@ -56,30 +85,60 @@ func (registry *MemoryRegistry) GetPod(podID string) (*api.Pod, error) {
 }
 
 func (registry *MemoryRegistry) CreatePod(machine string, pod api.Pod) error {
-	registry.podData[pod.ID] = pod
+	pod.Namespace = defaultNamespace(pod.Namespace)
+	key := makeMemoryKey(pod.Namespace, pod.ID)
+	if _, found := registry.podData[key]; found {
+		return api.NewAlreadyExists("pod", pod.ID)
+	}
+	registry.podData[key] = pod
+	registry.podMux.Action(watch.Added, pod)
 	return nil
 }
 
-func (registry *MemoryRegistry) DeletePod(podID string) error {
-	delete(registry.podData, podID)
+func (registry *MemoryRegistry) DeletePod(namespace, podID string) error {
+	key := makeMemoryKey(namespace, podID)
+	if pod, found := registry.podData[key]; found {
+		delete(registry.podData, key)
+		registry.podMux.Action(watch.Deleted, pod)
+	}
 	return nil
 }
 
 func (registry *MemoryRegistry) UpdatePod(pod api.Pod) error {
-	registry.podData[pod.ID] = pod
+	pod.Namespace = defaultNamespace(pod.Namespace)
+	registry.podData[makeMemoryKey(pod.Namespace, pod.ID)] = pod
+	registry.podMux.Action(watch.Modified, pod)
+	return nil
+}
+
+func (registry *MemoryRegistry) MovePod(namespace, podID, machine string) error {
+	key := makeMemoryKey(namespace, podID)
+	pod, found := registry.podData[key]
+	if !found {
+		return api.NewNotFound("pod", podID)
+	}
+	pod.CurrentState.Host = machine
+	registry.podData[key] = pod
+	registry.podMux.Action(watch.Modified, pod)
 	return nil
 }
 
-func (registry *MemoryRegistry) ListControllers() ([]api.ReplicationController, error) {
+func (registry *MemoryRegistry) WatchPods(resourceVersion uint64) (watch.Interface, error) {
+	return registry.podMux.Watch(), nil
+}
+
+func (registry *MemoryRegistry) ListControllers(namespace string) ([]api.ReplicationController, error) {
 	result := []api.ReplicationController{}
 	for _, value := range registry.controllerData {
-		result = append(result, value)
+		if inNamespace(namespace, value.Namespace) {
+			result = append(result, value)
+		}
 	}
 	return result, nil
 }
 
-func (registry *MemoryRegistry) GetController(controllerID string) (*api.ReplicationController, error) {
-	controller, found := registry.controllerData[controllerID]
+func (registry *MemoryRegistry) GetController(namespace, controllerID string) (*api.ReplicationController, error) {
+	controller, found := registry.controllerData[makeMemoryKey(namespace, controllerID)]
 	if found {
 		return &controller, nil
 	} else {
@@ -88,35 +147,59 @@ func (registry *MemoryRegistry) GetController(controllerID string) (*api.Replica
 }
 
 func (registry *MemoryRegistry) CreateController(controller api.ReplicationController) error {
-	registry.controllerData[controller.ID] = controller
+	controller.Namespace = defaultNamespace(controller.Namespace)
+	key := makeMemoryKey(controller.Namespace, controller.ID)
+	if _, found := registry.controllerData[key]; found {
+		return api.NewAlreadyExists("replicationController", controller.ID)
+	}
+	registry.controllerData[key] = controller
+	registry.controllerMux.Action(watch.Added, controller)
 	return nil
 }
 
-func (registry *MemoryRegistry) DeleteController(controllerId string) error {
-	delete(registry.controllerData, controllerId)
+func (registry *MemoryRegistry) DeleteController(namespace, controllerId string) error {
+	key := makeMemoryKey(namespace, controllerId)
+	if controller, found := registry.controllerData[key]; found {
+		delete(registry.controllerData, key)
+		registry.controllerMux.Action(watch.Deleted, controller)
+	}
 	return nil
 }
 
 func (registry *MemoryRegistry) UpdateController(controller api.ReplicationController) error {
-	registry.controllerData[controller.ID] = controller
+	controller.Namespace = defaultNamespace(controller.Namespace)
+	registry.controllerData[makeMemoryKey(controller.Namespace, controller.ID)] = controller
+	registry.controllerMux.Action(watch.Modified, controller)
 	return nil
 }
 
-func (registry *MemoryRegistry) ListServices() (api.ServiceList, error) {
+func (registry *MemoryRegistry) WatchControllers(resourceVersion uint64) (watch.Interface, error) {
+	return registry.controllerMux.Watch(), nil
+}
+
+func (registry *MemoryRegistry) ListServices(namespace string) (api.ServiceList, error) {
 	var list []api.Service
 	for _, value := range registry.serviceData {
-		list = append(list, value)
+		if inNamespace(namespace, value.Namespace) {
+			list = append(list, value)
+		}
 	}
 	return api.ServiceList{Items: list}, nil
 }
 
 func (registry *MemoryRegistry) CreateService(svc api.Service) error {
-	registry.serviceData[svc.ID] = svc
+	svc.Namespace = defaultNamespace(svc.Namespace)
+	key := makeMemoryKey(svc.Namespace, svc.ID)
+	if _, found := registry.serviceData[key]; found {
+		return api.NewAlreadyExists("service", svc.ID)
+	}
+	registry.serviceData[key] = svc
+	registry.serviceMux.Action(watch.Added, svc)
 	return nil
 }
 
-func (registry *MemoryRegistry) GetService(name string) (*api.Service, error) {
-	svc, found := registry.serviceData[name]
+func (registry *MemoryRegistry) GetService(namespace, name string) (*api.Service, error) {
+	svc, found := registry.serviceData[makeMemoryKey(namespace, name)]
 	if found {
 		return &svc, nil
 	} else {
@@ -124,15 +207,71 @@ func (registry *MemoryRegistry) GetService(name string) (*api.Service, error) {
 	}
 }
 
-func (registry *MemoryRegistry) DeleteService(name string) error {
-	delete(registry.serviceData, name)
+func (registry *MemoryRegistry) DeleteService(namespace, name string) error {
+	key := makeMemoryKey(namespace, name)
+	if svc, found := registry.serviceData[key]; found {
+		delete(registry.serviceData, key)
+		registry.serviceMux.Action(watch.Deleted, svc)
+	}
 	return nil
 }
 
 func (registry *MemoryRegistry) UpdateService(svc api.Service) error {
-	return registry.CreateService(svc)
+	svc.Namespace = defaultNamespace(svc.Namespace)
+	registry.serviceData[makeMemoryKey(svc.Namespace, svc.ID)] = svc
+	registry.serviceMux.Action(watch.Modified, svc)
+	return nil
+}
+
+func (registry *MemoryRegistry) WatchServices(resourceVersion uint64) (watch.Interface, error) {
+	return registry.serviceMux.Watch(), nil
 }
 
 func (registry *MemoryRegistry) UpdateEndpoints(e api.Endpoints) error {
+	e.Namespace = defaultNamespace(e.Namespace)
+	registry.endpointsData[makeMemoryKey(e.Namespace, e.Name)] = e
+	return nil
+}
+
+func (registry *MemoryRegistry) GetEndpoints(namespace, name string) (*api.Endpoints, error) {
+	endpoints, found := registry.endpointsData[makeMemoryKey(namespace, name)]
+	if !found {
+		return nil, api.NewNotFound("endpoints", name)
+	}
+	return &endpoints, nil
+}
+
+func (registry *MemoryRegistry) ListMinions() ([]api.Minion, error) {
+	result := []api.Minion{}
+	for _, value := range registry.minionData {
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+func (registry *MemoryRegistry) GetMinion(minionID string) (*api.Minion, error) {
+	minion, found := registry.minionData[minionID]
+	if found {
+		return &minion, nil
+	} else {
+		return nil, nil
+	}
+}
+
+func (registry *MemoryRegistry) CreateMinion(minion api.Minion) error {
+	if _, found := registry.minionData[minion.ID]; found {
+		return api.NewAlreadyExists("minion", minion.ID)
+	}
+	registry.minionData[minion.ID] = minion
+	return nil
+}
+
+func (registry *MemoryRegistry) UpdateMinion(minion api.Minion) error {
+	registry.minionData[minion.ID] = minion
+	return nil
+}
+
+func (registry *MemoryRegistry) DeleteMinion(minionID string) error {
+	delete(registry.minionData, minionID)
 	return nil
 }
@@ -16,15 +16,17 @@ limitations under the License.
 package registry
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
 func TestListPodsEmpty(t *testing.T) {
 	registry := MakeMemoryRegistry()
-	pods, err := registry.ListPods(labels.Everything())
+	pods, err := registry.ListPods(api.NamespaceAll, labels.Everything())
 	expectNoError(t, err)
 	if len(pods) != 0 {
 		t.Errorf("Unexpected pod list: %#v", pods)
@@ -34,18 +36,45 @@ func TestListPodsEmpty(t *testing.T) {
 func TestMemoryListPods(t *testing.T) {
 	registry := MakeMemoryRegistry()
 	registry.CreatePod("machine", api.Pod{JSONBase: api.JSONBase{ID: "foo"}})
-	pods, err := registry.ListPods(labels.Everything())
+	pods, err := registry.ListPods(api.NamespaceAll, labels.Everything())
 	expectNoError(t, err)
 	if len(pods) != 1 || pods[0].ID != "foo" {
 		t.Errorf("Unexpected pod list: %#v", pods)
 	}
 }
 
+func TestMemoryNamespaces(t *testing.T) {
+	registry := MakeMemoryRegistry()
+	registry.CreatePod("machine", api.Pod{JSONBase: api.JSONBase{ID: "foo"}})
+	registry.CreatePod("machine", api.Pod{JSONBase: api.JSONBase{ID: "foo", Namespace: "team"}, Labels: map[string]string{"team": "a"}})
+	pods, err := registry.ListPods(api.NamespaceAll, labels.Everything())
+	expectNoError(t, err)
+	if len(pods) != 2 {
+		t.Errorf("Unexpected pod list: %#v", pods)
+	}
+	pods, err = registry.ListPods("team", labels.Everything())
+	expectNoError(t, err)
+	if len(pods) != 1 || pods[0].Labels["team"] != "a" {
+		t.Errorf("Unexpected pod list: %#v", pods)
+	}
+	registry.DeletePod("team", "foo")
+	pod, err := registry.GetPod(api.NamespaceDefault, "foo")
+	expectNoError(t, err)
+	if pod == nil || pod.Namespace != api.NamespaceDefault {
+		t.Errorf("Unexpected pod: %#v", pod)
+	}
+	pod, err = registry.GetPod("team", "foo")
+	expectNoError(t, err)
+	if pod != nil {
+		t.Errorf("Unexpected pod: %#v", pod)
+	}
+}
+
 func TestMemorySetGetPods(t *testing.T) {
 	registry := MakeMemoryRegistry()
 	expectedPod := api.Pod{JSONBase: api.JSONBase{ID: "foo"}}
 	registry.CreatePod("machine", expectedPod)
-	pod, err := registry.GetPod("foo")
+	pod, err := registry.GetPod(api.NamespaceDefault, "foo")
 	expectNoError(t, err)
 	if expectedPod.ID != pod.ID {
 		t.Errorf("Unexpected pod, expected %#v, actual %#v", expectedPod, pod)
@@ -65,7 +94,7 @@ func TestMemorySetUpdateGetPods(t *testing.T) {
 	}
 	registry.CreatePod("machine", oldPod)
 	registry.UpdatePod(expectedPod)
-	pod, err := registry.GetPod("foo")
+	pod, err := registry.GetPod(api.NamespaceDefault, "foo")
 	expectNoError(t, err)
 	if expectedPod.ID != pod.ID || pod.DesiredState.Host != expectedPod.DesiredState.Host {
 		t.Errorf("Unexpected pod, expected %#v, actual %#v", expectedPod, pod)
@@ -76,8 +105,8 @@ func TestMemorySetDeleteGetPods(t *testing.T) {
 	registry := MakeMemoryRegistry()
 	expectedPod := api.Pod{JSONBase: api.JSONBase{ID: "foo"}}
 	registry.CreatePod("machine", expectedPod)
-	registry.DeletePod("foo")
-	pod, err := registry.GetPod("foo")
+	registry.DeletePod(api.NamespaceDefault, "foo")
+	pod, err := registry.GetPod(api.NamespaceDefault, "foo")
 	expectNoError(t, err)
 	if pod != nil {
 		t.Errorf("Unexpected pod: %#v", pod)
@@ -86,7 +115,7 @@ func TestMemorySetDeleteGetPods(t *testing.T) {
 
 func TestListControllersEmpty(t *testing.T) {
 	registry := MakeMemoryRegistry()
-	pods, err := registry.ListControllers()
+	pods, err := registry.ListControllers(api.NamespaceAll)
 	expectNoError(t, err)
 	if len(pods) != 0 {
 		t.Errorf("Unexpected pod list: %#v", pods)
@@ -96,7 +125,7 @@ func TestListControllersEmpty(t *testing.T) {
 func TestMemoryListControllers(t *testing.T) {
 	registry := MakeMemoryRegistry()
 	registry.CreateController(api.ReplicationController{JSONBase: api.JSONBase{ID: "foo"}})
-	pods, err := registry.ListControllers()
+	pods, err := registry.ListControllers(api.NamespaceAll)
 	expectNoError(t, err)
 	if len(pods) != 1 || pods[0].ID != "foo" {
 		t.Errorf("Unexpected pod list: %#v", pods)
@@ -107,7 +136,7 @@ func TestMemorySetGetControllers(t *testing.T) {
 	registry := MakeMemoryRegistry()
 	expectedController := api.ReplicationController{JSONBase: api.JSONBase{ID: "foo"}}
 	registry.CreateController(expectedController)
-	pod, err := registry.GetController("foo")
+	pod, err := registry.GetController(api.NamespaceDefault, "foo")
 	expectNoError(t, err)
 	if expectedController.ID != pod.ID {
 		t.Errorf("Unexpected pod, expected %#v, actual %#v", expectedController, pod)
@@ -127,7 +156,7 @@ func TestMemorySetUpdateGetControllers(t *testing.T) {
 	}
 	registry.CreateController(oldController)
 	registry.UpdateController(expectedController)
-	pod, err := registry.GetController("foo")
+	pod, err := registry.GetController(api.NamespaceDefault, "foo")
 	expectNoError(t, err)
 	if expectedController.ID != pod.ID || pod.DesiredState.Replicas != expectedController.DesiredState.Replicas {
 		t.Errorf("Unexpected pod, expected %#v, actual %#v", expectedController, pod)
@@ -138,10 +167,59 @@ func TestMemorySetDeleteGetControllers(t *testing.T) {
 	registry := MakeMemoryRegistry()
 	expectedController := api.ReplicationController{JSONBase: api.JSONBase{ID: "foo"}}
 	registry.CreateController(expectedController)
-	registry.DeleteController("foo")
-	pod, err := registry.GetController("foo")
+	registry.DeleteController(api.NamespaceDefault, "foo")
+	pod, err := registry.GetController(api.NamespaceDefault, "foo")
 	expectNoError(t, err)
 	if pod != nil {
 		t.Errorf("Unexpected pod: %#v", pod)
 	}
 }
+
+func TestMemoryMinions(t *testing.T) {
+	registry := MakeMemoryMinionRegistry([]string{"m1"})
+	err := registry.CreateMinion(api.Minion{JSONBase: api.JSONBase{ID: "m2"}})
+	expectNoError(t, err)
+	names, err := listMinionNames(registry)
+	expectNoError(t, err)
+	if !reflect.DeepEqual(names, []string{"m1", "m2"}) {
+		t.Errorf("Unexpected minion names: %#v", names)
+	}
+	minion, err := registry.GetMinion("m2")
+	expectNoError(t, err)
+	if minion == nil || minion.ID != "m2" {
+		t.Errorf("Unexpected minion: %#v", minion)
+	}
+	err = registry.DeleteMinion("m1")
+	expectNoError(t, err)
+	minion, err = registry.GetMinion("m1")
+	expectNoError(t, err)
+	if minion != nil {
+		t.Errorf("Unexpected minion: %#v", minion)
+	}
+}
+
+func TestMemoryWatchPods(t *testing.T) {
+	registry := MakeMemoryRegistry()
+	registry.CreatePod("machine", api.Pod{JSONBase: api.JSONBase{ID: "before"}})
+	watching, err := registry.WatchPods(0)
+	expectNoError(t, err)
+	pod := api.Pod{JSONBase: api.JSONBase{ID: "foo", Namespace: api.NamespaceDefault}}
+	registry.CreatePod("machine", pod)
+	registry.MovePod(api.NamespaceDefault, "foo", "other")
+	registry.DeletePod(api.NamespaceDefault, "foo")
+	registry.DeletePod(api.NamespaceDefault, "missing")
+
+	moved := pod
+	moved.CurrentState.Host = "other"
+	expected := []watch.Event{{Type: watch.Added, Object: pod}, {Type: watch.Modified, Object: moved}, {Type: watch.Deleted, Object: moved}}
+	for _, item := range expected {
+		event := <-watching.ResultChan()
+		if !reflect.DeepEqual(item, event) {
+			t.Errorf("Expected %#v, got %#v", item, event)
+		}
+	}
+	watching.Stop()
+	if _, ok := <-watching.ResultChan(); ok {
+		t.Errorf("Expected the watch to be closed")
+	}
+}
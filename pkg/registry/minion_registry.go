@@ -0,0 +1,274 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/ipam"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+// listMinionNames returns the sorted IDs of the minions in 'registry'.
+func listMinionNames(registry MinionRegistry) ([]string, error) {
+	minions, err := registry.ListMinions()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, minion := range minions {
+		names = append(names, minion.ID)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// AddMinions adds a minion for each of 'ids' that 'registry' does not have yet. Minions that
+// are already registered, e.g. by their kubelets, are left as they are.
+func AddMinions(registry MinionRegistry, ids []string) {
+	for _, id := range ids {
+		minion, err := registry.GetMinion(id)
+		if err != nil && !api.IsNotFound(err) {
+			log.Printf("Failed to look up minion %s: %v", id, err)
+			continue
+		}
+		if err == nil && minion != nil {
+			continue
+		}
+		err = registry.CreateMinion(api.Minion{JSONBase: api.JSONBase{ID: id}})
+		if err != nil && !api.IsAlreadyExists(err) {
+			log.Printf("Failed to register minion %s: %v", id, err)
+		}
+	}
+}
+
+// Implementation of RESTStorage for the api server.
+type MinionRegistryStorage struct {
+	registry MinionRegistry
+	// subnets assigns pod subnets to minions. If nil, minions only get the subnets they ask for.
+	subnets *ipam.SubnetAllocator
+	// subnetLock serializes subnet assignment, so that two minions can't be given the same subnet.
+	subnetLock sync.Mutex
+	// kubeletPort is the port the kubelets of minions serve on.
+	kubeletPort uint
+}
+
+func MakeMinionRegistryStorage(registry MinionRegistry, subnets *ipam.SubnetAllocator, kubeletPort uint) apiserver.RESTStorage {
+	return &MinionRegistryStorage{
+		registry:    registry,
+		subnets:     subnets,
+		kubeletPort: kubeletPort,
+	}
+}
+
+// ClusterScoped implements apiserver.ClusterScoped: minions are not in a namespace.
+func (storage *MinionRegistryStorage) ClusterScoped() bool {
+	return true
+}
+
+func (storage *MinionRegistryStorage) List(namespace string, query labels.Query) (interface{}, error) {
+	result := api.MinionList{JSONBase: api.JSONBase{Kind: "cluster#minionList"}}
+	minions, err := storage.registry.ListMinions()
+	if err == nil {
+		for _, minion := range minions {
+			minion.Kind = "cluster#minion"
+			result.Items = append(result.Items, minion)
+		}
+	}
+	return result, err
+}
+
+func (storage *MinionRegistryStorage) Get(namespace, id string) (interface{}, error) {
+	minion, err := storage.registry.GetMinion(id)
+	if err != nil {
+		return nil, err
+	}
+	if minion == nil {
+		return nil, api.NewNotFound("minion", id)
+	}
+	minion.Kind = "cluster#minion"
+	return minion, err
+}
+
+// ResourceLocation implements apiserver.ResourceLocator: requests to a minion are proxied to
+// its kubelet.
+func (storage *MinionRegistryStorage) ResourceLocation(namespace, id string) (string, error) {
+	minion, err := storage.registry.GetMinion(id)
+	if err != nil {
+		return "", err
+	}
+	if minion == nil {
+		return "", api.NewNotFound("minion", id)
+	}
+	host := minion.HostIP
+	if len(host) == 0 {
+		host = minion.ID
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(storage.kubeletPort))), nil
+}
+
+func (storage *MinionRegistryStorage) Delete(namespace, id string) error {
+	return storage.registry.DeleteMinion(id)
+}
+
+func (storage *MinionRegistryStorage) Extract(body string, version string) (interface{}, error) {
+	result := api.Minion{}
+	if err := api.DecodeVersionInto([]byte(body), version, &result); err != nil {
+		return nil, api.NewInvalid("minion", "", err)
+	}
+	result.Kind = "cluster#minion"
+	return result, nil
+}
+
+// recordHeartbeat marks 'minion' as ready and alive now.
+func recordHeartbeat(minion *api.Minion) {
+	minion.Condition = api.MinionReady
+	minion.LastHeartbeat = time.Now().Unix()
+}
+
+// Act implements apiserver.RESTActor. The kubelet of a minion reports that it is alive with the
+// "heartbeat" action, which marks the minion as ready.
+func (storage *MinionRegistryStorage) Act(namespace, id, action string, params url.Values) (interface{}, error) {
+	if action != "heartbeat" {
+		return nil, nil
+	}
+	minion, err := storage.registry.GetMinion(id)
+	if err != nil || minion == nil {
+		return nil, err
+	}
+	recordHeartbeat(minion)
+	if err := storage.registry.UpdateMinion(*minion); err != nil {
+		return nil, err
+	}
+	minion.Kind = "cluster#minion"
+	return minion, nil
+}
+
+// assignPodCIDR gives 'minion' a pod subnet, unless it asked for one that no other minion uses.
+// A minion keeps the subnet it was given before, so that the pod IPs its kubelet handed out stay valid.
+func (storage *MinionRegistryStorage) assignPodCIDR(minion *api.Minion) error {
+	minions, err := storage.registry.ListMinions()
+	if err != nil {
+		return err
+	}
+	used := []string{}
+	for _, existing := range minions {
+		if existing.ID == minion.ID {
+			if len(minion.PodCIDR) == 0 {
+				minion.PodCIDR = existing.PodCIDR
+			}
+			continue
+		}
+		if ipam.Overlaps(minion.PodCIDR, existing.PodCIDR) {
+			return api.NewInvalid("minion", minion.ID, fmt.Errorf("pod subnet %s overlaps %s of minion %s", minion.PodCIDR, existing.PodCIDR, existing.ID))
+		}
+		used = append(used, existing.PodCIDR)
+	}
+	if len(minion.PodCIDR) > 0 {
+		return nil
+	}
+	if storage.subnets == nil {
+		return nil
+	}
+	minion.PodCIDR, err = storage.subnets.Allocate(used)
+	return err
+}
+
+func (storage *MinionRegistryStorage) Create(minion interface{}) error {
+	m := minion.(api.Minion)
+	if len(m.ID) == 0 {
+		return api.NewInvalid("minion", "", fmt.Errorf("id is required"))
+	}
+	storage.subnetLock.Lock()
+	defer storage.subnetLock.Unlock()
+	if err := storage.assignPodCIDR(&m); err != nil {
+		return err
+	}
+	return storage.registry.CreateMinion(m)
+}
+
+func (storage *MinionRegistryStorage) Update(minion interface{}) error {
+	m := minion.(api.Minion)
+	existing, err := storage.registry.GetMinion(m.ID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return api.NewNotFound("minion", m.ID)
+	}
+	// Only heartbeats and the MinionController change whether a minion is ready.
+	m.Condition = existing.Condition
+	m.LastHeartbeat = existing.LastHeartbeat
+	storage.subnetLock.Lock()
+	defer storage.subnetLock.Unlock()
+	if err := storage.assignPodCIDR(&m); err != nil {
+		return err
+	}
+	return storage.registry.UpdateMinion(m)
+}
+
+// MinionController marks minions whose kubelets stop sending heartbeats as not ready.
+type MinionController struct {
+	registry MinionRegistry
+	timeout  time.Duration
+	now      func() time.Time
+}
+
+// MakeMinionController creates a controller that expects a heartbeat from each minion at least every 'timeout'.
+func MakeMinionController(registry MinionRegistry, timeout time.Duration) *MinionController {
+	return &MinionController{
+		registry: registry,
+		timeout:  timeout,
+		now:      time.Now,
+	}
+}
+
+// SyncMinionConditions marks minions that have missed their heartbeats as NotReady.
+func (c *MinionController) SyncMinionConditions() error {
+	minions, err := c.registry.ListMinions()
+	if err != nil {
+		return err
+	}
+	deadline := c.now().Add(-c.timeout).Unix()
+	var resultErr error
+	for _, minion := range minions {
+		if minion.LastHeartbeat == 0 || minion.LastHeartbeat >= deadline || minion.Condition == api.MinionNotReady {
+			continue
+		}
+		log.Printf("Minion %s missed its heartbeat, marking it %s", minion.ID, api.MinionNotReady)
+		minion.Condition = api.MinionNotReady
+		err := c.registry.UpdateMinion(minion)
+		if api.IsConflict(err) {
+			// The minion changed since it was listed, most likely because of a heartbeat.
+			continue
+		}
+		if err != nil {
+			log.Printf("Error updating minion: %#v", err)
+			resultErr = err
+		}
+	}
+	return resultErr
+}
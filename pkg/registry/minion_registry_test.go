@@ -0,0 +1,221 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"net"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/ipam"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/coreos/go-etcd/etcd"
+)
+
+func TestMinionRegistryStorage(t *testing.T) {
+	storage := MakeMinionRegistryStorage(MakeMemoryMinionRegistry([]string{"m1"}), nil, 10250)
+	obj, err := storage.Extract(`{"id": "m2", "hostIP": "10.0.0.2"}`, api.StorageVersion)
+	expectNoError(t, err)
+	err = storage.Create(obj)
+	expectNoError(t, err)
+
+	obj, err = storage.List(api.NamespaceAll, labels.Everything())
+	expectNoError(t, err)
+	list := obj.(api.MinionList)
+	names := []string{}
+	for _, minion := range list.Items {
+		names = append(names, minion.ID)
+	}
+	if len(names) != 2 || list.Kind != "cluster#minionList" {
+		t.Errorf("Unexpected minion list: %#v", list)
+	}
+
+	obj, err = storage.Get(api.NamespaceDefault, "m2")
+	expectNoError(t, err)
+	minion := obj.(*api.Minion)
+	// Creating a minion isn't a heartbeat, only its kubelet sends those.
+	expected := &api.Minion{JSONBase: api.JSONBase{Kind: "cluster#minion", ID: "m2"}, HostIP: "10.0.0.2"}
+	if !reflect.DeepEqual(minion, expected) {
+		t.Errorf("Unexpected minion: %#v, expected %#v", minion, expected)
+	}
+
+	err = storage.Delete(api.NamespaceDefault, "m2")
+	expectNoError(t, err)
+	obj, err = storage.Get(api.NamespaceDefault, "m2")
+	if !api.IsNotFound(err) || obj != nil {
+		t.Errorf("Unexpected minion: %#v, %#v", obj, err)
+	}
+}
+
+func TestAddMinions(t *testing.T) {
+	fakeClient := MakeFakeEtcdClient(t)
+	registered := api.Minion{
+		JSONBase:      api.JSONBase{ID: "m1"},
+		HostIP:        "10.0.0.1",
+		Condition:     api.MinionReady,
+		LastHeartbeat: 1234,
+		PodCIDR:       "10.244.1.0/24",
+	}
+	fakeClient.Set("/registry/minions/m1", util.MakeJSONString(registered), 0)
+	fakeClient.Data["/registry/minions/m2"] = EtcdResponseWithError{
+		R: &etcd.Response{},
+		E: &etcd.EtcdError{ErrorCode: 100},
+	}
+	registry := MakeEtcdRegistry(fakeClient, nil)
+	AddMinions(registry, []string{"m1", "m2"})
+
+	minion, err := registry.GetMinion("m1")
+	expectNoError(t, err)
+	minion.ResourceVersion = 0
+	if !reflect.DeepEqual(*minion, registered) {
+		t.Errorf("Expected a registered minion to be left alone: %#v", minion)
+	}
+	minion, err = registry.GetMinion("m2")
+	expectNoError(t, err)
+	if minion.ID != "m2" {
+		t.Errorf("Unexpected minion: %#v", minion)
+	}
+}
+
+func TestMinionRegistryStorageCreateRequiresID(t *testing.T) {
+	storage := MakeMinionRegistryStorage(MakeMemoryRegistry(), nil, 10250)
+	if err := storage.Create(api.Minion{}); err == nil {
+		t.Errorf("Expected error for minion without id")
+	}
+}
+
+func TestMinionRegistryStorageHeartbeat(t *testing.T) {
+	registry := MakeMemoryRegistry()
+	registry.CreateMinion(api.Minion{JSONBase: api.JSONBase{ID: "m1"}, Condition: api.MinionNotReady, LastHeartbeat: 1})
+	storage := MakeMinionRegistryStorage(registry, nil, 10250).(*MinionRegistryStorage)
+	obj, err := storage.Act("", "m1", "heartbeat", url.Values{})
+	expectNoError(t, err)
+	if minion, ok := obj.(*api.Minion); !ok || minion.Condition != api.MinionReady || minion.Kind != "cluster#minion" {
+		t.Errorf("Unexpected result: %#v", obj)
+	}
+	minion, err := registry.GetMinion("m1")
+	expectNoError(t, err)
+	if minion.Condition != api.MinionReady || minion.LastHeartbeat <= 1 {
+		t.Errorf("Unexpected minion: %#v", minion)
+	}
+	if obj, err := storage.Act("", "missing", "heartbeat", url.Values{}); obj != nil || err != nil {
+		t.Errorf("Unexpected result for a missing minion: %#v %#v", obj, err)
+	}
+	if obj, err := storage.Act("", "m1", "other", url.Values{}); obj != nil || err != nil {
+		t.Errorf("Unexpected result for an unknown action: %#v %#v", obj, err)
+	}
+}
+
+func TestMinionRegistryStorageUpdateKeepsCondition(t *testing.T) {
+	registry := MakeMemoryRegistry()
+	registry.CreateMinion(api.Minion{JSONBase: api.JSONBase{ID: "m1"}, Condition: api.MinionNotReady, LastHeartbeat: 1})
+	storage := MakeMinionRegistryStorage(registry, nil, 10250)
+	err := storage.Update(api.Minion{
+		JSONBase:  api.JSONBase{ID: "m1"},
+		HostIP:    "10.0.0.1",
+		Condition: api.MinionReady,
+	})
+	expectNoError(t, err)
+	minion, err := registry.GetMinion("m1")
+	expectNoError(t, err)
+	if minion.Condition != api.MinionNotReady || minion.LastHeartbeat != 1 || minion.HostIP != "10.0.0.1" {
+		t.Errorf("Unexpected minion: %#v", minion)
+	}
+	if err := storage.Update(api.Minion{JSONBase: api.JSONBase{ID: "missing"}}); !api.IsNotFound(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
+
+func TestMinionRegistryStorageAssignsPodCIDR(t *testing.T) {
+	_, network, _ := net.ParseCIDR("10.244.0.0/16")
+	subnets, err := ipam.MakeSubnetAllocator(network, 24)
+	expectNoError(t, err)
+	registry := MakeMemoryRegistry()
+	registry.CreateMinion(api.Minion{JSONBase: api.JSONBase{ID: "m1"}, PodCIDR: "10.244.0.0/24"})
+	registry.CreateMinion(api.Minion{JSONBase: api.JSONBase{ID: "m2"}})
+	storage := MakeMinionRegistryStorage(registry, subnets, 10250)
+
+	expectNoError(t, storage.Create(api.Minion{JSONBase: api.JSONBase{ID: "m4"}, PodCIDR: "10.244.1.0/24"}))
+	expectNoError(t, storage.Create(api.Minion{JSONBase: api.JSONBase{ID: "m3"}}))
+	if err := storage.Create(api.Minion{JSONBase: api.JSONBase{ID: "m5"}, PodCIDR: "10.244.0.0/23"}); err == nil {
+		t.Errorf("Expected error for an overlapping pod subnet")
+	}
+	expectNoError(t, storage.Update(api.Minion{JSONBase: api.JSONBase{ID: "m1"}}))
+	expectNoError(t, storage.Update(api.Minion{JSONBase: api.JSONBase{ID: "m2"}}))
+	expectNoError(t, storage.Update(api.Minion{JSONBase: api.JSONBase{ID: "m3"}}))
+
+	expected := map[string]string{
+		"m1": "10.244.0.0/24",
+		"m2": "10.244.3.0/24",
+		"m3": "10.244.2.0/24",
+		"m4": "10.244.1.0/24",
+	}
+	for id, cidr := range expected {
+		minion, _ := registry.GetMinion(id)
+		if minion.PodCIDR != cidr {
+			t.Errorf("Unexpected pod CIDR for %s: %s, expected %s", id, minion.PodCIDR, cidr)
+		}
+	}
+}
+
+func TestMinionControllerMarksStaleMinions(t *testing.T) {
+	now := time.Unix(1000, 0)
+	registry := MakeMemoryRegistry()
+	registry.CreateMinion(api.Minion{JSONBase: api.JSONBase{ID: "static"}})
+	registry.CreateMinion(api.Minion{JSONBase: api.JSONBase{ID: "alive"}, Condition: api.MinionReady, LastHeartbeat: 990})
+	registry.CreateMinion(api.Minion{JSONBase: api.JSONBase{ID: "dead"}, Condition: api.MinionReady, LastHeartbeat: 900})
+	controller := MakeMinionController(registry, 30*time.Second)
+	controller.now = func() time.Time { return now }
+
+	err := controller.SyncMinionConditions()
+	expectNoError(t, err)
+	expected := map[string]api.MinionCondition{
+		"static": "",
+		"alive":  api.MinionReady,
+		"dead":   api.MinionNotReady,
+	}
+	for id, condition := range expected {
+		minion, _ := registry.GetMinion(id)
+		if minion.Condition != condition {
+			t.Errorf("Unexpected condition for %s: %s, expected %s", id, minion.Condition, condition)
+		}
+	}
+}
+
+func TestMinionRegistryStorageResourceLocation(t *testing.T) {
+	registry := MakeMemoryMinionRegistry([]string{"m1"})
+	registry.CreateMinion(api.Minion{JSONBase: api.JSONBase{ID: "m2"}, HostIP: "10.0.0.2"})
+	storage := MakeMinionRegistryStorage(registry, nil, 10251).(*MinionRegistryStorage)
+	table := map[string]string{
+		"m1": "m1:10251",
+		"m2": "10.0.0.2:10251",
+	}
+	for id, expected := range table {
+		location, err := storage.ResourceLocation(api.NamespaceAll, id)
+		expectNoError(t, err)
+		if location != expected {
+			t.Errorf("Unexpected location of %s: %s, expected %s", id, location, expected)
+		}
+	}
+	if _, err := storage.ResourceLocation(api.NamespaceAll, "m3"); !api.IsNotFound(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
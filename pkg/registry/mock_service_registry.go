@@ -17,6 +17,7 @@ package registry
 
 import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
 type MockServiceRegistry struct {
@@ -25,19 +26,25 @@ type MockServiceRegistry struct {
 	endpoints api.Endpoints
 }
 
-func (m *MockServiceRegistry) ListServices() (api.ServiceList, error) {
-	return m.list, m.err
+func (m *MockServiceRegistry) ListServices(namespace string) (api.ServiceList, error) {
+	list := api.ServiceList{JSONBase: m.list.JSONBase}
+	for _, svc := range m.list.Items {
+		if inNamespace(namespace, svc.Namespace) {
+			list.Items = append(list.Items, svc)
+		}
+	}
+	return list, m.err
 }
 
 func (m *MockServiceRegistry) CreateService(svc api.Service) error {
 	return m.err
 }
 
-func (m *MockServiceRegistry) GetService(name string) (*api.Service, error) {
+func (m *MockServiceRegistry) GetService(namespace, name string) (*api.Service, error) {
 	return nil, m.err
 }
 
-func (m *MockServiceRegistry) DeleteService(name string) error {
+func (m *MockServiceRegistry) DeleteService(namespace, name string) error {
 	return m.err
 }
 
@@ -49,3 +56,11 @@ func (m *MockServiceRegistry) UpdateEndpoints(e api.Endpoints) error {
 	m.endpoints = e
 	return m.err
 }
+
+func (m *MockServiceRegistry) GetEndpoints(namespace, name string) (*api.Endpoints, error) {
+	return &m.endpoints, m.err
+}
+
+func (m *MockServiceRegistry) WatchServices(resourceVersion uint64) (watch.Interface, error) {
+	return nil, m.err
+}
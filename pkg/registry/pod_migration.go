@@ -0,0 +1,102 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+)
+
+// moveAttempts is how often Migrate tries to move a migrated pod in the registry.
+const moveAttempts = 3
+
+// PodMigrator live migrates pods between minions. The kubelet of the target minion prepares to
+// receive the pod, the kubelet of the pod's minion migrates it there, and once it runs on the
+// target the pod is moved in the registry.
+type PodMigrator struct {
+	pods     PodRegistry
+	minions  MinionRegistry
+	kubelets client.PodMigration
+}
+
+func MakePodMigrator(pods PodRegistry, minions MinionRegistry, kubelets client.PodMigration) *PodMigrator {
+	return &PodMigrator{
+		pods:     pods,
+		minions:  minions,
+		kubelets: kubelets,
+	}
+}
+
+// Migrate moves pod 'id' in 'namespace' to minion 'target', and returns the pod once it runs
+// there. It returns nil if there is no such pod.
+func (m *PodMigrator) Migrate(namespace, id, target string) (*api.Pod, error) {
+	pod, err := m.pods.GetPod(namespace, id)
+	if err != nil || pod == nil {
+		return nil, err
+	}
+	source := pod.CurrentState.Host
+	if len(source) == 0 {
+		return nil, api.NewConflict("pod", id, fmt.Errorf("not assigned to a host"))
+	}
+	if len(target) == 0 {
+		return nil, api.NewInvalid("pod", id, fmt.Errorf("no target to migrate to"))
+	}
+	if target == source {
+		return nil, api.NewInvalid("pod", id, fmt.Errorf("already runs on %s", target))
+	}
+	minion, err := m.minions.GetMinion(target)
+	if err != nil && !api.IsNotFound(err) {
+		return nil, err
+	}
+	if minion == nil || err != nil {
+		return nil, api.NewInvalid("pod", id, fmt.Errorf("unknown minion %s", target))
+	}
+	if minion.Condition == api.MinionNotReady {
+		return nil, api.NewConflict("pod", id, fmt.Errorf("minion %s is not ready", target))
+	}
+	manifest := pod.DesiredState.Manifest
+	manifest.Id = makeManifestID(namespace, id)
+	names, err := m.kubelets.PrepareMigration(target, manifest)
+	if err != nil {
+		return nil, err
+	}
+	err = m.kubelets.MigratePod(source, api.PodMigration{PodID: manifest.Id, Target: target, Members: names})
+	if err != nil {
+		// The pod stays on the source, whose next sync starts again any members that
+		// already moved away. The target stops the members that arrived, so that they
+		// don't run twice.
+		if abortErr := m.kubelets.AbortMigration(target, manifest.Id); abortErr != nil {
+			log.Printf("Failed to abort the migration of %s to %s: %v", id, target, abortErr)
+		}
+		return nil, err
+	}
+	// MovePod finishes an interrupted move when it is retried.
+	for attempt := 1; ; attempt++ {
+		err = m.pods.MovePod(namespace, id, target)
+		if err == nil || attempt == moveAttempts {
+			break
+		}
+		log.Printf("Failed to move %s to %s, retrying: %v", id, target, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m.pods.GetPod(namespace, id)
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+)
+
+func makeMigrationTestRegistry() *MemoryRegistry {
+	registry := MakeMemoryMinionRegistry([]string{"machine", "other", "down"})
+	registry.UpdateMinion(api.Minion{JSONBase: api.JSONBase{ID: "down"}, Condition: api.MinionNotReady})
+	registry.CreatePod("machine", api.Pod{
+		JSONBase:     api.JSONBase{ID: "foo"},
+		DesiredState: api.PodState{Manifest: api.ContainerManifest{Containers: []api.Container{{Name: "bar"}}}},
+		CurrentState: api.PodState{Host: "machine"},
+	})
+	return registry
+}
+
+func TestPodMigratorMigrate(t *testing.T) {
+	registry := makeMigrationTestRegistry()
+	kubelets := &client.FakePodMigration{Names: map[string]string{"bar": "bar--foo--1234"}}
+	migrator := MakePodMigrator(registry, registry, kubelets)
+	pod, err := migrator.Migrate(api.NamespaceDefault, "foo", "other")
+	expectNoError(t, err)
+	if pod == nil || pod.CurrentState.Host != "other" {
+		t.Errorf("Unexpected pod: %#v", pod)
+	}
+	if !reflect.DeepEqual(kubelets.Actions, []string{"prepare-migration:other", "migrate:machine"}) {
+		t.Errorf("Unexpected actions: %#v", kubelets.Actions)
+	}
+	expected := api.PodMigration{PodID: "foo.default", Target: "other", Members: kubelets.Names}
+	if !reflect.DeepEqual(kubelets.Migrations, []api.PodMigration{expected}) {
+		t.Errorf("Unexpected migrations: %#v", kubelets.Migrations)
+	}
+}
+
+func TestPodMigratorInvalidTargets(t *testing.T) {
+	for _, target := range []string{"", "machine", "missing", "down"} {
+		registry := makeMigrationTestRegistry()
+		kubelets := &client.FakePodMigration{}
+		migrator := MakePodMigrator(registry, registry, kubelets)
+		if _, err := migrator.Migrate(api.NamespaceDefault, "foo", target); err == nil {
+			t.Errorf("Unexpected non-error migrating to %q", target)
+		}
+		if len(kubelets.Actions) != 0 {
+			t.Errorf("Unexpected actions: %#v", kubelets.Actions)
+		}
+	}
+}
+
+func TestPodMigratorMigrateError(t *testing.T) {
+	registry := makeMigrationTestRegistry()
+	kubelets := &client.FakePodMigration{MigrateErr: fmt.Errorf("test error")}
+	migrator := MakePodMigrator(registry, registry, kubelets)
+	if _, err := migrator.Migrate(api.NamespaceDefault, "foo", "other"); err == nil {
+		t.Error("Unexpected non-error")
+	}
+	if pod, _ := registry.GetPod(api.NamespaceDefault, "foo"); pod.CurrentState.Host != "machine" {
+		t.Errorf("Unexpected pod: %#v", pod)
+	}
+	expected := []string{"prepare-migration:other", "migrate:machine", "abort-migration:other"}
+	if !reflect.DeepEqual(kubelets.Actions, expected) {
+		t.Errorf("Unexpected actions: %#v", kubelets.Actions)
+	}
+}
+
+func TestPodRegistryStorageMigrate(t *testing.T) {
+	registry := makeMigrationTestRegistry()
+	kubelets := &client.FakePodMigration{}
+	storage := PodRegistryStorage{
+		registry: registry,
+		migrator: MakePodMigrator(registry, registry, kubelets),
+	}
+	obj, err := storage.Act(api.NamespaceDefault, "foo", "migrate", url.Values{"target": []string{"other"}})
+	expectNoError(t, err)
+	if pod, ok := obj.(*api.Pod); !ok || pod.CurrentState.Host != "other" || pod.Kind != "cluster#pod" {
+		t.Errorf("Unexpected result: %#v", obj)
+	}
+	if obj, err := storage.Act(api.NamespaceDefault, "missing", "migrate", url.Values{"target": []string{"other"}}); obj != nil || err != nil {
+		t.Errorf("Unexpected result for a missing pod: %#v %#v", obj, err)
+	}
+	if obj, err := storage.Act(api.NamespaceDefault, "foo", "other", url.Values{}); obj != nil || err != nil {
+		t.Errorf("Unexpected result for an unknown action: %#v %#v", obj, err)
+	}
+}
@@ -16,33 +16,44 @@ limitations under the License.
 package registry
 
 import (
-	"encoding/json"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/validation"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
 // PodRegistryStorage implements the RESTStorage interface in terms of a PodRegistry
 type PodRegistryStorage struct {
 	registry      PodRegistry
 	containerInfo client.ContainerInfo
+	podConsole    client.PodConsole
+	migrator      *PodMigrator
 	scheduler     Scheduler
 }
 
-func MakePodRegistryStorage(registry PodRegistry, containerInfo client.ContainerInfo, scheduler Scheduler) apiserver.RESTStorage {
+// MakePodRegistryStorage makes a RESTStorage for pods. If 'migrator' is nil, pods can't be migrated.
+func MakePodRegistryStorage(registry PodRegistry, containerInfo client.ContainerInfo, podConsole client.PodConsole, migrator *PodMigrator, scheduler Scheduler) apiserver.RESTStorage {
 	return &PodRegistryStorage{
 		registry:      registry,
 		containerInfo: containerInfo,
+		podConsole:    podConsole,
+		migrator:      migrator,
 		scheduler:     scheduler,
 	}
 }
 
-func (storage *PodRegistryStorage) List(query labels.Query) (interface{}, error) {
+func (storage *PodRegistryStorage) List(namespace string, query labels.Query) (interface{}, error) {
 	var result api.PodList
-	pods, err := storage.registry.ListPods(query)
+	pods, err := storage.registry.ListPods(namespace, query)
 	if err == nil {
 		result.Items = pods
 	}
@@ -63,36 +74,155 @@ func makePodStatus(info interface{}) string {
 	return "Pending"
 }
 
-func (storage *PodRegistryStorage) Get(id string) (interface{}, error) {
-	pod, err := storage.registry.GetPod(id)
+// makePodIP returns the address of the pod on the pod network, for runtimes that report one.
+func makePodIP(info interface{}) string {
+	if fields, ok := info.(map[string]interface{}); ok {
+		if ip, ok := fields["PodIP"].(string); ok {
+			return ip
+		}
+	}
+	return ""
+}
+
+func (storage *PodRegistryStorage) Get(namespace, id string) (interface{}, error) {
+	pod, err := storage.registry.GetPod(namespace, id)
 	if err != nil {
-		return pod, err
+		return nil, err
+	}
+	if pod == nil {
+		return nil, api.NewNotFound("pod", id)
 	}
-	info, err := storage.containerInfo.GetContainerInfo(pod.CurrentState.Host, id)
+	info, err := storage.containerInfo.GetContainerInfo(pod.CurrentState.Host, makeManifestID(namespace, id))
 	if err != nil {
 		return pod, err
 	}
 	pod.CurrentState.Info = info
 	pod.CurrentState.Status = makePodStatus(info)
+	pod.CurrentState.PodIP = makePodIP(info)
 	pod.Kind = "cluster#pod"
 	return pod, err
 }
 
-func (storage *PodRegistryStorage) Delete(id string) error {
-	return storage.registry.DeletePod(id)
+// ResourceLocation implements apiserver.ResourceLocator. 'id' is the id of a pod, optionally
+// followed by ":" and one of its container ports; without one, the first port of the pod is
+// used. Requests are proxied to the pod IP if the pod has one, or else to the host port the
+// container port is mapped to.
+func (storage *PodRegistryStorage) ResourceLocation(namespace, id string) (string, error) {
+	name, port := id, 0
+	if i := strings.LastIndex(id, ":"); i >= 0 {
+		var err error
+		name = id[:i]
+		if port, err = strconv.Atoi(id[i+1:]); err != nil || port <= 0 {
+			return "", api.NewInvalid("port", id[i+1:], fmt.Errorf("not a port number"))
+		}
+	}
+	pod, err := storage.registry.GetPod(namespace, name)
+	if err != nil {
+		return "", err
+	}
+	if pod == nil {
+		return "", api.NewNotFound("pod", name)
+	}
+	var found *api.Port
+	for _, container := range pod.DesiredState.Manifest.Containers {
+		for i := range container.Ports {
+			if found == nil && (port == 0 || container.Ports[i].ContainerPort == port) {
+				found = &container.Ports[i]
+			}
+		}
+	}
+	if found == nil {
+		if port == 0 {
+			return "", api.NewInvalid("pod", name, fmt.Errorf("the pod has no ports"))
+		}
+		return "", api.NewInvalid("pod", name, fmt.Errorf("the pod has no port %d", port))
+	}
+	if info, err := storage.containerInfo.GetContainerInfo(pod.CurrentState.Host, makeManifestID(namespace, name)); err == nil {
+		if ip := makePodIP(info); len(ip) > 0 {
+			return net.JoinHostPort(ip, strconv.Itoa(found.ContainerPort)), nil
+		}
+	}
+	if found.HostPort == 0 || len(pod.CurrentState.Host) == 0 {
+		return "", api.NewInvalid("pod", name, fmt.Errorf("port %d of the pod can't be reached", found.ContainerPort))
+	}
+	return net.JoinHostPort(pod.CurrentState.Host, strconv.Itoa(found.HostPort)), nil
+}
+
+// Stream implements apiserver.RESTStreamer. The "console" of a pod is read from the kubelet
+// of its host. The "container" parameter picks the container, and "follow=true" keeps the
+// stream open for new output.
+func (storage *PodRegistryStorage) Stream(namespace, id, name string, params url.Values) (io.ReadCloser, error) {
+	if name != "console" {
+		return nil, nil
+	}
+	pod, err := storage.registry.GetPod(namespace, id)
+	if err != nil || pod == nil {
+		return nil, err
+	}
+	if len(pod.CurrentState.Host) == 0 {
+		return nil, api.NewConflict("pod", id, fmt.Errorf("not assigned to a host"))
+	}
+	return storage.podConsole.GetPodConsole(pod.CurrentState.Host, makeManifestID(namespace, id), params.Get("container"), params.Get("follow") == "true")
+}
+
+// Act implements apiserver.RESTActor. A pod is live migrated to the minion named by the
+// "target" parameter with the "migrate" action.
+func (storage *PodRegistryStorage) Act(namespace, id, action string, params url.Values) (interface{}, error) {
+	if action != "migrate" || storage.migrator == nil {
+		return nil, nil
+	}
+	pod, err := storage.migrator.Migrate(namespace, id, params.Get("target"))
+	if err != nil || pod == nil {
+		return nil, err
+	}
+	pod.Kind = "cluster#pod"
+	return pod, nil
+}
+
+// Watch implements apiserver.ResourceWatcher.
+func (storage *PodRegistryStorage) Watch(namespace string, query labels.Query, resourceVersion uint64) (watch.Interface, error) {
+	w, err := storage.registry.WatchPods(resourceVersion)
+	if err != nil {
+		return nil, err
+	}
+	return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+		pod := event.Object.(api.Pod)
+		pod.Kind = "cluster#pod"
+		event.Object = pod
+		return event, inNamespace(namespace, pod.Namespace) && query.Matches(labels.Set(pod.Labels))
+	}), nil
 }
 
-func (storage *PodRegistryStorage) Extract(body string) (interface{}, error) {
+// Completed implements apiserver.RESTCompleter. A pod is done when its host reports it running.
+func (storage *PodRegistryStorage) Completed(obj interface{}) (interface{}, bool, error) {
+	pod, err := storage.Get(obj.(api.Pod).Namespace, obj.(api.Pod).ID)
+	if pod == nil {
+		return nil, false, err
+	}
+	if err != nil {
+		// The host may not have started the pod yet.
+		return pod, false, nil
+	}
+	return pod, pod.(*api.Pod).CurrentState.Status == "Running", nil
+}
+
+func (storage *PodRegistryStorage) Delete(namespace, id string) error {
+	return storage.registry.DeletePod(namespace, id)
+}
+
+func (storage *PodRegistryStorage) Extract(body string, version string) (interface{}, error) {
 	pod := api.Pod{}
-	err := json.Unmarshal([]byte(body), &pod)
+	if err := api.DecodeVersionInto([]byte(body), version, &pod); err != nil {
+		return nil, api.NewInvalid("pod", "", err)
+	}
 	pod.Kind = "cluster#pod"
-	return pod, err
+	return pod, nil
 }
 
 func (storage *PodRegistryStorage) Create(pod interface{}) error {
 	podObj := pod.(api.Pod)
-	if len(podObj.ID) == 0 {
-		return fmt.Errorf("id is unspecified: %#v", pod)
+	if errs := validation.ValidatePod(&podObj); len(errs) > 0 {
+		return api.NewInvalidFields("pod", podObj.ID, errs.Causes())
 	}
 	machine, err := storage.scheduler.Schedule(podObj)
 	if err != nil {
@@ -101,6 +231,15 @@ func (storage *PodRegistryStorage) Create(pod interface{}) error {
 	return storage.registry.CreatePod(machine, podObj)
 }
 
+// Unpatchable implements apiserver.Unpatchable: the registries can't update pods yet.
+func (storage *PodRegistryStorage) Unpatchable() bool {
+	return true
+}
+
 func (storage *PodRegistryStorage) Update(pod interface{}) error {
-	return storage.registry.UpdatePod(pod.(api.Pod))
+	podObj := pod.(api.Pod)
+	if errs := validation.ValidatePod(&podObj); len(errs) > 0 {
+		return api.NewInvalidFields("pod", podObj.ID, errs.Causes())
+	}
+	return storage.registry.UpdatePod(podObj)
 }
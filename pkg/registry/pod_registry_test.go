@@ -18,16 +18,21 @@ package registry
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/url"
 	"reflect"
 	"testing"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
 type MockPodRegistry struct {
-	err  error
-	pods []api.Pod
+	err     error
+	pods    []api.Pod
+	watcher watch.Interface
 }
 
 func expectNoError(t *testing.T, err error) {
@@ -36,20 +41,20 @@ func expectNoError(t *testing.T, err error) {
 	}
 }
 
-func (registry *MockPodRegistry) ListPods(query labels.Query) ([]api.Pod, error) {
+func (registry *MockPodRegistry) ListPods(namespace string, query labels.Query) ([]api.Pod, error) {
 	if registry.err != nil {
 		return registry.pods, registry.err
 	}
 	var filtered []api.Pod
 	for _, pod := range registry.pods {
-		if query.Matches(labels.Set(pod.Labels)) {
+		if inNamespace(namespace, pod.Namespace) && query.Matches(labels.Set(pod.Labels)) {
 			filtered = append(filtered, pod)
 		}
 	}
 	return filtered, nil
 }
 
-func (registry *MockPodRegistry) GetPod(podId string) (*api.Pod, error) {
+func (registry *MockPodRegistry) GetPod(namespace, podId string) (*api.Pod, error) {
 	return &api.Pod{}, registry.err
 }
 
@@ -60,9 +65,15 @@ func (registry *MockPodRegistry) CreatePod(machine string, pod api.Pod) error {
 func (registry *MockPodRegistry) UpdatePod(pod api.Pod) error {
 	return registry.err
 }
-func (registry *MockPodRegistry) DeletePod(podId string) error {
+func (registry *MockPodRegistry) DeletePod(namespace, podId string) error {
 	return registry.err
 }
+func (registry *MockPodRegistry) MovePod(namespace, podId, machine string) error {
+	return registry.err
+}
+func (registry *MockPodRegistry) WatchPods(resourceVersion uint64) (watch.Interface, error) {
+	return registry.watcher, registry.err
+}
 
 func TestListPodsError(t *testing.T) {
 	mockRegistry := MockPodRegistry{
@@ -71,7 +82,7 @@ func TestListPodsError(t *testing.T) {
 	storage := PodRegistryStorage{
 		registry: &mockRegistry,
 	}
-	pods, err := storage.List(labels.Everything())
+	pods, err := storage.List(api.NamespaceAll, labels.Everything())
 	if err != mockRegistry.err {
 		t.Errorf("Expected %#v, Got %#v", mockRegistry.err, err)
 	}
@@ -85,7 +96,7 @@ func TestListEmptyPodList(t *testing.T) {
 	storage := PodRegistryStorage{
 		registry: &mockRegistry,
 	}
-	pods, err := storage.List(labels.Everything())
+	pods, err := storage.List(api.NamespaceAll, labels.Everything())
 	expectNoError(t, err)
 	if len(pods.(api.PodList).Items) != 0 {
 		t.Errorf("Unexpected non-zero pod list: %#v", pods)
@@ -110,7 +121,7 @@ func TestListPodList(t *testing.T) {
 	storage := PodRegistryStorage{
 		registry: &mockRegistry,
 	}
-	podsObj, err := storage.List(labels.Everything())
+	podsObj, err := storage.List(api.NamespaceAll, labels.Everything())
 	pods := podsObj.(api.PodList)
 	expectNoError(t, err)
 	if len(pods.Items) != 2 {
@@ -136,7 +147,7 @@ func TestExtractJson(t *testing.T) {
 	}
 	body, err := json.Marshal(pod)
 	expectNoError(t, err)
-	podOut, err := storage.Extract(string(body))
+	podOut, err := storage.Extract(string(body), api.StorageVersion)
 	expectNoError(t, err)
 	// Extract adds in a kind
 	pod.Kind = "cluster#pod"
@@ -145,6 +156,104 @@ func TestExtractJson(t *testing.T) {
 	}
 }
 
+func TestExtractInvalidJson(t *testing.T) {
+	storage := PodRegistryStorage{
+		registry: &MockPodRegistry{},
+	}
+	_, err := storage.Extract("{", api.StorageVersion)
+	if !api.IsInvalid(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
+
+func TestGetPodNotFound(t *testing.T) {
+	storage := MakePodRegistryStorage(MakeMemoryRegistry(), &client.FakeContainerInfo{}, nil, nil, nil)
+	pod, err := storage.Get(api.NamespaceDefault, "foo")
+	if !api.IsNotFound(err) || pod != nil {
+		t.Errorf("Unexpected pod: %#v, %#v", pod, err)
+	}
+}
+
+func TestPodCompleted(t *testing.T) {
+	registry := MakeMemoryRegistry()
+	registry.CreatePod("machine", api.Pod{JSONBase: api.JSONBase{ID: "foo"}})
+	containerInfo := &client.FakeContainerInfo{Err: fmt.Errorf("not started")}
+	storage := PodRegistryStorage{
+		registry:      registry,
+		containerInfo: containerInfo,
+	}
+
+	if _, done, err := storage.Completed(api.Pod{JSONBase: api.JSONBase{ID: "foo"}}); done || err != nil {
+		t.Errorf("Unexpected completion: %v %#v", done, err)
+	}
+	containerInfo.Err = nil
+	containerInfo.Data = map[string]interface{}{"State": map[string]interface{}{"Running": false}}
+	if _, done, err := storage.Completed(api.Pod{JSONBase: api.JSONBase{ID: "foo"}}); done || err != nil {
+		t.Errorf("Unexpected completion: %v %#v", done, err)
+	}
+	containerInfo.Data = map[string]interface{}{"State": map[string]interface{}{"Running": true}}
+	pod, done, err := storage.Completed(api.Pod{JSONBase: api.JSONBase{ID: "foo"}})
+	if !done || err != nil || pod.(*api.Pod).CurrentState.Status != "Running" {
+		t.Errorf("Unexpected completion: %#v %v %#v", pod, done, err)
+	}
+	if _, _, err := storage.Completed(api.Pod{JSONBase: api.JSONBase{ID: "bar"}}); !api.IsNotFound(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
+
+func TestCreatePodWithoutID(t *testing.T) {
+	storage := MakePodRegistryStorage(MakeMemoryRegistry(), nil, nil, nil, nil)
+	err := storage.Create(api.Pod{})
+	if !api.IsInvalid(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
+
+func TestCreatePodInvalid(t *testing.T) {
+	storage := MakePodRegistryStorage(MakeMemoryRegistry(), nil, nil, nil, nil)
+	err := storage.Create(api.Pod{
+		JSONBase: api.JSONBase{ID: "foo"},
+		DesiredState: api.PodState{
+			Manifest: api.ContainerManifest{
+				Containers: []api.Container{{Name: "web", Image: "dockerfile/nginx", Ports: []api.Port{{HostPort: 8080}}}},
+			},
+		},
+	})
+	if !api.IsInvalid(err) {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	causes := api.ErrorToStatus(err).Details.Causes
+	if len(causes) != 1 || causes[0].Field != "desiredState.manifest.containers[0].ports[0].containerPort" || causes[0].Type != api.CauseTypeFieldValueRequired {
+		t.Errorf("Unexpected causes: %#v", causes)
+	}
+}
+
+func TestPodWatch(t *testing.T) {
+	fakeWatcher := watch.MakeFakeWatcher()
+	storage := PodRegistryStorage{
+		registry: &MockPodRegistry{watcher: fakeWatcher},
+	}
+	query, err := labels.ParseQuery("name=foo")
+	expectNoError(t, err)
+	watching, err := storage.Watch(api.NamespaceAll, query, 0)
+	expectNoError(t, err)
+
+	foo := api.Pod{JSONBase: api.JSONBase{ID: "foo"}, Labels: map[string]string{"name": "foo"}}
+	go func() {
+		fakeWatcher.Add(api.Pod{JSONBase: api.JSONBase{ID: "bar"}, Labels: map[string]string{"name": "bar"}})
+		fakeWatcher.Modify(foo)
+		fakeWatcher.Stop()
+	}()
+	event := <-watching.ResultChan()
+	foo.Kind = "cluster#pod"
+	if event.Type != watch.Modified || !reflect.DeepEqual(event.Object, foo) {
+		t.Errorf("Unexpected event: %#v", event)
+	}
+	if _, ok := <-watching.ResultChan(); ok {
+		t.Errorf("Expected the watch to be closed")
+	}
+}
+
 func TestMakePodStatus(t *testing.T) {
 	status := makePodStatus(map[string]interface{}{})
 	if status != "Pending" {
@@ -171,3 +280,91 @@ func TestMakePodStatus(t *testing.T) {
 		t.Errorf("Expected 'Running', got '%s'", status)
 	}
 }
+
+func TestMakePodIP(t *testing.T) {
+	if ip := makePodIP(map[string]interface{}{"PodIP": "10.244.1.2"}); ip != "10.244.1.2" {
+		t.Errorf("Unexpected pod IP: %s", ip)
+	}
+	if ip := makePodIP(map[string]interface{}{"State": map[string]interface{}{}}); ip != "" {
+		t.Errorf("Unexpected pod IP: %s", ip)
+	}
+	if ip := makePodIP(nil); ip != "" {
+		t.Errorf("Unexpected pod IP: %s", ip)
+	}
+}
+
+func TestPodConsoleStream(t *testing.T) {
+	registry := MakeMemoryRegistry()
+	registry.CreatePod("machine", api.Pod{JSONBase: api.JSONBase{ID: "foo"}, CurrentState: api.PodState{Host: "machine"}})
+	registry.CreatePod("", api.Pod{JSONBase: api.JSONBase{ID: "unscheduled"}})
+	storage := PodRegistryStorage{
+		registry:   registry,
+		podConsole: &client.FakePodConsole{Data: "booting\n"},
+	}
+	stream, err := storage.Stream(api.NamespaceDefault, "foo", "console", url.Values{})
+	expectNoError(t, err)
+	data, err := ioutil.ReadAll(stream)
+	expectNoError(t, err)
+	if string(data) != "booting\n" {
+		t.Errorf("Unexpected console: %s", string(data))
+	}
+	if stream, err := storage.Stream(api.NamespaceDefault, "missing", "console", url.Values{}); stream != nil || err != nil {
+		t.Errorf("Unexpected stream for a missing pod: %#v %#v", stream, err)
+	}
+	if stream, err := storage.Stream(api.NamespaceDefault, "foo", "other", url.Values{}); stream != nil || err != nil {
+		t.Errorf("Unexpected stream for an unknown sub resource: %#v %#v", stream, err)
+	}
+	if _, err := storage.Stream(api.NamespaceDefault, "unscheduled", "console", url.Values{}); err == nil {
+		t.Error("Unexpected non-error for an unscheduled pod")
+	}
+}
+
+func TestPodResourceLocation(t *testing.T) {
+	registry := MakeMemoryRegistry()
+	pod := api.Pod{JSONBase: api.JSONBase{ID: "foo", Namespace: "team"}}
+	pod.CurrentState.Host = "machine"
+	pod.DesiredState.Manifest.Containers = []api.Container{
+		{Name: "a"},
+		{Name: "b", Ports: []api.Port{{ContainerPort: 80, HostPort: 8080}, {ContainerPort: 443}}},
+	}
+	registry.CreatePod("machine", pod)
+	registry.CreatePod("machine", api.Pod{JSONBase: api.JSONBase{ID: "bar", Namespace: "team"}})
+	containerInfo := &client.FakeContainerInfo{Err: fmt.Errorf("not started")}
+	storage := PodRegistryStorage{
+		registry:      registry,
+		containerInfo: containerInfo,
+	}
+
+	table := []struct {
+		id       string
+		location string
+		ok       bool
+	}{
+		{"foo", "machine:8080", true},
+		{"foo:80", "machine:8080", true},
+		{"foo:443", "", false},
+		{"foo:22", "", false},
+		{"foo:http", "", false},
+		{"bar", "", false},
+	}
+	for _, item := range table {
+		location, err := storage.ResourceLocation("team", item.id)
+		if location != item.location || (err == nil) != item.ok {
+			t.Errorf("Unexpected location of %s: %s %#v", item.id, location, err)
+		}
+	}
+	if _, err := storage.ResourceLocation(api.NamespaceDefault, "foo"); !api.IsNotFound(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	// Pods with an IP of their own are reached at their container ports.
+	containerInfo.Err = nil
+	containerInfo.Data = map[string]interface{}{"PodIP": "10.244.1.5"}
+	for id, expected := range map[string]string{"foo": "10.244.1.5:80", "foo:443": "10.244.1.5:443"} {
+		location, err := storage.ResourceLocation("team", id)
+		expectNoError(t, err)
+		if location != expected {
+			t.Errorf("Unexpected location of %s: %s, expected %s", id, location, expected)
+		}
+	}
+}
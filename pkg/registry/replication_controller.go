@@ -16,7 +16,6 @@ limitations under the License.
 package registry
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"math/rand"
@@ -42,15 +41,21 @@ type ReplicationManager struct {
 
 // An interface that knows how to add or delete pods
 // created as an interface to allow testing.
+// Replicas are created in the namespace of their controller.
 type PodControlInterface interface {
 	createReplica(controllerSpec api.ReplicationController)
-	deletePod(podID string) error
+	deletePod(namespace, podID string) error
 }
 
 type RealPodControl struct {
 	kubeClient client.ClientInterface
 }
 
+// inNamespace returns a client for the objects in 'namespace'.
+func (r RealPodControl) inNamespace(namespace string) client.ClientInterface {
+	return r.kubeClient.InNamespace(defaultNamespace(namespace))
+}
+
 func (r RealPodControl) createReplica(controllerSpec api.ReplicationController) {
 	labels := controllerSpec.DesiredState.PodTemplate.Labels
 	if labels != nil {
@@ -63,14 +68,14 @@ func (r RealPodControl) createReplica(controllerSpec api.ReplicationController)
 		DesiredState: controllerSpec.DesiredState.PodTemplate.DesiredState,
 		Labels:       controllerSpec.DesiredState.PodTemplate.Labels,
 	}
-	_, err := r.kubeClient.CreatePod(pod)
+	_, err := r.inNamespace(controllerSpec.Namespace).CreatePod(pod)
 	if err != nil {
 		log.Printf("%#v\n", err)
 	}
 }
 
-func (r RealPodControl) deletePod(podID string) error {
-	return r.kubeClient.DeletePod(podID)
+func (r RealPodControl) deletePod(namespace, podID string) error {
+	return r.inNamespace(namespace).DeletePod(podID)
 }
 
 func MakeReplicationManager(etcdClient *etcd.Client, kubeClient client.ClientInterface) *ReplicationManager {
@@ -115,10 +120,11 @@ func (rm *ReplicationManager) WatchControllers() {
 }
 
 func (rm *ReplicationManager) handleWatchResponse(response *etcd.Response) (*api.ReplicationController, error) {
-	if response.Action == "set" {
+	switch response.Action {
+	case "set", "create", "compareAndSwap":
 		if response.Node != nil {
 			var controllerSpec api.ReplicationController
-			err := json.Unmarshal([]byte(response.Node.Value), &controllerSpec)
+			err := api.DecodeInto([]byte(response.Node.Value), &controllerSpec)
 			if err != nil {
 				return nil, err
 			}
@@ -142,7 +148,8 @@ func (rm *ReplicationManager) filterActivePods(pods []api.Pod) []api.Pod {
 
 func (rm *ReplicationManager) syncReplicationController(controllerSpec api.ReplicationController) error {
 	rm.updateLock.Lock()
-	podList, err := rm.kubeClient.ListPods(controllerSpec.DesiredState.ReplicasInSet)
+	kubeClient := rm.kubeClient.InNamespace(defaultNamespace(controllerSpec.Namespace))
+	podList, err := kubeClient.ListPods(controllerSpec.DesiredState.ReplicasInSet)
 	if err != nil {
 		return err
 	}
@@ -158,7 +165,7 @@ func (rm *ReplicationManager) syncReplicationController(controllerSpec api.Repli
 	} else if diff > 0 {
 		log.Print("Too many replicas, deleting")
 		for i := 0; i < diff; i++ {
-			rm.podControl.deletePod(filteredList[i].ID)
+			rm.podControl.deletePod(controllerSpec.Namespace, filteredList[i].ID)
 		}
 	}
 	rm.updateLock.Unlock()
@@ -167,7 +174,7 @@ func (rm *ReplicationManager) syncReplicationController(controllerSpec api.Repli
 
 func (rm *ReplicationManager) Synchronize() {
 	for {
-		response, err := rm.etcdClient.Get("/registry/controllers", false, false)
+		response, err := rm.etcdClient.Get("/registry/controllers", false, true)
 		if err != nil {
 			log.Printf("Synchronization error %#v", err)
 		}
@@ -179,9 +186,9 @@ func (rm *ReplicationManager) Synchronize() {
 		// Punting on this for now, but this could lead to some nasty bugs, so we should really fix it
 		// sooner rather than later.
 		if response != nil && response.Node != nil && response.Node.Nodes != nil {
-			for _, value := range response.Node.Nodes {
+			for _, value := range flattenNodes(response.Node.Nodes) {
 				var controllerSpec api.ReplicationController
-				err := json.Unmarshal([]byte(value.Value), &controllerSpec)
+				err := api.DecodeInto([]byte(value.Value), &controllerSpec)
 				if err != nil {
 					log.Printf("Unexpected error: %#v", err)
 					continue
@@ -44,7 +44,7 @@ func (f *FakePodControl) createReplica(spec api.ReplicationController) {
 	f.controllerSpec = append(f.controllerSpec, spec)
 }
 
-func (f *FakePodControl) deletePod(podID string) error {
+func (f *FakePodControl) deletePod(namespace, podID string) error {
 	f.deletePodID = append(f.deletePodID, podID)
 	return nil
 }
@@ -108,7 +108,7 @@ func TestSyncReplicationControllerDoesNothing(t *testing.T) {
 
 	fakePodControl := FakePodControl{}
 
-	manager := MakeReplicationManager(nil, &client)
+	manager := MakeReplicationManager(nil, client)
 	manager.podControl = &fakePodControl
 
 	controllerSpec := makeReplicationController(2)
@@ -130,7 +130,7 @@ func TestSyncReplicationControllerDeletes(t *testing.T) {
 
 	fakePodControl := FakePodControl{}
 
-	manager := MakeReplicationManager(nil, &client)
+	manager := MakeReplicationManager(nil, client)
 	manager.podControl = &fakePodControl
 
 	controllerSpec := makeReplicationController(1)
@@ -152,7 +152,7 @@ func TestSyncReplicationControllerCreates(t *testing.T) {
 
 	fakePodControl := FakePodControl{}
 
-	manager := MakeReplicationManager(nil, &client)
+	manager := MakeReplicationManager(nil, client)
 	manager.podControl = &fakePodControl
 
 	controllerSpec := makeReplicationController(2)
@@ -161,6 +161,40 @@ func TestSyncReplicationControllerCreates(t *testing.T) {
 	validateSyncReplication(t, &fakePodControl, 2, 0)
 }
 
+// FakeListPodsClient is a client that only lists pods, and records the namespace they
+// were listed in.
+type FakeListPodsClient struct {
+	client.ClientInterface
+	pods      api.PodList
+	namespace string
+}
+
+func (f *FakeListPodsClient) InNamespace(namespace string) client.ClientInterface {
+	f.namespace = namespace
+	return f
+}
+
+func (f *FakeListPodsClient) ListPods(labelQuery map[string]string) (api.PodList, error) {
+	return f.pods, nil
+}
+
+func TestSyncReplicationControllerNamespace(t *testing.T) {
+	fakeClient := &FakeListPodsClient{pods: makePodList(1)}
+	fakePodControl := FakePodControl{}
+
+	manager := MakeReplicationManager(nil, fakeClient)
+	manager.podControl = &fakePodControl
+
+	controllerSpec := makeReplicationController(2)
+	controllerSpec.Namespace = "other"
+
+	manager.syncReplicationController(controllerSpec)
+	validateSyncReplication(t, &fakePodControl, 1, 0)
+	if fakeClient.namespace != "other" {
+		t.Errorf("Unexpected namespace: %s", fakeClient.namespace)
+	}
+}
+
 func TestCreateReplica(t *testing.T) {
 	body := "{}"
 	fakeHandler := util.FakeHandler{
@@ -203,7 +237,7 @@ func TestCreateReplica(t *testing.T) {
 	//	DesiredState: controllerSpec.DesiredState.PodTemplate.DesiredState,
 	//}
 	// TODO: fix this so that it validates the body.
-	fakeHandler.ValidateRequest(t, makeUrl("/pods"), "POST", nil)
+	fakeHandler.ValidateRequest(t, makeUrl("/namespaces/default/pods"), "POST", nil)
 }
 
 func TestHandleWatchResponseNotSet(t *testing.T) {
@@ -219,7 +253,7 @@ func TestHandleWatchResponseNotSet(t *testing.T) {
 
 	fakePodControl := FakePodControl{}
 
-	manager := MakeReplicationManager(nil, &client)
+	manager := MakeReplicationManager(nil, client)
 	manager.podControl = &fakePodControl
 	_, err := manager.handleWatchResponse(&etcd.Response{
 		Action: "delete",
@@ -240,7 +274,7 @@ func TestHandleWatchResponseNoNode(t *testing.T) {
 
 	fakePodControl := FakePodControl{}
 
-	manager := MakeReplicationManager(nil, &client)
+	manager := MakeReplicationManager(nil, client)
 	manager.podControl = &fakePodControl
 	_, err := manager.handleWatchResponse(&etcd.Response{
 		Action: "set",
@@ -263,7 +297,7 @@ func TestHandleWatchResponseBadData(t *testing.T) {
 
 	fakePodControl := FakePodControl{}
 
-	manager := MakeReplicationManager(nil, &client)
+	manager := MakeReplicationManager(nil, client)
 	manager.podControl = &fakePodControl
 	_, err := manager.handleWatchResponse(&etcd.Response{
 		Action: "set",
@@ -289,7 +323,7 @@ func TestHandleWatchResponse(t *testing.T) {
 
 	fakePodControl := FakePodControl{}
 
-	manager := MakeReplicationManager(nil, &client)
+	manager := MakeReplicationManager(nil, client)
 	manager.podControl = &fakePodControl
 
 	controller := makeReplicationController(2)
@@ -18,6 +18,7 @@ package registry
 import (
 	"fmt"
 	"math/rand"
+	"sort"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
@@ -28,51 +29,80 @@ type Scheduler interface {
 	Schedule(api.Pod) (string, error)
 }
 
+// listSchedulableMinions returns the sorted machines that pods may currently be scheduled onto.
+// Minions that are not ready are left out.
+func listSchedulableMinions(registry MinionRegistry) ([]string, error) {
+	minions, err := registry.ListMinions()
+	if err != nil {
+		return nil, err
+	}
+	machines := []string{}
+	for _, minion := range minions {
+		if minion.Condition != api.MinionNotReady {
+			machines = append(machines, minion.ID)
+		}
+	}
+	sort.Strings(machines)
+	if len(machines) == 0 {
+		return nil, fmt.Errorf("no minions available")
+	}
+	return machines, nil
+}
+
 // RandomScheduler choses machines uniformly at random.
 type RandomScheduler struct {
-	machines []string
-	random   rand.Rand
+	minions MinionRegistry
+	random  rand.Rand
 }
 
-func MakeRandomScheduler(machines []string, random rand.Rand) Scheduler {
+func MakeRandomScheduler(minions MinionRegistry, random rand.Rand) Scheduler {
 	return &RandomScheduler{
-		machines: machines,
-		random:   random,
+		minions: minions,
+		random:  random,
 	}
 }
 
 func (s *RandomScheduler) Schedule(pod api.Pod) (string, error) {
-	return s.machines[s.random.Int()%len(s.machines)], nil
+	machines, err := listSchedulableMinions(s.minions)
+	if err != nil {
+		return "", err
+	}
+	return machines[s.random.Int()%len(machines)], nil
 }
 
 // RoundRobinScheduler chooses machines in order.
 type RoundRobinScheduler struct {
-	machines     []string
+	minions      MinionRegistry
 	currentIndex int
 }
 
-func MakeRoundRobinScheduler(machines []string) Scheduler {
+func MakeRoundRobinScheduler(minions MinionRegistry) Scheduler {
 	return &RoundRobinScheduler{
-		machines:     machines,
+		minions:      minions,
 		currentIndex: 0,
 	}
 }
 
 func (s *RoundRobinScheduler) Schedule(pod api.Pod) (string, error) {
-	result := s.machines[s.currentIndex]
-	s.currentIndex = (s.currentIndex + 1) % len(s.machines)
+	machines, err := listSchedulableMinions(s.minions)
+	if err != nil {
+		return "", err
+	}
+	// The set of machines may have shrunk since the last call.
+	result := machines[s.currentIndex%len(machines)]
+	s.currentIndex = (s.currentIndex + 1) % len(machines)
 	return result, nil
 }
 
 type FirstFitScheduler struct {
-	machines []string
+	minions  MinionRegistry
 	registry PodRegistry
 	random   *rand.Rand
 }
 
-func MakeFirstFitScheduler(machines []string, registry PodRegistry, random *rand.Rand) Scheduler {
+func MakeFirstFitScheduler(minions MinionRegistry, registry PodRegistry, random *rand.Rand) Scheduler {
 	return &FirstFitScheduler{
-		machines: machines,
+		minions:  minions,
 		registry: registry,
 		random:   random,
 	}
@@ -90,8 +120,12 @@ func (s *FirstFitScheduler) containsPort(pod api.Pod, port api.Port) bool {
 }
 
 func (s *FirstFitScheduler) Schedule(pod api.Pod) (string, error) {
+	machines, err := listSchedulableMinions(s.minions)
+	if err != nil {
+		return "", err
+	}
 	machineToPods := map[string][]api.Pod{}
-	pods, err := s.registry.ListPods(labels.Everything())
+	pods, err := s.registry.ListPods(api.NamespaceAll, labels.Everything())
 	if err != nil {
 		return "", err
 	}
@@ -100,7 +134,7 @@ func (s *FirstFitScheduler) Schedule(pod api.Pod) (string, error) {
 		machineToPods[host] = append(machineToPods[host], scheduledPod)
 	}
 	var machineOptions []string
-	for _, machine := range s.machines {
+	for _, machine := range machines {
 		podFits := true
 		for _, scheduledPod := range machineToPods[machine] {
 			for _, container := range pod.DesiredState.Manifest.Containers {
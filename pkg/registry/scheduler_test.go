@@ -31,16 +31,43 @@ func expectSchedule(scheduler Scheduler, pod api.Pod, expected string, t *testin
 }
 
 func TestRoundRobinScheduler(t *testing.T) {
-	scheduler := MakeRoundRobinScheduler([]string{"m1", "m2", "m3", "m4"})
+	scheduler := MakeRoundRobinScheduler(MakeMemoryMinionRegistry([]string{"m1", "m2", "m3", "m4"}))
 	expectSchedule(scheduler, api.Pod{}, "m1", t)
 	expectSchedule(scheduler, api.Pod{}, "m2", t)
 	expectSchedule(scheduler, api.Pod{}, "m3", t)
 	expectSchedule(scheduler, api.Pod{}, "m4", t)
 }
 
+func TestRoundRobinSchedulerFollowsMinions(t *testing.T) {
+	minions := MakeMemoryMinionRegistry([]string{"m1", "m2"})
+	scheduler := MakeRoundRobinScheduler(minions)
+	expectSchedule(scheduler, api.Pod{}, "m1", t)
+	minions.DeleteMinion("m2")
+	expectSchedule(scheduler, api.Pod{}, "m1", t)
+	minions.DeleteMinion("m1")
+	if _, err := scheduler.Schedule(api.Pod{}); err == nil {
+		t.Errorf("Expected error with no minions")
+	}
+}
+
+func TestSchedulersSkipNotReadyMinions(t *testing.T) {
+	minions := MakeMemoryMinionRegistry([]string{"m1", "m2"})
+	minions.UpdateMinion(api.Minion{JSONBase: api.JSONBase{ID: "m1"}, Condition: api.MinionNotReady})
+	r := rand.New(rand.NewSource(0))
+	schedulers := []Scheduler{
+		MakeRoundRobinScheduler(minions),
+		MakeRandomScheduler(minions, *r),
+		MakeFirstFitScheduler(minions, &MockPodRegistry{}, r),
+	}
+	for _, scheduler := range schedulers {
+		expectSchedule(scheduler, api.Pod{}, "m2", t)
+		expectSchedule(scheduler, api.Pod{}, "m2", t)
+	}
+}
+
 func TestRandomScheduler(t *testing.T) {
 	random := rand.New(rand.NewSource(0))
-	scheduler := MakeRandomScheduler([]string{"m1", "m2", "m3", "m4"}, *random)
+	scheduler := MakeRandomScheduler(MakeMemoryMinionRegistry([]string{"m1", "m2", "m3", "m4"}), *random)
 	_, err := scheduler.Schedule(api.Pod{})
 	expectNoError(t, err)
 }
@@ -48,7 +75,7 @@ func TestRandomScheduler(t *testing.T) {
 func TestFirstFitSchedulerNothingScheduled(t *testing.T) {
 	mockRegistry := MockPodRegistry{}
 	r := rand.New(rand.NewSource(0))
-	scheduler := MakeFirstFitScheduler([]string{"m1", "m2", "m3"}, &mockRegistry, r)
+	scheduler := MakeFirstFitScheduler(MakeMemoryMinionRegistry([]string{"m1", "m2", "m3"}), &mockRegistry, r)
 	expectSchedule(scheduler, api.Pod{}, "m3", t)
 }
 
@@ -80,7 +107,7 @@ func TestFirstFitSchedulerFirstScheduled(t *testing.T) {
 		},
 	}
 	r := rand.New(rand.NewSource(0))
-	scheduler := MakeFirstFitScheduler([]string{"m1", "m2", "m3"}, &mockRegistry, r)
+	scheduler := MakeFirstFitScheduler(MakeMemoryMinionRegistry([]string{"m1", "m2", "m3"}), &mockRegistry, r)
 	expectSchedule(scheduler, makePod("", 8080), "m3", t)
 }
 
@@ -93,7 +120,7 @@ func TestFirstFitSchedulerFirstScheduledComplicated(t *testing.T) {
 		},
 	}
 	r := rand.New(rand.NewSource(0))
-	scheduler := MakeFirstFitScheduler([]string{"m1", "m2", "m3"}, &mockRegistry, r)
+	scheduler := MakeFirstFitScheduler(MakeMemoryMinionRegistry([]string{"m1", "m2", "m3"}), &mockRegistry, r)
 	expectSchedule(scheduler, makePod("", 8080, 8081), "m3", t)
 }
 
@@ -106,7 +133,7 @@ func TestFirstFitSchedulerFirstScheduledImpossible(t *testing.T) {
 		},
 	}
 	r := rand.New(rand.NewSource(0))
-	scheduler := MakeFirstFitScheduler([]string{"m1", "m2", "m3"}, &mockRegistry, r)
+	scheduler := MakeFirstFitScheduler(MakeMemoryMinionRegistry([]string{"m1", "m2", "m3"}), &mockRegistry, r)
 	_, err := scheduler.Schedule(makePod("", 8080, 8081))
 	if err == nil {
 		t.Error("Unexpected non-error.")
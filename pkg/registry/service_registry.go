@@ -16,36 +16,47 @@ limitations under the License.
 package registry
 
 import (
-	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/validation"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/cloudprovider"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
 type ServiceRegistryStorage struct {
 	registry ServiceRegistry
 	cloud    cloudprovider.Interface
-	hosts    []string
+	minions  MinionRegistry
+	// next counts the requests proxied to services, to spread them over their endpoints.
+	next uint32
 }
 
-func MakeServiceRegistryStorage(registry ServiceRegistry, cloud cloudprovider.Interface, hosts []string) apiserver.RESTStorage {
+func MakeServiceRegistryStorage(registry ServiceRegistry, cloud cloudprovider.Interface, minions MinionRegistry) apiserver.RESTStorage {
 	return &ServiceRegistryStorage{
 		registry: registry,
 		cloud:    cloud,
-		hosts:    hosts,
+		minions:  minions,
 	}
 }
 
+// balancerName returns the name of the external load balancer of service 'id' in 'namespace'.
+// Services of different namespaces may have the same ID, so the name includes the namespace.
+func balancerName(namespace, id string) string {
+	return defaultNamespace(namespace) + "-" + id
+}
+
 // GetServiceEnvironmentVariables populates a list of environment variables that are use
-// in the container environment to get access to services.
-func GetServiceEnvironmentVariables(registry ServiceRegistry, machine string) ([]api.EnvVar, error) {
+// in the container environment to get access to services. Only the services in 'namespace',
+// the namespace of the pod, are included.
+func GetServiceEnvironmentVariables(registry ServiceRegistry, namespace, machine string) ([]api.EnvVar, error) {
 	var result []api.EnvVar
-	services, err := registry.ListServices()
+	services, err := registry.ListServices(defaultNamespace(namespace))
 	if err != nil {
 		return result, err
 	}
@@ -58,8 +69,8 @@ func GetServiceEnvironmentVariables(registry ServiceRegistry, machine string) ([
 	return result, nil
 }
 
-func (sr *ServiceRegistryStorage) List(query labels.Query) (interface{}, error) {
-	list, err := sr.registry.ListServices()
+func (sr *ServiceRegistryStorage) List(namespace string, query labels.Query) (interface{}, error) {
+	list, err := sr.registry.ListServices(namespace)
 	if err != nil {
 		return nil, err
 	}
@@ -74,17 +85,48 @@ func (sr *ServiceRegistryStorage) List(query labels.Query) (interface{}, error)
 	return list, err
 }
 
-func (sr *ServiceRegistryStorage) Get(id string) (interface{}, error) {
-	service, err := sr.registry.GetService(id)
+func (sr *ServiceRegistryStorage) Get(namespace, id string) (interface{}, error) {
+	service, err := sr.registry.GetService(namespace, id)
 	if err != nil {
 		return nil, err
 	}
+	if service == nil {
+		return nil, api.NewNotFound("service", id)
+	}
 	service.Kind = "cluster#service"
 	return service, err
 }
 
-func (sr *ServiceRegistryStorage) Delete(id string) error {
-	svc, err := sr.Get(id)
+// ResourceLocation implements apiserver.ResourceLocator: requests to a service are proxied to
+// its endpoints in turn.
+func (sr *ServiceRegistryStorage) ResourceLocation(namespace, id string) (string, error) {
+	endpoints, err := sr.registry.GetEndpoints(namespace, id)
+	if err != nil {
+		return "", err
+	}
+	if len(endpoints.Endpoints) == 0 {
+		return "", api.NewNotFound("endpoints", id)
+	}
+	next := atomic.AddUint32(&sr.next, 1) - 1
+	return endpoints.Endpoints[int(next%uint32(len(endpoints.Endpoints)))], nil
+}
+
+// Watch implements apiserver.ResourceWatcher.
+func (sr *ServiceRegistryStorage) Watch(namespace string, query labels.Query, resourceVersion uint64) (watch.Interface, error) {
+	w, err := sr.registry.WatchServices(resourceVersion)
+	if err != nil {
+		return nil, err
+	}
+	return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+		svc := event.Object.(api.Service)
+		svc.Kind = "cluster#service"
+		event.Object = svc
+		return event, inNamespace(namespace, svc.Namespace) && query.Matches(labels.Set(svc.Labels))
+	}), nil
+}
+
+func (sr *ServiceRegistryStorage) Delete(namespace, id string) error {
+	svc, err := sr.Get(namespace, id)
 	if err != nil {
 		return err
 	}
@@ -97,45 +139,101 @@ func (sr *ServiceRegistryStorage) Delete(id string) error {
 			}
 		}
 		if balancer != nil {
-			err = balancer.DeleteTCPLoadBalancer(id, "us-central1")
+			name := balancerName(namespace, id)
+			if defaultNamespace(namespace) == api.NamespaceDefault {
+				// Balancers made before namespaces were added are named by the service ID alone.
+				exists, err := balancer.TCPLoadBalancerExists(name, "us-central1")
+				if err != nil {
+					return err
+				}
+				if !exists {
+					name = id
+				}
+			}
+			err = balancer.DeleteTCPLoadBalancer(name, "us-central1")
 			if err != nil {
 				return err
 			}
 		}
 	}
-	return sr.registry.DeleteService(id)
+	return sr.registry.DeleteService(namespace, id)
+}
+
+// Completed implements apiserver.RESTCompleter. A service is done when its external load
+// balancer, if it asked for one, exists.
+func (sr *ServiceRegistryStorage) Completed(obj interface{}) (interface{}, bool, error) {
+	srv := obj.(api.Service)
+	if !srv.CreateExternalLoadBalancer || sr.cloud == nil {
+		return srv, true, nil
+	}
+	balancer, err := sr.cloud.TCPLoadBalancer()
+	if err != nil {
+		return nil, false, err
+	}
+	if balancer == nil {
+		return srv, true, nil
+	}
+	exists, err := balancer.TCPLoadBalancerExists(balancerName(srv.Namespace, srv.ID), "us-central1")
+	return srv, exists, err
 }
 
-func (sr *ServiceRegistryStorage) Extract(body string) (interface{}, error) {
+func (sr *ServiceRegistryStorage) Extract(body string, version string) (interface{}, error) {
 	var svc api.Service
-	err := json.Unmarshal([]byte(body), &svc)
+	if err := api.DecodeVersionInto([]byte(body), version, &svc); err != nil {
+		return nil, api.NewInvalid("service", "", err)
+	}
 	svc.Kind = "cluster#service"
-	return svc, err
+	return svc, nil
 }
 
 func (sr *ServiceRegistryStorage) Create(obj interface{}) error {
 	srv := obj.(api.Service)
-	if srv.CreateExternalLoadBalancer {
-		var balancer cloudprovider.TCPLoadBalancer
-		if sr.cloud != nil {
-			var err error
-			balancer, err = sr.cloud.TCPLoadBalancer()
-			if err != nil {
-				return err
-			}
+	if errs := validation.ValidateService(&srv); len(errs) > 0 {
+		return api.NewInvalidFields("service", srv.ID, errs.Causes())
+	}
+	if !srv.CreateExternalLoadBalancer {
+		return sr.registry.CreateService(srv)
+	}
+	var balancer cloudprovider.TCPLoadBalancer
+	if sr.cloud != nil {
+		var err error
+		balancer, err = sr.cloud.TCPLoadBalancer()
+		if err != nil {
+			return err
 		}
-		if balancer != nil {
-			err := balancer.CreateTCPLoadBalancer(srv.ID, "us-central1", srv.Port, sr.hosts)
-			if err != nil {
-				return err
-			}
-		} else {
-			return fmt.Errorf("requested an external service, but no cloud provider supplied.")
+	}
+	if balancer == nil {
+		return api.NewInvalid("service", srv.ID, fmt.Errorf("requested an external service, but no cloud provider supplied."))
+	}
+	// Check that the service is new before making a balancer that the create would leave behind.
+	existing, err := sr.registry.GetService(srv.Namespace, srv.ID)
+	if err != nil && !api.IsNotFound(err) {
+		return err
+	}
+	if err == nil && existing != nil {
+		return api.NewAlreadyExists("service", srv.ID)
+	}
+	hosts, err := listMinionNames(sr.minions)
+	if err != nil {
+		return err
+	}
+	name := balancerName(srv.Namespace, srv.ID)
+	if err := balancer.CreateTCPLoadBalancer(name, "us-central1", srv.Port, hosts); err != nil {
+		return err
+	}
+	if err := sr.registry.CreateService(srv); err != nil {
+		if deleteErr := balancer.DeleteTCPLoadBalancer(name, "us-central1"); deleteErr != nil {
+			return fmt.Errorf("external load balancer %s is left behind: %v, %v", name, err, deleteErr)
 		}
+		return err
 	}
-	return sr.registry.CreateService(srv)
+	return nil
 }
 
 func (sr *ServiceRegistryStorage) Update(obj interface{}) error {
-	return sr.registry.UpdateService(obj.(api.Service))
+	srv := obj.(api.Service)
+	if errs := validation.ValidateService(&srv); len(errs) > 0 {
+		return api.NewInvalidFields("service", srv.ID, errs.Causes())
+	}
+	return sr.registry.UpdateService(srv)
 }
@@ -0,0 +1,157 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/cloudprovider"
+)
+
+// FakeCloud is a cloud whose load balancers are names in a set.
+type FakeCloud struct {
+	balancers map[string]bool
+	err       error
+}
+
+func (f *FakeCloud) TCPLoadBalancer() (cloudprovider.TCPLoadBalancer, error) {
+	return f, nil
+}
+
+func (f *FakeCloud) Instances() (cloudprovider.Instances, error) {
+	return nil, nil
+}
+
+func (f *FakeCloud) TCPLoadBalancerExists(name, region string) (bool, error) {
+	return f.balancers[name], f.err
+}
+
+func (f *FakeCloud) CreateTCPLoadBalancer(name, region string, port int, hosts []string) error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.balancers[name] {
+		return fmt.Errorf("balancer %s exists", name)
+	}
+	f.balancers[name] = true
+	return nil
+}
+
+func (f *FakeCloud) UpdateTCPLoadBalancer(name, region string, hosts []string) error {
+	return f.err
+}
+
+func (f *FakeCloud) DeleteTCPLoadBalancer(name, region string) error {
+	if f.err != nil {
+		return f.err
+	}
+	if !f.balancers[name] {
+		return fmt.Errorf("balancer %s doesn't exist", name)
+	}
+	delete(f.balancers, name)
+	return nil
+}
+
+func TestServiceResourceLocation(t *testing.T) {
+	registry := MakeMemoryRegistry()
+	registry.UpdateEndpoints(api.Endpoints{Name: "foo", Namespace: "team", Endpoints: []string{"m1:8080", "m2:8080"}})
+	registry.UpdateEndpoints(api.Endpoints{Name: "bar", Namespace: "team"})
+	storage := MakeServiceRegistryStorage(registry, nil, registry).(*ServiceRegistryStorage)
+
+	locations := []string{}
+	for i := 0; i < 3; i++ {
+		location, err := storage.ResourceLocation("team", "foo")
+		expectNoError(t, err)
+		locations = append(locations, location)
+	}
+	if locations[0] != "m1:8080" || locations[1] != "m2:8080" || locations[2] != "m1:8080" {
+		t.Errorf("Unexpected locations: %#v", locations)
+	}
+
+	if _, err := storage.ResourceLocation("team", "bar"); !api.IsNotFound(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if _, err := storage.ResourceLocation(api.NamespaceDefault, "foo"); !api.IsNotFound(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
+
+func TestServiceBalancersInNamespaces(t *testing.T) {
+	registry := MakeMemoryMinionRegistry([]string{"m1"})
+	services := MakeMemoryRegistry()
+	cloud := &FakeCloud{balancers: map[string]bool{}}
+	storage := MakeServiceRegistryStorage(services, cloud, registry)
+	for _, namespace := range []string{api.NamespaceDefault, "team"} {
+		svc := api.Service{JSONBase: api.JSONBase{ID: "foo", Namespace: namespace}, Port: 80, CreateExternalLoadBalancer: true}
+		expectNoError(t, storage.Create(svc))
+	}
+	if len(cloud.balancers) != 2 || !cloud.balancers["default-foo"] || !cloud.balancers["team-foo"] {
+		t.Errorf("Unexpected balancers: %#v", cloud.balancers)
+	}
+	expectNoError(t, storage.Delete("team", "foo"))
+	if len(cloud.balancers) != 1 || !cloud.balancers["default-foo"] {
+		t.Errorf("Unexpected balancers: %#v", cloud.balancers)
+	}
+}
+
+func TestServiceDeleteLegacyBalancer(t *testing.T) {
+	services := MakeMemoryRegistry()
+	services.CreateService(api.Service{JSONBase: api.JSONBase{ID: "foo", Namespace: api.NamespaceDefault}, Port: 80, CreateExternalLoadBalancer: true})
+	cloud := &FakeCloud{balancers: map[string]bool{"foo": true}}
+	storage := MakeServiceRegistryStorage(services, cloud, MakeMemoryMinionRegistry([]string{"m1"}))
+	expectNoError(t, storage.Delete(api.NamespaceDefault, "foo"))
+	if len(cloud.balancers) != 0 {
+		t.Errorf("Unexpected balancers: %#v", cloud.balancers)
+	}
+}
+
+// failingCreateServiceRegistry is a service registry that fails to store services.
+type failingCreateServiceRegistry struct {
+	*MemoryRegistry
+}
+
+func (f failingCreateServiceRegistry) CreateService(svc api.Service) error {
+	return fmt.Errorf("sample error")
+}
+
+func TestServiceCreateBalancerCleanup(t *testing.T) {
+	services := MakeMemoryRegistry()
+	services.CreateService(api.Service{JSONBase: api.JSONBase{ID: "foo", Namespace: api.NamespaceDefault}, Port: 80})
+	cloud := &FakeCloud{balancers: map[string]bool{}}
+	minions := MakeMemoryMinionRegistry([]string{"m1"})
+	svc := api.Service{JSONBase: api.JSONBase{ID: "foo", Namespace: api.NamespaceDefault}, Port: 80, CreateExternalLoadBalancer: true}
+
+	// A service that exists gets no balancer.
+	storage := MakeServiceRegistryStorage(services, cloud, minions)
+	if err := storage.Create(svc); !api.IsAlreadyExists(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if len(cloud.balancers) != 0 {
+		t.Errorf("Unexpected balancers: %#v", cloud.balancers)
+	}
+
+	// A service that can't be stored leaves no balancer behind.
+	storage = MakeServiceRegistryStorage(failingCreateServiceRegistry{MakeMemoryRegistry()}, cloud, minions)
+	if err := storage.Create(svc); err == nil {
+		t.Error("Unexpected non-error")
+	}
+	if len(cloud.balancers) != 0 {
+		t.Errorf("Unexpected balancers: %#v", cloud.balancers)
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"io"
+	"net/http"
+)
+
+// StreamResponse copies 'stream' to the body of 'w' as it is read, flushing after every write so
+// that the client sees output as soon as it's available. It returns when the stream ends or the
+// client goes away, in which case the stream is closed to unblock a pending read.
+func StreamResponse(w http.ResponseWriter, stream io.ReadCloser) error {
+	if notifier, ok := w.(http.CloseNotifier); ok {
+		gone := notifier.CloseNotify()
+		finished := make(chan struct{})
+		defer close(finished)
+		go func() {
+			select {
+			case <-gone:
+				stream.Close()
+			case <-finished:
+			}
+		}()
+	}
+	flusher, _ := w.(http.Flusher)
+	data := make([]byte, 4096)
+	for {
+		n, err := stream.Read(data)
+		if n > 0 {
+			if _, err := w.Write(data[:n]); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
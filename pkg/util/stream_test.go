@@ -0,0 +1,33 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStreamResponse(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	err := StreamResponse(recorder, ioutil.NopCloser(strings.NewReader("booting\nlogin: ")))
+	expectNoError(t, err)
+	if recorder.Body.String() != "booting\nlogin: " || !recorder.Flushed {
+		t.Errorf("Unexpected response: %#v", recorder)
+	}
+}
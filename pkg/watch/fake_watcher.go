@@ -0,0 +1,70 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"sync"
+)
+
+// FakeWatcher is a watch whose events are sent by a test.
+type FakeWatcher struct {
+	result  chan Event
+	lock    sync.Mutex
+	stopped bool
+}
+
+func MakeFakeWatcher() *FakeWatcher {
+	return &FakeWatcher{
+		result: make(chan Event),
+	}
+}
+
+func (f *FakeWatcher) ResultChan() <-chan Event {
+	return f.result
+}
+
+// Stop closes the result channel. It must not be called while an event is being sent.
+func (f *FakeWatcher) Stop() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if !f.stopped {
+		f.stopped = true
+		close(f.result)
+	}
+}
+
+// IsStopped returns true once Stop has been called.
+func (f *FakeWatcher) IsStopped() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.stopped
+}
+
+// Add sends an Added event for 'obj', and blocks until it is received.
+func (f *FakeWatcher) Add(obj interface{}) {
+	f.result <- Event{Added, obj}
+}
+
+// Modify sends a Modified event for 'obj', and blocks until it is received.
+func (f *FakeWatcher) Modify(obj interface{}) {
+	f.result <- Event{Modified, obj}
+}
+
+// Delete sends a Deleted event for 'obj', and blocks until it is received.
+func (f *FakeWatcher) Delete(obj interface{}) {
+	f.result <- Event{Deleted, obj}
+}
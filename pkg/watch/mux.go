@@ -0,0 +1,107 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"sync"
+)
+
+// Mux broadcasts the events passed to Action to every watch made with Watch. Watches only
+// see the events that happen after they are made. A watch that falls behind is closed, so
+// that it can't hold up Action or the other watches.
+type Mux struct {
+	lock        sync.Mutex
+	watchers    map[int]*muxWatcher
+	nextID      int
+	queueLength int
+}
+
+// MakeMux creates a Mux that queues up to 'queueLength' events for each watch. A watch with
+// a full queue is closed by the next Action.
+func MakeMux(queueLength int) *Mux {
+	return &Mux{
+		watchers:    map[int]*muxWatcher{},
+		queueLength: queueLength,
+	}
+}
+
+// Watch returns a watch of the events passed to Action from now on.
+func (m *Mux) Watch() Interface {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	id := m.nextID
+	m.nextID++
+	w := &muxWatcher{
+		result: make(chan Event, m.queueLength),
+		id:     id,
+		m:      m,
+	}
+	m.watchers[id] = w
+	return w
+}
+
+// Action sends an event of type 'action' for 'obj' to all watches. It never blocks: watches
+// whose queue is full are closed instead, and their clients must watch again.
+func (m *Mux) Action(action EventType, obj interface{}) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	event := Event{action, obj}
+	for id, w := range m.watchers {
+		select {
+		case w.result <- event:
+		default:
+			delete(m.watchers, id)
+			close(w.result)
+		}
+	}
+}
+
+// Shutdown closes all watches.
+func (m *Mux) Shutdown() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for id, w := range m.watchers {
+		delete(m.watchers, id)
+		close(w.result)
+	}
+}
+
+func (m *Mux) stopWatching(id int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if w, ok := m.watchers[id]; ok {
+		delete(m.watchers, id)
+		close(w.result)
+	}
+}
+
+type muxWatcher struct {
+	result   chan Event
+	stopOnce sync.Once
+	id       int
+	m        *Mux
+}
+
+func (w *muxWatcher) ResultChan() <-chan Event {
+	return w.result
+}
+
+func (w *muxWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		w.m.stopWatching(w.id)
+	})
+}
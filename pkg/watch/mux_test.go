@@ -0,0 +1,84 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestMux(t *testing.T) {
+	m := MakeMux(3)
+	expected := []Event{{Added, "foo"}, {Modified, "foo"}, {Deleted, "bar"}}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		w := m.Watch()
+		wg.Add(1)
+		go func(w Interface) {
+			defer wg.Done()
+			events := []Event{}
+			for event := range w.ResultChan() {
+				events = append(events, event)
+			}
+			if !reflect.DeepEqual(expected, events) {
+				t.Errorf("Expected %#v, got %#v", expected, events)
+			}
+		}(w)
+	}
+	for _, event := range expected {
+		m.Action(event.Type, event.Object)
+	}
+	m.Shutdown()
+	wg.Wait()
+}
+
+func TestMuxStoppedWatcher(t *testing.T) {
+	m := MakeMux(1)
+	stopped := m.Watch()
+	w := m.Watch()
+	stopped.Stop()
+	m.Action(Added, "foo")
+	event := <-w.ResultChan()
+	if event.Type != Added || event.Object != "foo" {
+		t.Errorf("Unexpected event: %#v", event)
+	}
+	if _, ok := <-stopped.ResultChan(); ok {
+		t.Errorf("Expected the stopped watch to be closed")
+	}
+	m.Shutdown()
+}
+
+func TestMuxSlowWatcher(t *testing.T) {
+	m := MakeMux(1)
+	slow := m.Watch()
+	m.Action(Added, "foo")
+	m.Action(Modified, "foo")
+	w := m.Watch()
+	m.Action(Deleted, "foo")
+	if event, ok := <-slow.ResultChan(); !ok || event.Type != Added {
+		t.Errorf("Unexpected event: %#v", event)
+	}
+	if _, ok := <-slow.ResultChan(); ok {
+		t.Errorf("Expected the slow watch to be closed")
+	}
+	if event := <-w.ResultChan(); event.Type != Deleted {
+		t.Errorf("Unexpected event: %#v", event)
+	}
+	slow.Stop()
+	m.Shutdown()
+}
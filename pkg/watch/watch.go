@@ -0,0 +1,93 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"sync"
+)
+
+// Interface is implemented by anything that reports changes to a set of objects.
+type Interface interface {
+	// ResultChan returns the channel the events are delivered on. It is closed when the
+	// watch is stopped or fails.
+	ResultChan() <-chan Event
+	// Stop ends the watch and releases its resources.
+	Stop()
+}
+
+// EventType is the kind of change an Event reports.
+type EventType string
+
+const (
+	Added    EventType = "ADDED"
+	Modified EventType = "MODIFIED"
+	Deleted  EventType = "DELETED"
+)
+
+// Event is a single change to an object.
+type Event struct {
+	Type EventType
+	// Object is the object after the change, or its last state if it was deleted.
+	Object interface{}
+}
+
+// FilterFunc decides whether an event is passed on by a filtered watch, and may change it.
+type FilterFunc func(in Event) (out Event, keep bool)
+
+type filteredWatch struct {
+	incoming Interface
+	result   chan Event
+	filter   FilterFunc
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// Filter returns a watch that passes on the events of 'w' for which 'filter' returns true,
+// as changed by 'filter'. Stopping it stops 'w'.
+func Filter(w Interface, filter FilterFunc) Interface {
+	fw := &filteredWatch{
+		incoming: w,
+		result:   make(chan Event),
+		filter:   filter,
+		stopped:  make(chan struct{}),
+	}
+	go fw.loop()
+	return fw
+}
+
+func (fw *filteredWatch) ResultChan() <-chan Event {
+	return fw.result
+}
+
+func (fw *filteredWatch) Stop() {
+	fw.stopOnce.Do(func() {
+		close(fw.stopped)
+		fw.incoming.Stop()
+	})
+}
+
+func (fw *filteredWatch) loop() {
+	defer close(fw.result)
+	for event := range fw.incoming.ResultChan() {
+		if event, keep := fw.filter(event); keep {
+			select {
+			case fw.result <- event:
+			case <-fw.stopped:
+			}
+		}
+	}
+}
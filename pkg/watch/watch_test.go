@@ -0,0 +1,57 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	source := MakeFakeWatcher()
+	filtered := Filter(source, func(e Event) (Event, bool) {
+		name := e.Object.(string)
+		e.Object = name + "!"
+		return e, name != "skip"
+	})
+	go func() {
+		source.Add("foo")
+		source.Modify("skip")
+		source.Delete("bar")
+		source.Stop()
+	}()
+	events := []Event{}
+	for event := range filtered.ResultChan() {
+		events = append(events, event)
+	}
+	expected := []Event{{Added, "foo!"}, {Deleted, "bar!"}}
+	if !reflect.DeepEqual(expected, events) {
+		t.Errorf("Expected %#v, got %#v", expected, events)
+	}
+}
+
+func TestFilterStop(t *testing.T) {
+	source := MakeFakeWatcher()
+	filtered := Filter(source, func(e Event) (Event, bool) { return e, true })
+	filtered.Stop()
+	if !source.IsStopped() {
+		t.Errorf("Expected stopping the filter to stop its source")
+	}
+	if _, ok := <-filtered.ResultChan(); ok {
+		t.Errorf("Expected the result channel to be closed")
+	}
+}